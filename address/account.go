@@ -0,0 +1,145 @@
+package address
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+// BIP43 purposes of the supported single key account layouts.
+const (
+	PurposeBIP44 uint32 = 44 // P2PKH
+	PurposeBIP49 uint32 = 49 // P2WPKH nested in P2SH
+	PurposeBIP84 uint32 = 84 // P2WPKH
+	PurposeBIP86 uint32 = 86 // P2TR key path only
+)
+
+// Purpose returns the BIP43 purpose for addresses of addrType.
+func Purpose(addrType types.AddrType) (uint32, error) {
+	switch addrType {
+	case types.P2PKH:
+		return PurposeBIP44, nil
+	case types.P2WPKH_NESTED:
+		return PurposeBIP49, nil
+	case types.P2WPKH:
+		return PurposeBIP84, nil
+	case types.P2TR:
+		return PurposeBIP86, nil
+	default:
+		return 0, fmt.Errorf("no account layout for address type %s", addrType)
+	}
+}
+
+// Account is a BIP44 style account m/purpose'/coin_type'/account' whose
+// addresses are derived at .../0/i (receive) and .../1/i (change).
+type Account struct {
+	key      *types.HDKey
+	addrType types.AddrType
+	params   *chaincfg.Params
+}
+
+// DerivedAddress is one address of an account together with its key.
+type DerivedAddress struct {
+	Address string
+	Key     *types.HDKey
+}
+
+// Origin returns the BIP32 origin of the address key, for
+// transaction.TxBuilder.KeyOrigin.
+func (d *DerivedAddress) Origin() (*types.KeyOrigin, error) {
+	return d.Key.Origin()
+}
+
+// NewAccount derives account number index for addrType from a BIP32 seed.
+// The coin type is the one of net (0 on mainnet, 1 on test networks).
+func NewAccount(seed []byte, net types.Network, addrType types.AddrType, index uint32) (*Account, error) {
+	purpose, err := Purpose(addrType)
+	if err != nil {
+		return nil, err
+	}
+	params := types.GetParams(net)
+	master, err := types.NewHDKeyFromSeed(seed, params)
+	if err != nil {
+		return nil, err
+	}
+	key, err := master.Derive(
+		purpose+types.HardenedKeyStart,
+		params.HDCoinType+types.HardenedKeyStart,
+		index+types.HardenedKeyStart,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{key: key, addrType: addrType, params: params}, nil
+}
+
+// NewAccountFromMnemonic is NewAccount for a BIP39 mnemonic and passphrase.
+func NewAccountFromMnemonic(mnemonic string, passphrase string, net types.Network, addrType types.AddrType, index uint32) (*Account, error) {
+	seed, err := MnemonicToSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return NewAccount(seed, net, addrType, index)
+}
+
+// NewWatchOnlyAccount wraps an account level extended public key, e.g. one
+// exported by a hardware wallet. Its addresses can be derived but not signed
+// for.
+func NewWatchOnlyAccount(key *types.HDKey, net types.Network, addrType types.AddrType) (*Account, error) {
+	if _, err := Purpose(addrType); err != nil {
+		return nil, err
+	}
+	key, err := key.Neuter()
+	if err != nil {
+		return nil, err
+	}
+	return &Account{key: key, addrType: addrType, params: types.GetParams(net)}, nil
+}
+
+// Key returns the account level extended key.
+func (a *Account) Key() *types.HDKey { return a.key }
+
+func (a *Account) AddrType() types.AddrType { return a.addrType }
+
+// ReceiveAddress returns the i-th address of the external chain.
+func (a *Account) ReceiveAddress(i uint32) (*DerivedAddress, error) {
+	return a.DeriveAddress(false, i)
+}
+
+// ChangeAddress returns the i-th address of the internal chain.
+func (a *Account) ChangeAddress(i uint32) (*DerivedAddress, error) {
+	return a.DeriveAddress(true, i)
+}
+
+func (a *Account) DeriveAddress(change bool, i uint32) (*DerivedAddress, error) {
+	var chain uint32
+	if change {
+		chain = 1
+	}
+	key, err := a.key.Derive(chain, i)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := key.PubKey()
+	if err != nil {
+		return nil, err
+	}
+	if a.addrType == types.P2TR {
+		// BIP86: the output key commits to the derived key and no scripts
+		internal, err := btcec.ParsePubKey(pub)
+		if err != nil {
+			return nil, err
+		}
+		pub = schnorr.SerializePubKey(txscript.ComputeTaprootKeyNoScript(internal))
+	}
+	addr, err := types.PubKeyToAddr(pub, a.addrType, a.params)
+	if err != nil {
+		return nil, err
+	}
+	return &DerivedAddress{Address: addr, Key: key}, nil
+}
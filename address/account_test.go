@@ -0,0 +1,95 @@
+package address
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+// Test vectors of BIP44 (community), BIP49, BIP84 and BIP86 for testMnemonic.
+func TestAccount_BIPVectors(t *testing.T) {
+	tests := []struct {
+		name     string
+		net      types.Network
+		addrType types.AddrType
+		receive  []string
+		change   string
+	}{
+		{
+			name:     "bip44",
+			net:      types.BTC,
+			addrType: types.P2PKH,
+			receive:  []string{"1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA", "1Ak8PffB2meyfYnbXZR9EGfLfFZVpzJvQP"},
+			change:   "1J3J6EvPrv8q6AC3VCjWV45Uf3nssNMRtH",
+		},
+		{
+			name:     "bip49",
+			net:      types.BTC_Testnet3,
+			addrType: types.P2WPKH_NESTED,
+			receive:  []string{"2Mww8dCYPUpKHofjgcXcBCEGmniw9CoaiD2"},
+		},
+		{
+			name:     "bip84",
+			net:      types.BTC,
+			addrType: types.P2WPKH,
+			receive:  []string{"bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", "bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g"},
+			change:   "bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el",
+		},
+		{
+			name:     "bip86",
+			net:      types.BTC,
+			addrType: types.P2TR,
+			receive:  []string{"bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr", "bc1p4qhjn9zdvkux4e44uhx8tc55attvtyu358kutcqkudyccelu0was9fqzwh"},
+			change:   "bc1p3qkhfews2uk44qtvauqyr2ttdsw7svhkl9nkm9s9c3x4ax5h60wqwruhk7",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			account, err := NewAccountFromMnemonic(testMnemonic, "", tc.net, tc.addrType, 0)
+			require.NoError(t, err)
+
+			for i, want := range tc.receive {
+				addr, err := account.ReceiveAddress(uint32(i))
+				require.NoError(t, err)
+				require.Equal(t, want, addr.Address)
+			}
+			if tc.change != "" {
+				addr, err := account.ChangeAddress(0)
+				require.NoError(t, err)
+				require.Equal(t, tc.change, addr.Address)
+			}
+		})
+	}
+}
+
+func TestAccount_OriginAndWatchOnly(t *testing.T) {
+	account, err := NewAccountFromMnemonic(testMnemonic, "", types.BTC_Signet, types.P2WPKH, 1)
+	require.NoError(t, err)
+
+	addr, err := account.ChangeAddress(3)
+	require.NoError(t, err)
+	origin, err := addr.Origin()
+	require.NoError(t, err)
+	require.Equal(t, "[73c5da0a/84'/1'/1'/1/3]", origin.String())
+
+	// the account xpub derives the same addresses
+	watch, err := NewWatchOnlyAccount(account.Key(), types.BTC_Signet, types.P2WPKH)
+	require.NoError(t, err)
+	watchAddr, err := watch.ChangeAddress(3)
+	require.NoError(t, err)
+	require.Equal(t, addr.Address, watchAddr.Address)
+	watchOrigin, err := watchAddr.Origin()
+	require.NoError(t, err)
+	require.Equal(t, origin, watchOrigin)
+
+	_, err = watchAddr.Key.ECDSASigner()
+	require.Error(t, err)
+
+	_, err = NewAccountFromMnemonic(testMnemonic, "", types.BTC, types.P2WSH, 0)
+	require.Error(t, err)
+}
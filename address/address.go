@@ -1,33 +1,40 @@
-package address
-
-import (
-	"encoding/hex"
-
-	"github.com/gosuda/btctxbuilder/types"
-)
-
-func GenerateAddress(addrType types.AddrType) (privHex string, pubHex string, addr string, err error) {
-	var priv, pub []byte
-	switch addrType {
-	case types.P2TR:
-		signer, err := types.NewSchnorrSigner("")
-		if err != nil {
-			return "", "", "", err
-		}
-		priv = signer.PrivKey()
-		pub = signer.PubKey()
-	case types.P2PK, types.P2PKH, types.P2WPKH, types.P2SH, types.P2WPKH_NESTED:
-		signer, err := types.NewECDSASigner("")
-		if err != nil {
-			return "", "", "", err
-		}
-		priv = signer.PrivateKey()
-		pub = signer.PubKey()
-	}
-
-	addr, err = types.PubKeyToAddr(pub, addrType, types.GetParams(types.BTC_Testnet3))
-	if err != nil {
-		return "", "", "", err
-	}
-	return hex.EncodeToString(priv), hex.EncodeToString(pub), addr, nil
-}
+package address
+
+import (
+	"encoding/hex"
+
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+// GenerateAddress is GenerateNetworkAddress on testnet3.
+func GenerateAddress(addrType types.AddrType) (privHex string, pubHex string, addr string, err error) {
+	return GenerateNetworkAddress(types.BTC_Testnet3, addrType)
+}
+
+// GenerateNetworkAddress creates a fresh random key and its addrType address
+// on net. Use NewAccountFromMnemonic for keys that can be recovered.
+func GenerateNetworkAddress(net types.Network, addrType types.AddrType) (privHex string, pubHex string, addr string, err error) {
+	var priv, pub []byte
+	switch addrType {
+	case types.P2TR:
+		signer, err := types.NewSchnorrSigner("")
+		if err != nil {
+			return "", "", "", err
+		}
+		priv = signer.PrivKey()
+		pub = signer.PubKey()
+	case types.P2PK, types.P2PKH, types.P2WPKH, types.P2SH, types.P2WPKH_NESTED:
+		signer, err := types.NewECDSASigner("")
+		if err != nil {
+			return "", "", "", err
+		}
+		priv = signer.PrivateKey()
+		pub = signer.PubKey()
+	}
+
+	addr, err = types.PubKeyToAddr(pub, addrType, types.GetParams(net))
+	if err != nil {
+		return "", "", "", err
+	}
+	return hex.EncodeToString(priv), hex.EncodeToString(pub), addr, nil
+}
@@ -0,0 +1,128 @@
+package address
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+//go:embed english.txt
+var englishWordlist string
+
+var (
+	wordList  = strings.Fields(englishWordlist)
+	wordIndex = func() map[string]int {
+		m := make(map[string]int, len(wordList))
+		for i, w := range wordList {
+			m[w] = i
+		}
+		return m
+	}()
+)
+
+var (
+	ErrInvalidEntropy  = errors.New("entropy must be 128 to 256 bits in steps of 32")
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+	ErrChecksum        = errors.New("mnemonic checksum mismatch")
+)
+
+// GenerateMnemonic returns a random BIP39 mnemonic of the given number of
+// words: 12, 15, 18, 21 or 24.
+func GenerateMnemonic(words int) (string, error) {
+	if words%3 != 0 {
+		return "", ErrInvalidEntropy
+	}
+	entropy := make([]byte, words/3*4)
+	if err := checkEntropy(entropy); err != nil {
+		return "", err
+	}
+	if _, err := rand.Read(entropy); err != nil {
+		return "", err
+	}
+	return NewMnemonic(entropy)
+}
+
+// NewMnemonic encodes entropy as a BIP39 mnemonic in the English word list.
+func NewMnemonic(entropy []byte) (string, error) {
+	if err := checkEntropy(entropy); err != nil {
+		return "", err
+	}
+
+	// entropy || checksum, read 11 bits per word
+	csBits := len(entropy) / 4
+	hash := sha256.Sum256(entropy)
+	n := new(big.Int).SetBytes(entropy)
+	n.Lsh(n, uint(csBits))
+	n.Or(n, big.NewInt(int64(hash[0]>>(8-csBits))))
+
+	count := (len(entropy)*8 + csBits) / 11
+	words := make([]string, count)
+	mask := big.NewInt(2047)
+	idx := new(big.Int)
+	for i := count - 1; i >= 0; i-- {
+		idx.And(n, mask)
+		words[i] = wordList[idx.Int64()]
+		n.Rsh(n, 11)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// MnemonicToEntropy decodes a mnemonic, verifying its words and checksum.
+func MnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	if len(words)%3 != 0 || len(words) < 12 || len(words) > 24 {
+		return nil, fmt.Errorf("%w: %d words", ErrInvalidMnemonic, len(words))
+	}
+
+	n := new(big.Int)
+	for _, w := range words {
+		i, ok := wordIndex[w]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown word %q", ErrInvalidMnemonic, w)
+		}
+		n.Lsh(n, 11)
+		n.Or(n, big.NewInt(int64(i)))
+	}
+
+	csBits := len(words) / 3
+	checksum := new(big.Int).And(n, big.NewInt(1<<csBits-1)).Int64()
+	n.Rsh(n, uint(csBits))
+	entropy := n.FillBytes(make([]byte, len(words)/3*4))
+
+	hash := sha256.Sum256(entropy)
+	if int64(hash[0]>>(8-csBits)) != checksum {
+		return nil, ErrChecksum
+	}
+	return entropy, nil
+}
+
+// ValidateMnemonic reports why mnemonic is not a valid BIP39 mnemonic, or nil.
+func ValidateMnemonic(mnemonic string) error {
+	_, err := MnemonicToEntropy(mnemonic)
+	return err
+}
+
+// MnemonicToSeed validates mnemonic and stretches it with the optional
+// passphrase into the 64-byte BIP32 seed. A passphrase outside ASCII must be
+// NFKD normalised by the caller.
+func MnemonicToSeed(mnemonic string, passphrase string) ([]byte, error) {
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	normalized := strings.Join(strings.Fields(mnemonic), " ")
+	return pbkdf2.Key(sha512.New, normalized, []byte("mnemonic"+passphrase), 2048, 64)
+}
+
+func checkEntropy(entropy []byte) error {
+	bits := len(entropy) * 8
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return ErrInvalidEntropy
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+// Backend is a source of chain data and a way to relay transactions.
+// Transactions are passed and returned hex encoded.
+type Backend interface {
+	GetParams() *chaincfg.Params
+
+	// BestBlockHeight returns the height of the chain tip.
+	BestBlockHeight(ctx context.Context) (uint64, error)
+	// FeeEstimate maps confirmation targets in blocks, e.g. "6", to fee
+	// rates in sat/vB.
+	FeeEstimate(ctx context.Context) (types.FeeEstimate, error)
+
+	// GetUTXO returns the unspent outputs of address, GetUTXOWithRawTx
+	// also the transactions creating them.
+	GetUTXO(ctx context.Context, address string) ([]*types.Utxo, error)
+	GetUTXOWithRawTx(ctx context.Context, address string) ([]*types.Utxo, error)
+
+	GetRawTx(ctx context.Context, txid string) (string, error)
+	// GetTxStatus returns whether and where txid confirmed.
+	GetTxStatus(ctx context.Context, txid string) (*types.BlockStatus, error)
+	// BroadcastTx relays rawTx and returns its txid.
+	BroadcastTx(ctx context.Context, rawTx string) (string, error)
+}
+
+var (
+	_ Backend = (*Client)(nil)
+	_ Backend = (*MempoolClient)(nil)
+	_ Backend = (*RPCClient)(nil)
+	_ Backend = (*ElectrumClient)(nil)
+)
+
+// withRawTx fetches from b the transaction creating each of utxos, each
+// once and up to concurrency at a time. The first failure cancels the
+// fetches left.
+func withRawTx(ctx context.Context, b Backend, utxos []*types.Utxo, concurrency int) ([]*types.Utxo, error) {
+	byTxid := make(map[string][]*types.Utxo)
+	var txids []string
+	for _, utxo := range utxos {
+		if _, ok := byTxid[utxo.Txid]; !ok {
+			txids = append(txids, utxo.Txid)
+		}
+		byTxid[utxo.Txid] = append(byTxid[utxo.Txid], utxo)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	txs := make([]*wire.MsgTx, len(txids))
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+	sem := make(chan struct{}, max(concurrency, 1))
+	for i, txid := range txids {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			fail(ctx.Err())
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			rawTx, err := b.GetRawTx(ctx, txid)
+			if err != nil {
+				fail(err)
+				return
+			}
+			txs[i], err = types.DecodeRawTransaction(rawTx)
+			if err != nil {
+				fail(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	for i, txid := range txids {
+		for _, utxo := range byTxid[txid] {
+			utxo.RawTx = txs[i]
+		}
+	}
+	return utxos, nil
+}
@@ -0,0 +1,239 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosuda/btctxbuilder/types"
+	"github.com/gosuda/btctxbuilder/utils"
+)
+
+const testAddress = "tb1q307vt2zz3f66hhs90p0le2pp6r53tvyqnzsy42"
+
+// testRawTx is a transaction paying 5000 sats, hex encoded, and its txid.
+func testRawTx(t *testing.T) (string, string) {
+	t.Helper()
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(5000, []byte{0x51}))
+	var buf bytes.Buffer
+	require.NoError(t, tx.Serialize(&buf))
+	return utils.HexEncode(buf.Bytes()), tx.TxID()
+}
+
+func TestEsploraClient(t *testing.T) {
+	ctx := context.Background()
+	rawTx, txid := testRawTx(t)
+	var broadcast string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/blocks/tip/height", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "850000")
+	})
+	mux.HandleFunc("GET /api/fee-estimates", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"1": 20.5, "6": 8.1, "144": 1.0}`)
+	})
+	mux.HandleFunc("GET /api/address/{addr}/utxo", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, testAddress, r.PathValue("addr"))
+		io.WriteString(w, `[{"txid": "`+txid+`", "vout": 0, "value": 5000, "status": {"confirmed": true, "block_height": 849990}}]`)
+	})
+	mux.HandleFunc("GET /api/tx/{txid}/raw", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, rawTx)
+	})
+	mux.HandleFunc("GET /api/tx/{txid}/status", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"confirmed": true, "block_height": 849990, "block_hash": "00ab", "block_time": 1700000000}`)
+	})
+	mux.HandleFunc("POST /api/tx", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		broadcast = string(body)
+		io.WriteString(w, txid)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var backend Backend = NewEsploraClient(&chaincfg.SigNetParams, server.URL+"/api/")
+	require.Equal(t, &chaincfg.SigNetParams, backend.GetParams())
+
+	height, err := backend.BestBlockHeight(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(850000), height)
+
+	fees, err := backend.FeeEstimate(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 8.1, fees["6"])
+
+	utxos, err := backend.GetUTXOWithRawTx(ctx, testAddress)
+	require.NoError(t, err)
+	require.Len(t, utxos, 1)
+	require.Equal(t, int64(5000), utxos[0].Value)
+	require.Equal(t, 849990, utxos[0].Status.BlockHeight)
+	require.Equal(t, txid, utxos[0].RawTx.TxID())
+
+	status, err := backend.GetTxStatus(ctx, txid)
+	require.NoError(t, err)
+	require.Equal(t, &types.BlockStatus{Confirmed: true, BlockHeight: 849990, BlockHash: "00ab", BlockTime: 1700000000}, status)
+
+	sent, err := backend.BroadcastTx(ctx, rawTx)
+	require.NoError(t, err)
+	require.Equal(t, txid, sent)
+	require.Equal(t, rawTx, broadcast)
+}
+
+func TestMempoolClient(t *testing.T) {
+	ctx := context.Background()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/fees/recommended", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"fastestFee": 30, "halfHourFee": 20, "hourFee": 12, "economyFee": 4, "minimumFee": 1}`)
+	})
+	mux.HandleFunc("GET /api/blocks/tip/height", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "850001")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var backend Backend = NewMempoolClientWithURL(&chaincfg.MainNetParams, server.URL+"/api")
+	fees, err := backend.FeeEstimate(ctx)
+	require.NoError(t, err)
+	require.Equal(t, types.FeeEstimate{"1": 30, "3": 20, "6": 12, "144": 4}, fees)
+
+	// everything else is the Esplora API
+	height, err := backend.BestBlockHeight(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(850001), height)
+
+	_, err = NewMempoolClient(types.BTC_Regressionnet)
+	require.Error(t, err)
+}
+
+// rpcServer stands in for bitcoind, answering each method with the result
+// of handle, or with the error it returns.
+func rpcServer(t *testing.T, handle func(path, method string, params []json.RawMessage) (any, *RPCError)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "user" || password != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			ID     uint64            `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		result, rpcErr := handle(r.URL.Path, req.Method, req.Params)
+		if rpcErr != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"result": result, "error": rpcErr, "id": req.ID})
+	}))
+}
+
+func TestRPCClient(t *testing.T) {
+	ctx := context.Background()
+	rawTx, txid := testRawTx(t)
+	var sent []string
+	server := rpcServer(t, func(path, method string, params []json.RawMessage) (any, *RPCError) {
+		switch method {
+		case "getblockcount":
+			return 200, nil
+		case "estimatesmartfee":
+			switch string(params[0]) {
+			case "1":
+				return map[string]any{"errors": []string{"Insufficient data or no feerate found"}, "blocks": 1}, nil
+			case "6":
+				return map[string]any{"feerate": 0.00012, "blocks": 6}, nil
+			default:
+				return map[string]any{"feerate": 0.00002, "blocks": 144}, nil
+			}
+		case "scantxoutset":
+			require.JSONEq(t, `["addr(`+testAddress+`)"]`, string(params[1]))
+			return map[string]any{"unspents": []map[string]any{
+				{"txid": txid, "vout": 0, "amount": 0.00005, "height": 190},
+			}}, nil
+		case "listunspent":
+			require.Equal(t, "/wallet/hot", path)
+			return []map[string]any{
+				{"txid": txid, "vout": 0, "amount": 0.00005, "confirmations": 11},
+				{"txid": txid, "vout": 1, "amount": 0.001, "confirmations": 0},
+			}, nil
+		case "getrawtransaction":
+			if string(params[1]) == "true" {
+				return map[string]any{"txid": txid, "blockhash": "00cd", "blocktime": 1700000000, "confirmations": 11}, nil
+			}
+			return rawTx, nil
+		case "getblockheader":
+			return map[string]any{"hash": "00cd", "height": 190}, nil
+		case "testmempoolaccept":
+			var txs []string
+			require.NoError(t, json.Unmarshal(params[0], &txs))
+			if txs[0] != rawTx {
+				return []map[string]any{{"txid": "", "allowed": false, "reject-reason": "bad-txns-inputs-missingorspent"}}, nil
+			}
+			return []map[string]any{{"txid": txid, "allowed": true}}, nil
+		case "sendrawtransaction":
+			sent = append(sent, string(params[0]))
+			return txid, nil
+		}
+		return nil, &RPCError{Code: -32601, Message: "Method not found"}
+	})
+	defer server.Close()
+
+	c := NewRPCClient(&chaincfg.RegressionNetParams, server.URL, "user", "pass")
+	var backend Backend = c
+
+	height, err := backend.BestBlockHeight(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(200), height)
+
+	// BTC/kvB to sat/vB, without the target lacking data
+	fees, err := backend.FeeEstimate(ctx)
+	require.NoError(t, err)
+	require.Len(t, fees, 3)
+	require.InDelta(t, 12.0, fees["6"], 1e-9)
+	require.InDelta(t, 2.0, fees["144"], 1e-9)
+
+	utxos, err := backend.GetUTXOWithRawTx(ctx, testAddress)
+	require.NoError(t, err)
+	require.Len(t, utxos, 1)
+	require.Equal(t, int64(5000), utxos[0].Value)
+	require.Equal(t, types.BlockStatus{Confirmed: true, BlockHeight: 190}, utxos[0].Status)
+	require.Equal(t, txid, utxos[0].RawTx.TxID())
+
+	utxos, err = c.Wallet("hot").GetUTXO(ctx, testAddress)
+	require.NoError(t, err)
+	require.Len(t, utxos, 2)
+	require.Equal(t, types.BlockStatus{Confirmed: true, BlockHeight: 190}, utxos[0].Status)
+	require.Equal(t, int64(100000), utxos[1].Value)
+	require.False(t, utxos[1].Status.Confirmed)
+
+	status, err := backend.GetTxStatus(ctx, txid)
+	require.NoError(t, err)
+	require.Equal(t, &types.BlockStatus{Confirmed: true, BlockHeight: 190, BlockHash: "00cd", BlockTime: 1700000000}, status)
+
+	got, err := backend.BroadcastTx(ctx, rawTx)
+	require.NoError(t, err)
+	require.Equal(t, txid, got)
+	require.Equal(t, []string{`"` + rawTx + `"`}, sent)
+
+	// a rejected transaction never reaches sendrawtransaction
+	_, err = backend.BroadcastTx(ctx, "00")
+	require.ErrorContains(t, err, "bad-txns-inputs-missingorspent")
+	require.Len(t, sent, 1)
+
+	_, err = RequestRPC[any](ctx, c, "", "getmempoolinfo")
+	var rpcErr *RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	require.Equal(t, -32601, rpcErr.Code)
+
+	_, err = NewRPCClient(&chaincfg.RegressionNetParams, server.URL, "user", "wrong").BestBlockHeight(ctx)
+	require.ErrorContains(t, err, "401")
+}
@@ -1,135 +1,134 @@
-package client
-
-import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
-
-	"github.com/btcsuite/btcd/chaincfg"
-	"github.com/gosuda/btctxbuilder/types"
-)
-
-const (
-	// https://github.com/blockstream/esplora/blob/master/API.md
-	ClientURL = "https://blockstream.info"
-
-	//
-	// ClientURL = "https://mempool.space"
-)
-
-func NewClient(net types.Network) (*Client, error) {
-	client := &Client{
-		http:   http.DefaultClient,
-		params: types.GetParams(net),
-	}
-
-	switch net {
-	case types.BTC:
-		client.params = &chaincfg.MainNetParams
-		client.url = ClientURL + "/api"
-	case types.BTC_Testnet3:
-		client.params = &chaincfg.TestNet3Params
-		client.url = ClientURL + "/testnet/api"
-	case types.BTC_Testnet4:
-		client.params = &chaincfg.TestNet4Params
-		client.url = ClientURL + "/testnet4/api"
-	case types.BTC_Signet:
-		client.params = &chaincfg.SigNetParams
-		client.url = ClientURL + "/signet/api"
-	default:
-		return nil, fmt.Errorf("network not supported [%s]", net)
-	}
-
-	return client, nil
-}
-
-type Client struct {
-	params *chaincfg.Params
-	url    string
-
-	http *http.Client
-}
-
-func (t *Client) GetParams() *chaincfg.Params {
-	return t.params
-}
-
-func (t *Client) Close() {
-	if t.http == nil {
-		return
-	}
-	t.http = nil
-}
-
-func RequestGet[T any](client *Client, endpoint string) (T, error) {
-	fullURL := fmt.Sprintf("%s%s", client.url, endpoint)
-	resp, err := client.http.Get(fullURL)
-	if err != nil {
-		return *new(T), fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return *new(T), fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	var result T
-	if _, ok := any(result).(string); ok {
-		return any(string(body)).(T), nil
-	}
-	if json.Valid(body) {
-		if err := json.Unmarshal(body, &result); err != nil {
-			return *new(T), fmt.Errorf("failed to unmarshal response: %w", err)
-		}
-		return result, nil
-	}
-
-	// Non-JSON response, only supported for strings
-	return *new(T), fmt.Errorf("non-JSON response cannot be parsed into %T | res : %s", result, body)
-}
-
-func RequestPost[T any](client *Client, endpoint string, payload interface{}) (T, error) {
-	fullURL := fmt.Sprintf("%s%s", client.url, endpoint)
-
-	var err error
-	var resp *http.Response
-	if payloadStr, ok := payload.(string); ok {
-		resp, err = client.http.Post(fullURL, "text/plain", bytes.NewBuffer([]byte(payloadStr)))
-		if err != nil {
-			return *new(T), fmt.Errorf("failed to make request: %w", err)
-		}
-	} else {
-		jsonData, err := json.Marshal(payload)
-		if err != nil {
-			return *new(T), fmt.Errorf("failed to marshal payload: %w", err)
-		}
-		resp, err = client.http.Post(fullURL, "application/json", bytes.NewBuffer(jsonData))
-		if err != nil {
-			return *new(T), fmt.Errorf("failed to make request: %w", err)
-		}
-	}
-
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return *new(T), fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	var result T
-	if _, ok := any(result).(string); ok {
-		return any(string(body)).(T), nil
-	}
-	if json.Valid(body) {
-		if err := json.Unmarshal(body, &result); err != nil {
-			return *new(T), fmt.Errorf("failed to unmarshal response: %w", err)
-		}
-		return result, nil
-	}
-
-	// Non-JSON response, only supported for strings
-	return *new(T), fmt.Errorf("non-JSON response cannot be parsed into %T", result)
-}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+const (
+	// https://github.com/blockstream/esplora/blob/master/API.md
+	ClientURL = "https://blockstream.info"
+
+	// https://mempool.space/docs/api/rest
+	MempoolURL = "https://mempool.space"
+)
+
+// NewClient connects to the Esplora API of blockstream.info for net.
+func NewClient(net types.Network, opts ...Option) (*Client, error) {
+	params, path, err := esploraNetwork(net)
+	if err != nil {
+		return nil, err
+	}
+	return NewEsploraClient(params, ClientURL+path, opts...), nil
+}
+
+// NewEsploraClient connects to the Esplora API at url, e.g.
+// "http://localhost:3000/api" for a self-hosted instance on params.
+func NewEsploraClient(params *chaincfg.Params, url string, opts ...Option) *Client {
+	return &Client{
+		params: params,
+		url:    strings.TrimRight(url, "/"),
+		opts:   newOptions(opts),
+	}
+}
+
+// esploraNetwork returns the params of net and the path of its API on the
+// public Esplora and mempool.space instances.
+func esploraNetwork(net types.Network) (*chaincfg.Params, string, error) {
+	switch net {
+	case types.BTC:
+		return &chaincfg.MainNetParams, "/api", nil
+	case types.BTC_Testnet3:
+		return &chaincfg.TestNet3Params, "/testnet/api", nil
+	case types.BTC_Testnet4:
+		return &chaincfg.TestNet4Params, "/testnet4/api", nil
+	case types.BTC_Signet:
+		return &chaincfg.SigNetParams, "/signet/api", nil
+	default:
+		return nil, "", fmt.Errorf("network not supported [%s]", net)
+	}
+}
+
+// Client is a Backend on the Esplora HTTP API.
+type Client struct {
+	params *chaincfg.Params
+	url    string
+
+	opts   options
+	closed atomic.Bool
+}
+
+// ErrClosed is returned by requests on a closed client.
+var ErrClosed = errors.New("client closed")
+
+func (t *Client) GetParams() *chaincfg.Params {
+	return t.params
+}
+
+// Close makes later requests fail with ErrClosed. It may be called while
+// requests are in flight, which it doesn't interrupt.
+func (t *Client) Close() {
+	t.closed.Store(true)
+}
+
+func RequestGet[T any](ctx context.Context, client *Client, endpoint string) (T, error) {
+	return request[T](ctx, client, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, client.url+endpoint, nil)
+	}, true)
+}
+
+func RequestPost[T any](ctx context.Context, client *Client, endpoint string, payload interface{}) (T, error) {
+	contentType := "text/plain"
+	data := []byte{}
+	if payloadStr, ok := payload.(string); ok {
+		data = []byte(payloadStr)
+	} else {
+		var err error
+		data, err = json.Marshal(payload)
+		if err != nil {
+			return *new(T), fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		contentType = "application/json"
+	}
+	return request[T](ctx, client, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, client.url+endpoint, bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", contentType)
+		return req, nil
+	}, false)
+}
+
+func request[T any](ctx context.Context, client *Client, newRequest func(ctx context.Context) (*http.Request, error), idempotent bool) (T, error) {
+	if client.closed.Load() {
+		return *new(T), ErrClosed
+	}
+	body, err := client.opts.do(ctx, newRequest, nil, idempotent)
+	if err != nil {
+		return *new(T), err
+	}
+
+	var result T
+	if _, ok := any(result).(string); ok {
+		return any(string(body)).(T), nil
+	}
+	if json.Valid(body) {
+		if err := json.Unmarshal(body, &result); err != nil {
+			return *new(T), fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		return result, nil
+	}
+
+	// Non-JSON response, only supported for strings
+	return *new(T), fmt.Errorf("non-JSON response cannot be parsed into %T | res : %s", result, body)
+}
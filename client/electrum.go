@@ -0,0 +1,534 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/gosuda/btctxbuilder/script"
+	"github.com/gosuda/btctxbuilder/types"
+	"github.com/gosuda/btctxbuilder/utils"
+)
+
+// ElectrumProtocolVersion is the version of the Electrum protocol the client
+// negotiates.
+const ElectrumProtocolVersion = "1.4"
+
+// ErrElectrumClosed is returned by calls on a closed connection.
+var ErrElectrumClosed = errors.New("electrum connection closed")
+
+// ElectrumClient is a Backend on an Electrum server such as electrs or
+// Fulcrum, speaking JSON-RPC over TCP or TLS. Addresses are looked up by
+// the scripthash of their output script. Calls may be made concurrently.
+// Of the options, it applies the timeout, the rate limit and the
+// concurrency; calls aren't retried, as the connection is lost with them.
+type ElectrumClient struct {
+	params *chaincfg.Params
+	conn   net.Conn
+	opts   options
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan electrumResponse
+	subs    map[string][]chan string
+	err     error
+}
+
+type electrumRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      uint64 `json:"id"`
+	Method  string `json:"method"`
+	Params  []any  `json:"params"`
+}
+
+// electrumResponse is a response to a request, or a notification if it
+// carries a method.
+type electrumResponse struct {
+	ID     *uint64           `json:"id"`
+	Result json.RawMessage   `json:"result"`
+	Error  *RPCError         `json:"error"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+// DialElectrum connects to the Electrum server at addr, e.g.
+// "electrum.blockstream.info:50002", for params. With tlsConfig nil the
+// connection is plain TCP.
+func DialElectrum(ctx context.Context, params *chaincfg.Params, addr string, tlsConfig *tls.Config, opts ...Option) (*ElectrumClient, error) {
+	var conn net.Conn
+	var err error
+	if tlsConfig != nil {
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	return NewElectrumClient(ctx, params, conn, opts...)
+}
+
+// NewElectrumClient speaks the Electrum protocol over conn, negotiating the
+// protocol version first.
+func NewElectrumClient(ctx context.Context, params *chaincfg.Params, conn net.Conn, opts ...Option) (*ElectrumClient, error) {
+	c := &ElectrumClient{
+		params:  params,
+		conn:    conn,
+		opts:    newOptions(opts),
+		pending: make(map[uint64]chan electrumResponse),
+		subs:    make(map[string][]chan string),
+	}
+	go c.read()
+	if _, err := RequestElectrum[[]string](ctx, c, "server.version", "btctxbuilder", ElectrumProtocolVersion); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *ElectrumClient) GetParams() *chaincfg.Params {
+	return c.params
+}
+
+// Close closes the connection, failing the calls in flight and closing the
+// channels of subscriptions.
+func (c *ElectrumClient) Close() {
+	c.conn.Close()
+}
+
+// read dispatches the lines the server sends until the connection fails.
+func (c *ElectrumClient) read() {
+	reader := bufio.NewReader(c.conn)
+	var err error
+	for {
+		var line []byte
+		line, err = reader.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+		var res electrumResponse
+		if err = json.Unmarshal(line, &res); err != nil {
+			err = fmt.Errorf("failed to unmarshal response: %w", err)
+			break
+		}
+		c.dispatch(res)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = errors.Join(ErrElectrumClosed, err)
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	for scripthash, chs := range c.subs {
+		for _, ch := range chs {
+			close(ch)
+		}
+		delete(c.subs, scripthash)
+	}
+	c.conn.Close()
+}
+
+func (c *ElectrumClient) dispatch(res electrumResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if res.ID != nil {
+		if ch, ok := c.pending[*res.ID]; ok {
+			ch <- res
+			delete(c.pending, *res.ID)
+		}
+		return
+	}
+	if res.Method != "blockchain.scripthash.subscribe" || len(res.Params) != 2 {
+		return
+	}
+	var scripthash, status string
+	if json.Unmarshal(res.Params[0], &scripthash) != nil {
+		return
+	}
+	// a null status, of a script without history, stays ""
+	json.Unmarshal(res.Params[1], &status)
+	for _, ch := range c.subs[scripthash] {
+		// a status sums up the whole history, so a reader behind only
+		// misses the statuses a newer one replaces
+		select {
+		case ch <- status:
+		default:
+		}
+	}
+}
+
+// RequestElectrum calls method on the server with params and decodes its
+// result.
+func RequestElectrum[T any](ctx context.Context, client *ElectrumClient, method string, params ...any) (T, error) {
+	if params == nil {
+		params = []any{}
+	}
+	if err := client.opts.limiter.Wait(ctx); err != nil {
+		return *new(T), err
+	}
+	if client.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, client.opts.timeout)
+		defer cancel()
+	}
+
+	ch := make(chan electrumResponse, 1)
+	client.mu.Lock()
+	if client.err != nil {
+		client.mu.Unlock()
+		return *new(T), client.err
+	}
+	client.nextID++
+	id := client.nextID
+	client.pending[id] = ch
+	payload, err := json.Marshal(electrumRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+	if err == nil {
+		_, err = client.conn.Write(append(payload, '\n'))
+	}
+	if err != nil {
+		delete(client.pending, id)
+		client.mu.Unlock()
+		return *new(T), fmt.Errorf("failed to make request: %w", err)
+	}
+	client.mu.Unlock()
+
+	var res electrumResponse
+	var ok bool
+	select {
+	case res, ok = <-ch:
+	case <-ctx.Done():
+		// the response, if it comes, is dropped
+		client.mu.Lock()
+		delete(client.pending, id)
+		client.mu.Unlock()
+		return *new(T), fmt.Errorf("%s: %w", method, ctx.Err())
+	}
+	if !ok {
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		return *new(T), client.err
+	}
+	if res.Error != nil {
+		return *new(T), fmt.Errorf("%s: %w", method, res.Error)
+	}
+	var result T
+	if err := json.Unmarshal(res.Result, &result); err != nil {
+		return *new(T), fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return result, nil
+}
+
+// ElectrumScripthash returns the scripthash Electrum servers index the
+// output script of address by: its SHA256, byte reversed, in hex.
+func ElectrumScripthash(params *chaincfg.Params, address string) (string, error) {
+	addr, _, err := types.DecodeAddress(address, params)
+	if err != nil {
+		return "", err
+	}
+	pkScript, err := script.EncodeTransferScript(addr)
+	if err != nil {
+		return "", err
+	}
+	return scripthash(pkScript), nil
+}
+
+func scripthash(pkScript []byte) string {
+	hash := sha256.Sum256(pkScript)
+	slices.Reverse(hash[:])
+	return utils.HexEncode(hash[:])
+}
+
+type electrumHeader struct {
+	Height uint64 `json:"height"`
+	Hex    string `json:"hex"`
+}
+
+// BestBlockHeight subscribes to headers for the tip, the only way the
+// protocol tells it.
+func (c *ElectrumClient) BestBlockHeight(ctx context.Context) (uint64, error) {
+	header, err := RequestElectrum[electrumHeader](ctx, c, "blockchain.headers.subscribe")
+	if err != nil {
+		return 0, err
+	}
+	return header.Height, nil
+}
+
+// FeeEstimate converts the blockchain.estimatefee rates of the usual
+// targets from BTC/kB. Targets the server has no estimate for are left out.
+func (c *ElectrumClient) FeeEstimate(ctx context.Context) (types.FeeEstimate, error) {
+	estimate := make(types.FeeEstimate)
+	for _, target := range feeTargets {
+		fee, err := RequestElectrum[float64](ctx, c, "blockchain.estimatefee", target)
+		if err != nil {
+			return nil, err
+		}
+		if fee > 0 {
+			estimate[strconv.Itoa(target)] = fee * btcutil.SatoshiPerBitcoin / 1000
+		}
+	}
+	if len(estimate) == 0 {
+		return nil, errors.New("blockchain.estimatefee: no estimate available")
+	}
+	return estimate, nil
+}
+
+type electrumUnspent struct {
+	TxHash string `json:"tx_hash"`
+	TxPos  uint32 `json:"tx_pos"`
+	Height int    `json:"height"`
+	Value  int64  `json:"value"`
+}
+
+// GetUTXO lists the unspent outputs of address with
+// blockchain.scripthash.listunspent, those in the mempool included.
+func (c *ElectrumClient) GetUTXO(ctx context.Context, address string) ([]*types.Utxo, error) {
+	hash, err := ElectrumScripthash(c.params, address)
+	if err != nil {
+		return nil, err
+	}
+	unspents, err := RequestElectrum[[]electrumUnspent](ctx, c, "blockchain.scripthash.listunspent", hash)
+	if err != nil {
+		return nil, err
+	}
+	utxos := make([]*types.Utxo, 0, len(unspents))
+	for _, u := range unspents {
+		utxo := &types.Utxo{Txid: u.TxHash, Vout: u.TxPos, Value: u.Value}
+		if u.Height > 0 {
+			utxo.Status = types.BlockStatus{Confirmed: true, BlockHeight: u.Height}
+		}
+		utxos = append(utxos, utxo)
+	}
+	return utxos, nil
+}
+
+func (c *ElectrumClient) GetUTXOWithRawTx(ctx context.Context, address string) ([]*types.Utxo, error) {
+	utxos, err := c.GetUTXO(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	return withRawTx(ctx, c, utxos, c.opts.concurrency)
+}
+
+// ElectrumHistory is a transaction touching a scripthash. Height is 0 for
+// one in the mempool and -1 if it also spends unconfirmed outputs, in which
+// case Fee is set.
+type ElectrumHistory struct {
+	TxHash string `json:"tx_hash"`
+	Height int    `json:"height"`
+	Fee    int64  `json:"fee,omitempty"`
+}
+
+// GetHistory returns the transactions paying to or spending from address,
+// confirmed ones first in block order.
+func (c *ElectrumClient) GetHistory(ctx context.Context, address string) ([]ElectrumHistory, error) {
+	hash, err := ElectrumScripthash(c.params, address)
+	if err != nil {
+		return nil, err
+	}
+	return RequestElectrum[[]ElectrumHistory](ctx, c, "blockchain.scripthash.get_history", hash)
+}
+
+// Subscribe returns the status of address, a hash of its history or "" if
+// it has none, and a channel receiving its status each time the history
+// changes. The channel is closed with the connection.
+func (c *ElectrumClient) Subscribe(ctx context.Context, address string) (string, <-chan string, error) {
+	hash, err := ElectrumScripthash(c.params, address)
+	if err != nil {
+		return "", nil, err
+	}
+	// registered first so that no notification goes missing
+	ch := make(chan string, 16)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return "", nil, c.err
+	}
+	c.subs[hash] = append(c.subs[hash], ch)
+	c.mu.Unlock()
+
+	status, err := RequestElectrum[*string](ctx, c, "blockchain.scripthash.subscribe", hash)
+	if err != nil {
+		c.unsubscribe(hash, ch)
+		return "", nil, err
+	}
+	if status == nil {
+		return "", ch, nil
+	}
+	return *status, ch, nil
+}
+
+func (c *ElectrumClient) unsubscribe(scripthash string, ch chan string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	chs := c.subs[scripthash]
+	if i := slices.Index(chs, ch); i >= 0 {
+		c.subs[scripthash] = slices.Delete(chs, i, i+1)
+		close(ch)
+	}
+}
+
+func (c *ElectrumClient) GetRawTx(ctx context.Context, txid string) (string, error) {
+	return RequestElectrum[string](ctx, c, "blockchain.transaction.get", txid, false)
+}
+
+// GetTx decodes the transaction txid into the shape Esplora returns it in.
+// The values of the outputs it spends, and so its fee, are left out.
+func (c *ElectrumClient) GetTx(ctx context.Context, txid string) (*types.Transaction, error) {
+	rawTx, err := c.GetRawTx(ctx, txid)
+	if err != nil {
+		return nil, err
+	}
+	msgTx, err := types.DecodeRawTransaction(rawTx)
+	if err != nil {
+		return nil, err
+	}
+	status, err := c.txStatus(ctx, msgTx)
+	if err != nil {
+		return nil, err
+	}
+	tx := newTransaction(c.params, msgTx)
+	tx.Status = *status
+	return tx, nil
+}
+
+// GetTxStatus finds txid in the history of one of its outputs, since the
+// protocol has no lookup of a transaction's block.
+func (c *ElectrumClient) GetTxStatus(ctx context.Context, txid string) (*types.BlockStatus, error) {
+	rawTx, err := c.GetRawTx(ctx, txid)
+	if err != nil {
+		return nil, err
+	}
+	msgTx, err := types.DecodeRawTransaction(rawTx)
+	if err != nil {
+		return nil, err
+	}
+	return c.txStatus(ctx, msgTx)
+}
+
+func (c *ElectrumClient) txStatus(ctx context.Context, tx *wire.MsgTx) (*types.BlockStatus, error) {
+	var pkScript []byte
+	for _, out := range tx.TxOut {
+		if !txscript.IsUnspendable(out.PkScript) {
+			pkScript = out.PkScript
+			break
+		}
+	}
+	if pkScript == nil {
+		return nil, fmt.Errorf("transaction %s has no output to look it up by", tx.TxID())
+	}
+	history, err := RequestElectrum[[]ElectrumHistory](ctx, c, "blockchain.scripthash.get_history", scripthash(pkScript))
+	if err != nil {
+		return nil, err
+	}
+	txid := tx.TxID()
+	i := slices.IndexFunc(history, func(h ElectrumHistory) bool { return h.TxHash == txid })
+	if i < 0 || history[i].Height <= 0 {
+		return &types.BlockStatus{}, nil
+	}
+
+	height := history[i].Height
+	headerHex, err := RequestElectrum[string](ctx, c, "blockchain.block.header", height)
+	if err != nil {
+		return nil, err
+	}
+	headerBytes, err := utils.HexDecode(headerHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode header: %w", err)
+	}
+	var header wire.BlockHeader
+	if err := header.Deserialize(bytes.NewReader(headerBytes)); err != nil {
+		return nil, fmt.Errorf("failed to deserialize header: %w", err)
+	}
+	return &types.BlockStatus{
+		Confirmed:   true,
+		BlockHeight: height,
+		BlockHash:   header.BlockHash().String(),
+		BlockTime:   int(header.Timestamp.Unix()),
+	}, nil
+}
+
+func (c *ElectrumClient) BroadcastTx(ctx context.Context, rawTx string) (string, error) {
+	return RequestElectrum[string](ctx, c, "blockchain.transaction.broadcast", rawTx)
+}
+
+// newTransaction describes tx the way the Esplora API does.
+func newTransaction(params *chaincfg.Params, tx *wire.MsgTx) *types.Transaction {
+	btcTx := btcutil.NewTx(tx)
+	res := &types.Transaction{
+		Txid:     tx.TxID(),
+		Version:  int(tx.Version),
+		Locktime: int(tx.LockTime),
+		Size:     tx.SerializeSize(),
+		Weight:   int(blockchain.GetTransactionWeight(btcTx)),
+	}
+	isCoinbase := blockchain.IsCoinBaseTx(tx)
+	for _, in := range tx.TxIn {
+		vin := types.Vin{
+			Txid:       in.PreviousOutPoint.Hash.String(),
+			Vout:       in.PreviousOutPoint.Index,
+			Scriptsig:  utils.HexEncode(in.SignatureScript),
+			IsCoinbase: isCoinbase,
+			Sequence:   int64(in.Sequence),
+		}
+		vin.ScriptsigAsm, _ = txscript.DisasmString(in.SignatureScript)
+		for _, item := range in.Witness {
+			vin.Witness = append(vin.Witness, utils.HexEncode(item))
+		}
+		res.Vin = append(res.Vin, vin)
+	}
+	for _, out := range tx.TxOut {
+		vout := types.Vout{
+			Scriptpubkey:     utils.HexEncode(out.PkScript),
+			ScriptpubkeyType: esploraScriptType(out.PkScript),
+			Value:            out.Value,
+		}
+		vout.ScriptpubkeyAsm, _ = txscript.DisasmString(out.PkScript)
+		// Esplora gives no address for bare public keys
+		if addr, err := script.DecodeTransferScript(out.PkScript, params); err == nil && vout.ScriptpubkeyType != "p2pk" {
+			vout.ScriptpubkeyAddress = addr.EncodeAddress()
+		}
+		res.Vout = append(res.Vout, vout)
+	}
+	return res
+}
+
+// esploraScriptType names the type of pkScript as Esplora does.
+func esploraScriptType(pkScript []byte) string {
+	switch txscript.GetScriptClass(pkScript) {
+	case txscript.PubKeyTy:
+		return "p2pk"
+	case txscript.PubKeyHashTy:
+		return "p2pkh"
+	case txscript.ScriptHashTy:
+		return "p2sh"
+	case txscript.WitnessV0PubKeyHashTy:
+		return "v0_p2wpkh"
+	case txscript.WitnessV0ScriptHashTy:
+		return "v0_p2wsh"
+	case txscript.WitnessV1TaprootTy:
+		return "v1_p2tr"
+	case txscript.MultiSigTy:
+		return "multisig"
+	case txscript.NullDataTy:
+		return "op_return"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,283 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"math/big"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosuda/btctxbuilder/script"
+	"github.com/gosuda/btctxbuilder/types"
+	"github.com/gosuda/btctxbuilder/utils"
+)
+
+// fakeElectrum is an in-process Electrum server answering each request with
+// the result of handle, or with the error it returns.
+type fakeElectrum struct {
+	listener net.Listener
+	handle   func(method string, params []json.RawMessage) (any, *RPCError)
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func newFakeElectrum(t *testing.T, tlsConfig *tls.Config, handle func(method string, params []json.RawMessage) (any, *RPCError)) *fakeElectrum {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	s := &fakeElectrum{listener: listener, handle: handle}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fakeElectrum) serve(conn net.Conn) {
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var req struct {
+			ID     uint64            `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &req) != nil {
+			return
+		}
+		result, rpcErr := s.handle(req.Method, req.Params)
+		s.send(conn, map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result, "error": rpcErr})
+	}
+}
+
+func (s *fakeElectrum) send(conn net.Conn, msg any) {
+	line, _ := json.Marshal(msg)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	conn.Write(append(line, '\n'))
+}
+
+// notify sends a notification to every client.
+func (s *fakeElectrum) notify(method string, params ...any) {
+	s.mu.Lock()
+	conns := append([]net.Conn(nil), s.conns...)
+	s.mu.Unlock()
+	for _, conn := range conns {
+		s.send(conn, map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+	}
+}
+
+func (s *fakeElectrum) Close() {
+	s.listener.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+}
+
+// selfSigned returns a server TLS config for 127.0.0.1 and a client config
+// trusting it.
+func selfSigned(t *testing.T) (*tls.Config, *tls.Config) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}},
+		&tls.Config{RootCAs: pool}
+}
+
+func TestElectrumScripthash(t *testing.T) {
+	// the example of the protocol documentation
+	hash, err := ElectrumScripthash(&chaincfg.MainNetParams, "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa")
+	require.NoError(t, err)
+	require.Equal(t, "8b01df4e368ea28f8dc0423bcf7a4923e3a12d307c875e47a0cfbf90b5c39161", hash)
+
+	_, err = ElectrumScripthash(&chaincfg.MainNetParams, "not an address")
+	require.Error(t, err)
+}
+
+func TestElectrumClient(t *testing.T) {
+	ctx := context.Background()
+	params := &chaincfg.SigNetParams
+	hash, err := ElectrumScripthash(params, testAddress)
+	require.NoError(t, err)
+
+	// the funding transaction pays testAddress and is confirmed at 190
+	fundTx := wire.NewMsgTx(wire.TxVersion)
+	fundTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), nil, nil))
+	addr, _, err := types.DecodeAddress(testAddress, params)
+	require.NoError(t, err)
+	pkScript, err := script.EncodeTransferScript(addr)
+	require.NoError(t, err)
+	fundTx.AddTxOut(wire.NewTxOut(50000, pkScript))
+	var buf bytes.Buffer
+	require.NoError(t, fundTx.Serialize(&buf))
+	fundHex, fundID := utils.HexEncode(buf.Bytes()), fundTx.TxID()
+
+	header := wire.NewBlockHeader(4, &chainhash.Hash{2}, &chainhash.Hash{3}, 0x1d00ffff, 7)
+	header.Timestamp = time.Unix(1700000000, 0)
+	buf.Reset()
+	require.NoError(t, header.Serialize(&buf))
+	headerHex := utils.HexEncode(buf.Bytes())
+
+	serverTLS, clientTLS := selfSigned(t)
+	for _, tt := range []struct {
+		name   string
+		server *tls.Config
+		client *tls.Config
+	}{
+		{"tcp", nil, nil},
+		{"tls", serverTLS, clientTLS},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeElectrum(t, tt.server, func(method string, params []json.RawMessage) (any, *RPCError) {
+				switch method {
+				case "server.version":
+					return []string{"fake 1.0", ElectrumProtocolVersion}, nil
+				case "blockchain.headers.subscribe":
+					return map[string]any{"height": 200, "hex": headerHex}, nil
+				case "blockchain.estimatefee":
+					if string(params[0]) == "1" {
+						return -1, nil
+					}
+					return 0.0001, nil
+				case "blockchain.scripthash.listunspent":
+					require.JSONEq(t, `"`+hash+`"`, string(params[0]))
+					return []map[string]any{
+						{"tx_hash": fundID, "tx_pos": 0, "height": 190, "value": 50000},
+					}, nil
+				case "blockchain.scripthash.get_history":
+					require.JSONEq(t, `"`+hash+`"`, string(params[0]))
+					return []map[string]any{
+						{"tx_hash": fundID, "height": 190},
+						{"tx_hash": "ab", "height": 0, "fee": 300},
+					}, nil
+				case "blockchain.scripthash.subscribe":
+					return nil, nil
+				case "blockchain.transaction.get":
+					if string(params[0]) != `"`+fundID+`"` {
+						return nil, &RPCError{Code: 2, Message: "missing transaction"}
+					}
+					return fundHex, nil
+				case "blockchain.block.header":
+					require.Equal(t, "190", string(params[0]))
+					return headerHex, nil
+				case "blockchain.transaction.broadcast":
+					var rawTx string
+					json.Unmarshal(params[0], &rawTx)
+					tx, err := types.DecodeRawTransaction(rawTx)
+					if err != nil {
+						return nil, &RPCError{Code: 1, Message: err.Error()}
+					}
+					return tx.TxID(), nil
+				}
+				return nil, &RPCError{Code: -32601, Message: "unknown method"}
+			})
+
+			c, err := DialElectrum(ctx, params, server.listener.Addr().String(), tt.client)
+			require.NoError(t, err)
+			defer c.Close()
+			var backend Backend = c
+
+			height, err := backend.BestBlockHeight(ctx)
+			require.NoError(t, err)
+			require.Equal(t, uint64(200), height)
+
+			fees, err := backend.FeeEstimate(ctx)
+			require.NoError(t, err)
+			require.Len(t, fees, 3)
+			require.InDelta(t, 10.0, fees["6"], 1e-9)
+
+			utxos, err := backend.GetUTXOWithRawTx(ctx, testAddress)
+			require.NoError(t, err)
+			require.Len(t, utxos, 1)
+			require.Equal(t, types.BlockStatus{Confirmed: true, BlockHeight: 190}, utxos[0].Status)
+			require.Equal(t, fundID, utxos[0].RawTx.TxID())
+
+			history, err := c.GetHistory(ctx, testAddress)
+			require.NoError(t, err)
+			require.Equal(t, []ElectrumHistory{{TxHash: fundID, Height: 190}, {TxHash: "ab", Fee: 300}}, history)
+
+			status := &types.BlockStatus{Confirmed: true, BlockHeight: 190, BlockHash: header.BlockHash().String(), BlockTime: 1700000000}
+			got, err := backend.GetTxStatus(ctx, fundID)
+			require.NoError(t, err)
+			require.Equal(t, status, got)
+
+			tx, err := c.GetTx(ctx, fundID)
+			require.NoError(t, err)
+			require.Equal(t, fundID, tx.Txid)
+			require.Equal(t, *status, tx.Status)
+			require.Equal(t, types.Vout{
+				Scriptpubkey:        utils.HexEncode(pkScript),
+				ScriptpubkeyAsm:     tx.Vout[0].ScriptpubkeyAsm,
+				ScriptpubkeyType:    "v0_p2wpkh",
+				ScriptpubkeyAddress: testAddress,
+				Value:               50000,
+			}, tx.Vout[0])
+
+			sent, err := backend.BroadcastTx(ctx, fundHex)
+			require.NoError(t, err)
+			require.Equal(t, fundID, sent)
+
+			_, err = backend.GetRawTx(ctx, "cd")
+			var rpcErr *RPCError
+			require.ErrorAs(t, err, &rpcErr)
+			require.Equal(t, 2, rpcErr.Code)
+
+			cancelled, cancel := context.WithCancel(ctx)
+			cancel()
+			_, err = backend.BestBlockHeight(cancelled)
+			require.ErrorIs(t, err, context.Canceled)
+
+			// notifications reach the subscription until the connection closes
+			initial, updates, err := c.Subscribe(ctx, testAddress)
+			require.NoError(t, err)
+			require.Empty(t, initial)
+			server.notify("blockchain.scripthash.subscribe", hash, "f00d")
+			require.Equal(t, "f00d", <-updates)
+			server.Close()
+			_, ok := <-updates
+			require.False(t, ok)
+			_, err = backend.BestBlockHeight(ctx)
+			require.ErrorIs(t, err, ErrElectrumClosed)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"context"
+
+	"github.com/btcsuite/btcd/chaincfg"
+
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+// MempoolClient is a Backend on the mempool.space API, which serves the
+// Esplora API next to its own fee recommendations.
+type MempoolClient struct {
+	*Client
+}
+
+// NewMempoolClient connects to mempool.space for net.
+func NewMempoolClient(net types.Network, opts ...Option) (*MempoolClient, error) {
+	params, path, err := esploraNetwork(net)
+	if err != nil {
+		return nil, err
+	}
+	return NewMempoolClientWithURL(params, MempoolURL+path, opts...), nil
+}
+
+// NewMempoolClientWithURL connects to the mempool API at url, e.g.
+// "http://localhost:8999/api" for a self-hosted instance on params.
+func NewMempoolClientWithURL(params *chaincfg.Params, url string, opts ...Option) *MempoolClient {
+	return &MempoolClient{Client: NewEsploraClient(params, url, opts...)}
+}
+
+type recommendedFees struct {
+	FastestFee  float64 `json:"fastestFee"`
+	HalfHourFee float64 `json:"halfHourFee"`
+	HourFee     float64 `json:"hourFee"`
+	EconomyFee  float64 `json:"economyFee"`
+	MinimumFee  float64 `json:"minimumFee"`
+}
+
+// FeeEstimate maps the recommended fees of mempool.space to the targets
+// they aim at: the next block, half an hour, an hour and a day.
+func (c *MempoolClient) FeeEstimate(ctx context.Context) (types.FeeEstimate, error) {
+	fees, err := RequestGet[recommendedFees](ctx, c.Client, "/v1/fees/recommended")
+	if err != nil {
+		return nil, err
+	}
+	return types.FeeEstimate{
+		"1":   fees.FastestFee,
+		"3":   fees.HalfHourFee,
+		"6":   fees.HourFee,
+		"144": fees.EconomyFee,
+	}, nil
+}
@@ -1,73 +1,67 @@
-package client
-
-import (
-	"fmt"
-
-	"github.com/gosuda/btctxbuilder/types"
-)
-
-func (c *Client) BestBlockHeight() (uint64, error) {
-	return RequestGet[uint64](c, "/blocks/tip/height")
-}
-
-func (c *Client) BestBlockHash() (string, error) {
-	return RequestGet[string](c, "/blocks/tip/hash")
-}
-
-func (c *Client) GetBlockHashByHeight(height uint64) (string, error) {
-	return RequestGet[string](c, fmt.Sprintf("/block-height/%d", height))
-}
-
-func (c *Client) GetBlock(hash string) (*types.Block, error) {
-	return RequestGet[*types.Block](c, fmt.Sprintf("/block/%s", hash))
-}
-
-func (c *Client) GetBlockTx(hash string, offset int) ([]*types.Transaction, error) {
-	if offset > 0 {
-		return RequestGet[[]*types.Transaction](c, fmt.Sprintf("/block/%s/txs/%d", hash, offset))
-	}
-	return RequestGet[[]*types.Transaction](c, fmt.Sprintf("/block/%s/txs", hash))
-}
-
-func (c *Client) GetTx(txid string) (*types.Transaction, error) {
-	return RequestGet[*types.Transaction](c, fmt.Sprintf("/tx/%s", txid))
-}
-
-func (c *Client) GetRawTx(txid string) (string, error) {
-	return RequestGet[string](c, fmt.Sprintf("/tx/%s/raw", txid))
-}
-
-func (c *Client) GetAddress(address string) (*types.Address, error) {
-	return RequestGet[*types.Address](c, fmt.Sprintf("/address/%s", address))
-}
-
-func (c *Client) GetUTXO(address string) ([]*types.Utxo, error) {
-	return RequestGet[[]*types.Utxo](c, fmt.Sprintf("/address/%s/utxo", address))
-}
-
-func (c *Client) GetUTXOWithRawTx(address string) ([]*types.Utxo, error) {
-	utxos, err := c.GetUTXO(address)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, utxo := range utxos {
-		rawTx, err := c.GetRawTx(utxo.Txid)
-		if err != nil {
-			return nil, err
-		}
-		utxo.RawTx, err = types.DecodeRawTransaction(rawTx)
-		if err != nil {
-			return nil, err
-		}
-	}
-	return utxos, nil
-}
-
-func (c *Client) FeeEstimate() (types.FeeEstimate, error) {
-	return RequestGet[types.FeeEstimate](c, "/fee-estimates")
-}
-
-func (c *Client) BroadcastTx(rawTx string) (string, error) {
-	return RequestPost[string](c, "/tx", rawTx)
-}
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+func (c *Client) BestBlockHeight(ctx context.Context) (uint64, error) {
+	return RequestGet[uint64](ctx, c, "/blocks/tip/height")
+}
+
+func (c *Client) BestBlockHash(ctx context.Context) (string, error) {
+	return RequestGet[string](ctx, c, "/blocks/tip/hash")
+}
+
+func (c *Client) GetBlockHashByHeight(ctx context.Context, height uint64) (string, error) {
+	return RequestGet[string](ctx, c, fmt.Sprintf("/block-height/%d", height))
+}
+
+func (c *Client) GetBlock(ctx context.Context, hash string) (*types.Block, error) {
+	return RequestGet[*types.Block](ctx, c, fmt.Sprintf("/block/%s", hash))
+}
+
+func (c *Client) GetBlockTx(ctx context.Context, hash string, offset int) ([]*types.Transaction, error) {
+	if offset > 0 {
+		return RequestGet[[]*types.Transaction](ctx, c, fmt.Sprintf("/block/%s/txs/%d", hash, offset))
+	}
+	return RequestGet[[]*types.Transaction](ctx, c, fmt.Sprintf("/block/%s/txs", hash))
+}
+
+func (c *Client) GetTx(ctx context.Context, txid string) (*types.Transaction, error) {
+	return RequestGet[*types.Transaction](ctx, c, fmt.Sprintf("/tx/%s", txid))
+}
+
+func (c *Client) GetTxStatus(ctx context.Context, txid string) (*types.BlockStatus, error) {
+	return RequestGet[*types.BlockStatus](ctx, c, fmt.Sprintf("/tx/%s/status", txid))
+}
+
+func (c *Client) GetRawTx(ctx context.Context, txid string) (string, error) {
+	return RequestGet[string](ctx, c, fmt.Sprintf("/tx/%s/raw", txid))
+}
+
+func (c *Client) GetAddress(ctx context.Context, address string) (*types.Address, error) {
+	return RequestGet[*types.Address](ctx, c, fmt.Sprintf("/address/%s", address))
+}
+
+func (c *Client) GetUTXO(ctx context.Context, address string) ([]*types.Utxo, error) {
+	return RequestGet[[]*types.Utxo](ctx, c, fmt.Sprintf("/address/%s/utxo", address))
+}
+
+func (c *Client) GetUTXOWithRawTx(ctx context.Context, address string) ([]*types.Utxo, error) {
+	utxos, err := c.GetUTXO(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	return withRawTx(ctx, c, utxos, c.opts.concurrency)
+}
+
+func (c *Client) FeeEstimate(ctx context.Context) (types.FeeEstimate, error) {
+	return RequestGet[types.FeeEstimate](ctx, c, "/fee-estimates")
+}
+
+func (c *Client) BroadcastTx(ctx context.Context, rawTx string) (string, error) {
+	return RequestPost[string](ctx, c, "/tx", rawTx)
+}
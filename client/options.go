@@ -0,0 +1,96 @@
+package client
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultTimeout     = 30 * time.Second
+	DefaultMaxRetries  = 3
+	DefaultBaseDelay   = 500 * time.Millisecond
+	DefaultMaxDelay    = 10 * time.Second
+	DefaultConcurrency = 4
+)
+
+// Option configures how a client talks to its server.
+type Option func(*options)
+
+type options struct {
+	http        *http.Client
+	timeout     time.Duration
+	retry       RetryPolicy
+	limiter     *RateLimiter
+	concurrency int
+	// retryUnsafe retries requests that aren't idempotent too
+	retryUnsafe bool
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		http:        http.DefaultClient,
+		timeout:     DefaultTimeout,
+		retry:       RetryPolicy{MaxRetries: DefaultMaxRetries, BaseDelay: DefaultBaseDelay, MaxDelay: DefaultMaxDelay},
+		concurrency: DefaultConcurrency,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithHTTPClient sends requests with c instead of http.DefaultClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) {
+		o.http = c
+	}
+}
+
+// WithTimeout bounds each attempt of a request by d, 0 for no bound. The
+// context of the call bounds all attempts together.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithRetry retries a request failing with a network error, a 429 or a 5xx
+// up to maxRetries times, waiting a random delay of up to baseDelay
+// doubled per retry and capped at maxDelay in between, or as long as the
+// server asks with Retry-After, up to maxDelay. 0 retries disables
+// retrying. Broadcasts aren't retried unless WithRetryBroadcast.
+func WithRetry(maxRetries int, baseDelay, maxDelay time.Duration) Option {
+	return func(o *options) {
+		o.retry = RetryPolicy{MaxRetries: maxRetries, BaseDelay: baseDelay, MaxDelay: maxDelay}
+	}
+}
+
+// WithRetryBroadcast retries broadcasts and the other requests that aren't
+// idempotent as well. A broadcast whose response was lost may then fail as
+// already known although the transaction went through.
+func WithRetryBroadcast() Option {
+	return func(o *options) {
+		o.retryUnsafe = true
+	}
+}
+
+// WithRateLimit makes at most perSecond requests a second on average, in
+// bursts of up to burst. A limiter can be shared between clients with
+// WithRateLimiter.
+func WithRateLimit(perSecond float64, burst int) Option {
+	return WithRateLimiter(NewRateLimiter(perSecond, burst))
+}
+
+func WithRateLimiter(l *RateLimiter) Option {
+	return func(o *options) {
+		o.limiter = l
+	}
+}
+
+// WithConcurrency fetches up to n raw transactions at once in
+// GetUTXOWithRawTx.
+func WithConcurrency(n int) Option {
+	return func(o *options) {
+		o.concurrency = max(n, 1)
+	}
+}
@@ -0,0 +1,155 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RetryPolicy spaces the retries of a failed request with exponential
+// backoff and full jitter.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// Delay returns a random wait before retry n, counted from 0, of up to
+// BaseDelay doubled n times and capped at MaxDelay.
+func (p RetryPolicy) Delay(n int) time.Duration {
+	ceiling := p.MaxDelay
+	if n < 62 && p.BaseDelay<<n > 0 && p.BaseDelay<<n < ceiling {
+		ceiling = p.BaseDelay << n
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling + 1)
+}
+
+// RateLimiter is a token bucket: it holds up to burst tokens, refilled at
+// perSecond, and each request takes one. It is safe for concurrent use.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a full bucket. A non-positive rate never limits.
+func NewRateLimiter(perSecond float64, burst int) *RateLimiter {
+	burst = max(burst, 1)
+	return &RateLimiter{rate: perSecond, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// Wait takes a token, waiting for one if the bucket is empty, or fails
+// with the error of ctx if it is done first.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil || l.rate <= 0 {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	// the token is taken up front, so waiters are served in order
+	l.tokens--
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// do sends the request newRequest makes for each attempt and returns the
+// body of the response. A response with a status other than 2xx is an
+// *HTTPError, unless keep accepts it. Network errors and temporary
+// HTTPErrors are retried as o.retry allows, if the request is idempotent
+// or o.retryUnsafe.
+func (o *options) do(ctx context.Context, newRequest func(ctx context.Context) (*http.Request, error), keep func(status int, body []byte) bool, idempotent bool) ([]byte, error) {
+	maxRetries := o.retry.MaxRetries
+	if !idempotent && !o.retryUnsafe {
+		maxRetries = 0
+	}
+	for retry := 0; ; retry++ {
+		body, err := o.attempt(ctx, newRequest, keep)
+		if err == nil {
+			return body, nil
+		}
+		var wait time.Duration
+		var httpErr *HTTPError
+		switch {
+		case ctx.Err() != nil:
+			return nil, err
+		case errors.As(err, &httpErr):
+			if !httpErr.Temporary() {
+				return nil, err
+			}
+			// a server asking for longer than MaxDelay is only waited for
+			// that long
+			wait = min(httpErr.RetryAfter, o.retry.MaxDelay)
+		case errors.Is(err, errBuildRequest):
+			return nil, err
+		}
+		if retry >= maxRetries {
+			return nil, err
+		}
+
+		timer := time.NewTimer(max(wait, o.retry.Delay(retry)))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%w: %w", ctx.Err(), err)
+		}
+	}
+}
+
+var errBuildRequest = errors.New("failed to build request")
+
+func (o *options) attempt(ctx context.Context, newRequest func(ctx context.Context) (*http.Request, error), keep func(status int, body []byte) bool) ([]byte, error) {
+	if err := o.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+	req, err := newRequest(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errBuildRequest, err)
+	}
+	resp, err := o.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode/100 != 2 && (keep == nil || !keep(resp.StatusCode, body)) {
+		return nil, newHTTPError(resp, body)
+	}
+	return body, nil
+}
@@ -0,0 +1,296 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosuda/btctxbuilder/utils"
+)
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for n, ceiling := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		for range 50 {
+			delay := p.Delay(n)
+			require.GreaterOrEqual(t, delay, time.Duration(0))
+			require.LessOrEqual(t, delay, ceiling, n)
+		}
+	}
+	require.LessOrEqual(t, p.Delay(100), time.Second)
+	require.Zero(t, RetryPolicy{}.Delay(3))
+}
+
+func TestRequest_Retry(t *testing.T) {
+	ctx := context.Background()
+	var calls atomic.Int32
+	status := []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/blocks/tip/height":
+			w.WriteHeader(status[calls.Add(1)-1])
+			io.WriteString(w, "100")
+		case "/tx/missing/status":
+			calls.Add(1)
+			http.Error(w, "Transaction not found", http.StatusNotFound)
+		case "/fee-estimates":
+			calls.Add(1)
+			http.Error(w, `{"error": "overloaded"}`, http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	c := NewEsploraClient(&chaincfg.SigNetParams, server.URL, WithRetry(3, time.Millisecond, 10*time.Millisecond))
+
+	// a 503 and a 429 are retried
+	height, err := c.BestBlockHeight(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), height)
+	require.Equal(t, int32(3), calls.Load())
+
+	// a 404 isn't
+	calls.Store(0)
+	_, err = c.GetTxStatus(ctx, "missing")
+	require.ErrorIs(t, err, ErrNotFound)
+	var httpErr *HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	require.Equal(t, "Transaction not found\n", httpErr.Body)
+	require.Equal(t, int32(1), calls.Load())
+
+	// the JSON body of an error is never taken for data
+	calls.Store(0)
+	_, err = c.FeeEstimate(ctx)
+	require.ErrorIs(t, err, ErrServer)
+	require.Equal(t, int32(4), calls.Load())
+
+	calls.Store(0)
+	_, err = NewEsploraClient(&chaincfg.SigNetParams, server.URL, WithRetry(0, 0, 0)).FeeEstimate(ctx)
+	require.ErrorIs(t, err, ErrServer)
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestRequest_RetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	var first time.Time
+	retryAfter := "1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1)%2 == 1 {
+			first = time.Now()
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		io.WriteString(w, "7")
+	}))
+	defer server.Close()
+
+	c := NewEsploraClient(&chaincfg.SigNetParams, server.URL, WithRetry(1, time.Millisecond, 2*time.Second))
+	height, err := c.BestBlockHeight(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), height)
+	require.GreaterOrEqual(t, time.Since(first), time.Second)
+
+	// the wait is capped at the maximum delay
+	retryAfter = "3600"
+	c = NewEsploraClient(&chaincfg.SigNetParams, server.URL, WithRetry(1, time.Millisecond, 50*time.Millisecond))
+	_, err = c.BestBlockHeight(context.Background())
+	require.NoError(t, err)
+	require.Less(t, time.Since(first), time.Second)
+}
+
+func TestRequest_NoRetryBroadcast(t *testing.T) {
+	ctx := context.Background()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	retry := WithRetry(3, time.Millisecond, time.Millisecond)
+
+	// the response may have been lost after the transaction went through
+	_, err := NewEsploraClient(&chaincfg.SigNetParams, server.URL, retry).BroadcastTx(ctx, "00")
+	require.ErrorIs(t, err, ErrServer)
+	require.Equal(t, int32(1), calls.Load())
+	calls.Store(0)
+	_, err = RequestRPC[string](ctx, NewRPCClient(&chaincfg.RegressionNetParams, server.URL, "", "", retry), "", "sendrawtransaction", "00")
+	require.ErrorIs(t, err, ErrServer)
+	require.Equal(t, int32(1), calls.Load())
+
+	// unless asked for
+	calls.Store(0)
+	_, err = NewEsploraClient(&chaincfg.SigNetParams, server.URL, retry, WithRetryBroadcast()).BroadcastTx(ctx, "00")
+	require.ErrorIs(t, err, ErrServer)
+	require.Equal(t, int32(4), calls.Load())
+
+	// other RPC calls are retried
+	calls.Store(0)
+	_, err = NewRPCClient(&chaincfg.RegressionNetParams, server.URL, "", "", retry).BestBlockHeight(ctx)
+	require.ErrorIs(t, err, ErrServer)
+	require.Equal(t, int32(4), calls.Load())
+}
+
+func TestRequest_Timeout(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/blocks/tip/height" && calls.Add(1) == 1 {
+			<-r.Context().Done()
+			return
+		}
+		if r.URL.Path == "/blocks/tip/hash" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}
+		io.WriteString(w, "5")
+	}))
+	defer server.Close()
+	defer close(release)
+
+	// an attempt timing out is retried
+	c := NewEsploraClient(&chaincfg.SigNetParams, server.URL, WithTimeout(50*time.Millisecond), WithRetry(2, time.Millisecond, time.Millisecond))
+	height, err := c.BestBlockHeight(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), height)
+	require.Equal(t, int32(2), calls.Load())
+
+	// the context bounds every attempt together
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = NewEsploraClient(&chaincfg.SigNetParams, server.URL, WithTimeout(0)).BestBlockHash(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	l := NewRateLimiter(50, 2)
+	start := time.Now()
+	for range 7 {
+		require.NoError(t, l.Wait(ctx))
+	}
+	// a burst of 2, then 5 at 50 a second
+	require.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	require.ErrorIs(t, l.Wait(cancelled), context.Canceled)
+
+	var never *RateLimiter
+	require.NoError(t, never.Wait(ctx))
+	require.NoError(t, NewRateLimiter(0, 1).Wait(ctx))
+}
+
+func TestGetUTXOWithRawTx_Concurrency(t *testing.T) {
+	ctx := context.Background()
+	var utxos []map[string]any
+	rawTxs := make(map[string]string)
+	for i := range 6 {
+		tx := wire.NewMsgTx(wire.TxVersion)
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{byte(i)}, 0), nil, nil))
+		tx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+		tx.AddTxOut(wire.NewTxOut(2000, []byte{0x51}))
+		var buf bytes.Buffer
+		require.NoError(t, tx.Serialize(&buf))
+		rawTxs[tx.TxID()] = utils.HexEncode(buf.Bytes())
+		utxos = append(utxos, map[string]any{"txid": tx.TxID(), "vout": 0, "value": 1000})
+		if i == 0 {
+			// two outputs of one transaction need it once
+			utxos = append(utxos, map[string]any{"txid": tx.TxID(), "vout": 1, "value": 2000})
+		}
+	}
+
+	var inFlight, peak, fetches atomic.Int32
+	// handlers of cancelled fetches may still be running
+	var mu sync.Mutex
+	limiter := NewRateLimiter(1000, 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/address/"+testAddress+"/utxo" {
+			json.NewEncoder(w).Encode(utxos)
+			return
+		}
+		var txid string
+		fmt.Sscanf(r.URL.Path, "/tx/%64s", &txid)
+		fetches.Add(1)
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		io.WriteString(w, rawTxs[txid])
+	}))
+	defer server.Close()
+
+	c := NewEsploraClient(&chaincfg.SigNetParams, server.URL, WithConcurrency(2), WithRateLimiter(limiter))
+	got, err := c.GetUTXOWithRawTx(ctx, testAddress)
+	require.NoError(t, err)
+	require.Len(t, got, 7)
+	for _, utxo := range got {
+		require.Equal(t, utxo.Txid, utxo.RawTx.TxID())
+	}
+	require.Same(t, got[0].RawTx, got[1].RawTx)
+	require.Equal(t, int32(6), fetches.Load())
+	require.Equal(t, int32(2), peak.Load())
+
+	// the first failure fails the call
+	mu.Lock()
+	missing := rawTxs[got[3].Txid]
+	delete(rawTxs, got[3].Txid)
+	mu.Unlock()
+	_, err = c.GetUTXOWithRawTx(ctx, testAddress)
+	require.Error(t, err)
+	mu.Lock()
+	rawTxs[got[3].Txid] = missing
+	mu.Unlock()
+
+	// closing while fetching fails the fetches not yet sent
+	done := make(chan error)
+	go func() {
+		_, err := c.GetUTXOWithRawTx(ctx, testAddress)
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	c.Close()
+	require.ErrorIs(t, <-done, ErrClosed)
+	_, err = c.GetUTXOWithRawTx(ctx, testAddress)
+	require.ErrorIs(t, err, ErrClosed)
+}
+
+func TestRPCClient_NoRetryOnRPCError(t *testing.T) {
+	var calls atomic.Int32
+	server := rpcServer(t, func(path, method string, params []json.RawMessage) (any, *RPCError) {
+		calls.Add(1)
+		return nil, &RPCError{Code: -5, Message: "No such mempool or blockchain transaction"}
+	})
+	defer server.Close()
+
+	c := NewRPCClient(&chaincfg.RegressionNetParams, server.URL, "user", "pass", WithRetry(3, time.Millisecond, time.Millisecond))
+	_, err := c.GetRawTx(context.Background(), "00")
+	var rpcErr *RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	require.Equal(t, -5, rpcErr.Code)
+	require.Equal(t, int32(1), calls.Load())
+
+	// a wrong password isn't worth retrying either
+	calls.Store(0)
+	_, err = NewRPCClient(&chaincfg.RegressionNetParams, server.URL, "user", "wrong").BestBlockHeight(context.Background())
+	require.ErrorIs(t, err, ErrUnauthorized)
+	require.Zero(t, calls.Load())
+}
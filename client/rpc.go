@@ -0,0 +1,318 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+// feeTargets are the confirmation targets RPCClient.FeeEstimate asks
+// estimatesmartfee for.
+var feeTargets = []int{1, 3, 6, 144}
+
+// unsafeMethods are the RPC methods sending funds or transactions, which
+// RequestRPC doesn't retry unless WithRetryBroadcast.
+var unsafeMethods = map[string]bool{
+	"sendrawtransaction": true,
+	"submitpackage":      true,
+	"send":               true,
+	"sendall":            true,
+	"sendmany":           true,
+	"sendtoaddress":      true,
+}
+
+// RPCClient is a Backend on the JSON-RPC API of Bitcoin Core. Without a
+// wallet it finds UTXOs with scantxoutset, which only sees confirmed ones
+// and takes a while. With one it uses listunspent, which only knows the
+// addresses the wallet watches. GetRawTx of other transactions needs
+// -txindex.
+type RPCClient struct {
+	params   *chaincfg.Params
+	url      string
+	user     string
+	password string
+	wallet   string
+
+	opts   options
+	id     atomic.Uint64
+	closed atomic.Bool
+}
+
+// NewRPCClient connects to the RPC server of a node on params at url, e.g.
+// "http://localhost:8332", authenticating as user.
+func NewRPCClient(params *chaincfg.Params, url, user, password string, opts ...Option) *RPCClient {
+	return &RPCClient{
+		params:   params,
+		url:      strings.TrimRight(url, "/"),
+		user:     user,
+		password: password,
+		opts:     newOptions(opts),
+	}
+}
+
+// Wallet makes GetUTXO list the unspent outputs of the loaded wallet name.
+func (c *RPCClient) Wallet(name string) *RPCClient {
+	c.wallet = name
+	return c
+}
+
+func (c *RPCClient) GetParams() *chaincfg.Params {
+	return c.params
+}
+
+// Close makes later calls fail with ErrClosed. It may be called while
+// calls are in flight, which it doesn't interrupt.
+func (c *RPCClient) Close() {
+	c.closed.Store(true)
+}
+
+// RPCError is an error returned by the node.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      uint64 `json:"id"`
+	Method  string `json:"method"`
+	Params  []any  `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// RequestRPC calls method on the node with params, at path for wallet
+// methods, and decodes its result.
+func RequestRPC[T any](ctx context.Context, client *RPCClient, path string, method string, params ...any) (T, error) {
+	if client.closed.Load() {
+		return *new(T), ErrClosed
+	}
+	if params == nil {
+		params = []any{}
+	}
+	payload, err := json.Marshal(rpcRequest{JSONRPC: "1.0", ID: client.id.Add(1), Method: method, Params: params})
+	if err != nil {
+		return *new(T), fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	body, err := client.opts.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, client.url+path, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if client.user != "" || client.password != "" {
+			req.SetBasicAuth(client.user, client.password)
+		}
+		return req, nil
+	}, isRPCError, !unsafeMethods[method])
+	if err != nil {
+		return *new(T), fmt.Errorf("%s: %w", method, err)
+	}
+
+	var res rpcResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return *new(T), fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if res.Error != nil {
+		return *new(T), fmt.Errorf("%s: %w", method, res.Error)
+	}
+	var result T
+	if err := json.Unmarshal(res.Result, &result); err != nil {
+		return *new(T), fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return result, nil
+}
+
+// isRPCError reports whether body is an error of the node, which comes
+// with a status of 500 or 404 and isn't worth retrying.
+func isRPCError(_ int, body []byte) bool {
+	var res rpcResponse
+	return json.Unmarshal(body, &res) == nil && res.Error != nil
+}
+
+func (c *RPCClient) BestBlockHeight(ctx context.Context) (uint64, error) {
+	return RequestRPC[uint64](ctx, c, "", "getblockcount")
+}
+
+type smartFee struct {
+	FeeRate float64  `json:"feerate"`
+	Errors  []string `json:"errors"`
+}
+
+// FeeEstimate converts the estimatesmartfee rates of the usual targets
+// from BTC/kvB. Targets the node has no estimate for yet are left out.
+func (c *RPCClient) FeeEstimate(ctx context.Context) (types.FeeEstimate, error) {
+	estimate := make(types.FeeEstimate)
+	for _, target := range feeTargets {
+		fee, err := RequestRPC[smartFee](ctx, c, "", "estimatesmartfee", target)
+		if err != nil {
+			return nil, err
+		}
+		if fee.FeeRate > 0 {
+			estimate[strconv.Itoa(target)] = fee.FeeRate * btcutil.SatoshiPerBitcoin / 1000
+		}
+	}
+	if len(estimate) == 0 {
+		return nil, errors.New("estimatesmartfee: no estimate available")
+	}
+	return estimate, nil
+}
+
+type scanResult struct {
+	Unspents []struct {
+		Txid   string  `json:"txid"`
+		Vout   uint32  `json:"vout"`
+		Amount float64 `json:"amount"`
+		Height int     `json:"height"`
+	} `json:"unspents"`
+}
+
+type unspent struct {
+	Txid          string  `json:"txid"`
+	Vout          uint32  `json:"vout"`
+	Amount        float64 `json:"amount"`
+	Confirmations int     `json:"confirmations"`
+}
+
+func (c *RPCClient) GetUTXO(ctx context.Context, address string) ([]*types.Utxo, error) {
+	if c.wallet != "" {
+		return c.listUnspent(ctx, address)
+	}
+	scan, err := RequestRPC[scanResult](ctx, c, "", "scantxoutset", "start", []string{"addr(" + address + ")"})
+	if err != nil {
+		return nil, err
+	}
+	utxos := make([]*types.Utxo, 0, len(scan.Unspents))
+	for _, u := range scan.Unspents {
+		value, err := btcutil.NewAmount(u.Amount)
+		if err != nil {
+			return nil, err
+		}
+		utxos = append(utxos, &types.Utxo{
+			Txid:   u.Txid,
+			Vout:   u.Vout,
+			Value:  int64(value),
+			Status: types.BlockStatus{Confirmed: true, BlockHeight: u.Height},
+		})
+	}
+	return utxos, nil
+}
+
+// listUnspent lists the outputs of address in the wallet, unconfirmed ones
+// included.
+func (c *RPCClient) listUnspent(ctx context.Context, address string) ([]*types.Utxo, error) {
+	unspents, err := RequestRPC[[]unspent](ctx, c, "/wallet/"+c.wallet, "listunspent", 0, 9999999, []string{address})
+	if err != nil {
+		return nil, err
+	}
+	var height uint64
+	if len(unspents) > 0 {
+		if height, err = c.BestBlockHeight(ctx); err != nil {
+			return nil, err
+		}
+	}
+	utxos := make([]*types.Utxo, 0, len(unspents))
+	for _, u := range unspents {
+		value, err := btcutil.NewAmount(u.Amount)
+		if err != nil {
+			return nil, err
+		}
+		utxo := &types.Utxo{Txid: u.Txid, Vout: u.Vout, Value: int64(value)}
+		if u.Confirmations > 0 {
+			utxo.Status = types.BlockStatus{Confirmed: true, BlockHeight: int(height) - u.Confirmations + 1}
+		}
+		utxos = append(utxos, utxo)
+	}
+	return utxos, nil
+}
+
+func (c *RPCClient) GetUTXOWithRawTx(ctx context.Context, address string) ([]*types.Utxo, error) {
+	utxos, err := c.GetUTXO(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	return withRawTx(ctx, c, utxos, c.opts.concurrency)
+}
+
+func (c *RPCClient) GetRawTx(ctx context.Context, txid string) (string, error) {
+	return RequestRPC[string](ctx, c, "", "getrawtransaction", txid, false)
+}
+
+type verboseTx struct {
+	BlockHash string `json:"blockhash"`
+	BlockTime int    `json:"blocktime"`
+}
+
+type blockHeader struct {
+	Height int `json:"height"`
+}
+
+func (c *RPCClient) GetTxStatus(ctx context.Context, txid string) (*types.BlockStatus, error) {
+	tx, err := RequestRPC[verboseTx](ctx, c, "", "getrawtransaction", txid, true)
+	if err != nil {
+		return nil, err
+	}
+	if tx.BlockHash == "" {
+		return &types.BlockStatus{}, nil
+	}
+	header, err := RequestRPC[blockHeader](ctx, c, "", "getblockheader", tx.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+	return &types.BlockStatus{
+		Confirmed:   true,
+		BlockHeight: header.Height,
+		BlockHash:   tx.BlockHash,
+		BlockTime:   tx.BlockTime,
+	}, nil
+}
+
+type mempoolAccept struct {
+	Txid         string `json:"txid"`
+	Allowed      bool   `json:"allowed"`
+	RejectReason string `json:"reject-reason"`
+}
+
+// TestMempoolAccept reports whether the node would accept rawTx into its
+// mempool, and why not.
+func (c *RPCClient) TestMempoolAccept(ctx context.Context, rawTx string) (bool, string, error) {
+	results, err := RequestRPC[[]mempoolAccept](ctx, c, "", "testmempoolaccept", []string{rawTx})
+	if err != nil {
+		return false, "", err
+	}
+	if len(results) != 1 {
+		return false, "", fmt.Errorf("testmempoolaccept: %d results for one transaction", len(results))
+	}
+	return results[0].Allowed, results[0].RejectReason, nil
+}
+
+// BroadcastTx checks rawTx with testmempoolaccept first, so that a rejected
+// transaction fails with the reason the node gives.
+func (c *RPCClient) BroadcastTx(ctx context.Context, rawTx string) (string, error) {
+	allowed, reason, err := c.TestMempoolAccept(ctx, rawTx)
+	if err != nil {
+		return "", err
+	}
+	if !allowed {
+		return "", fmt.Errorf("transaction rejected: %s", reason)
+	}
+	return RequestRPC[string](ctx, c, "", "sendrawtransaction", rawTx)
+}
@@ -1,16 +1,16 @@
-package main
-
-import (
-	"log"
-	"os"
-
-	tea "github.com/charmbracelet/bubbletea"
-)
-
-func main() {
-	p := tea.NewProgram(initialModel())
-	if _, err := p.Run(); err != nil {
-		log.Printf("Error: %v\n", err)
-		os.Exit(1)
-	}
-}
+package main
+
+import (
+	"log"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func main() {
+	p := tea.NewProgram(initialModel())
+	if _, err := p.Run(); err != nil {
+		log.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
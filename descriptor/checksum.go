@@ -0,0 +1,90 @@
+package descriptor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BIP380 descriptor checksum, a BCH code over the descriptor characters.
+
+const (
+	inputCharset    = "0123456789()[],'/*abcdefgh@:$%{}IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+	checksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	checksumLength  = 8
+)
+
+var checksumGenerator = [5]uint64{0xf5dee51989, 0xa9fdca3312, 0x1bab10e32d, 0x3706b1677a, 0x644d626ffd}
+
+func polymod(c uint64, value int) uint64 {
+	top := c >> 35
+	c = (c&0x7ffffffff)<<5 ^ uint64(value)
+	for i, g := range checksumGenerator {
+		if (top>>i)&1 == 1 {
+			c ^= g
+		}
+	}
+	return c
+}
+
+// Checksum returns the 8 character checksum of desc, which must not carry
+// one already.
+func Checksum(desc string) (string, error) {
+	c := uint64(1)
+	cls, clsCount := 0, 0
+	for i := 0; i < len(desc); i++ {
+		pos := strings.IndexByte(inputCharset, desc[i])
+		if pos < 0 {
+			return "", fmt.Errorf("invalid character %q in descriptor", desc[i])
+		}
+		// symbols are 5 bits of the position, every 3 symbols the upper
+		// bits of their positions are folded into one more
+		c = polymod(c, pos&31)
+		cls = cls*3 + pos>>5
+		if clsCount++; clsCount == 3 {
+			c = polymod(c, cls)
+			cls, clsCount = 0, 0
+		}
+	}
+	if clsCount > 0 {
+		c = polymod(c, cls)
+	}
+	for range checksumLength {
+		c = polymod(c, 0)
+	}
+	c ^= 1
+
+	var sb strings.Builder
+	for i := range checksumLength {
+		sb.WriteByte(checksumCharset[(c>>(5*(7-i)))&31])
+	}
+	return sb.String(), nil
+}
+
+// AddChecksum returns desc followed by '#' and its checksum.
+func AddChecksum(desc string) (string, error) {
+	sum, err := Checksum(desc)
+	if err != nil {
+		return "", err
+	}
+	return desc + "#" + sum, nil
+}
+
+// splitChecksum separates a trailing "#checksum" from desc and verifies it.
+func splitChecksum(desc string) (string, error) {
+	i := strings.LastIndexByte(desc, '#')
+	if i < 0 {
+		return desc, nil
+	}
+	body, sum := desc[:i], desc[i+1:]
+	if len(sum) != checksumLength {
+		return "", fmt.Errorf("checksum %q must be %d characters", sum, checksumLength)
+	}
+	want, err := Checksum(body)
+	if err != nil {
+		return "", err
+	}
+	if sum != want {
+		return "", fmt.Errorf("checksum mismatch: got %s, want %s", sum, want)
+	}
+	return body, nil
+}
@@ -0,0 +1,661 @@
+// Package descriptor parses output script descriptors (BIP380-386) and
+// derives the scripts, addresses and spend metadata they describe.
+package descriptor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+
+	"github.com/gosuda/btctxbuilder/script"
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+const maxMultisigKeys = 20
+
+// Descriptor is a parsed output descriptor.
+type Descriptor struct {
+	desc string // without checksum
+	root *node
+}
+
+// Output is one scriptPubKey described by a descriptor.
+type Output struct {
+	Index    uint32
+	PkScript []byte
+	// Address is empty for scripts without an address form, like bare
+	// multisig or raw().
+	Address  string
+	AddrType types.AddrType
+	Spend    *types.SpendInfo
+}
+
+// Parse parses desc. A trailing checksum is optional but verified when
+// present.
+func Parse(desc string) (*Descriptor, error) {
+	body, err := splitChecksum(desc)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := Checksum(body); err != nil {
+		return nil, err
+	}
+	root, err := parseScript(body, ctxTop)
+	if err != nil {
+		return nil, err
+	}
+	return &Descriptor{desc: body, root: root}, nil
+}
+
+// String returns the descriptor with its checksum.
+func (d *Descriptor) String() string {
+	s, _ := AddChecksum(d.desc)
+	return s
+}
+
+// IsRange reports whether the descriptor contains a /* wildcard and thus
+// describes a different script per index.
+func (d *Descriptor) IsRange() bool {
+	return d.root.isRange()
+}
+
+// Derive returns the output at index. index is ignored unless IsRange.
+func (d *Descriptor) Derive(params *chaincfg.Params, index uint32) (*Output, error) {
+	out, err := d.root.output(params, index)
+	if err != nil {
+		return nil, err
+	}
+	out.Index = index
+	return out, nil
+}
+
+// DeriveRange returns the outputs for indexes in [from, to).
+func (d *Descriptor) DeriveRange(params *chaincfg.Params, from, to uint32) ([]*Output, error) {
+	if to < from {
+		return nil, fmt.Errorf("invalid range [%d, %d)", from, to)
+	}
+	outs := make([]*Output, 0, to-from)
+	for i := from; i < to; i++ {
+		out, err := d.Derive(params, i)
+		if err != nil {
+			return nil, fmt.Errorf("index %d: %w", i, err)
+		}
+		outs = append(outs, out)
+	}
+	return outs, nil
+}
+
+// -----------------------------------------------------------------------------
+// parsing
+// -----------------------------------------------------------------------------
+
+type scriptContext int
+
+const (
+	ctxTop scriptContext = iota
+	ctxSH
+	ctxWSH
+	ctxTapLeaf
+)
+
+type node struct {
+	fn        string
+	keys      []*keyExpr
+	threshold int
+	sub       *node    // sh, wsh
+	tree      *tapTree // tr
+	addr      string   // addr
+	raw       []byte   // raw
+}
+
+type tapTree struct {
+	left, right *tapTree
+	leaf        *node
+}
+
+func parseScript(s string, ctx scriptContext) (*node, error) {
+	fn, args, err := splitCall(s)
+	if err != nil {
+		return nil, err
+	}
+	n := &node{fn: fn}
+
+	keyCtx := ctxLegacy
+	if ctx == ctxWSH {
+		keyCtx = ctxSegwitV0
+	}
+
+	switch fn {
+	case "pk", "pkh":
+		if ctx == ctxTapLeaf {
+			keyCtx = ctxTaproot
+			if fn == "pkh" {
+				return nil, fmt.Errorf("pkh() is not supported in tapscript")
+			}
+		}
+		if err := n.parseKeys(args, 1, keyCtx); err != nil {
+			return nil, err
+		}
+	case "wpkh":
+		if ctx != ctxTop && ctx != ctxSH {
+			return nil, fmt.Errorf("wpkh() only allowed at top level or in sh()")
+		}
+		if err := n.parseKeys(args, 1, ctxSegwitV0); err != nil {
+			return nil, err
+		}
+	case "multi", "sortedmulti":
+		if ctx == ctxTapLeaf {
+			return nil, fmt.Errorf("%s() not allowed in tapscript, use %s_a()", fn, fn)
+		}
+		if err := n.parseMulti(args, keyCtx); err != nil {
+			return nil, err
+		}
+		// OP_CHECKMULTISIG takes up to 20 keys, but outside of P2WSH a
+		// script with more than 16 is nonstandard
+		if len(n.keys) > 16 && ctx != ctxWSH {
+			return nil, fmt.Errorf("%s() supports at most 16 keys outside wsh()", fn)
+		}
+	case "multi_a", "sortedmulti_a":
+		if ctx != ctxTapLeaf {
+			return nil, fmt.Errorf("%s() only allowed in tapscript", fn)
+		}
+		if err := n.parseMulti(args, ctxTaproot); err != nil {
+			return nil, err
+		}
+	case "sh":
+		if ctx != ctxTop {
+			return nil, fmt.Errorf("sh() only allowed at top level")
+		}
+		if n.sub, err = parseScript(args, ctxSH); err != nil {
+			return nil, err
+		}
+	case "wsh":
+		if ctx != ctxTop && ctx != ctxSH {
+			return nil, fmt.Errorf("wsh() only allowed at top level or in sh()")
+		}
+		if n.sub, err = parseScript(args, ctxWSH); err != nil {
+			return nil, err
+		}
+	case "tr":
+		if ctx != ctxTop {
+			return nil, fmt.Errorf("tr() only allowed at top level")
+		}
+		parts := splitArgs(args)
+		if len(parts) > 2 {
+			return nil, fmt.Errorf("tr() takes a key and an optional tree")
+		}
+		key, err := parseKey(parts[0], ctxTaproot)
+		if err != nil {
+			return nil, err
+		}
+		n.keys = []*keyExpr{key}
+		if len(parts) == 2 {
+			if n.tree, err = parseTapTree(parts[1], 0); err != nil {
+				return nil, err
+			}
+		}
+	case "addr":
+		if ctx != ctxTop {
+			return nil, fmt.Errorf("addr() only allowed at top level")
+		}
+		n.addr = args
+	case "raw":
+		if ctx != ctxTop {
+			return nil, fmt.Errorf("raw() only allowed at top level")
+		}
+		if n.raw, err = hex.DecodeString(args); err != nil {
+			return nil, fmt.Errorf("raw(): %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown script expression %s()", fn)
+	}
+	return n, nil
+}
+
+func (n *node) parseKeys(args string, count int, ctx keyContext) error {
+	parts := splitArgs(args)
+	if len(parts) != count {
+		return fmt.Errorf("%s() takes %d key(s), got %d", n.fn, count, len(parts))
+	}
+	for _, p := range parts {
+		key, err := parseKey(p, ctx)
+		if err != nil {
+			return err
+		}
+		n.keys = append(n.keys, key)
+	}
+	return nil
+}
+
+func (n *node) parseMulti(args string, ctx keyContext) error {
+	parts := splitArgs(args)
+	if len(parts) < 2 {
+		return fmt.Errorf("%s() needs a threshold and keys", n.fn)
+	}
+	k, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return fmt.Errorf("%s() threshold: %w", n.fn, err)
+	}
+	if len(parts)-1 > maxMultisigKeys && ctx != ctxTaproot {
+		return fmt.Errorf("%s() supports at most %d keys", n.fn, maxMultisigKeys)
+	}
+	if k < 1 || k > len(parts)-1 {
+		return fmt.Errorf("%s() threshold %d out of range", n.fn, k)
+	}
+	n.threshold = k
+	return n.parseKeys(strings.Join(parts[1:], ","), len(parts)-1, ctx)
+}
+
+func parseTapTree(s string, depth int) (*tapTree, error) {
+	if depth > txscript.ControlBlockMaxNodeCount {
+		return nil, fmt.Errorf("taproot tree deeper than %d", txscript.ControlBlockMaxNodeCount)
+	}
+	if !strings.HasPrefix(s, "{") {
+		leaf, err := parseScript(s, ctxTapLeaf)
+		if err != nil {
+			return nil, err
+		}
+		return &tapTree{leaf: leaf}, nil
+	}
+	if !strings.HasSuffix(s, "}") {
+		return nil, fmt.Errorf("taproot tree %q not closed", s)
+	}
+	parts := splitArgs(s[1 : len(s)-1])
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("taproot tree branch needs two children: %q", s)
+	}
+	left, err := parseTapTree(parts[0], depth+1)
+	if err != nil {
+		return nil, err
+	}
+	right, err := parseTapTree(parts[1], depth+1)
+	if err != nil {
+		return nil, err
+	}
+	return &tapTree{left: left, right: right}, nil
+}
+
+// splitCall splits "fn(args)" into fn and args.
+func splitCall(s string) (string, string, error) {
+	open := strings.IndexByte(s, '(')
+	if open <= 0 || !strings.HasSuffix(s, ")") {
+		return "", "", fmt.Errorf("invalid script expression %q", s)
+	}
+	return s[:open], s[open+1 : len(s)-1], nil
+}
+
+// splitArgs splits s on commas outside of brackets.
+func splitArgs(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func (n *node) isRange() bool {
+	for _, k := range n.keys {
+		if k.isRange() {
+			return true
+		}
+	}
+	if n.sub != nil && n.sub.isRange() {
+		return true
+	}
+	return n.tree != nil && n.tree.isRange()
+}
+
+func (t *tapTree) isRange() bool {
+	if t.leaf != nil {
+		return t.leaf.isRange()
+	}
+	return t.left.isRange() || t.right.isRange()
+}
+
+// -----------------------------------------------------------------------------
+// derivation
+// -----------------------------------------------------------------------------
+
+func (n *node) deriveKeys(index uint32) ([]*types.KeyOrigin, error) {
+	origins := make([]*types.KeyOrigin, 0, len(n.keys))
+	for _, k := range n.keys {
+		origin, err := k.derive(index)
+		if err != nil {
+			return nil, err
+		}
+		origins = append(origins, origin)
+	}
+	return origins, nil
+}
+
+// output derives a top level expression.
+func (n *node) output(params *chaincfg.Params, index uint32) (*Output, error) {
+	switch n.fn {
+	case "pkh", "wpkh":
+		origins, err := n.deriveKeys(index)
+		if err != nil {
+			return nil, err
+		}
+		addrType := types.P2PKH
+		if n.fn == "wpkh" {
+			addrType = types.P2WPKH
+		}
+		return addressOutput(params, origins[0].PubKey, addrType, &types.SpendInfo{Origins: origins})
+	case "pk", "multi", "sortedmulti":
+		pkScript, origins, err := n.script(index)
+		if err != nil {
+			return nil, err
+		}
+		out := &Output{PkScript: pkScript, Spend: &types.SpendInfo{Origins: origins}}
+		if n.fn == "pk" {
+			out.AddrType = types.P2PK
+			out.Address, err = types.PubKeyToAddr(origins[0].PubKey, types.P2PK, params)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case "sh":
+		return n.shOutput(params, index)
+	case "wsh":
+		witnessScript, origins, err := n.sub.script(index)
+		if err != nil {
+			return nil, err
+		}
+		addr, err := btcutil.NewAddressWitnessScriptHash(witnessScriptHash(witnessScript), params)
+		if err != nil {
+			return nil, err
+		}
+		return scriptOutput(addr, types.P2WSH, &types.SpendInfo{WitnessScript: witnessScript, Origins: origins})
+	case "tr":
+		return n.trOutput(params, index)
+	case "addr":
+		addr, addrType, err := types.DecodeAddress(n.addr, params)
+		if err != nil {
+			return nil, err
+		}
+		return scriptOutput(addr, addrType, nil)
+	case "raw":
+		out := &Output{PkScript: n.raw}
+		if addr, err := script.DecodeTransferScript(n.raw, params); err == nil {
+			out.Address = addr.EncodeAddress()
+			out.AddrType = types.GetAddressType(addr)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("%s() is not a top level expression", n.fn)
+}
+
+func (n *node) shOutput(params *chaincfg.Params, index uint32) (*Output, error) {
+	sub := n.sub
+	switch sub.fn {
+	case "wpkh":
+		origins, err := sub.deriveKeys(index)
+		if err != nil {
+			return nil, err
+		}
+		spend := &types.SpendInfo{Origins: origins}
+		spend.RedeemScript, err = txscript.NewScriptBuilder().
+			AddOp(txscript.OP_0).
+			AddData(btcutil.Hash160(origins[0].PubKey)).
+			Script()
+		if err != nil {
+			return nil, err
+		}
+		return addressOutput(params, origins[0].PubKey, types.P2WPKH_NESTED, spend)
+	case "wsh":
+		witnessScript, origins, err := sub.sub.script(index)
+		if err != nil {
+			return nil, err
+		}
+		redeemScript, err := txscript.NewScriptBuilder().
+			AddOp(txscript.OP_0).
+			AddData(witnessScriptHash(witnessScript)).
+			Script()
+		if err != nil {
+			return nil, err
+		}
+		addr, err := btcutil.NewAddressScriptHash(redeemScript, params)
+		if err != nil {
+			return nil, err
+		}
+		return scriptOutput(addr, types.P2WSH_NESTED, &types.SpendInfo{
+			RedeemScript:  redeemScript,
+			WitnessScript: witnessScript,
+			Origins:       origins,
+		})
+	default:
+		redeemScript, origins, err := sub.script(index)
+		if err != nil {
+			return nil, err
+		}
+		if len(redeemScript) > txscript.MaxScriptElementSize {
+			return nil, fmt.Errorf("redeem script of %d bytes exceeds %d", len(redeemScript), txscript.MaxScriptElementSize)
+		}
+		addr, err := btcutil.NewAddressScriptHash(redeemScript, params)
+		if err != nil {
+			return nil, err
+		}
+		return scriptOutput(addr, types.P2SH, &types.SpendInfo{RedeemScript: redeemScript, Origins: origins})
+	}
+}
+
+func (n *node) trOutput(params *chaincfg.Params, index uint32) (*Output, error) {
+	internal, err := n.keys[0].derive(index)
+	if err != nil {
+		return nil, err
+	}
+	internalKey, err := schnorr.ParsePubKey(internal.XOnlyPubKey())
+	if err != nil {
+		return nil, err
+	}
+
+	spend := &types.SpendInfo{
+		TaprootInternalKey: internal.XOnlyPubKey(),
+		Origins:            []*types.KeyOrigin{internal},
+	}
+	var merkleRoot []byte
+	var leaves []*leafInfo
+	if n.tree != nil {
+		var root [32]byte
+		root, leaves, err = n.tree.build(index)
+		if err != nil {
+			return nil, err
+		}
+		merkleRoot = root[:]
+		spend.TaprootMerkleRoot = merkleRoot
+	}
+
+	outputKey := txscript.ComputeTaprootOutputKey(internalKey, merkleRoot)
+	for _, leaf := range leaves {
+		cb := txscript.ControlBlock{
+			InternalKey:     internalKey,
+			OutputKeyYIsOdd: outputKey.SerializeCompressed()[0] == 0x03,
+			LeafVersion:     txscript.BaseLeafVersion,
+			InclusionProof:  leaf.proof,
+		}
+		cbBytes, err := cb.ToBytes()
+		if err != nil {
+			return nil, err
+		}
+		spend.TaprootLeafScripts = append(spend.TaprootLeafScripts, &psbt.TaprootTapLeafScript{
+			ControlBlock: cbBytes,
+			Script:       leaf.script,
+			LeafVersion:  txscript.BaseLeafVersion,
+		})
+		leafHash := txscript.NewBaseTapLeaf(leaf.script).TapHash()
+		for _, o := range leaf.origins {
+			spend.Origins = addLeafOrigin(spend.Origins, o, leafHash[:])
+		}
+	}
+
+	return addressOutput(params, schnorr.SerializePubKey(outputKey), types.P2TR, spend)
+}
+
+// addLeafOrigin merges the origin of a key used in a tapscript leaf into
+// origins, collecting the hashes of all leaves the key appears in.
+func addLeafOrigin(origins []*types.KeyOrigin, o *types.KeyOrigin, leafHash []byte) []*types.KeyOrigin {
+	for i, have := range origins {
+		// the internal key entry (i == 0) never lists leaves
+		if i > 0 && bytes.Equal(have.XOnlyPubKey(), o.XOnlyPubKey()) {
+			have.LeafHashes = append(have.LeafHashes, leafHash)
+			return origins
+		}
+	}
+	o.LeafHashes = [][]byte{leafHash}
+	return append(origins, o)
+}
+
+type leafInfo struct {
+	script  []byte
+	origins []*types.KeyOrigin
+	proof   []byte // sibling hashes from the leaf up
+}
+
+// build returns the merkle root of the tree and its leaves in depth first
+// order with their inclusion proofs.
+func (t *tapTree) build(index uint32) ([32]byte, []*leafInfo, error) {
+	if t.leaf != nil {
+		leafScript, origins, err := t.leaf.script(index)
+		if err != nil {
+			return [32]byte{}, nil, err
+		}
+		hash := txscript.NewBaseTapLeaf(leafScript).TapHash()
+		return hash, []*leafInfo{{script: leafScript, origins: origins}}, nil
+	}
+
+	leftHash, leftLeaves, err := t.left.build(index)
+	if err != nil {
+		return [32]byte{}, nil, err
+	}
+	rightHash, rightLeaves, err := t.right.build(index)
+	if err != nil {
+		return [32]byte{}, nil, err
+	}
+	for _, l := range leftLeaves {
+		l.proof = append(l.proof, rightHash[:]...)
+	}
+	for _, l := range rightLeaves {
+		l.proof = append(l.proof, leftHash[:]...)
+	}
+	// BIP341 hashes the two children in lexicographic order
+	if bytes.Compare(leftHash[:], rightHash[:]) > 0 {
+		leftHash, rightHash = rightHash, leftHash
+	}
+	branch := chainhash.TaggedHash(chainhash.TagTapBranch, leftHash[:], rightHash[:])
+	return *branch, append(leftLeaves, rightLeaves...), nil
+}
+
+// script builds the script of a non top level expression: the redeem
+// script in sh(), the witness script in wsh() or a tapscript leaf.
+func (n *node) script(index uint32) ([]byte, []*types.KeyOrigin, error) {
+	origins, err := n.deriveKeys(index)
+	if err != nil {
+		return nil, nil, err
+	}
+	pubs := make([][]byte, len(origins))
+	for i, o := range origins {
+		pubs[i] = o.PubKey
+	}
+
+	b := txscript.NewScriptBuilder()
+	switch n.fn {
+	case "pk":
+		b.AddData(pubs[0]).AddOp(txscript.OP_CHECKSIG)
+	case "pkh":
+		b.AddOp(txscript.OP_DUP).AddOp(txscript.OP_HASH160).
+			AddData(btcutil.Hash160(pubs[0])).
+			AddOp(txscript.OP_EQUALVERIFY).AddOp(txscript.OP_CHECKSIG)
+	case "multi", "sortedmulti":
+		if n.fn == "sortedmulti" {
+			sortKeys(pubs)
+		}
+		b.AddInt64(int64(n.threshold))
+		for _, pub := range pubs {
+			b.AddData(pub)
+		}
+		b.AddInt64(int64(len(pubs))).AddOp(txscript.OP_CHECKMULTISIG)
+	case "multi_a", "sortedmulti_a":
+		for i := range pubs {
+			pubs[i] = origins[i].XOnlyPubKey()
+		}
+		if n.fn == "sortedmulti_a" {
+			sortKeys(pubs)
+		}
+		for i, pub := range pubs {
+			b.AddData(pub)
+			if i == 0 {
+				b.AddOp(txscript.OP_CHECKSIG)
+			} else {
+				b.AddOp(txscript.OP_CHECKSIGADD)
+			}
+		}
+		b.AddInt64(int64(n.threshold)).AddOp(txscript.OP_NUMEQUAL)
+	default:
+		return nil, nil, fmt.Errorf("%s() cannot be used here", n.fn)
+	}
+	s, err := b.Script()
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, origins, nil
+}
+
+// sortKeys orders keys lexicographically (BIP67).
+func sortKeys(keys [][]byte) {
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i], keys[j]) < 0
+	})
+}
+
+func addressOutput(params *chaincfg.Params, pub []byte, addrType types.AddrType, spend *types.SpendInfo) (*Output, error) {
+	addrStr, err := types.PubKeyToAddr(pub, addrType, params)
+	if err != nil {
+		return nil, err
+	}
+	addr, _, err := types.DecodeAddress(addrStr, params)
+	if err != nil {
+		return nil, err
+	}
+	return scriptOutput(addr, addrType, spend)
+}
+
+func scriptOutput(addr btcutil.Address, addrType types.AddrType, spend *types.SpendInfo) (*Output, error) {
+	pkScript, err := script.EncodeTransferScript(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Output{
+		PkScript: pkScript,
+		Address:  addr.EncodeAddress(),
+		AddrType: addrType,
+		Spend:    spend,
+	}, nil
+}
+
+func witnessScriptHash(witnessScript []byte) []byte {
+	h := sha256.Sum256(witnessScript)
+	return h[:]
+}
@@ -0,0 +1,247 @@
+package descriptor
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosuda/btctxbuilder/address"
+	"github.com/gosuda/btctxbuilder/types"
+	"github.com/gosuda/btctxbuilder/utils"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func TestChecksum(t *testing.T) {
+	// BIP380 test vectors
+	d, err := Parse("raw(deadbeef)#89f8spxm")
+	require.NoError(t, err)
+	require.Equal(t, "raw(deadbeef)#89f8spxm", d.String())
+
+	_, err = Parse("raw(deadbeef)")
+	require.NoError(t, err)
+
+	for _, bad := range []string{
+		"raw(deadbeef)#",
+		"raw(deadbeef)#89f8spxmx",
+		"raw(deadbeef)#89f8spx",
+		"raw(deedbeef)#89f8spxm",
+		"raw(deadbeef)##9f8spxm",
+		"raw(Ü)#00000000",
+	} {
+		_, err := Parse(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func accountXPub(t *testing.T, addrType types.AddrType) string {
+	t.Helper()
+	account, err := address.NewAccountFromMnemonic(testMnemonic, "", types.BTC, addrType, 0)
+	require.NoError(t, err)
+	xpub, err := account.Key().Neuter()
+	require.NoError(t, err)
+	return xpub.String()
+}
+
+// The same addresses as the BIP84/BIP86 test vectors, from account xpubs.
+func TestDerive_AccountXPub(t *testing.T) {
+	params := types.GetParams(types.BTC)
+
+	wpkh, err := Parse("wpkh([73c5da0a/84'/0'/0']" + accountXPub(t, types.P2WPKH) + "/0/*)")
+	require.NoError(t, err)
+	require.True(t, wpkh.IsRange())
+	outs, err := wpkh.DeriveRange(params, 0, 2)
+	require.NoError(t, err)
+	require.Equal(t, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", outs[0].Address)
+	require.Equal(t, "bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g", outs[1].Address)
+	require.Equal(t, types.P2WPKH, outs[1].AddrType)
+	require.Equal(t, uint32(1), outs[1].Index)
+	origin := outs[1].Spend.Origins[0]
+	require.Equal(t, "[73c5da0a/84'/0'/0'/0/1]", origin.String())
+
+	tr, err := Parse("tr([73c5da0a/86'/0'/0']" + accountXPub(t, types.P2TR) + "/1/*)")
+	require.NoError(t, err)
+	out, err := tr.Derive(params, 0)
+	require.NoError(t, err)
+	require.Equal(t, "bc1p3qkhfews2uk44qtvauqyr2ttdsw7svhkl9nkm9s9c3x4ax5h60wqwruhk7", out.Address)
+	require.Equal(t, out.Spend.Origins[0].XOnlyPubKey(), out.Spend.InternalKey())
+
+	np2wpkh, err := Parse("sh(wpkh([73c5da0a/49'/1'/0']" + func() string {
+		account, err := address.NewAccountFromMnemonic(testMnemonic, "", types.BTC_Testnet3, types.P2WPKH_NESTED, 0)
+		require.NoError(t, err)
+		xpub, err := account.Key().Neuter()
+		require.NoError(t, err)
+		return xpub.String()
+	}() + "/0/*))")
+	require.NoError(t, err)
+	out, err = np2wpkh.Derive(types.GetParams(types.BTC_Testnet3), 0)
+	require.NoError(t, err)
+	require.Equal(t, "2Mww8dCYPUpKHofjgcXcBCEGmniw9CoaiD2", out.Address)
+	require.True(t, txscript.IsPayToWitnessPubKeyHash(out.Spend.RedeemScript))
+}
+
+func TestDerive_PrivateRoot(t *testing.T) {
+	seed, err := address.MnemonicToSeed(testMnemonic, "")
+	require.NoError(t, err)
+	master, err := types.NewHDKeyFromSeed(seed, types.GetParams(types.BTC))
+	require.NoError(t, err)
+
+	d, err := Parse("pkh(" + master.String() + "/44'/0'/0'/0/*)")
+	require.NoError(t, err)
+	out, err := d.Derive(types.GetParams(types.BTC), 0)
+	require.NoError(t, err)
+	require.Equal(t, "1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA", out.Address)
+	// a master key without origin is its own origin
+	require.Equal(t, "[73c5da0a/44'/0'/0'/0/0]", out.Spend.Origins[0].String())
+
+	// hardened wildcards need the private key
+	d, err = Parse("wpkh(" + master.String() + "/84'/0'/0'/*')")
+	require.NoError(t, err)
+	_, err = d.Derive(types.GetParams(types.BTC), 3)
+	require.NoError(t, err)
+	xpub, err := master.Neuter()
+	require.NoError(t, err)
+	d, err = Parse("wpkh(" + xpub.String() + "/*')")
+	require.NoError(t, err)
+	_, err = d.Derive(types.GetParams(types.BTC), 3)
+	require.Error(t, err)
+}
+
+const (
+	key1 = "03a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd"
+	key2 = "02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9"
+	key3 = "025cbdf0646e5db4eaa398f365f2ea7a0e3d419b7e0330e39ce92bddedcac4f9bc"
+)
+
+func TestDerive_Multisig(t *testing.T) {
+	params := types.GetParams(types.BTC)
+
+	multi, err := Parse("multi(2," + key1 + "," + key2 + "," + key3 + ")")
+	require.NoError(t, err)
+	require.False(t, multi.IsRange())
+	out, err := multi.Derive(params, 0)
+	require.NoError(t, err)
+	require.Empty(t, out.Address)
+	require.Equal(t, txscript.MultiSigTy, txscript.GetScriptClass(out.PkScript))
+	require.Len(t, out.Spend.Origins, 3)
+
+	// sortedmulti orders keys by their serialisation (BIP67)
+	sorted, err := Parse("sh(sortedmulti(2," + key1 + "," + key2 + "," + key3 + "))")
+	require.NoError(t, err)
+	want, err := Parse("sh(multi(2," + key3 + "," + key2 + "," + key1 + "))")
+	require.NoError(t, err)
+	got, err := sorted.Derive(params, 0)
+	require.NoError(t, err)
+	wantOut, err := want.Derive(params, 0)
+	require.NoError(t, err)
+	require.Equal(t, wantOut.Address, got.Address)
+	require.Equal(t, types.P2SH, got.AddrType)
+	addr, err := btcutil.NewAddressScriptHash(got.Spend.RedeemScript, params)
+	require.NoError(t, err)
+	require.Equal(t, addr.EncodeAddress(), got.Address)
+
+	wsh, err := Parse("wsh(sortedmulti(2," + key1 + "," + key2 + "))")
+	require.NoError(t, err)
+	out, err = wsh.Derive(params, 0)
+	require.NoError(t, err)
+	require.Equal(t, types.P2WSH, out.AddrType)
+	require.Nil(t, out.Spend.RedeemScript)
+	require.True(t, txscript.IsPayToWitnessScriptHash(out.PkScript))
+
+	shwsh, err := Parse("sh(wsh(sortedmulti(2," + key1 + "," + key2 + ")))")
+	require.NoError(t, err)
+	nested, err := shwsh.Derive(params, 0)
+	require.NoError(t, err)
+	require.Equal(t, types.P2WSH_NESTED, nested.AddrType)
+	require.Equal(t, out.Spend.WitnessScript, nested.Spend.WitnessScript)
+	require.Equal(t, out.PkScript, nested.Spend.RedeemScript)
+}
+
+func TestDerive_MultisigKeyLimit(t *testing.T) {
+	params := types.GetParams(types.BTC)
+	var keys []string
+	for i := range 21 {
+		priv, _ := btcec.PrivKeyFromBytes([]byte{byte(i + 1)})
+		keys = append(keys, utils.HexEncode(priv.PubKey().SerializeCompressed()))
+	}
+	multi := func(n int) string {
+		return "multi(" + strconv.Itoa(n) + "," + strings.Join(keys[:n], ",") + ")"
+	}
+
+	// up to 20 keys under wsh()
+	for _, n := range []int{17, 20} {
+		d, err := Parse("wsh(" + multi(n) + ")")
+		require.NoError(t, err, n)
+		out, err := d.Derive(params, 0)
+		require.NoError(t, err)
+		require.Equal(t, types.P2WSH, out.AddrType)
+		require.Len(t, out.Spend.Origins, n)
+		tokenizer := txscript.MakeScriptTokenizer(0, out.Spend.WitnessScript)
+		var ops [][]byte
+		for tokenizer.Next() {
+			ops = append(ops, tokenizer.Data())
+		}
+		require.NoError(t, tokenizer.Err())
+		require.Len(t, ops, n+3)
+		require.Equal(t, []byte{byte(n)}, ops[n+1])
+		require.Equal(t, byte(txscript.OP_CHECKMULTISIG), tokenizer.Opcode())
+	}
+
+	// 16 elsewhere, and never more than 20
+	for _, bad := range []string{multi(17), "sh(" + multi(17) + ")", "wsh(" + multi(21) + ")"} {
+		_, err := Parse(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestDerive_TaprootTree(t *testing.T) {
+	params := types.GetParams(types.BTC)
+	d, err := Parse("tr(" + key1 + ",{pk(" + key2[2:] + "),{pk(" + key3 + "),sortedmulti_a(1," + key2 + "," + key3 + ")}})")
+	require.NoError(t, err)
+	out, err := d.Derive(params, 0)
+	require.NoError(t, err)
+	require.Equal(t, types.P2TR, out.AddrType)
+	require.Len(t, out.Spend.TaprootLeafScripts, 3)
+	require.Len(t, out.Spend.TaprootMerkleRoot, 32)
+	require.Equal(t, utils.HexMustDecode(key1)[1:], out.Spend.TaprootInternalKey)
+
+	for _, leaf := range out.Spend.TaprootLeafScripts {
+		cb, err := txscript.ParseControlBlock(leaf.ControlBlock)
+		require.NoError(t, err)
+		require.NoError(t, txscript.VerifyTaprootLeafCommitment(cb, out.PkScript[2:], leaf.Script))
+	}
+
+	// key2 and key3 appear in two leaves each, next to the internal key
+	require.Len(t, out.Spend.Origins, 3)
+	require.Empty(t, out.Spend.Origins[0].LeafHashes)
+	for _, o := range out.Spend.Origins[1:] {
+		require.Len(t, o.LeafHashes, 2)
+	}
+
+	multiA := out.Spend.TaprootLeafScripts[2].Script
+	require.True(t, bytes.Contains(multiA, []byte{txscript.OP_CHECKSIGADD}))
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, bad := range []string{
+		"wpkh(04a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd5b8dec5235a0fa8722476c7709c02559e3aa73aa03918ba2d492eea75abea235)",
+		"sh(sh(pk(" + key1 + ")))",
+		"wsh(wpkh(" + key1 + "))",
+		"tr(" + key1 + ",multi(1," + key2 + "))",
+		"pk(" + key1[2:] + ")",
+		"multi(3," + key1 + "," + key2 + ")",
+		"multi_a(1," + key1 + ")",
+		"foo(" + key1 + ")",
+		"pkh([deadbeef" + key1 + ")",
+		"pkh(xpub/0)",
+	} {
+		_, err := Parse(bad)
+		require.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,189 @@
+package descriptor
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+type wildcard int
+
+const (
+	noWildcard wildcard = iota
+	unhardenedWildcard
+	hardenedWildcard
+)
+
+// keyExpr is a KEY expression: an optional [fingerprint/path] origin
+// followed by a hex public key, a WIF private key or an extended key with a
+// derivation path and optional /* wildcard.
+type keyExpr struct {
+	hasOrigin   bool
+	fingerprint uint32 // psbt byte order
+	originPath  []uint32
+
+	pubKey []byte // fixed keys: 33, 65 or (x-only) 32 bytes
+
+	ext      *types.HDKey
+	extPath  []uint32
+	wildcard wildcard
+}
+
+type keyContext int
+
+const (
+	ctxLegacy   keyContext = iota // uncompressed keys allowed
+	ctxSegwitV0                   // compressed keys only
+	ctxTaproot                    // x-only keys allowed
+)
+
+func parseKey(s string, ctx keyContext) (*keyExpr, error) {
+	k := &keyExpr{}
+	if strings.HasPrefix(s, "[") {
+		end := strings.IndexByte(s, ']')
+		if end < 0 {
+			return nil, fmt.Errorf("key origin %q not closed", s)
+		}
+		if err := k.parseOrigin(s[1:end]); err != nil {
+			return nil, err
+		}
+		s = s[end+1:]
+	}
+
+	parts := strings.Split(s, "/")
+	if len(parts) == 1 {
+		return k, k.parseFixedKey(s, ctx)
+	}
+
+	ext, err := types.ParseHDKey(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", parts[0], err)
+	}
+	k.ext = ext
+	steps := parts[1:]
+	switch steps[len(steps)-1] {
+	case "*":
+		k.wildcard = unhardenedWildcard
+		steps = steps[:len(steps)-1]
+	case "*'", "*h", "*H":
+		k.wildcard = hardenedWildcard
+		steps = steps[:len(steps)-1]
+	}
+	k.extPath, err = types.ParseDerivationPath(strings.Join(steps, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if len(steps) > 0 && len(k.extPath) != len(steps) {
+		return nil, fmt.Errorf("invalid derivation path in %q", s)
+	}
+	return k, nil
+}
+
+func (k *keyExpr) parseOrigin(s string) error {
+	fp, path, _ := strings.Cut(s, "/")
+	raw, err := hex.DecodeString(fp)
+	if err != nil || len(raw) != 4 {
+		return fmt.Errorf("invalid key origin fingerprint %q", fp)
+	}
+	k.hasOrigin = true
+	k.fingerprint = binary.LittleEndian.Uint32(raw)
+	if path == "" && strings.HasSuffix(s, "/") {
+		return fmt.Errorf("empty key origin path element in %q", s)
+	}
+	k.originPath, err = types.ParseDerivationPath(path)
+	return err
+}
+
+func (k *keyExpr) parseFixedKey(s string, ctx keyContext) error {
+	if raw, err := hex.DecodeString(s); err == nil {
+		switch {
+		case len(raw) == 32 && ctx == ctxTaproot:
+			if _, err := schnorr.ParsePubKey(raw); err != nil {
+				return fmt.Errorf("key %q: %w", s, err)
+			}
+		case len(raw) == 33:
+			if _, err := btcec.ParsePubKey(raw); err != nil {
+				return fmt.Errorf("key %q: %w", s, err)
+			}
+		case len(raw) == 65 && ctx == ctxLegacy:
+			if _, err := btcec.ParsePubKey(raw); err != nil {
+				return fmt.Errorf("key %q: %w", s, err)
+			}
+		default:
+			return fmt.Errorf("key %q not allowed here", s)
+		}
+		k.pubKey = raw
+		return nil
+	}
+
+	wif, err := btcutil.DecodeWIF(s)
+	if err != nil {
+		return fmt.Errorf("invalid key %q", s)
+	}
+	if !wif.CompressPubKey && ctx != ctxLegacy {
+		return fmt.Errorf("uncompressed key %q not allowed here", s)
+	}
+	k.pubKey = wif.SerializePubKey()
+	return nil
+}
+
+func (k *keyExpr) isRange() bool { return k.wildcard != noWildcard }
+
+// derive returns the public key at index (ignored without a wildcard) and
+// its origin.
+func (k *keyExpr) derive(index uint32) (*types.KeyOrigin, error) {
+	if k.ext == nil {
+		origin := &types.KeyOrigin{
+			PubKey:      k.pubKey,
+			Fingerprint: k.fingerprint,
+			Path:        append([]uint32(nil), k.originPath...),
+		}
+		if !k.hasOrigin {
+			origin.Fingerprint = keyFingerprint(k.pubKey)
+		}
+		return origin, nil
+	}
+
+	path := append([]uint32(nil), k.extPath...)
+	switch k.wildcard {
+	case unhardenedWildcard:
+		path = append(path, index)
+	case hardenedWildcard:
+		path = append(path, index+types.HardenedKeyStart)
+	}
+	child, err := k.ext.Derive(path...)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := child.PubKey()
+	if err != nil {
+		return nil, err
+	}
+
+	// Without an explicit origin the extended key is taken as the root.
+	fingerprint, err := k.ext.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+	var fullPath []uint32
+	if k.hasOrigin {
+		fingerprint = k.fingerprint
+		fullPath = append(fullPath, k.originPath...)
+	}
+	return &types.KeyOrigin{
+		PubKey:      pub,
+		Fingerprint: fingerprint,
+		Path:        append(fullPath, path...),
+	}, nil
+}
+
+func keyFingerprint(pub []byte) uint32 {
+	return binary.LittleEndian.Uint32(btcutil.Hash160(pub)[:4])
+}
@@ -0,0 +1,124 @@
+package frost
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+// Coordinator runs both signing rounds over participants living in the
+// same process. It stands in for the networked coordinator of a real
+// deployment and makes the group usable as a types.Signer.
+type Coordinator struct {
+	GroupKey           *btcec.PublicKey
+	Threshold          int
+	VerificationShares map[uint16]*btcec.PublicKey
+
+	// Signers lists the participants asked to sign. When empty, the first
+	// Threshold participants are used.
+	Signers []uint16
+
+	participants map[uint16]*Participant
+}
+
+// NewCoordinator returns a coordinator for participants, which must share
+// one group key and be at least the threshold.
+func NewCoordinator(participants ...*Participant) (*Coordinator, error) {
+	if len(participants) == 0 {
+		return nil, errors.New("no participants")
+	}
+	first := participants[0].share
+	c := &Coordinator{
+		GroupKey:           first.GroupKey,
+		Threshold:          first.Threshold,
+		VerificationShares: first.VerificationShares,
+		participants:       make(map[uint16]*Participant, len(participants)),
+	}
+	for _, p := range participants {
+		if !p.share.GroupKey.IsEqual(c.GroupKey) || p.share.Threshold != c.Threshold {
+			return nil, fmt.Errorf("participant %d belongs to another group", p.ID())
+		}
+		if _, ok := c.participants[p.ID()]; ok {
+			return nil, fmt.Errorf("duplicate participant %d", p.ID())
+		}
+		c.participants[p.ID()] = p
+		c.Signers = append(c.Signers, p.ID())
+	}
+	if len(c.participants) < c.Threshold {
+		return nil, fmt.Errorf("%d participants can't meet threshold %d", len(c.participants), c.Threshold)
+	}
+	c.Signers = c.Signers[:c.Threshold]
+	return c, nil
+}
+
+// OutputKey returns the x-only taproot output key of the group for a key
+// path spend committing to merkleRoot, or to no scripts (BIP86) if nil.
+func (c *Coordinator) OutputKey(merkleRoot []byte) []byte {
+	return schnorr.SerializePubKey(txscript.ComputeTaprootOutputKey(c.GroupKey, merkleRoot))
+}
+
+// Address returns the taproot address of OutputKey on net.
+func (c *Coordinator) Address(merkleRoot []byte, net *chaincfg.Params) (*btcutil.AddressTaproot, error) {
+	return btcutil.NewAddressTaproot(c.OutputKey(merkleRoot), net)
+}
+
+// Sign runs both rounds with the chosen signers and returns the aggregated
+// BIP340 signature of msg.
+func (c *Coordinator) Sign(msg []byte, keyPath bool, merkleRoot []byte) ([]byte, error) {
+	if len(c.Signers) < c.Threshold {
+		return nil, fmt.Errorf("%d signers can't meet threshold %d", len(c.Signers), c.Threshold)
+	}
+	sessionID := make([]byte, 32)
+	if _, err := rand.Read(sessionID); err != nil {
+		return nil, err
+	}
+
+	pkg := &SigningPackage{Message: msg, KeyPath: keyPath, MerkleRoot: merkleRoot}
+	signers := make([]*Participant, len(c.Signers))
+	for i, id := range c.Signers {
+		p, ok := c.participants[id]
+		if !ok {
+			return nil, fmt.Errorf("unknown signer %d", id)
+		}
+		commitment, err := p.Commit(sessionID)
+		if err != nil {
+			return nil, err
+		}
+		signers[i] = p
+		pkg.Commitments = append(pkg.Commitments, commitment)
+	}
+
+	shares := make([]*SignatureShare, len(signers))
+	for i, p := range signers {
+		share, err := p.Sign(sessionID, pkg)
+		if err != nil {
+			return nil, fmt.Errorf("participant %d: %w", p.ID(), err)
+		}
+		shares[i] = share
+	}
+	return Aggregate(c.GroupKey, pkg, shares, c.VerificationShares)
+}
+
+// Signer returns a types.Signer for taproot key path spends of the output
+// committing to merkleRoot, to be passed to transaction.SignTx.
+func (c *Coordinator) Signer(merkleRoot []byte) types.Signer {
+	return func(msgHash []byte) ([]byte, error) {
+		return c.Sign(msgHash, true, merkleRoot)
+	}
+}
+
+// TapscriptSigner returns a types.Signer for tapscript leaves containing
+// the x-only group key.
+func (c *Coordinator) TapscriptSigner() types.Signer {
+	return func(msgHash []byte) ([]byte, error) {
+		return c.Sign(msgHash, false, nil)
+	}
+}
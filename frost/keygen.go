@@ -0,0 +1,257 @@
+package frost
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+)
+
+// Deal splits secret, a 32-byte private key, into n shares of which any
+// threshold can sign, using Feldman verifiable secret sharing. A nil secret
+// draws a random one. The returned commitments let every participant check
+// its share with KeyShare.Verify before trusting the dealer.
+func Deal(secret []byte, threshold, n int) ([]*KeyShare, []*btcec.PublicKey, error) {
+	if err := validateParams(threshold, n); err != nil {
+		return nil, nil, err
+	}
+	coeffs, err := randomPolynomial(threshold)
+	if err != nil {
+		return nil, nil, err
+	}
+	if secret != nil {
+		if len(secret) != 32 || coeffs[0].SetByteSlice(secret) || coeffs[0].IsZero() {
+			return nil, nil, errors.New("secret must be a non-zero 32-byte scalar")
+		}
+	}
+
+	commitments := commit(coeffs)
+	secrets := make(map[uint16]btcec.ModNScalar, n)
+	for id := uint16(1); int(id) <= n; id++ {
+		secrets[id] = evalPolynomial(coeffs, id)
+	}
+	shares, err := newKeyShares(threshold, n, commitments, secrets)
+	if err != nil {
+		return nil, nil, err
+	}
+	return shares, commitments, nil
+}
+
+// newKeyShares assembles the key shares of participants 1..n from the
+// group commitments and the secret shares at hand.
+func newKeyShares(threshold, n int, commitments []*btcec.PublicKey, secrets map[uint16]btcec.ModNScalar) ([]*KeyShare, error) {
+	verificationShares := make(map[uint16]*btcec.PublicKey, n)
+	for id := uint16(1); int(id) <= n; id++ {
+		y := evalCommitments(commitments, id)
+		pub, err := toPubKey(&y)
+		if err != nil {
+			return nil, fmt.Errorf("participant %d: %w", id, err)
+		}
+		verificationShares[id] = pub
+	}
+
+	var shares []*KeyShare
+	for id := uint16(1); int(id) <= n; id++ {
+		secret, ok := secrets[id]
+		if !ok {
+			continue
+		}
+		if err := verifyShare(id, &secret, commitments); err != nil {
+			return nil, err
+		}
+		shares = append(shares, &KeyShare{
+			ID:                 id,
+			Threshold:          threshold,
+			GroupKey:           commitments[0],
+			VerificationShares: verificationShares,
+			secret:             secret,
+		})
+	}
+	return shares, nil
+}
+
+// DKG is one participant of the Pedersen distributed key generation of the
+// FROST paper, in which no party ever learns the group secret. Every
+// participant broadcasts its Round1 message, sends the shares returned by
+// Round2 privately to their recipients and calls Finish with the shares it
+// received, including its own.
+type DKG struct {
+	ID        uint16
+	Threshold int
+	N         int
+
+	coeffs      []btcec.ModNScalar
+	commitments map[uint16][]*btcec.PublicKey
+}
+
+// DKGRound1 is the broadcast message of the first DKG round: commitments to
+// the participant's polynomial and a proof of knowledge of its constant
+// term, which stops a participant from cancelling out the others' keys.
+type DKGRound1 struct {
+	From        uint16
+	Commitments []*btcec.PublicKey
+	ProofR      *btcec.PublicKey
+	ProofS      btcec.ModNScalar
+}
+
+// NewDKG starts the key generation of participant id out of n.
+func NewDKG(id uint16, threshold, n int) (*DKG, error) {
+	if err := validateParams(threshold, n); err != nil {
+		return nil, err
+	}
+	if id == 0 || int(id) > n {
+		return nil, fmt.Errorf("participant id %d out of range 1..%d", id, n)
+	}
+	return &DKG{ID: id, Threshold: threshold, N: n}, nil
+}
+
+// Round1 draws the participant's polynomial and returns the message to
+// broadcast.
+func (d *DKG) Round1() (*DKGRound1, error) {
+	if d.coeffs != nil {
+		return nil, errors.New("DKG round 1 already done")
+	}
+	coeffs, err := randomPolynomial(d.Threshold)
+	if err != nil {
+		return nil, err
+	}
+	k, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	d.coeffs = coeffs
+
+	msg := &DKGRound1{From: d.ID, Commitments: commit(coeffs)}
+	var r btcec.JacobianPoint
+	btcec.ScalarBaseMultNonConst(&k, &r)
+	if msg.ProofR, err = toPubKey(&r); err != nil {
+		return nil, err
+	}
+	c := dkgChallenge(d.ID, msg.Commitments[0], msg.ProofR)
+	msg.ProofS = *c.Mul(&coeffs[0]).Add(&k)
+	return msg, nil
+}
+
+// Round2 checks the broadcasts of all n participants and returns the secret
+// share f(j) for each participant j, to be sent over a private channel.
+func (d *DKG) Round2(broadcasts []*DKGRound1) (map[uint16]btcec.ModNScalar, error) {
+	if d.coeffs == nil {
+		return nil, errors.New("DKG round 1 not done")
+	}
+	commitments := make(map[uint16][]*btcec.PublicKey, d.N)
+	for _, msg := range broadcasts {
+		if msg.From == 0 || int(msg.From) > d.N {
+			return nil, fmt.Errorf("participant id %d out of range 1..%d", msg.From, d.N)
+		}
+		if _, ok := commitments[msg.From]; ok {
+			return nil, fmt.Errorf("duplicate round 1 message from %d", msg.From)
+		}
+		if len(msg.Commitments) != d.Threshold {
+			return nil, fmt.Errorf("participant %d committed to %d coefficients, want %d", msg.From, len(msg.Commitments), d.Threshold)
+		}
+		if err := verifyDKGProof(msg); err != nil {
+			return nil, err
+		}
+		commitments[msg.From] = msg.Commitments
+	}
+	if len(commitments) != d.N {
+		return nil, fmt.Errorf("got round 1 messages from %d of %d participants", len(commitments), d.N)
+	}
+	d.commitments = commitments
+
+	shares := make(map[uint16]btcec.ModNScalar, d.N)
+	for id := uint16(1); int(id) <= d.N; id++ {
+		shares[id] = evalPolynomial(d.coeffs, id)
+	}
+	return shares, nil
+}
+
+// Finish checks the share received from every participant against its
+// commitments and returns the participant's key share.
+func (d *DKG) Finish(shares map[uint16]btcec.ModNScalar) (*KeyShare, error) {
+	if d.commitments == nil {
+		return nil, errors.New("DKG round 2 not done")
+	}
+	if len(shares) != d.N {
+		return nil, fmt.Errorf("got shares from %d of %d participants", len(shares), d.N)
+	}
+
+	var secret btcec.ModNScalar
+	group := make([]btcec.JacobianPoint, d.Threshold)
+	for from, share := range shares {
+		commitments, ok := d.commitments[from]
+		if !ok {
+			return nil, fmt.Errorf("share from unknown participant %d", from)
+		}
+		if err := verifyShare(d.ID, &share, commitments); err != nil {
+			return nil, fmt.Errorf("share from participant %d: %w", from, err)
+		}
+		secret.Add(&share)
+		for k, c := range commitments {
+			var p btcec.JacobianPoint
+			c.AsJacobian(&p)
+			btcec.AddNonConst(&group[k], &p, &group[k])
+		}
+	}
+
+	commitments := make([]*btcec.PublicKey, d.Threshold)
+	for k := range group {
+		var err error
+		if commitments[k], err = toPubKey(&group[k]); err != nil {
+			return nil, err
+		}
+	}
+	keyShares, err := newKeyShares(d.Threshold, d.N, commitments, map[uint16]btcec.ModNScalar{d.ID: secret})
+	if err != nil {
+		return nil, err
+	}
+	d.coeffs = nil
+	return keyShares[0], nil
+}
+
+func randomPolynomial(threshold int) ([]btcec.ModNScalar, error) {
+	coeffs := make([]btcec.ModNScalar, threshold)
+	for i := range coeffs {
+		var err error
+		if coeffs[i], err = randomScalar(); err != nil {
+			return nil, err
+		}
+	}
+	return coeffs, nil
+}
+
+// commit returns the Feldman commitments a_k*G of the coefficients.
+func commit(coeffs []btcec.ModNScalar) []*btcec.PublicKey {
+	commitments := make([]*btcec.PublicKey, len(coeffs))
+	for i := range coeffs {
+		var p btcec.JacobianPoint
+		btcec.ScalarBaseMultNonConst(&coeffs[i], &p)
+		p.ToAffine()
+		commitments[i] = btcec.NewPublicKey(&p.X, &p.Y)
+	}
+	return commitments
+}
+
+func dkgChallenge(id uint16, c0, r *btcec.PublicKey) btcec.ModNScalar {
+	return hashToScalar(tagDKG, encodeID(id), c0.SerializeCompressed(), r.SerializeCompressed())
+}
+
+// verifyDKGProof checks ProofS*G == ProofR + c*C_0.
+func verifyDKGProof(msg *DKGRound1) error {
+	if msg.ProofR == nil || msg.Commitments[0] == nil {
+		return fmt.Errorf("participant %d sent an incomplete round 1 message", msg.From)
+	}
+	c := dkgChallenge(msg.From, msg.Commitments[0], msg.ProofR)
+	var lhs, rhs, c0, r btcec.JacobianPoint
+	btcec.ScalarBaseMultNonConst(&msg.ProofS, &lhs)
+	msg.Commitments[0].AsJacobian(&c0)
+	msg.ProofR.AsJacobian(&r)
+	btcec.ScalarMultNonConst(&c, &c0, &rhs)
+	btcec.AddNonConst(&rhs, &r, &rhs)
+	lhs.ToAffine()
+	rhs.ToAffine()
+	if !lhs.X.Equals(&rhs.X) || !lhs.Y.Equals(&rhs.Y) {
+		return fmt.Errorf("invalid proof of knowledge from participant %d", msg.From)
+	}
+	return nil
+}
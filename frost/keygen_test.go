@@ -0,0 +1,141 @@
+package frost
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// runDKG runs the key generation among n in-process participants.
+func runDKG(t *testing.T, threshold, n int) []*KeyShare {
+	t.Helper()
+	dkgs := make([]*DKG, n)
+	var broadcasts []*DKGRound1
+	for i := range dkgs {
+		d, err := NewDKG(uint16(i+1), threshold, n)
+		require.NoError(t, err)
+		msg, err := d.Round1()
+		require.NoError(t, err)
+		dkgs[i] = d
+		broadcasts = append(broadcasts, msg)
+	}
+
+	received := make([]map[uint16]btcec.ModNScalar, n)
+	for i := range received {
+		received[i] = map[uint16]btcec.ModNScalar{}
+	}
+	for _, d := range dkgs {
+		shares, err := d.Round2(broadcasts)
+		require.NoError(t, err)
+		for to, share := range shares {
+			received[to-1][d.ID] = share
+		}
+	}
+
+	keyShares := make([]*KeyShare, n)
+	for i, d := range dkgs {
+		share, err := d.Finish(received[i])
+		require.NoError(t, err)
+		keyShares[i] = share
+	}
+	return keyShares
+}
+
+// groupSecret interpolates the group secret from shares, which only a
+// test can do.
+func groupSecret(t *testing.T, shares []*KeyShare) btcec.ModNScalar {
+	t.Helper()
+	var ids []uint16
+	for _, s := range shares {
+		ids = append(ids, s.ID)
+	}
+	var secret btcec.ModNScalar
+	for _, s := range shares {
+		l, err := lagrange(s.ID, ids)
+		require.NoError(t, err)
+		secret.Add(l.Mul(&s.secret))
+	}
+	return secret
+}
+
+func TestDeal(t *testing.T) {
+	secret := make([]byte, 32)
+	secret[31] = 42
+	shares, commitments, err := Deal(secret, 2, 3)
+	require.NoError(t, err)
+	require.Len(t, shares, 3)
+	require.Len(t, commitments, 2)
+
+	priv, pub := btcec.PrivKeyFromBytes(secret)
+	require.True(t, shares[0].GroupKey.IsEqual(pub))
+	for _, s := range shares {
+		require.NoError(t, s.Verify(commitments))
+	}
+	for _, pair := range [][]*KeyShare{shares[:2], shares[1:], {shares[0], shares[2]}} {
+		got := groupSecret(t, pair)
+		require.True(t, got.Equals(&priv.Key))
+	}
+
+	// a tampered share no longer matches the commitments
+	bad := *shares[1]
+	one := scalarFromID(1)
+	bad.secret.Add(&one)
+	require.Error(t, bad.Verify(commitments))
+
+	_, _, err = Deal(nil, 4, 3)
+	require.Error(t, err)
+	_, _, err = Deal(make([]byte, 32), 2, 3)
+	require.Error(t, err)
+}
+
+func TestDKG(t *testing.T) {
+	shares := runDKG(t, 3, 5)
+	for _, s := range shares[1:] {
+		require.True(t, s.GroupKey.IsEqual(shares[0].GroupKey))
+	}
+	for _, s := range shares {
+		require.True(t, s.PubKey().IsEqual(scalarBasePoint(&s.secret)))
+	}
+
+	secret := groupSecret(t, shares[:3])
+	require.True(t, scalarBasePoint(&secret).IsEqual(shares[0].GroupKey))
+	other := groupSecret(t, []*KeyShare{shares[4], shares[1], shares[3]})
+	require.True(t, other.Equals(&secret))
+
+	t.Run("bad proof", func(t *testing.T) {
+		a, err := NewDKG(1, 2, 2)
+		require.NoError(t, err)
+		b, err := NewDKG(2, 2, 2)
+		require.NoError(t, err)
+		m1, err := a.Round1()
+		require.NoError(t, err)
+		m2, err := b.Round1()
+		require.NoError(t, err)
+
+		m2.ProofS.Add(&m1.ProofS)
+		_, err = a.Round2([]*DKGRound1{m1, m2})
+		require.ErrorContains(t, err, "proof of knowledge")
+		_, err = a.Round2([]*DKGRound1{m1})
+		require.Error(t, err)
+	})
+
+	t.Run("bad share", func(t *testing.T) {
+		a, err := NewDKG(1, 2, 2)
+		require.NoError(t, err)
+		b, err := NewDKG(2, 2, 2)
+		require.NoError(t, err)
+		m1, err := a.Round1()
+		require.NoError(t, err)
+		m2, err := b.Round1()
+		require.NoError(t, err)
+		fromA, err := a.Round2([]*DKGRound1{m1, m2})
+		require.NoError(t, err)
+		fromB, err := b.Round2([]*DKGRound1{m1, m2})
+		require.NoError(t, err)
+
+		// b sends a the share meant for b
+		_, err = a.Finish(map[uint16]btcec.ModNScalar{1: fromA[1], 2: fromB[2]})
+		require.ErrorContains(t, err, "participant 2")
+	})
+}
@@ -0,0 +1,342 @@
+package frost
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// NonceCommitment is the first round message of a signer: the public
+// hiding and binding nonces D = d*G and E = e*G.
+type NonceCommitment struct {
+	ID      uint16
+	Hiding  *btcec.PublicKey
+	Binding *btcec.PublicKey
+}
+
+// SignatureShare is the second round message of a signer.
+type SignatureShare struct {
+	ID uint16
+	Z  btcec.ModNScalar
+}
+
+// SigningPackage is what the coordinator hands every signer in the second
+// round. The signers are the participants that sent Commitments.
+type SigningPackage struct {
+	Message     []byte
+	Commitments []*NonceCommitment
+
+	// KeyPath signs for the taproot output key committing to MerkleRoot,
+	// or to no script tree (BIP86) when it is nil. Otherwise the signature
+	// is for the x-only group key itself, as used in a tapscript leaf.
+	KeyPath    bool
+	MerkleRoot []byte
+}
+
+// signingSession holds what both signers and the coordinator derive from a
+// SigningPackage.
+type signingSession struct {
+	ids         []uint16
+	commitments map[uint16]*NonceCommitment
+	rho         map[uint16]btcec.ModNScalar
+
+	outputKey *btcec.PublicKey // even y
+	r         btcec.JacobianPoint
+	negR      bool // R had an odd y, so every nonce is negated
+	c         btcec.ModNScalar
+
+	// keySign is +-1 and turns the group secret into the secret of the
+	// even-y output key: s_out = keySign*x + tweakSign*tweak.
+	keySign   btcec.ModNScalar
+	tweakTerm btcec.ModNScalar // tweakSign*tweak, added once at aggregation
+}
+
+func newSigningSession(groupKey *btcec.PublicKey, pkg *SigningPackage) (*signingSession, error) {
+	if len(pkg.Commitments) == 0 {
+		return nil, errors.New("no nonce commitments")
+	}
+	s := &signingSession{
+		commitments: make(map[uint16]*NonceCommitment, len(pkg.Commitments)),
+		rho:         make(map[uint16]btcec.ModNScalar, len(pkg.Commitments)),
+	}
+	for _, c := range pkg.Commitments {
+		if c == nil || c.Hiding == nil || c.Binding == nil {
+			return nil, errors.New("incomplete nonce commitment")
+		}
+		if _, ok := s.commitments[c.ID]; ok || c.ID == 0 {
+			return nil, fmt.Errorf("invalid or duplicate signer %d", c.ID)
+		}
+		s.commitments[c.ID] = c
+		s.ids = append(s.ids, c.ID)
+	}
+	slices.Sort(s.ids)
+
+	// Output key Q = gP*P + t*G, where gP makes the group key even; then
+	// Q itself is negated if its y is odd, which flips both terms.
+	var p, q btcec.JacobianPoint
+	groupKey.AsJacobian(&p)
+	s.keySign.SetInt(1)
+	if groupKey.SerializeCompressed()[0] == 0x03 {
+		s.keySign.Negate()
+		p.Y.Negate(1).Normalize()
+	}
+	q = p
+	if pkg.KeyPath {
+		tweak := hashToScalar(chainhash.TagTapTweak, schnorr.SerializePubKey(groupKey), pkg.MerkleRoot)
+		var tG btcec.JacobianPoint
+		btcec.ScalarBaseMultNonConst(&tweak, &tG)
+		btcec.AddNonConst(&p, &tG, &q)
+		s.tweakTerm = tweak
+	}
+	outputKey, err := toPubKey(&q)
+	if err != nil {
+		return nil, err
+	}
+	if outputKey.SerializeCompressed()[0] == 0x03 {
+		s.keySign.Negate()
+		s.tweakTerm.Negate()
+	}
+	s.outputKey, err = schnorr.ParsePubKey(schnorr.SerializePubKey(outputKey))
+	if err != nil {
+		return nil, err
+	}
+	outputKeyX := schnorr.SerializePubKey(s.outputKey)
+
+	// rho_i = H(Q || msg || commitments || i) binds every nonce to the
+	// whole signer set and message.
+	var encoded bytes.Buffer
+	for _, id := range s.ids {
+		c := s.commitments[id]
+		encoded.Write(encodeID(id))
+		encoded.Write(c.Hiding.SerializeCompressed())
+		encoded.Write(c.Binding.SerializeCompressed())
+	}
+	msgHash := chainhash.HashB(pkg.Message)
+	for _, id := range s.ids {
+		s.rho[id] = hashToScalar(tagBinding, outputKeyX, msgHash, encoded.Bytes(), encodeID(id))
+	}
+
+	// R = sum(D_i + rho_i*E_i)
+	for _, id := range s.ids {
+		ri := s.signerNonce(id)
+		btcec.AddNonConst(&s.r, &ri, &s.r)
+	}
+	rKey, err := toPubKey(&s.r)
+	if err != nil {
+		return nil, err
+	}
+	s.negR = rKey.SerializeCompressed()[0] == 0x03
+	s.c = hashToScalar(chainhash.TagBIP0340Challenge, schnorr.SerializePubKey(rKey), outputKeyX, pkg.Message)
+	return s, nil
+}
+
+// signerNonce returns D_i + rho_i*E_i.
+func (s *signingSession) signerNonce(id uint16) btcec.JacobianPoint {
+	c := s.commitments[id]
+	rho := s.rho[id]
+	var d, e btcec.JacobianPoint
+	c.Hiding.AsJacobian(&d)
+	c.Binding.AsJacobian(&e)
+	btcec.ScalarMultNonConst(&rho, &e, &e)
+	btcec.AddNonConst(&d, &e, &d)
+	return d
+}
+
+// keyFactor returns lambda_i * c * keySign, the factor of the secret share
+// of id in its signature share.
+func (s *signingSession) keyFactor(id uint16) (btcec.ModNScalar, error) {
+	l, err := lagrange(id, s.ids)
+	if err != nil {
+		return btcec.ModNScalar{}, err
+	}
+	return *l.Mul(&s.c).Mul(&s.keySign), nil
+}
+
+// verifyShare checks z_i*G == +-(D_i + rho_i*E_i) + keyFactor_i*Y_i.
+func (s *signingSession) verifyShare(share *SignatureShare, pubKey *btcec.PublicKey) error {
+	if _, ok := s.commitments[share.ID]; !ok {
+		return fmt.Errorf("signature share from %d, who didn't commit to a nonce", share.ID)
+	}
+	factor, err := s.keyFactor(share.ID)
+	if err != nil {
+		return err
+	}
+	nonce := s.signerNonce(share.ID)
+	if s.negR {
+		nonce.Y.Negate(1).Normalize()
+	}
+	var lhs, rhs, y btcec.JacobianPoint
+	btcec.ScalarBaseMultNonConst(&share.Z, &lhs)
+	pubKey.AsJacobian(&y)
+	btcec.ScalarMultNonConst(&factor, &y, &rhs)
+	btcec.AddNonConst(&rhs, &nonce, &rhs)
+	lhs.ToAffine()
+	rhs.ToAffine()
+	if !lhs.X.Equals(&rhs.X) || !lhs.Y.Equals(&rhs.Y) {
+		return fmt.Errorf("invalid signature share from participant %d", share.ID)
+	}
+	return nil
+}
+
+// aggregate sums the shares into a BIP340 signature and verifies it.
+func (s *signingSession) aggregate(shares []*SignatureShare, msg []byte) ([]byte, error) {
+	var z btcec.ModNScalar
+	seen := map[uint16]bool{}
+	for _, share := range shares {
+		if seen[share.ID] {
+			return nil, fmt.Errorf("duplicate signature share from %d", share.ID)
+		}
+		seen[share.ID] = true
+		z.Add(&share.Z)
+	}
+	for _, id := range s.ids {
+		if !seen[id] {
+			return nil, fmt.Errorf("missing signature share from %d", id)
+		}
+	}
+	tweak := s.tweakTerm
+	z.Add(tweak.Mul(&s.c))
+
+	r := s.r
+	r.ToAffine()
+	sig := schnorr.NewSignature(&r.X, &z)
+	if !sig.Verify(msg, s.outputKey) {
+		return nil, errors.New("aggregated signature does not verify")
+	}
+	return sig.Serialize(), nil
+}
+
+// Participant signs with one key share. It keeps the secret nonces of every
+// open session until the matching Sign call and never uses them twice.
+type Participant struct {
+	share *KeyShare
+
+	mu     sync.Mutex
+	nonces map[string][2]btcec.ModNScalar
+}
+
+// NewParticipant creates a signer for share.
+func NewParticipant(share *KeyShare) *Participant {
+	return &Participant{share: share, nonces: map[string][2]btcec.ModNScalar{}}
+}
+
+// ID returns the identifier of the participant.
+func (p *Participant) ID() uint16 {
+	return p.share.ID
+}
+
+// KeyShare returns the key share the participant signs with.
+func (p *Participant) KeyShare() *KeyShare {
+	return p.share
+}
+
+// Commit runs the first round for the session identified by sessionID.
+func (p *Participant) Commit(sessionID []byte) (*NonceCommitment, error) {
+	var nonces [2]btcec.ModNScalar
+	points := make([]*btcec.PublicKey, 2)
+	for i := range nonces {
+		// Hedge the randomness with the secret share, as RFC 9591 does,
+		// so a weak RNG alone doesn't leak the key.
+		random, err := randomScalar()
+		if err != nil {
+			return nil, err
+		}
+		rb, sb := random.Bytes(), p.share.secret.Bytes()
+		nonces[i] = hashToScalar(tagNonce, rb[:], sb[:])
+		if nonces[i].IsZero() {
+			return nil, errors.New("zero nonce")
+		}
+		var pt btcec.JacobianPoint
+		btcec.ScalarBaseMultNonConst(&nonces[i], &pt)
+		pt.ToAffine()
+		points[i] = btcec.NewPublicKey(&pt.X, &pt.Y)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.nonces[string(sessionID)]; ok {
+		return nil, fmt.Errorf("session %x already has a nonce", sessionID)
+	}
+	p.nonces[string(sessionID)] = nonces
+	return &NonceCommitment{ID: p.share.ID, Hiding: points[0], Binding: points[1]}, nil
+}
+
+// Sign runs the second round for the session started with Commit, which
+// ends the session.
+func (p *Participant) Sign(sessionID []byte, pkg *SigningPackage) (*SignatureShare, error) {
+	p.mu.Lock()
+	nonces, ok := p.nonces[string(sessionID)]
+	delete(p.nonces, string(sessionID))
+	p.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no nonce for session %x", sessionID)
+	}
+
+	s, err := newSigningSession(p.share.GroupKey, pkg)
+	if err != nil {
+		return nil, err
+	}
+	own, ok := s.commitments[p.share.ID]
+	if !ok {
+		return nil, fmt.Errorf("participant %d is not a signer", p.share.ID)
+	}
+	if !own.Hiding.IsEqual(scalarBasePoint(&nonces[0])) || !own.Binding.IsEqual(scalarBasePoint(&nonces[1])) {
+		return nil, errors.New("signing package carries a different nonce commitment")
+	}
+
+	// z_i = +-(d_i + rho_i*e_i) + lambda_i*c*keySign*s_i
+	rho := s.rho[p.share.ID]
+	z := nonces[1]
+	z.Mul(&rho).Add(&nonces[0])
+	if s.negR {
+		z.Negate()
+	}
+	factor, err := s.keyFactor(p.share.ID)
+	if err != nil {
+		return nil, err
+	}
+	z.Add(factor.Mul(&p.share.secret))
+	return &SignatureShare{ID: p.share.ID, Z: z}, nil
+}
+
+func scalarBasePoint(k *btcec.ModNScalar) *btcec.PublicKey {
+	var p btcec.JacobianPoint
+	btcec.ScalarBaseMultNonConst(k, &p)
+	p.ToAffine()
+	return btcec.NewPublicKey(&p.X, &p.Y)
+}
+
+// VerifySignatureShare checks the share of one signer against its public
+// key share, which identifies a misbehaving signer when aggregation fails.
+func VerifySignatureShare(groupKey *btcec.PublicKey, pkg *SigningPackage, share *SignatureShare, pubKey *btcec.PublicKey) error {
+	s, err := newSigningSession(groupKey, pkg)
+	if err != nil {
+		return err
+	}
+	return s.verifyShare(share, pubKey)
+}
+
+// Aggregate combines the signature shares of every signer in pkg into a
+// 64-byte BIP340 signature. Each share is verified first.
+func Aggregate(groupKey *btcec.PublicKey, pkg *SigningPackage, shares []*SignatureShare, verificationShares map[uint16]*btcec.PublicKey) ([]byte, error) {
+	s, err := newSigningSession(groupKey, pkg)
+	if err != nil {
+		return nil, err
+	}
+	for _, share := range shares {
+		pubKey, ok := verificationShares[share.ID]
+		if !ok {
+			return nil, fmt.Errorf("no public key share for participant %d", share.ID)
+		}
+		if err := s.verifyShare(share, pubKey); err != nil {
+			return nil, err
+		}
+	}
+	return s.aggregate(shares, pkg.Message)
+}
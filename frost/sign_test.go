@@ -0,0 +1,158 @@
+package frost
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCoordinator(t *testing.T, shares []*KeyShare) *Coordinator {
+	t.Helper()
+	participants := make([]*Participant, len(shares))
+	for i, s := range shares {
+		participants[i] = NewParticipant(s)
+	}
+	c, err := NewCoordinator(participants...)
+	require.NoError(t, err)
+	return c
+}
+
+func TestCoordinator_Sign(t *testing.T) {
+	dealt, _, err := Deal(nil, 2, 3)
+	require.NoError(t, err)
+
+	msg := sha256.Sum256([]byte("frost"))
+	merkleRoot := sha256.Sum256([]byte("tree"))
+	for name, shares := range map[string][]*KeyShare{
+		"dealer 2-of-3": dealt,
+		"dkg 3-of-5":    runDKG(t, 3, 5),
+		"dkg 1-of-1":    runDKG(t, 1, 1),
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := newTestCoordinator(t, shares)
+			groupKey, err := schnorr.ParsePubKey(schnorr.SerializePubKey(c.GroupKey))
+			require.NoError(t, err)
+
+			// every subset of threshold signers produces a valid signature
+			combinations(len(shares), c.Threshold, func(idx []int) {
+				c.Signers = nil
+				for _, i := range idx {
+					c.Signers = append(c.Signers, shares[i].ID)
+				}
+
+				sig, err := c.Signer(nil)(msg[:])
+				require.NoError(t, err)
+				outputKey, err := schnorr.ParsePubKey(c.OutputKey(nil))
+				require.NoError(t, err)
+				requireValidSig(t, sig, msg[:], outputKey)
+
+				sig, err = c.Signer(merkleRoot[:])(msg[:])
+				require.NoError(t, err)
+				outputKey, err = schnorr.ParsePubKey(c.OutputKey(merkleRoot[:]))
+				require.NoError(t, err)
+				requireValidSig(t, sig, msg[:], outputKey)
+
+				sig, err = c.TapscriptSigner()(msg[:])
+				require.NoError(t, err)
+				requireValidSig(t, sig, msg[:], groupKey)
+			})
+
+			if c.Threshold > 1 {
+				c.Signers = c.Signers[:c.Threshold-1]
+				_, err = c.Sign(msg[:], true, nil)
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestCoordinator_OutputKey(t *testing.T) {
+	shares, _, err := Deal(nil, 2, 3)
+	require.NoError(t, err)
+	c := newTestCoordinator(t, shares)
+	want := txscript.ComputeTaprootKeyNoScript(c.GroupKey)
+	require.Equal(t, schnorr.SerializePubKey(want), c.OutputKey(nil))
+
+	_, err = NewCoordinator(NewParticipant(shares[0]))
+	require.Error(t, err)
+	other, _, err := Deal(nil, 2, 3)
+	require.NoError(t, err)
+	_, err = NewCoordinator(NewParticipant(shares[0]), NewParticipant(other[1]))
+	require.Error(t, err)
+}
+
+func TestParticipant_Sign(t *testing.T) {
+	shares, _, err := Deal(nil, 2, 3)
+	require.NoError(t, err)
+	p1, p2 := NewParticipant(shares[0]), NewParticipant(shares[1])
+	msg := sha256.Sum256([]byte("frost"))
+	session := []byte("session")
+
+	c1, err := p1.Commit(session)
+	require.NoError(t, err)
+	c2, err := p2.Commit(session)
+	require.NoError(t, err)
+	_, err = p1.Commit(session)
+	require.Error(t, err)
+
+	pkg := &SigningPackage{Message: msg[:], Commitments: []*NonceCommitment{c2, c1}, KeyPath: true}
+	z1, err := p1.Sign(session, pkg)
+	require.NoError(t, err)
+	z2, err := p2.Sign(session, pkg)
+	require.NoError(t, err)
+
+	groupKey := shares[0].GroupKey
+	require.NoError(t, VerifySignatureShare(groupKey, pkg, z1, shares[0].PubKey()))
+	require.Error(t, VerifySignatureShare(groupKey, pkg, z1, shares[1].PubKey()))
+
+	// a nonce is used once
+	_, err = p1.Sign(session, pkg)
+	require.ErrorContains(t, err, "no nonce")
+
+	// a bad share is caught before aggregation
+	bad := *z2
+	one := scalarFromID(1)
+	bad.Z.Add(&one)
+	_, err = Aggregate(groupKey, pkg, []*SignatureShare{z1, &bad}, shares[0].VerificationShares)
+	require.ErrorContains(t, err, "participant 2")
+	_, err = Aggregate(groupKey, pkg, []*SignatureShare{z1}, shares[0].VerificationShares)
+	require.ErrorContains(t, err, "missing")
+
+	sig, err := Aggregate(groupKey, pkg, []*SignatureShare{z1, z2}, shares[0].VerificationShares)
+	require.NoError(t, err)
+	outputKey, err := schnorr.ParsePubKey(schnorr.SerializePubKey(txscript.ComputeTaprootKeyNoScript(groupKey)))
+	require.NoError(t, err)
+	requireValidSig(t, sig, msg[:], outputKey)
+
+	// a package swapping in another nonce is refused
+	c3, err := p1.Commit(session)
+	require.NoError(t, err)
+	c3.Hiding = c1.Hiding
+	_, err = p1.Sign(session, &SigningPackage{Message: msg[:], Commitments: []*NonceCommitment{c3, c2}})
+	require.Error(t, err)
+}
+
+func combinations(n, k int, fn func([]int)) {
+	var rec func(start int, idx []int)
+	rec = func(start int, idx []int) {
+		if len(idx) == k {
+			fn(idx)
+			return
+		}
+		for i := start; i < n; i++ {
+			rec(i+1, append(idx, i))
+		}
+	}
+	rec(0, nil)
+}
+
+func requireValidSig(t *testing.T, sig, msg []byte, pubKey *btcec.PublicKey) {
+	t.Helper()
+	parsed, err := schnorr.ParseSignature(sig)
+	require.NoError(t, err)
+	require.True(t, parsed.Verify(msg, pubKey))
+}
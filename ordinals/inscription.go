@@ -1,50 +1,365 @@
-package ordinals
-
-import (
-	"github.com/btcsuite/btcd/btcutil/psbt"
-	"github.com/btcsuite/btcd/chaincfg"
-
-	"github.com/gosuda/btctxbuilder/transaction"
-)
-
-// func NewInscribeTx(c *client.Client, fromAddress string, fundAddress string, inscriptionDatas []*InscriptionData) (revealTx []*psbt.Packet, commitTx *psbt.Packet, err error) {
-// 	fromPubKey, err := types.AddrP2TRToPubkey(fromAddress, c.GetParams())
-// 	if err != nil {
-// 		return nil, nil, err
-// 	}
-
-// 	for _, inscriptionData := range inscriptionDatas {
-// 		inscriptionScript, err := script.CreateInscriptionScript(fromPubKey, inscriptionData.ContentType, inscriptionData.Body, []byte(inscriptionData.RevealAddr))
-// 		if err != nil {
-// 			return nil, nil, err
-// 		}
-
-// 	}
-
-// 	return nil, nil, nil
-// }
-
-// 1. Build the empty reveal transactions
-func buildRevealTxs(params *chaincfg.Params, inscriptionDatas []*InscriptionData, feeRate float64) (revealTx []*psbt.Packet, err error) {
-	builder := transaction.NewTxBuilder(params)
-	_ = builder
-	for _, inscriptionData := range inscriptionDatas {
-		_ = inscriptionData
-	}
-	return nil, nil
-}
-
-// 2. Build commit transaction
-func buildCommitTx() {
-
-}
-
-// 3. Complete and Sign the reveal transactions
-func signRevealTxs() {
-
-}
-
-// 4. Sign the commit transaction
-func signCommitTx() {
-
-}
+package ordinals
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcwallet/wallet/txrules"
+
+	"github.com/gosuda/btctxbuilder/ordinals/runes"
+	"github.com/gosuda/btctxbuilder/script"
+	"github.com/gosuda/btctxbuilder/transaction"
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+// Inscriber inscribes data in two steps. The commit transaction pays to one
+// taproot output per inscription, whose single leaf locks the inscription
+// envelope to RevealKey. The reveal transaction of each inscription spends
+// its output through that leaf, which puts the envelope on chain, and sends
+// the postage to the reveal address.
+type Inscriber struct {
+	Params  *chaincfg.Params
+	FeeRate float64 // sat/vB
+
+	// RevealKey is the x-only key the envelopes are locked to, also the
+	// internal key of the commit outputs, and RevealSign signs for it. Keep
+	// the key until the reveals confirm: its tweaked form can sweep the
+	// commit outputs through the key path.
+	RevealKey  []byte
+	RevealSign types.Signer
+}
+
+// reveal is the plan of one reveal transaction. It spends the parent
+// first, if any, and then the commit output, and pays the parent back
+// before the postage of every inscription. An etching adds the runestone
+// output last.
+type reveal struct {
+	datas     []*InscriptionData
+	parent    *Parent
+	runestone []byte
+	commit    *script.TaprootOutput
+	fee       int64
+}
+
+// Inscribe funds the commit transaction from utxos of fromAddress, signed
+// with sign and pubkey, and returns it with one signed reveal transaction
+// per inscription, in the order of inscriptionDatas.
+func (ins *Inscriber) Inscribe(
+	utxos []*types.Utxo,
+	fromAddress string,
+	inscriptionDatas []*InscriptionData,
+	sign types.Signer,
+	pubkey []byte,
+) (revealTxs []*psbt.Packet, commitTx *psbt.Packet, err error) {
+	if len(inscriptionDatas) == 0 {
+		return nil, nil, errors.New("no inscriptions")
+	}
+	reveals := make([][]*InscriptionData, len(inscriptionDatas))
+	for i, data := range inscriptionDatas {
+		reveals[i] = []*InscriptionData{data}
+	}
+	return ins.inscribe(utxos, fromAddress, reveals, nil, nil, sign, pubkey)
+}
+
+// InscribeBatch reveals all of inscriptionDatas in a single reveal
+// transaction, each to an output of its own in order. With a parent, the
+// reveal also spends the parent inscription, which makes them its
+// children; the parent's owner signs that input.
+func (ins *Inscriber) InscribeBatch(
+	utxos []*types.Utxo,
+	fromAddress string,
+	inscriptionDatas []*InscriptionData,
+	parent *Parent,
+	sign types.Signer,
+	pubkey []byte,
+) (revealTx *psbt.Packet, commitTx *psbt.Packet, err error) {
+	if len(inscriptionDatas) == 0 {
+		return nil, nil, errors.New("no inscriptions")
+	}
+	revealTxs, commitTx, err := ins.inscribe(utxos, fromAddress, [][]*InscriptionData{inscriptionDatas}, parent, nil, sign, pubkey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return revealTxs[0], commitTx, nil
+}
+
+// Etch etches a rune together with the inscription of inscriptionData. The
+// envelope pushes the commitment of the rune name, and the reveal carries
+// the runestone, whose premine goes to the reveal address with the
+// inscription. The reveal spends the commit output with a relative lock of
+// CommitConfirmations-1 blocks, so nodes only accept it once mining it
+// makes the commitment mature.
+func (ins *Inscriber) Etch(
+	utxos []*types.Utxo,
+	fromAddress string,
+	etching *runes.Etching,
+	inscriptionData *InscriptionData,
+	sign types.Signer,
+	pubkey []byte,
+) (revealTx *psbt.Packet, commitTx *psbt.Packet, err error) {
+	if etching == nil || etching.Rune == nil {
+		return nil, nil, errors.New("etching needs a rune name to commit to")
+	}
+	if inscriptionData == nil {
+		return nil, nil, errors.New("no inscription")
+	}
+	revealTxs, commitTx, err := ins.inscribe(utxos, fromAddress, [][]*InscriptionData{{inscriptionData}}, nil, etching, sign, pubkey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return revealTxs[0], commitTx, nil
+}
+
+func (ins *Inscriber) inscribe(
+	utxos []*types.Utxo,
+	fromAddress string,
+	batches [][]*InscriptionData,
+	parent *Parent,
+	etching *runes.Etching,
+	sign types.Signer,
+	pubkey []byte,
+) ([]*psbt.Packet, *psbt.Packet, error) {
+	if len(ins.RevealKey) != schnorr.PubKeyBytesLen || ins.RevealSign == nil {
+		return nil, nil, errors.New("inscriber needs an x-only reveal key and its signer")
+	}
+	if parent != nil && (parent.Utxo == nil || parent.Utxo.RawTx == nil || parent.Sign == nil) {
+		return nil, nil, errors.New("parent needs its UTXO with the raw transaction and a signer")
+	}
+
+	reveals := make([]*reveal, 0, len(batches))
+	for i, datas := range batches {
+		r, err := ins.planReveal(datas, parent, etching)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reveal %d: %w", i, err)
+		}
+		reveals = append(reveals, r)
+	}
+	commitTx, err := ins.buildCommitTx(utxos, fromAddress, reveals, sign, pubkey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("commit: %w", err)
+	}
+	revealTxs, err := ins.buildRevealTxs(commitTx, reveals)
+	if err != nil {
+		return nil, nil, err
+	}
+	return revealTxs, commitTx, nil
+}
+
+// planReveal builds the commit output holding the envelopes of datas and
+// prices the reveal transaction from the size it will have once signed.
+func (ins *Inscriber) planReveal(datas []*InscriptionData, parent *Parent, etching *runes.Etching) (*reveal, error) {
+	r := &reveal{datas: datas, parent: parent}
+	var parentID script.InscriptionID
+	var offset uint64
+	if parent != nil {
+		var err error
+		if parentID, err = script.ParseInscriptionID(parent.ID); err != nil {
+			return nil, fmt.Errorf("parent: %w", err)
+		}
+		offset = uint64(parent.Utxo.Value)
+	}
+
+	inscriptions := make([]*script.Inscription, 0, len(datas))
+	for i, data := range datas {
+		inscription, err := data.inscription()
+		if err != nil {
+			return nil, fmt.Errorf("inscription %d: %w", i, err)
+		}
+		if parent != nil {
+			inscription.Parents = []script.InscriptionID{parentID}
+		}
+		// The first inscription lands on the first sat of the commit
+		// input, at the start of its output; the others point to the
+		// start of theirs.
+		if i > 0 {
+			pointer := offset
+			inscription.Pointer = &pointer
+		}
+		offset += uint64(data.postage())
+		inscriptions = append(inscriptions, inscription)
+	}
+	if etching != nil {
+		inscriptions[0].Rune = etching.Rune.Commitment()
+		runestone, err := (&runes.Runestone{Etching: etching}).Encipher()
+		if err != nil {
+			return nil, fmt.Errorf("runestone: %w", err)
+		}
+		r.runestone = runestone
+	}
+	envelopes, err := script.CreateInscriptionsScript(ins.RevealKey, inscriptions)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := script.NewTapTree(script.TapLeaf{Script: envelopes})
+	if err != nil {
+		return nil, err
+	}
+	if r.commit, err = tree.Output(ins.RevealKey, ins.Params); err != nil {
+		return nil, err
+	}
+
+	outs, err := r.outputs(ins.Params)
+	if err != nil {
+		return nil, err
+	}
+	for i, out := range outs {
+		if txscript.IsUnspendable(out.PkScript) {
+			continue
+		}
+		if txrules.IsDustOutput(out, txrules.DefaultRelayFeePerKb) {
+			return nil, fmt.Errorf("output %d of %d sats is dust", i, out.Value)
+		}
+	}
+	r.fee = int64(transaction.FeeForVirtualSize(ins.FeeRate, r.virtualSize(outs)))
+	return r, nil
+}
+
+// outputs returns the reveal outputs: the parent back to its owner, then
+// the postage of every inscription and the runestone.
+func (r *reveal) outputs(params *chaincfg.Params) ([]*wire.TxOut, error) {
+	var outs []*wire.TxOut
+	if r.parent != nil {
+		pkScript, err := payTo(params, r.parent.Address)
+		if err != nil {
+			return nil, fmt.Errorf("parent address: %w", err)
+		}
+		outs = append(outs, wire.NewTxOut(r.parent.Utxo.Value, pkScript))
+	}
+	for _, data := range r.datas {
+		pkScript, err := payTo(params, data.RevealAddr)
+		if err != nil {
+			return nil, fmt.Errorf("reveal address: %w", err)
+		}
+		outs = append(outs, wire.NewTxOut(data.postage(), pkScript))
+	}
+	if r.runestone != nil {
+		outs = append(outs, wire.NewTxOut(0, r.runestone))
+	}
+	return outs, nil
+}
+
+// commitValue returns the value of the commit output: the postage of every
+// inscription and the fee of the reveal.
+func (r *reveal) commitValue() int64 {
+	value := r.fee
+	for _, data := range r.datas {
+		value += data.postage()
+	}
+	return value
+}
+
+// virtualSize returns the size of the signed reveal transaction. The
+// commit input witness is a 64-byte signature, the envelope leaf and its
+// control block; the parent input is taken as spent by a single key.
+func (r *reveal) virtualSize(outs []*wire.TxOut) int {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	var parentWitness int
+	if r.parent != nil {
+		tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+		pkScript := r.parent.Utxo.RawTx.TxOut[r.parent.Utxo.Vout].PkScript
+		parentWitness = transaction.InputVirtualSize(pkScript) - tx.TxIn[0].SerializeSize()
+	}
+	txIn := wire.NewTxIn(&wire.OutPoint{}, nil, nil)
+	leaf := r.commit.LeafScripts[0]
+	txIn.Witness = wire.TxWitness{make([]byte, schnorr.SignatureSize), leaf.Script, leaf.ControlBlock}
+	tx.AddTxIn(txIn)
+	for _, out := range outs {
+		tx.AddTxOut(out)
+	}
+	weight := blockchain.GetTransactionWeight(btcutil.NewTx(tx))
+	return int((weight+blockchain.WitnessScaleFactor-1)/blockchain.WitnessScaleFactor) + parentWitness
+}
+
+// buildCommitTx funds one commit output per reveal and signs the
+// transaction.
+func (ins *Inscriber) buildCommitTx(utxos []*types.Utxo, fromAddress string, reveals []*reveal, sign types.Signer, pubkey []byte) (*psbt.Packet, error) {
+	builder := transaction.NewTxBuilder(ins.Params).
+		FeeRate(ins.FeeRate).
+		From(fromAddress)
+	for _, r := range reveals {
+		builder.To(r.commit.Address.EncodeAddress(), r.commitValue())
+	}
+	return builder.
+		SelectUtxo(utxos).
+		Build().
+		SignWith(sign, pubkey).
+		Packet()
+}
+
+// buildRevealTxs spends every commit output of the signed commitTx, along
+// with the parent, to the reveal outputs, and signs the commit input
+// through the envelope leaf and the parent with the parent's signer.
+func (ins *Inscriber) buildRevealTxs(commitTx *psbt.Packet, reveals []*reveal) ([]*psbt.Packet, error) {
+	raw, err := types.EncodePsbtToRawTx(commitTx)
+	if err != nil {
+		return nil, fmt.Errorf("commit isn't fully signed: %w", err)
+	}
+	commit := wire.NewMsgTx(wire.TxVersion)
+	if err := commit.Deserialize(bytes.NewReader(raw)); err != nil {
+		return nil, err
+	}
+
+	revealTxs := make([]*psbt.Packet, 0, len(reveals))
+	for i, r := range reveals {
+		revealTx, err := ins.buildRevealTx(commit, uint32(i), r)
+		if err != nil {
+			return nil, fmt.Errorf("reveal %d: %w", i, err)
+		}
+		revealTxs = append(revealTxs, revealTx)
+	}
+	return revealTxs, nil
+}
+
+func (ins *Inscriber) buildRevealTx(commit *wire.MsgTx, vout uint32, r *reveal) (*psbt.Packet, error) {
+	// the builder keeps the commit outputs first and in order
+	if int(vout) >= len(commit.TxOut) || !bytes.Equal(commit.TxOut[vout].PkScript, r.commit.PkScript) {
+		return nil, errors.New("commit output missing")
+	}
+	commitAddr := r.commit.Address.EncodeAddress()
+	builder := transaction.NewTxBuilder(ins.Params).
+		SpendInfo(commitAddr, r.commit.SpendInfo())
+	if p := r.parent; p != nil {
+		if p.Spend != nil {
+			builder.SpendInfo(p.Address, p.Spend)
+		}
+		if err := builder.Inputs.AddInput(ins.Params, p.Utxo.RawTx, p.Utxo.Vout, p.Utxo.Value, p.Address); err != nil {
+			return nil, fmt.Errorf("parent: %w", err)
+		}
+		builder.To(p.Address, p.Utxo.Value)
+	}
+	if err := builder.Inputs.AddInput(ins.Params, commit, vout, r.commitValue(), commitAddr); err != nil {
+		return nil, err
+	}
+	for _, data := range r.datas {
+		builder.To(data.RevealAddr, data.postage())
+	}
+	if r.runestone != nil {
+		builder.Outputs.AddOutputPkScript(r.runestone, 0)
+		// the commitment matures in the block the reveal can first be
+		// mined in
+		builder.InputSequence(commit.TxHash().String(), vout, blockchain.LockTimeToSequence(false, runes.CommitConfirmations-1))
+	}
+
+	// without a change address Build leaves the fee planned above
+	builder.Build().SignWith(ins.RevealSign, ins.RevealKey)
+	if r.parent != nil {
+		builder.SignWith(r.parent.Sign, r.parent.PubKey)
+	}
+	return builder.Packet()
+}
+
+func payTo(params *chaincfg.Params, address string) ([]byte, error) {
+	addr, _, err := types.DecodeAddress(address, params)
+	if err != nil {
+		return nil, err
+	}
+	return script.EncodeTransferScript(addr)
+}
@@ -0,0 +1,296 @@
+package ordinals
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+	"lukechampine.com/uint128"
+
+	"github.com/gosuda/btctxbuilder/ordinals/runes"
+	"github.com/gosuda/btctxbuilder/script"
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+// extractTx returns the final transaction of a signed packet.
+func extractTx(t *testing.T, packet *psbt.Packet) *wire.MsgTx {
+	t.Helper()
+	raw, err := types.EncodePsbtToRawTx(packet)
+	require.NoError(t, err)
+	tx := wire.NewMsgTx(wire.TxVersion)
+	require.NoError(t, tx.Deserialize(bytes.NewReader(raw)))
+	return tx
+}
+
+// executeTx runs every input of tx through the script engine and returns
+// its fee.
+func executeTx(t *testing.T, tx *wire.MsgTx, prevOuts map[wire.OutPoint]*wire.TxOut) int64 {
+	t.Helper()
+	fetcher := txscript.NewMultiPrevOutFetcher(prevOuts)
+	sigHashes := txscript.NewTxSigHashes(tx, fetcher)
+	var fee int64
+	for i, in := range tx.TxIn {
+		prevOut := fetcher.FetchPrevOutput(in.PreviousOutPoint)
+		require.NotNil(t, prevOut, "input %d", i)
+		vm, err := txscript.NewEngine(prevOut.PkScript, tx, i, txscript.StandardVerifyFlags, nil, sigHashes, prevOut.Value, fetcher)
+		require.NoError(t, err)
+		require.NoError(t, vm.Execute(), "input %d", i)
+		fee += prevOut.Value
+	}
+	for _, out := range tx.TxOut {
+		fee -= out.Value
+	}
+	return fee
+}
+
+// inscribeFixture is a funding wallet, a reveal key and the taproot
+// wallet receiving the inscriptions.
+type inscribeFixture struct {
+	from      string
+	funder    *types.ECDSASigner
+	utxos     []*types.Utxo
+	prevOuts  map[wire.OutPoint]*wire.TxOut
+	inscriber *Inscriber
+
+	owner      *types.SchnorrSigner
+	revealAddr string
+}
+
+func newInscribeFixture(t *testing.T, feeRate float64) *inscribeFixture {
+	t.Helper()
+	params := types.GetParams(types.BTC_Signet)
+	master, err := types.NewHDKeyFromSeed(make([]byte, 32), params)
+	require.NoError(t, err)
+	f := &inscribeFixture{}
+
+	fundKey, err := master.DerivePath("m/84'/1'/0'/0/0")
+	require.NoError(t, err)
+	f.funder, err = fundKey.ECDSASigner()
+	require.NoError(t, err)
+	f.from, err = types.PubKeyToAddr(f.funder.PubKey(), types.P2WPKH, params)
+	require.NoError(t, err)
+
+	revealKey, err := master.DerivePath("m/86'/1'/0'/0/0")
+	require.NoError(t, err)
+	revealSigner, err := revealKey.TapscriptSigner()
+	require.NoError(t, err)
+	f.inscriber = &Inscriber{
+		Params:     params,
+		FeeRate:    feeRate,
+		RevealKey:  revealSigner.PubKey(),
+		RevealSign: revealSigner.Sign,
+	}
+
+	ownerKey, err := master.DerivePath("m/86'/1'/0'/0/1")
+	require.NoError(t, err)
+	f.owner, err = ownerKey.SchnorrSigner()
+	require.NoError(t, err)
+	f.revealAddr, err = types.PubKeyToAddr(f.owner.PubKey(), types.P2TR, params)
+	require.NoError(t, err)
+
+	fundPkScript, err := payTo(params, f.from)
+	require.NoError(t, err)
+	fundTx := wire.NewMsgTx(wire.TxVersion)
+	fundTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), nil, nil))
+	fundTx.AddTxOut(wire.NewTxOut(200000, fundPkScript))
+	f.utxos = []*types.Utxo{{Txid: fundTx.TxID(), Vout: 0, Value: 200000, RawTx: fundTx}}
+	f.prevOuts = map[wire.OutPoint]*wire.TxOut{{Hash: fundTx.TxHash()}: fundTx.TxOut[0]}
+	return f
+}
+
+func TestInscriber_Inscribe(t *testing.T) {
+	const feeRate = 3
+	f := newInscribeFixture(t, feeRate)
+
+	// the second body takes several pushes
+	datas := []*InscriptionData{
+		{ContentType: "text/plain;charset=utf-8", Body: []byte("hello, world"), RevealAddr: f.revealAddr},
+		{ContentType: "application/octet-stream", Body: bytes.Repeat([]byte{0xab}, 1500), RevealAddr: f.revealAddr, Postage: 546},
+	}
+	revealTxs, commitTx, err := f.inscriber.Inscribe(f.utxos, f.from, datas, f.funder.Sign, f.funder.PubKey())
+	require.NoError(t, err)
+	require.Len(t, revealTxs, len(datas))
+
+	commit := extractTx(t, commitTx)
+	commitFee := executeTx(t, commit, f.prevOuts)
+	require.GreaterOrEqual(t, commitFee, feeRate*vSize(commit))
+
+	commitHash := commit.TxHash()
+	for i, revealTx := range revealTxs {
+		reveal := extractTx(t, revealTx)
+		require.Equal(t, wire.OutPoint{Hash: commitHash, Index: uint32(i)}, reveal.TxIn[0].PreviousOutPoint)
+		prevOuts := map[wire.OutPoint]*wire.TxOut{reveal.TxIn[0].PreviousOutPoint: commit.TxOut[i]}
+		fee := executeTx(t, reveal, prevOuts)
+
+		// the fee planned from the witness size is exactly the rate
+		require.Equal(t, feeRate*vSize(reveal), fee)
+
+		postage := datas[i].Postage
+		if postage == 0 {
+			postage = DefaultPostage
+		}
+		require.Len(t, reveal.TxOut, 1)
+		require.Equal(t, postage, reveal.TxOut[0].Value)
+
+		contentType, body, err := script.GetInscriptionContent(reveal)
+		require.NoError(t, err)
+		require.Equal(t, datas[i].ContentType, contentType)
+		require.Equal(t, datas[i].Body, body)
+	}
+
+	t.Run("dust postage", func(t *testing.T) {
+		_, _, err := f.inscriber.Inscribe(f.utxos, f.from, []*InscriptionData{
+			{ContentType: "text/plain", Body: []byte("x"), RevealAddr: f.revealAddr, Postage: 100},
+		}, f.funder.Sign, f.funder.PubKey())
+		require.ErrorContains(t, err, "dust")
+	})
+
+	t.Run("no reveal key", func(t *testing.T) {
+		_, _, err := (&Inscriber{Params: f.inscriber.Params, FeeRate: feeRate}).Inscribe(f.utxos, f.from, datas, f.funder.Sign, f.funder.PubKey())
+		require.Error(t, err)
+	})
+}
+
+func TestInscriber_InscribeBatch(t *testing.T) {
+	const feeRate = 2
+	f := newInscribeFixture(t, feeRate)
+
+	// inscribe the parent of the collection first
+	revealTxs, commitTx, err := f.inscriber.Inscribe(f.utxos, f.from, []*InscriptionData{
+		{ContentType: "text/plain", Body: []byte("collection"), RevealAddr: f.revealAddr},
+	}, f.funder.Sign, f.funder.PubKey())
+	require.NoError(t, err)
+	parentTx := extractTx(t, revealTxs[0])
+	parent := &Parent{
+		ID:      script.InscriptionID{Txid: parentTx.TxHash()}.String(),
+		Utxo:    &types.Utxo{Txid: parentTx.TxID(), Vout: 0, Value: parentTx.TxOut[0].Value, RawTx: parentTx},
+		Address: f.revealAddr,
+		Sign:    f.owner.Sign,
+		PubKey:  f.owner.PubKey(),
+	}
+
+	// the children are funded by the change of the parent's commit
+	commit := extractTx(t, commitTx)
+	change := uint32(len(commit.TxOut) - 1)
+	utxos := []*types.Utxo{{Txid: commit.TxID(), Vout: change, Value: commit.TxOut[change].Value, RawTx: commit}}
+
+	delegate := parent.ID
+	datas := []*InscriptionData{
+		{ContentType: "image/png", Body: []byte{0x89, 'P', 'N', 'G'}, RevealAddr: f.revealAddr},
+		{ContentType: "image/png", Body: []byte{0x89, 'P', 'N', 'G', 2}, RevealAddr: f.revealAddr, Postage: 546, Metadata: []byte{0xa1, 0x61, 0x6e, 0x02}},
+		{RevealAddr: f.from, Delegate: delegate, Metaprotocol: "collection"},
+	}
+	revealTx, commitTx, err := f.inscriber.InscribeBatch(utxos, f.from, datas, parent, f.funder.Sign, f.funder.PubKey())
+	require.NoError(t, err)
+
+	commit2 := extractTx(t, commitTx)
+	executeTx(t, commit2, map[wire.OutPoint]*wire.TxOut{{Hash: commit.TxHash(), Index: change}: commit.TxOut[change]})
+
+	reveal := extractTx(t, revealTx)
+	require.Len(t, reveal.TxIn, 2)
+	require.Equal(t, wire.OutPoint{Hash: parentTx.TxHash()}, reveal.TxIn[0].PreviousOutPoint)
+	require.Equal(t, wire.OutPoint{Hash: commit2.TxHash()}, reveal.TxIn[1].PreviousOutPoint)
+	fee := executeTx(t, reveal, map[wire.OutPoint]*wire.TxOut{
+		reveal.TxIn[0].PreviousOutPoint: parentTx.TxOut[0],
+		reveal.TxIn[1].PreviousOutPoint: commit2.TxOut[0],
+	})
+	require.GreaterOrEqual(t, fee, feeRate*vSize(reveal))
+	require.LessOrEqual(t, fee, feeRate*(vSize(reveal)+1))
+
+	// the parent goes back first, then every child to an output of its own
+	require.Len(t, reveal.TxOut, 4)
+	require.Equal(t, parentTx.TxOut[0], reveal.TxOut[0])
+	for i, data := range datas {
+		pkScript, err := payTo(f.inscriber.Params, data.RevealAddr)
+		require.NoError(t, err)
+		require.Equal(t, wire.NewTxOut(data.postage(), pkScript), reveal.TxOut[i+1])
+	}
+
+	// children point to the first sat of their outputs and name the parent
+	parentID, err := script.ParseInscriptionID(parent.ID)
+	require.NoError(t, err)
+	want := make([]*script.Inscription, len(datas))
+	offset := uint64(parent.Utxo.Value)
+	for i, data := range datas {
+		want[i], err = data.inscription()
+		require.NoError(t, err)
+		want[i].Parents = []script.InscriptionID{parentID}
+		if i > 0 {
+			pointer := offset
+			want[i].Pointer = &pointer
+		}
+		offset += uint64(data.postage())
+	}
+	wantLeaf, err := script.CreateInscriptionsScript(f.inscriber.RevealKey, want)
+	require.NoError(t, err)
+	require.Equal(t, wantLeaf, reveal.TxIn[1].Witness[1])
+	require.Len(t, reveal.TxIn[0].Witness, 1)
+
+	t.Run("invalid parent", func(t *testing.T) {
+		invalid := *parent
+		invalid.ID = "collection"
+		_, _, err := f.inscriber.InscribeBatch(utxos, f.from, datas, &invalid, f.funder.Sign, f.funder.PubKey())
+		require.Error(t, err)
+	})
+}
+
+func TestInscriber_Etch(t *testing.T) {
+	const feeRate = 2
+	f := newInscribeFixture(t, feeRate)
+
+	name, err := runes.RuneFromString("AAAAAAAAAAAAAB")
+	require.NoError(t, err)
+	divisibility := uint8(2)
+	premine := uint128.From64(1000000)
+	amount, limit := uint128.From64(100), uint128.From64(10000)
+	symbol := '¤'
+	etching := &runes.Etching{
+		Divisibility: &divisibility,
+		Premine:      &premine,
+		Rune:         name,
+		Symbol:       &symbol,
+		Terms:        &runes.Terms{Amount: &amount, Cap: &limit},
+	}
+	data := &InscriptionData{ContentType: "text/plain", Body: []byte("rune"), RevealAddr: f.revealAddr}
+
+	revealTx, commitTx, err := f.inscriber.Etch(f.utxos, f.from, etching, data, f.funder.Sign, f.funder.PubKey())
+	require.NoError(t, err)
+	commit := extractTx(t, commitTx)
+	executeTx(t, commit, f.prevOuts)
+
+	reveal := extractTx(t, revealTx)
+	prevOuts := map[wire.OutPoint]*wire.TxOut{reveal.TxIn[0].PreviousOutPoint: commit.TxOut[0]}
+	fee := executeTx(t, reveal, prevOuts)
+	require.Equal(t, feeRate*vSize(reveal), fee)
+
+	// the reveal waits for the commitment to mature
+	require.EqualValues(t, 2, reveal.Version)
+	require.Equal(t, blockchain.LockTimeToSequence(false, runes.CommitConfirmations-1), reveal.TxIn[0].Sequence)
+
+	envelopes := script.ParseEnvelopes(reveal)
+	require.Len(t, envelopes, 1)
+	require.Equal(t, name.Commitment(), envelopes[0].Rune)
+	require.Equal(t, data.Body, envelopes[0].Body)
+
+	// the inscription and the premine share the first output
+	require.Len(t, reveal.TxOut, 2)
+	require.Equal(t, int64(DefaultPostage), reveal.TxOut[0].Value)
+	runestone, cenotaph := runes.Decipher(reveal)
+	require.Nil(t, cenotaph)
+	require.Equal(t, &runes.Runestone{Etching: etching}, runestone)
+
+	t.Run("no rune name", func(t *testing.T) {
+		_, _, err := f.inscriber.Etch(f.utxos, f.from, &runes.Etching{Premine: &premine}, data, f.funder.Sign, f.funder.PubKey())
+		require.Error(t, err)
+	})
+}
+
+func vSize(tx *wire.MsgTx) int64 {
+	return (blockchain.GetTransactionWeight(btcutil.NewTx(tx)) + 3) / 4
+}
@@ -0,0 +1,21 @@
+package runes
+
+import "lukechampine.com/uint128"
+
+// Edict transfers Amount of the rune ID to output Output. An output equal
+// to the number of transaction outputs splits the amount between all
+// outputs but OP_RETURN ones.
+type Edict struct {
+	ID     RuneId
+	Amount uint128.Uint128
+	Output uint32
+}
+
+// newEdict returns the edict of the integers of a runestone body, or
+// false if output is past the outputs of a transaction with numOutputs.
+func newEdict(numOutputs int, id RuneId, amount, output uint128.Uint128) (Edict, bool) {
+	if output.Cmp64(uint64(numOutputs)) > 0 {
+		return Edict{}, false
+	}
+	return Edict{ID: id, Amount: amount, Output: uint32(output.Lo)}, true
+}
@@ -0,0 +1,105 @@
+package runes
+
+import (
+	"math"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"lukechampine.com/uint128"
+)
+
+// RuneEntry is the state of an etched rune.
+type RuneEntry struct {
+	// Block is the height of the etching, Etching its transaction.
+	Block        uint64
+	Etching      chainhash.Hash
+	SpacedRune   SpacedRune
+	Divisibility uint8
+	Symbol       *rune
+	Premine      uint128.Uint128
+	Terms        *Terms
+	Turbo        bool
+
+	Mints  uint128.Uint128
+	Burned uint128.Uint128
+}
+
+// Supply returns the runes created so far: the premine and every mint.
+func (e *RuneEntry) Supply() uint128.Uint128 {
+	supply := e.Premine
+	if e.Terms != nil && e.Terms.Amount != nil {
+		supply = supply.Add(e.Mints.Mul(*e.Terms.Amount))
+	}
+	return supply
+}
+
+// Start returns the first height the rune can be minted at, the later of
+// the absolute and relative starts, or nil if minting opens at once.
+func (e *RuneEntry) Start() *uint64 {
+	if e.Terms == nil {
+		return nil
+	}
+	relative, absolute := e.relative(e.Terms.Offset[0]), e.Terms.Height[0]
+	if relative != nil && absolute != nil {
+		h := max(*relative, *absolute)
+		return &h
+	}
+	if relative != nil {
+		return relative
+	}
+	return absolute
+}
+
+// End returns the height minting closes at, the earlier of the absolute
+// and relative ends, or nil if it never does.
+func (e *RuneEntry) End() *uint64 {
+	if e.Terms == nil {
+		return nil
+	}
+	relative, absolute := e.relative(e.Terms.Offset[1]), e.Terms.Height[1]
+	if relative != nil && absolute != nil {
+		h := min(*relative, *absolute)
+		return &h
+	}
+	if relative != nil {
+		return relative
+	}
+	return absolute
+}
+
+// relative returns the height offset blocks after the etching, saturating.
+func (e *RuneEntry) relative(offset *uint64) *uint64 {
+	if offset == nil {
+		return nil
+	}
+	h := e.Block + *offset
+	if h < e.Block {
+		h = math.MaxUint64
+	}
+	return &h
+}
+
+// Mintable returns the amount a mint in the block at height gets, or
+// false if the rune has no terms, the height is outside the mint window
+// or the cap is reached.
+func (e *RuneEntry) Mintable(height uint64) (uint128.Uint128, bool) {
+	if e.Terms == nil {
+		return uint128.Zero, false
+	}
+	if start := e.Start(); start != nil && height < *start {
+		return uint128.Zero, false
+	}
+	if end := e.End(); end != nil && height >= *end {
+		return uint128.Zero, false
+	}
+	var limit uint128.Uint128
+	if e.Terms.Cap != nil {
+		limit = *e.Terms.Cap
+	}
+	if e.Mints.Cmp(limit) >= 0 {
+		return uint128.Zero, false
+	}
+	if e.Terms.Amount == nil {
+		return uint128.Zero, true
+	}
+	return *e.Terms.Amount, true
+}
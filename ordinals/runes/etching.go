@@ -0,0 +1,54 @@
+package runes
+
+import (
+	"math/big"
+
+	"lukechampine.com/uint128"
+)
+
+const (
+	MaxDivisibility = 38
+	MaxSpacers      = 0b00000111_11111111_11111111_11111111
+
+	// CommitConfirmations is the number of confirmations the output
+	// committing to the rune name needs before the etching reveals it.
+	CommitConfirmations = 6
+)
+
+// Etching creates a new rune. Without Rune, the rune gets a reserved name
+// derived from the etching transaction.
+type Etching struct {
+	Divisibility *uint8
+	Premine      *uint128.Uint128
+	Rune         *Rune
+	Spacers      *uint32
+	Symbol       *rune
+	Terms        *Terms
+	Turbo        bool
+}
+
+// Terms are the conditions of open minting. Height and Offset are the
+// start and end of the mint window, in absolute block heights and in
+// blocks after the etching.
+type Terms struct {
+	Amount *uint128.Uint128
+	Cap    *uint128.Uint128
+	Height [2]*uint64
+	Offset [2]*uint64
+}
+
+// Supply returns the premine plus the cap times the mint amount, or false
+// if that overflows a u128.
+func (e *Etching) Supply() (uint128.Uint128, bool) {
+	supply := new(big.Int)
+	if e.Terms != nil && e.Terms.Cap != nil && e.Terms.Amount != nil {
+		supply.Mul(e.Terms.Cap.Big(), e.Terms.Amount.Big())
+	}
+	if e.Premine != nil {
+		supply.Add(supply, e.Premine.Big())
+	}
+	if supply.BitLen() > 128 {
+		return uint128.Zero, false
+	}
+	return uint128.FromBig(supply), true
+}
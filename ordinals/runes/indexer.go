@@ -0,0 +1,371 @@
+package runes
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"lukechampine.com/uint128"
+
+	"github.com/gosuda/btctxbuilder/script"
+)
+
+// Indexer maintains the runes ledger in Store from the blocks of a chain,
+// fed in order. Runes are indexed from FirstRuneHeight on; the taproot
+// outputs etchings commit from are tracked from the first block.
+type Indexer struct {
+	Params *chaincfg.Params
+	Store  Store
+}
+
+func NewIndexer(params *chaincfg.Params, store Store) *Indexer {
+	return &Indexer{Params: params, Store: store}
+}
+
+// IndexBlock applies the transactions of the block at height, in block
+// order, and must follow the last block indexed.
+func (ix *Indexer) IndexBlock(height uint64, txs []*wire.MsgTx) error {
+	last, ok, err := ix.Store.Height()
+	if err != nil {
+		return err
+	}
+	if ok && height != last+1 {
+		return fmt.Errorf("block %d doesn't follow block %d", height, last)
+	}
+
+	for i, tx := range txs {
+		if height >= FirstRuneHeight(ix.Params) {
+			if err := ix.indexTx(height, uint32(i), tx); err != nil {
+				return fmt.Errorf("tx %s: %w", tx.TxHash(), err)
+			}
+		}
+		if err := ix.trackTaproot(height, tx); err != nil {
+			return err
+		}
+	}
+	return ix.Store.SetHeight(height)
+}
+
+// indexTx moves the runes of the inputs of tx to its outputs, following
+// ord: mints and the premine of an etching join the runes of the inputs,
+// edicts allocate them in order, and the pointer output, or the first
+// output that isn't an OP_RETURN, gets the rest. A cenotaph burns them all.
+func (ix *Indexer) indexTx(height uint64, txIndex uint32, tx *wire.MsgTx) error {
+	runestone, cenotaph := Decipher(tx)
+
+	unallocated, err := ix.unallocated(tx)
+	if err != nil {
+		return err
+	}
+	allocated := make([]map[RuneId]uint128.Uint128, len(tx.TxOut))
+	allocate := func(output int, id RuneId, amount uint128.Uint128) {
+		if amount.IsZero() {
+			return
+		}
+		unallocated[id] = unallocated[id].Sub(amount)
+		if allocated[output] == nil {
+			allocated[output] = make(map[RuneId]uint128.Uint128)
+		}
+		allocated[output][id] = allocated[output][id].Add(amount)
+	}
+
+	var mint *RuneId
+	switch {
+	case runestone != nil:
+		mint = runestone.Mint
+	case cenotaph != nil:
+		mint = cenotaph.Mint
+	}
+	if mint != nil {
+		amount, ok, err := ix.mint(height, *mint)
+		if err != nil {
+			return err
+		}
+		if ok {
+			unallocated[*mint] = unallocated[*mint].Add(amount)
+		}
+	}
+
+	etched, name, err := ix.etched(height, txIndex, tx, runestone, cenotaph)
+	if err != nil {
+		return err
+	}
+
+	if runestone != nil {
+		if etched != nil && runestone.Etching.Premine != nil {
+			unallocated[*etched] = unallocated[*etched].Add(*runestone.Etching.Premine)
+		}
+		for _, edict := range runestone.Edicts {
+			id := edict.ID
+			if id == (RuneId{}) {
+				if etched == nil {
+					continue
+				}
+				id = *etched
+			}
+			balance, ok := unallocated[id]
+			if !ok {
+				continue
+			}
+
+			if int(edict.Output) < len(tx.TxOut) {
+				amount := edict.Amount
+				if amount.IsZero() || amount.Cmp(balance) > 0 {
+					amount = balance
+				}
+				allocate(int(edict.Output), id, amount)
+				continue
+			}
+
+			// an output past the last splits between all outputs but
+			// OP_RETURN ones
+			var destinations []int
+			for i, out := range tx.TxOut {
+				if !isOpReturn(out.PkScript) {
+					destinations = append(destinations, i)
+				}
+			}
+			if len(destinations) == 0 {
+				continue
+			}
+			if edict.Amount.IsZero() {
+				share, remainder := balance.QuoRem64(uint64(len(destinations)))
+				for i, output := range destinations {
+					amount := share
+					if uint64(i) < remainder {
+						amount = amount.Add64(1)
+					}
+					allocate(output, id, amount)
+				}
+			} else {
+				for _, output := range destinations {
+					allocate(output, id, min128(edict.Amount, unallocated[id]))
+				}
+			}
+		}
+	}
+
+	if etched != nil {
+		if err := ix.Store.PutEntry(*etched, newEntry(height, tx, runestone, name)); err != nil {
+			return err
+		}
+	}
+
+	burned := make(map[RuneId]uint128.Uint128)
+	if cenotaph != nil {
+		for id, amount := range unallocated {
+			burned[id] = burned[id].Add(amount)
+		}
+	} else {
+		output := slices.IndexFunc(tx.TxOut, func(out *wire.TxOut) bool { return !isOpReturn(out.PkScript) })
+		if runestone != nil && runestone.Pointer != nil {
+			output = int(*runestone.Pointer)
+		}
+		for id, amount := range unallocated {
+			if output < 0 {
+				burned[id] = burned[id].Add(amount)
+			} else {
+				allocate(output, id, amount)
+			}
+		}
+	}
+
+	txHash := tx.TxHash()
+	for vout, balances := range allocated {
+		if len(balances) == 0 {
+			continue
+		}
+		if isOpReturn(tx.TxOut[vout].PkScript) {
+			for id, amount := range balances {
+				burned[id] = burned[id].Add(amount)
+			}
+			continue
+		}
+		sorted := make([]Balance, 0, len(balances))
+		for id, amount := range balances {
+			sorted = append(sorted, Balance{ID: id, Amount: amount})
+		}
+		slices.SortFunc(sorted, func(a, b Balance) int { return a.ID.Cmp(b.ID) })
+		if err := ix.Store.PutBalances(wire.OutPoint{Hash: txHash, Index: uint32(vout)}, sorted); err != nil {
+			return err
+		}
+	}
+
+	for id, amount := range burned {
+		if amount.IsZero() {
+			continue
+		}
+		entry, err := ix.Store.Entry(id)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			continue
+		}
+		entry.Burned = entry.Burned.Add(amount)
+		if err := ix.Store.PutEntry(id, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unallocated takes the runes held by the outputs tx spends.
+func (ix *Indexer) unallocated(tx *wire.MsgTx) (map[RuneId]uint128.Uint128, error) {
+	unallocated := make(map[RuneId]uint128.Uint128)
+	for _, in := range tx.TxIn {
+		balances, err := ix.Store.Balances(in.PreviousOutPoint)
+		if err != nil {
+			return nil, err
+		}
+		if len(balances) == 0 {
+			continue
+		}
+		for _, balance := range balances {
+			unallocated[balance.ID] = unallocated[balance.ID].Add(balance.Amount)
+		}
+		if err := ix.Store.DeleteBalances(in.PreviousOutPoint); err != nil {
+			return nil, err
+		}
+	}
+	return unallocated, nil
+}
+
+// mint counts a mint of id at height and returns its amount, or false if
+// the rune doesn't exist or isn't mintable.
+func (ix *Indexer) mint(height uint64, id RuneId) (uint128.Uint128, bool, error) {
+	entry, err := ix.Store.Entry(id)
+	if err != nil || entry == nil {
+		return uint128.Zero, false, err
+	}
+	amount, ok := entry.Mintable(height)
+	if !ok {
+		return uint128.Zero, false, nil
+	}
+	entry.Mints = entry.Mints.Add64(1)
+	if err := ix.Store.PutEntry(id, entry); err != nil {
+		return uint128.Zero, false, err
+	}
+	return amount, true, nil
+}
+
+// etched returns the ID and name of the rune tx etches, if any. A name
+// must be unlocked at height, not reserved, not taken and committed to by
+// tx; an etching without one gets its reserved name.
+func (ix *Indexer) etched(height uint64, txIndex uint32, tx *wire.MsgTx, runestone *Runestone, cenotaph *Cenotaph) (*RuneId, Rune, error) {
+	var name *Rune
+	switch {
+	case runestone != nil && runestone.Etching != nil:
+		name = runestone.Etching.Rune
+	case cenotaph != nil && cenotaph.Etching != nil:
+		name = cenotaph.Etching
+	default:
+		return nil, Rune{}, nil
+	}
+
+	if name == nil {
+		reserved := ReservedRuneOf(height, txIndex)
+		name = &reserved
+	} else {
+		if name.Value.Cmp(MinimumAtHeight(ix.Params, height).Value) < 0 || name.IsReserved() {
+			return nil, Rune{}, nil
+		}
+		taken, err := ix.Store.RuneID(*name)
+		if err != nil || taken != nil {
+			return nil, Rune{}, err
+		}
+		commits, err := ix.commitsTo(height, tx, *name)
+		if err != nil || !commits {
+			return nil, Rune{}, err
+		}
+	}
+	return &RuneId{Block: height, Tx: txIndex}, *name, nil
+}
+
+// commitsTo reports whether an input of tx spends a taproot output with at
+// least CommitConfirmations confirmations through a tapscript pushing the
+// commitment of r.
+func (ix *Indexer) commitsTo(height uint64, tx *wire.MsgTx, r Rune) (bool, error) {
+	commitment := r.Commitment()
+	for _, in := range tx.TxIn {
+		tapscript := script.WitnessTapscript(in.Witness)
+		if tapscript == nil {
+			continue
+		}
+		tokenizer := txscript.MakeScriptTokenizer(0, tapscript)
+		for tokenizer.Next() {
+			if tokenizer.Opcode() > txscript.OP_PUSHDATA4 || !bytes.Equal(tokenizer.Data(), commitment) {
+				continue
+			}
+			commitHeight, ok, err := ix.Store.TaprootHeight(in.PreviousOutPoint)
+			if err != nil {
+				return false, err
+			}
+			if ok && height-commitHeight+1 >= CommitConfirmations {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// trackTaproot records the taproot outputs of tx and forgets those it
+// spends.
+func (ix *Indexer) trackTaproot(height uint64, tx *wire.MsgTx) error {
+	for _, in := range tx.TxIn {
+		if err := ix.Store.DeleteTaprootHeight(in.PreviousOutPoint); err != nil {
+			return err
+		}
+	}
+	txHash := tx.TxHash()
+	for vout, out := range tx.TxOut {
+		if !txscript.IsPayToTaproot(out.PkScript) {
+			continue
+		}
+		if err := ix.Store.PutTaprootHeight(wire.OutPoint{Hash: txHash, Index: uint32(vout)}, height); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newEntry returns the entry of the rune etched by tx as name. A cenotaph
+// etches the name alone, without terms, premine or symbol.
+func newEntry(height uint64, tx *wire.MsgTx, runestone *Runestone, name Rune) *RuneEntry {
+	entry := &RuneEntry{
+		Block:      height,
+		Etching:    tx.TxHash(),
+		SpacedRune: SpacedRune{Rune: name},
+	}
+	if runestone == nil {
+		return entry
+	}
+	e := runestone.Etching
+	if e.Divisibility != nil {
+		entry.Divisibility = *e.Divisibility
+	}
+	if e.Premine != nil {
+		entry.Premine = *e.Premine
+	}
+	if e.Spacers != nil {
+		entry.SpacedRune.Spacers = *e.Spacers
+	}
+	entry.Symbol = e.Symbol
+	entry.Terms = e.Terms
+	entry.Turbo = e.Turbo
+	return entry
+}
+
+func isOpReturn(pkScript []byte) bool {
+	return len(pkScript) > 0 && pkScript[0] == txscript.OP_RETURN
+}
+
+func min128(a, b uint128.Uint128) uint128.Uint128 {
+	if a.Cmp(b) < 0 {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,218 @@
+package runes
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+	"lukechampine.com/uint128"
+
+	"github.com/gosuda/btctxbuilder/script"
+)
+
+// newTestTx spends prevOuts, with witness on every input, to outs.
+func newTestTx(prevOuts []wire.OutPoint, witness wire.TxWitness, outs ...*wire.TxOut) *wire.MsgTx {
+	tx := wire.NewMsgTx(2)
+	for _, prevOut := range prevOuts {
+		tx.AddTxIn(wire.NewTxIn(&prevOut, nil, witness))
+	}
+	for _, out := range outs {
+		tx.AddTxOut(out)
+	}
+	return tx
+}
+
+// coinbaseTx is a coinbase unique to height.
+func coinbaseTx(height uint64) *wire.MsgTx {
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex), []byte{byte(height), byte(height >> 8)}, nil))
+	tx.AddTxOut(wire.NewTxOut(50e8, []byte{txscript.OP_TRUE}))
+	return tx
+}
+
+func taprootOut(key byte) *wire.TxOut {
+	return wire.NewTxOut(10000, append([]byte{txscript.OP_1, txscript.OP_DATA_32}, bytes.Repeat([]byte{key}, 32)...))
+}
+
+func runestoneOut(t *testing.T, runestone *Runestone) *wire.TxOut {
+	t.Helper()
+	pkScript, err := runestone.Encipher()
+	require.NoError(t, err)
+	return wire.NewTxOut(0, pkScript)
+}
+
+// commitWitness spends a taproot output through a leaf pushing the
+// commitment of r.
+func commitWitness(t *testing.T, r Rune) wire.TxWitness {
+	t.Helper()
+	leaf, err := txscript.NewScriptBuilder().
+		AddData(r.Commitment()).AddOp(txscript.OP_DROP).AddOp(txscript.OP_TRUE).
+		Script()
+	require.NoError(t, err)
+	return wire.TxWitness{make([]byte, 64), leaf, append([]byte{0xc0}, make([]byte, 32)...)}
+}
+
+func outpoint(tx *wire.MsgTx, vout uint32) wire.OutPoint {
+	return wire.OutPoint{Hash: tx.TxHash(), Index: vout}
+}
+
+func funding(i byte) []wire.OutPoint {
+	return []wire.OutPoint{{Hash: chainhash.Hash{i}}}
+}
+
+func TestIndexer(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	store := NewMemoryStore()
+	ix := NewIndexer(params, store)
+	index := func(height uint64, txs ...*wire.MsgTx) {
+		t.Helper()
+		require.NoError(t, ix.IndexBlock(height, append([]*wire.MsgTx{coinbaseTx(height)}, txs...)))
+	}
+	balances := func(op wire.OutPoint) []Balance {
+		t.Helper()
+		balances, err := store.Balances(op)
+		require.NoError(t, err)
+		return balances
+	}
+	entry := func(id RuneId) *RuneEntry {
+		t.Helper()
+		entry, err := store.Entry(id)
+		require.NoError(t, err)
+		require.NotNil(t, entry)
+		return entry
+	}
+	lookup := func(r Rune) *RuneId {
+		t.Helper()
+		id, err := store.RuneID(r)
+		require.NoError(t, err)
+		return id
+	}
+
+	name, err := RuneFromString("AAAAAAAAAAAAAB")
+	require.NoError(t, err)
+	early, err := RuneFromString("AAAAAAAAAAAAAC")
+	require.NoError(t, err)
+
+	// commitments confirmed at heights 1 and 2
+	commit := newTestTx(funding(1), nil, taprootOut(1))
+	index(1, commit)
+	earlyCommit := newTestTx(funding(2), nil, taprootOut(1))
+	index(2, earlyCommit)
+	for height := uint64(3); height < 6; height++ {
+		index(height)
+	}
+	require.ErrorContains(t, ix.IndexBlock(7, nil), "doesn't follow")
+
+	divisibility := uint8(1)
+	premine := uint128.From64(1000)
+	amount, limit, end := uint128.From64(100), uint128.From64(2), uint64(3)
+	etching := &Etching{
+		Divisibility: &divisibility,
+		Premine:      &premine,
+		Rune:         name,
+		Terms:        &Terms{Amount: &amount, Cap: &limit, Offset: [2]*uint64{nil, &end}},
+	}
+	etch := newTestTx([]wire.OutPoint{outpoint(commit, 0)}, commitWitness(t, *name), taprootOut(2), runestoneOut(t, &Runestone{Etching: etching}))
+	// five confirmations are too few
+	earlyPremine := uint128.From64(7)
+	earlyEtch := newTestTx([]wire.OutPoint{outpoint(earlyCommit, 0)}, commitWitness(t, *early), taprootOut(2),
+		runestoneOut(t, &Runestone{Etching: &Etching{Rune: early, Premine: &earlyPremine}}))
+	reservedPremine := uint128.From64(5)
+	reserved := newTestTx(funding(3), nil, taprootOut(3), runestoneOut(t, &Runestone{Etching: &Etching{Premine: &reservedPremine}}))
+	index(6, etch, earlyEtch, reserved)
+
+	id := RuneId{Block: 6, Tx: 1}
+	require.Equal(t, &id, lookup(*name))
+	require.Equal(t, etch.TxHash(), entry(id).Etching)
+	require.Equal(t, []Balance{{ID: id, Amount: premine}}, balances(outpoint(etch, 0)))
+	require.Nil(t, lookup(*early))
+	require.Empty(t, balances(outpoint(earlyEtch, 0)))
+	reservedID := RuneId{Block: 6, Tx: 3}
+	require.Equal(t, &reservedID, lookup(ReservedRuneOf(6, 3)))
+	require.Equal(t, []Balance{{ID: reservedID, Amount: reservedPremine}}, balances(outpoint(reserved, 0)))
+
+	// two mints fill the cap, the third gets nothing
+	mints := make([]*wire.MsgTx, 3)
+	for i := range mints {
+		mints[i] = newTestTx(funding(byte(10+i)), nil, taprootOut(4), runestoneOut(t, &Runestone{Mint: &id}))
+	}
+	index(7, mints[0], mints[1])
+	index(8, mints[2])
+	require.Equal(t, []Balance{{ID: id, Amount: amount}}, balances(outpoint(mints[0], 0)))
+	require.Equal(t, []Balance{{ID: id, Amount: amount}}, balances(outpoint(mints[1], 0)))
+	require.Empty(t, balances(outpoint(mints[2], 0)))
+	require.Equal(t, uint128.From64(2), entry(id).Mints)
+	require.Equal(t, uint128.From64(1200), entry(id).Supply())
+
+	// 300 to the first output, 50 burned in the OP_RETURN and the 750 left
+	// split between the other outputs
+	transfer := newTestTx([]wire.OutPoint{outpoint(etch, 0), outpoint(mints[0], 0), outpoint(reserved, 0)}, nil,
+		taprootOut(5), taprootOut(6), runestoneOut(t, &Runestone{Edicts: []Edict{
+			{ID: id, Amount: uint128.From64(300), Output: 0},
+			{ID: id, Amount: uint128.From64(50), Output: 2},
+			{ID: id, Amount: uint128.Zero, Output: 3},
+		}}))
+	index(9, transfer)
+	require.Empty(t, balances(outpoint(etch, 0)))
+	require.Equal(t, []Balance{
+		{ID: id, Amount: uint128.From64(675)},
+		{ID: reservedID, Amount: reservedPremine},
+	}, balances(outpoint(transfer, 0)))
+	require.Equal(t, []Balance{{ID: id, Amount: uint128.From64(375)}}, balances(outpoint(transfer, 1)))
+
+	// the pointer takes what edicts leave, a cenotaph burns everything
+	pointer := uint32(1)
+	pointed := newTestTx([]wire.OutPoint{outpoint(transfer, 0)}, nil, taprootOut(7), taprootOut(8), runestoneOut(t, &Runestone{Pointer: &pointer}))
+	cenotaphScript, err := script.RuneStoneScript(payload(t, TagCenotaph, 1))
+	require.NoError(t, err)
+	burn := newTestTx([]wire.OutPoint{outpoint(transfer, 1)}, nil, taprootOut(9), wire.NewTxOut(0, cenotaphScript))
+	index(10, pointed, burn)
+	require.Empty(t, balances(outpoint(pointed, 0)))
+	require.Equal(t, []Balance{
+		{ID: id, Amount: uint128.From64(675)},
+		{ID: reservedID, Amount: reservedPremine},
+	}, balances(outpoint(pointed, 1)))
+	require.Empty(t, balances(outpoint(burn, 0)))
+	require.Equal(t, uint128.From64(425), entry(id).Burned)
+
+	// every rune minted is held or burned
+	held := uint128.Zero
+	for _, outBalances := range store.balances {
+		for _, balance := range outBalances {
+			if balance.ID == id {
+				held = held.Add(balance.Amount)
+			}
+		}
+	}
+	require.Equal(t, entry(id).Supply(), held.Add(entry(id).Burned))
+}
+
+func TestRuneEntry_Mintable(t *testing.T) {
+	amount, limit := uint128.From64(10), uint128.From64(1)
+	start, end, offsetStart, offsetEnd := uint64(110), uint64(130), uint64(5), uint64(20)
+	e := &RuneEntry{Block: 100, Terms: &Terms{
+		Amount: &amount,
+		Cap:    &limit,
+		Height: [2]*uint64{&start, &end},
+		Offset: [2]*uint64{&offsetStart, &offsetEnd},
+	}}
+	// the later start and the earlier end win
+	require.Equal(t, uint64(110), *e.Start())
+	require.Equal(t, uint64(120), *e.End())
+	for height, mintable := range map[uint64]bool{109: false, 110: true, 119: true, 120: false} {
+		got, ok := e.Mintable(height)
+		require.Equal(t, mintable, ok, height)
+		if ok {
+			require.Equal(t, amount, got)
+		}
+	}
+	e.Mints = limit
+	_, ok := e.Mintable(115)
+	require.False(t, ok)
+	_, ok = (&RuneEntry{}).Mintable(115)
+	require.False(t, ok)
+}
@@ -0,0 +1,54 @@
+package runes
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/stretchr/testify/require"
+	"lukechampine.com/uint128"
+)
+
+func TestRune_Commitment(t *testing.T) {
+	for _, tt := range []struct {
+		n          uint128.Uint128
+		commitment []byte
+	}{
+		{uint128.Zero, []byte{}},
+		{uint128.From64(1), []byte{0x01}},
+		{uint128.From64(255), []byte{0xff}},
+		{uint128.From64(256), []byte{0x00, 0x01}},
+		{uint128.From64(65535), []byte{0xff, 0xff}},
+		{uint128.From64(65536), []byte{0x00, 0x00, 0x01}},
+		{uint128.Max, bytes.Repeat([]byte{0xff}, 16)},
+	} {
+		require.Equal(t, tt.commitment, NewRune(tt.n).Commitment(), tt.n.String())
+	}
+}
+
+func TestRune_Reserved(t *testing.T) {
+	require.Equal(t, "AAAAAAAAAAAAAAAAAAAAAAAAAAA", ReservedRune.String())
+	require.Equal(t, "6402364363415443603228541259936211926", ReservedRune.Value.String())
+	require.True(t, ReservedRune.IsReserved())
+	require.False(t, Rune{Value: ReservedRune.Value.Sub64(1)}.IsReserved())
+	require.Equal(t, ReservedRune.Value.Add(uint128.New(1<<32|7, 0)), ReservedRuneOf(1, 7).Value)
+	require.Equal(t, ReservedRune.Value.Add(uint128.New(0, 1)), ReservedRuneOf(1<<32, 0).Value)
+}
+
+func TestMinimumAtHeight(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	for _, tt := range []struct {
+		height  uint64
+		minimum string
+	}{
+		{0, "AAAAAAAAAAAAA"},
+		{840000 - 1, "AAAAAAAAAAAAA"},
+		{840000, "ZZYZXBRKWXVA"},
+		{840000 + 17500 - 1, "AAAAAAAAAAAA"},
+		{840000 + 210000 - 2, "B"},
+		{840000 + 210000 - 1, "A"},
+	} {
+		require.Equal(t, tt.minimum, MinimumAtHeight(params, tt.height).String(), tt.height)
+	}
+	require.Equal(t, uint64(0), FirstRuneHeight(&chaincfg.RegressionNetParams))
+}
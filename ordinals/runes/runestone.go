@@ -1,32 +1,375 @@
-package runes
-
-// func NewRunestoneEtchingTx(c *client.Client, utxos []*types.Utxo, fromAddress string, toAddress string, fundAddress string, etching *runestone.Etching) (*psbt.Packet, error) {
-// 	from, addrType, err := types.DecodeAddress(fromAddress, c.GetParams())
-// 	if err != nil {
-// 		return nil, err
-// 	} else if addrType != types.P2TR {
-// 		return nil, errors.New("from address must be a taproot address")
-// 	}
-// 	to, addrType, err := types.DecodeAddress(toAddress, c.GetParams())
-// 	if err != nil {
-// 		return nil, err
-// 	} else if addrType != types.P2TR {
-// 		return nil, errors.New("to address must be a taproot address")
-// 	}
-
-// 	fromPubKey, err := types.AddrP2TRToPubkey(fromAddress, c.GetParams())
-// 	if err != nil {
-// 		return nil, err
-// 	}
-
-// 	rs := &runestone.Runestone{
-// 		Etching: etching,
-// 	}
-// 	rsData, err := rs.Encipher()
-// 	if err != nil {
-// 		return nil, err
-// 	}
-// 	rsCommitMent := rs.Etching.Rune.Commitment()
-
-// 	return nil, nil
-// }
+package runes
+
+import (
+	"math"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"lukechampine.com/uint128"
+
+	"github.com/gosuda/btctxbuilder/script"
+)
+
+// Runestone is the message of an OP_RETURN OP_13 output: the payload is a
+// sequence of LEB128 integers, tag and value pairs followed by the edicts.
+type Runestone struct {
+	Edicts  []Edict
+	Etching *Etching
+	Mint    *RuneId
+	// Pointer is the output receiving the runes not transferred by
+	// edicts, by default the first output that isn't an OP_RETURN.
+	Pointer *uint32
+}
+
+// Tag is the tag of a runestone field. Even tags must be understood by
+// indexers, otherwise the runestone is a cenotaph.
+type Tag uint8
+
+const (
+	TagBody         Tag = 0
+	TagFlags        Tag = 2
+	TagRune         Tag = 4
+	TagPremine      Tag = 6
+	TagCap          Tag = 8
+	TagAmount       Tag = 10
+	TagHeightStart  Tag = 12
+	TagHeightEnd    Tag = 14
+	TagOffsetStart  Tag = 16
+	TagOffsetEnd    Tag = 18
+	TagMint         Tag = 20
+	TagPointer      Tag = 22
+	TagCenotaph     Tag = 126
+	TagDivisibility Tag = 1
+	TagSpacers      Tag = 3
+	TagSymbol       Tag = 5
+	TagNop          Tag = 127
+)
+
+// Flag is a bit of the flags field.
+type Flag uint8
+
+const (
+	FlagEtching  Flag = 0
+	FlagTerms    Flag = 1
+	FlagTurbo    Flag = 2
+	FlagCenotaph Flag = 127
+)
+
+// Mask returns the flags value with only f set.
+func (f Flag) Mask() uint128.Uint128 {
+	return uint128.From64(1).Lsh(uint(f))
+}
+
+// take reports whether f is set in flags and clears it.
+func (f Flag) take(flags *uint128.Uint128) bool {
+	set := !flags.And(f.Mask()).IsZero()
+	*flags = flags.And(f.Mask().Xor(uint128.Max))
+	return set
+}
+
+// Encipher returns the runestone output script, with the edicts sorted by
+// rune ID and delta encoded.
+func (r *Runestone) Encipher() ([]byte, error) {
+	var payload []byte
+	field := func(tag Tag, value uint128.Uint128) {
+		payload = AppendVarint(payload, uint128.From64(uint64(tag)))
+		payload = AppendVarint(payload, value)
+	}
+	field128 := func(tag Tag, value *uint128.Uint128) {
+		if value != nil {
+			field(tag, *value)
+		}
+	}
+	field64 := func(tag Tag, value *uint64) {
+		if value != nil {
+			field(tag, uint128.From64(*value))
+		}
+	}
+
+	if e := r.Etching; e != nil {
+		flags := FlagEtching.Mask()
+		if e.Terms != nil {
+			flags = flags.Or(FlagTerms.Mask())
+		}
+		if e.Turbo {
+			flags = flags.Or(FlagTurbo.Mask())
+		}
+		field(TagFlags, flags)
+		if e.Rune != nil {
+			field(TagRune, e.Rune.Value)
+		}
+		if e.Divisibility != nil {
+			field(TagDivisibility, uint128.From64(uint64(*e.Divisibility)))
+		}
+		if e.Spacers != nil {
+			field(TagSpacers, uint128.From64(uint64(*e.Spacers)))
+		}
+		if e.Symbol != nil {
+			field(TagSymbol, uint128.From64(uint64(*e.Symbol)))
+		}
+		field128(TagPremine, e.Premine)
+		if t := e.Terms; t != nil {
+			field128(TagAmount, t.Amount)
+			field128(TagCap, t.Cap)
+			field64(TagHeightStart, t.Height[0])
+			field64(TagHeightEnd, t.Height[1])
+			field64(TagOffsetStart, t.Offset[0])
+			field64(TagOffsetEnd, t.Offset[1])
+		}
+	}
+	if r.Mint != nil {
+		field(TagMint, uint128.From64(r.Mint.Block))
+		field(TagMint, uint128.From64(uint64(r.Mint.Tx)))
+	}
+	if r.Pointer != nil {
+		field(TagPointer, uint128.From64(uint64(*r.Pointer)))
+	}
+
+	if len(r.Edicts) > 0 {
+		payload = AppendVarint(payload, uint128.From64(uint64(TagBody)))
+		edicts := slices.Clone(r.Edicts)
+		slices.SortStableFunc(edicts, func(a, b Edict) int { return a.ID.Cmp(b.ID) })
+		var previous RuneId
+		for _, edict := range edicts {
+			block, tx, err := previous.Delta(edict.ID)
+			if err != nil {
+				return nil, err
+			}
+			payload = AppendVarint(payload, uint128.From64(block))
+			payload = AppendVarint(payload, uint128.From64(uint64(tx)))
+			payload = AppendVarint(payload, edict.Amount)
+			payload = AppendVarint(payload, uint128.From64(uint64(edict.Output)))
+			previous = edict.ID
+		}
+	}
+	return script.RuneStoneScript(payload)
+}
+
+// Decipher returns the runestone of the first OP_RETURN OP_13 output of
+// tx, or its cenotaph if the runestone is malformed, following ord. Both
+// are nil if tx has no runestone.
+func Decipher(tx *wire.MsgTx) (*Runestone, *Cenotaph) {
+	payload, flaw, ok := runestonePayload(tx)
+	if !ok {
+		return nil, nil
+	}
+	if flaw != nil {
+		return nil, &Cenotaph{Flaw: flaw}
+	}
+
+	var integers []uint128.Uint128
+	for len(payload) > 0 {
+		n, size, err := DecodeVarint(payload)
+		if err != nil {
+			return nil, &Cenotaph{Flaw: FlawP(Varint)}
+		}
+		integers = append(integers, n)
+		payload = payload[size:]
+	}
+
+	edicts, fields, flaw := parseMessage(len(tx.TxOut), integers)
+
+	var flags uint128.Uint128
+	fields.take(TagFlags, 1, func(v []uint128.Uint128) bool {
+		flags = v[0]
+		return true
+	})
+
+	var etching *Etching
+	if FlagEtching.take(&flags) {
+		etching = &Etching{}
+		fields.take(TagDivisibility, 1, func(v []uint128.Uint128) bool {
+			if v[0].Cmp64(MaxDivisibility) > 0 {
+				return false
+			}
+			divisibility := uint8(v[0].Lo)
+			etching.Divisibility = &divisibility
+			return true
+		})
+		fields.take(TagPremine, 1, func(v []uint128.Uint128) bool {
+			etching.Premine = &v[0]
+			return true
+		})
+		fields.take(TagRune, 1, func(v []uint128.Uint128) bool {
+			etching.Rune = &Rune{Value: v[0]}
+			return true
+		})
+		fields.take(TagSpacers, 1, func(v []uint128.Uint128) bool {
+			if v[0].Cmp64(MaxSpacers) > 0 {
+				return false
+			}
+			spacers := uint32(v[0].Lo)
+			etching.Spacers = &spacers
+			return true
+		})
+		fields.take(TagSymbol, 1, func(v []uint128.Uint128) bool {
+			if v[0].Cmp64(utf8.MaxRune) > 0 || !utf8.ValidRune(rune(v[0].Lo)) {
+				return false
+			}
+			symbol := rune(v[0].Lo)
+			etching.Symbol = &symbol
+			return true
+		})
+		if FlagTerms.take(&flags) {
+			terms := &Terms{}
+			fields.take(TagCap, 1, func(v []uint128.Uint128) bool {
+				terms.Cap = &v[0]
+				return true
+			})
+			fields.take(TagHeightStart, 1, takeU64(&terms.Height[0]))
+			fields.take(TagHeightEnd, 1, takeU64(&terms.Height[1]))
+			fields.take(TagAmount, 1, func(v []uint128.Uint128) bool {
+				terms.Amount = &v[0]
+				return true
+			})
+			fields.take(TagOffsetStart, 1, takeU64(&terms.Offset[0]))
+			fields.take(TagOffsetEnd, 1, takeU64(&terms.Offset[1]))
+			etching.Terms = terms
+		}
+		etching.Turbo = FlagTurbo.take(&flags)
+	}
+
+	var mint *RuneId
+	fields.take(TagMint, 2, func(v []uint128.Uint128) bool {
+		if v[0].Hi != 0 || v[1].Cmp64(math.MaxUint32) > 0 {
+			return false
+		}
+		id, err := NewRuneId(v[0].Lo, uint32(v[1].Lo))
+		if err != nil {
+			return false
+		}
+		mint = id
+		return true
+	})
+
+	var pointer *uint32
+	fields.take(TagPointer, 1, func(v []uint128.Uint128) bool {
+		if v[0].Cmp64(uint64(len(tx.TxOut))) >= 0 {
+			return false
+		}
+		output := uint32(v[0].Lo)
+		pointer = &output
+		return true
+	})
+
+	if etching != nil {
+		if _, ok := etching.Supply(); !ok && flaw == nil {
+			flaw = FlawP(SupplyOverflow)
+		}
+	}
+	if !flags.IsZero() && flaw == nil {
+		flaw = FlawP(UnrecognizedFlag)
+	}
+	for tag := range fields {
+		if tag.Lo%2 == 0 && flaw == nil {
+			flaw = FlawP(UnrecognizedEvenTag)
+		}
+	}
+
+	if flaw != nil {
+		cenotaph := &Cenotaph{Flaw: flaw, Mint: mint}
+		if etching != nil {
+			cenotaph.Etching = etching.Rune
+		}
+		return nil, cenotaph
+	}
+	return &Runestone{Edicts: edicts, Etching: etching, Mint: mint, Pointer: pointer}, nil
+}
+
+// runestonePayload concatenates the pushes following OP_RETURN OP_13 in
+// the first output starting with them. Any other opcode, or a script
+// that doesn't parse, is a flaw.
+func runestonePayload(tx *wire.MsgTx) (payload []byte, flaw *Flaw, ok bool) {
+	for _, out := range tx.TxOut {
+		tokenizer := txscript.MakeScriptTokenizer(0, out.PkScript)
+		if !tokenizer.Next() || tokenizer.Opcode() != txscript.OP_RETURN {
+			continue
+		}
+		if !tokenizer.Next() || tokenizer.Opcode() != txscript.OP_13 {
+			continue
+		}
+		payload = []byte{}
+		for tokenizer.Next() {
+			if tokenizer.Opcode() > txscript.OP_PUSHDATA4 {
+				return nil, FlawP(Opcode), true
+			}
+			payload = append(payload, tokenizer.Data()...)
+		}
+		if tokenizer.Err() != nil {
+			return nil, FlawP(InvalidScript), true
+		}
+		return payload, nil, true
+	}
+	return nil, nil, false
+}
+
+// fields are the values of a runestone by tag, in order.
+type fields map[uint128.Uint128][]uint128.Uint128
+
+// take passes the first n values of tag to with and consumes them if it
+// accepts them. Values left over show up as unrecognized tags.
+func (f fields) take(tag Tag, n int, with func(values []uint128.Uint128) bool) {
+	key := uint128.From64(uint64(tag))
+	values := f[key]
+	if len(values) < n || !with(values[:n]) {
+		return
+	}
+	if len(values) == n {
+		delete(f, key)
+	} else {
+		f[key] = values[n:]
+	}
+}
+
+// takeU64 sets *dst to a value that fits in 64 bits.
+func takeU64(dst **uint64) func(values []uint128.Uint128) bool {
+	return func(v []uint128.Uint128) bool {
+		if v[0].Hi != 0 {
+			return false
+		}
+		value := v[0].Lo
+		*dst = &value
+		return true
+	}
+}
+
+// parseMessage splits integers into fields and the edicts of the body,
+// which runs to the end of the payload.
+func parseMessage(numOutputs int, integers []uint128.Uint128) ([]Edict, fields, *Flaw) {
+	var edicts []Edict
+	var flaw *Flaw
+	f := fields{}
+	for i := 0; i < len(integers); i += 2 {
+		tag := integers[i]
+		if tag.Equals64(uint64(TagBody)) {
+			var id RuneId
+			for body := integers[i+1:]; len(body) > 0; body = body[4:] {
+				if len(body) < 4 {
+					flaw = FlawP(TrailingIntegers)
+					break
+				}
+				next, err := id.Next(body[0], body[1])
+				if err != nil {
+					flaw = FlawP(EdictRuneId)
+					break
+				}
+				edict, ok := newEdict(numOutputs, *next, body[2], body[3])
+				if !ok {
+					flaw = FlawP(EdictOutput)
+					break
+				}
+				id = *next
+				edicts = append(edicts, edict)
+			}
+			break
+		}
+		if i+1 == len(integers) {
+			flaw = FlawP(TruncatedField)
+			break
+		}
+		f[tag] = append(f[tag], integers[i+1])
+	}
+	return edicts, f, flaw
+}
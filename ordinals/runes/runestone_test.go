@@ -1 +1,391 @@
-package runes
+package runes
+
+import (
+	"math"
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+	"lukechampine.com/uint128"
+)
+
+// payload encodes integers given as a Tag, a Flag (its mask), an int or a
+// uint128.
+func payload(t *testing.T, integers ...any) []byte {
+	t.Helper()
+	var buf []byte
+	for _, integer := range integers {
+		var n uint128.Uint128
+		switch v := integer.(type) {
+		case Tag:
+			n = uint128.From64(uint64(v))
+		case Flag:
+			n = v.Mask()
+		case int:
+			n = uint128.From64(uint64(v))
+		case uint64:
+			n = uint128.From64(v)
+		case uint128.Uint128:
+			n = v
+		default:
+			t.Fatalf("unexpected integer %T", integer)
+		}
+		buf = AppendVarint(buf, n)
+	}
+	return buf
+}
+
+// runestoneTx returns a transaction whose only output is the runestone of
+// integers.
+func runestoneTx(t *testing.T, integers ...any) *wire.MsgTx {
+	t.Helper()
+	pkScript, err := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_RETURN).
+		AddOp(txscript.OP_13).
+		AddFullData(payload(t, integers...)).
+		Script()
+	require.NoError(t, err)
+	return txWithOutputs(pkScript)
+}
+
+func txWithOutputs(pkScripts ...[]byte) *wire.MsgTx {
+	tx := wire.NewMsgTx(2)
+	for _, pkScript := range pkScripts {
+		tx.AddTxOut(wire.NewTxOut(0, pkScript))
+	}
+	return tx
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func u128(n uint64) *uint128.Uint128 {
+	return ptr(uint128.From64(n))
+}
+
+func TestDecipher_NoRunestone(t *testing.T) {
+	for name, tx := range map[string]*wire.MsgTx{
+		"no outputs":           txWithOutputs(),
+		"not op_return":        txWithOutputs([]byte{txscript.OP_0}),
+		"bare op_return":       txWithOutputs([]byte{txscript.OP_RETURN}),
+		"other op_return":      txWithOutputs([]byte{txscript.OP_RETURN, 0x04, 'F', 'O', 'O', 'O'}),
+		"malformed first push": txWithOutputs([]byte{txscript.OP_DATA_4}),
+	} {
+		runestone, cenotaph := Decipher(tx)
+		require.Nil(t, runestone, name)
+		require.Nil(t, cenotaph, name)
+	}
+}
+
+func TestDecipher_Script(t *testing.T) {
+	prefix := []byte{txscript.OP_RETURN, txscript.OP_13}
+	edict := payload(t, TagBody, 1, 1, 2, 0)
+	wantEdict := &Runestone{Edicts: []Edict{{ID: RuneId{Block: 1, Tx: 1}, Amount: uint128.From64(2)}}}
+
+	runestone, cenotaph := Decipher(txWithOutputs(prefix))
+	require.Equal(t, &Runestone{}, runestone)
+	require.Nil(t, cenotaph)
+
+	// the payload may be split in any pushes, empty ones included
+	pkScript := append(append([]byte{}, prefix...), txscript.OP_0, 1, edict[0], 2, edict[1], edict[2], 2, edict[3], edict[4])
+	runestone, cenotaph = Decipher(txWithOutputs(pkScript))
+	require.Equal(t, wantEdict, runestone)
+	require.Nil(t, cenotaph)
+
+	// only the first runestone counts
+	second := append(append([]byte{}, prefix...), txscript.OP_1)
+	runestone, _ = Decipher(txWithOutputs([]byte{txscript.OP_RETURN}, append(append(append([]byte{}, prefix...), 5), edict...), second))
+	require.Equal(t, wantEdict, runestone)
+
+	for name, tt := range map[string]struct {
+		pkScript []byte
+		flaw     Flaw
+	}{
+		"invalid script": {append(prefix, txscript.OP_DATA_4), InvalidScript},
+		"opcode":         {append(prefix, txscript.OP_VERIFY, 1, 0), Opcode},
+		"pushnum":        {append(prefix, txscript.OP_1), Opcode},
+		"varint":         {append(prefix, 1, 0x80), Varint},
+	} {
+		runestone, cenotaph := Decipher(txWithOutputs(tt.pkScript))
+		require.Nil(t, runestone, name)
+		require.Equal(t, &Cenotaph{Flaw: FlawP(tt.flaw)}, cenotaph, name)
+	}
+}
+
+func TestDecipher(t *testing.T) {
+	edict := []any{TagBody, 1, 1, 2, 0}
+	edicts := []Edict{{ID: RuneId{Block: 1, Tx: 1}, Amount: uint128.From64(2)}}
+	with := func(integers ...any) []any { return append(integers, edict...) }
+
+	tests := []struct {
+		name     string
+		integers []any
+		want     *Runestone
+		cenotaph *Cenotaph
+	}{
+		{
+			name:     "edict",
+			integers: edict,
+			want:     &Runestone{Edicts: edicts},
+		},
+		{
+			name:     "edict ids are delta encoded",
+			integers: []any{TagBody, 1, 1, 5, 0, 0, 1, 6, 0, 2, 3, 7, 1},
+			want: &Runestone{Edicts: []Edict{
+				{ID: RuneId{Block: 1, Tx: 1}, Amount: uint128.From64(5)},
+				{ID: RuneId{Block: 1, Tx: 2}, Amount: uint128.From64(6)},
+				{ID: RuneId{Block: 3, Tx: 3}, Amount: uint128.From64(7), Output: 1},
+			}},
+		},
+		{
+			name:     "etching",
+			integers: with(TagFlags, FlagEtching),
+			want:     &Runestone{Edicts: edicts, Etching: &Etching{}},
+		},
+		{
+			name:     "etching with rune",
+			integers: with(TagFlags, FlagEtching, TagRune, 4),
+			want:     &Runestone{Edicts: edicts, Etching: &Etching{Rune: &Rune{Value: uint128.From64(4)}}},
+		},
+		{
+			name:     "etching with terms",
+			integers: with(TagFlags, FlagEtching.Mask().Or(FlagTerms.Mask()), TagOffsetEnd, 4, TagAmount, 5),
+			want: &Runestone{Edicts: edicts, Etching: &Etching{Terms: &Terms{
+				Amount: u128(5),
+				Offset: [2]*uint64{nil, ptr(uint64(4))},
+			}}},
+		},
+		{
+			name:     "terms without etching",
+			integers: with(TagFlags, FlagTerms),
+			cenotaph: &Cenotaph{Flaw: FlawP(UnrecognizedFlag)},
+		},
+		{
+			name:     "etching field without etching",
+			integers: with(TagRune, 4),
+			cenotaph: &Cenotaph{Flaw: FlawP(UnrecognizedEvenTag)},
+		},
+		{
+			name:     "duplicate even tag",
+			integers: with(TagFlags, FlagEtching, TagRune, 4, TagRune, 5),
+			cenotaph: &Cenotaph{Flaw: FlawP(UnrecognizedEvenTag), Etching: &Rune{Value: uint128.From64(4)}},
+		},
+		{
+			name:     "duplicate odd tag",
+			integers: with(TagFlags, FlagEtching, TagDivisibility, 4, TagDivisibility, 5),
+			want:     &Runestone{Edicts: edicts, Etching: &Etching{Divisibility: ptr(uint8(4))}},
+		},
+		{
+			name:     "unrecognized even tag",
+			integers: with(TagCenotaph, 0),
+			cenotaph: &Cenotaph{Flaw: FlawP(UnrecognizedEvenTag)},
+		},
+		{
+			name:     "unrecognized odd tag",
+			integers: with(TagNop, 0),
+			want:     &Runestone{Edicts: edicts},
+		},
+		{
+			name:     "unrecognized flag",
+			integers: with(TagFlags, FlagCenotaph),
+			cenotaph: &Cenotaph{Flaw: FlawP(UnrecognizedFlag)},
+		},
+		{
+			name:     "edict id with zero block and nonzero tx",
+			integers: []any{TagBody, 0, 1, 2, 0},
+			cenotaph: &Cenotaph{Flaw: FlawP(EdictRuneId)},
+		},
+		{
+			name:     "edict block overflow",
+			integers: []any{TagBody, 1, 0, 0, 0, uint64(math.MaxUint64), 0, 0, 0},
+			cenotaph: &Cenotaph{Flaw: FlawP(EdictRuneId)},
+		},
+		{
+			name:     "edict tx overflow",
+			integers: []any{TagBody, 1, 1, 0, 0, 0, math.MaxUint32, 0, 0},
+			cenotaph: &Cenotaph{Flaw: FlawP(EdictRuneId)},
+		},
+		{
+			name:     "edict output past outputs",
+			integers: []any{TagBody, 1, 1, 2, 2},
+			cenotaph: &Cenotaph{Flaw: FlawP(EdictOutput)},
+		},
+		{
+			name:     "edict output splitting between outputs",
+			integers: []any{TagBody, 1, 1, 2, 1},
+			want:     &Runestone{Edicts: []Edict{{ID: RuneId{Block: 1, Tx: 1}, Amount: uint128.From64(2), Output: 1}}},
+		},
+		{
+			name:     "truncated field",
+			integers: []any{TagFlags, 1, TagFlags},
+			cenotaph: &Cenotaph{Flaw: FlawP(TruncatedField)},
+		},
+		{
+			name:     "trailing integers",
+			integers: append(edict, 5),
+			cenotaph: &Cenotaph{Flaw: FlawP(TrailingIntegers)},
+		},
+		{
+			name:     "divisibility over max is ignored",
+			integers: []any{TagFlags, FlagEtching, TagDivisibility, MaxDivisibility + 1},
+			want:     &Runestone{Etching: &Etching{}},
+		},
+		{
+			name:     "max divisibility",
+			integers: []any{TagFlags, FlagEtching, TagDivisibility, MaxDivisibility},
+			want:     &Runestone{Etching: &Etching{Divisibility: ptr(uint8(MaxDivisibility))}},
+		},
+		{
+			name:     "spacers over max are ignored",
+			integers: []any{TagFlags, FlagEtching, TagSpacers, MaxSpacers + 1},
+			want:     &Runestone{Etching: &Etching{}},
+		},
+		{
+			name:     "invalid symbols are ignored",
+			integers: []any{TagFlags, FlagEtching, TagSymbol, 0x110000, TagSymbol, 0xd800},
+			want:     &Runestone{Etching: &Etching{}},
+		},
+		{
+			name:     "symbol",
+			integers: []any{TagFlags, FlagEtching, TagSymbol, int('ᚠ')},
+			want:     &Runestone{Etching: &Etching{Symbol: ptr('ᚠ')}},
+		},
+		{
+			name:     "mint",
+			integers: []any{TagMint, 1, TagMint, 1},
+			want:     &Runestone{Mint: &RuneId{Block: 1, Tx: 1}},
+		},
+		{
+			name:     "invalid mint",
+			integers: []any{TagMint, 0, TagMint, 1},
+			cenotaph: &Cenotaph{Flaw: FlawP(UnrecognizedEvenTag)},
+		},
+		{
+			name:     "mint without tx",
+			integers: []any{TagMint, 1},
+			cenotaph: &Cenotaph{Flaw: FlawP(UnrecognizedEvenTag)},
+		},
+		{
+			name:     "cenotaph keeps the mint",
+			integers: []any{TagMint, 1, TagMint, 1, TagCenotaph, 0},
+			cenotaph: &Cenotaph{Flaw: FlawP(UnrecognizedEvenTag), Mint: &RuneId{Block: 1, Tx: 1}},
+		},
+		{
+			name:     "pointer",
+			integers: []any{TagPointer, 0},
+			want:     &Runestone{Pointer: ptr(uint32(0))},
+		},
+		{
+			name:     "pointer past outputs",
+			integers: []any{TagPointer, 1},
+			cenotaph: &Cenotaph{Flaw: FlawP(UnrecognizedEvenTag)},
+		},
+		{
+			name:     "supply overflow",
+			integers: []any{TagFlags, FlagEtching.Mask().Or(FlagTerms.Mask()), TagPremine, uint128.Max, TagCap, 1, TagAmount, 1},
+			cenotaph: &Cenotaph{Flaw: FlawP(SupplyOverflow)},
+		},
+		{
+			name:     "max supply",
+			integers: []any{TagFlags, FlagEtching.Mask().Or(FlagTerms.Mask()), TagPremine, uint128.Max, TagCap, 0, TagAmount, 1},
+			want:     &Runestone{Etching: &Etching{Premine: &uint128.Max, Terms: &Terms{Cap: u128(0), Amount: u128(1)}}},
+		},
+		{
+			name:     "height over u64 is ignored",
+			integers: []any{TagFlags, FlagEtching.Mask().Or(FlagTerms.Mask()), TagHeightStart, uint128.New(0, 1)},
+			cenotaph: &Cenotaph{Flaw: FlawP(UnrecognizedEvenTag)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runestone, cenotaph := Decipher(runestoneTx(t, tt.integers...))
+			require.Equal(t, tt.want, runestone)
+			require.Equal(t, tt.cenotaph, cenotaph)
+		})
+	}
+}
+
+func TestRunestone_Encipher(t *testing.T) {
+	pkScript, err := (&Runestone{}).Encipher()
+	require.NoError(t, err)
+	require.Equal(t, []byte{txscript.OP_RETURN, txscript.OP_13}, pkScript)
+
+	runestone := &Runestone{
+		Edicts: []Edict{
+			{ID: RuneId{Block: 5, Tx: 6}, Amount: uint128.From64(4), Output: 1},
+			{ID: RuneId{Block: 2, Tx: 3}, Amount: uint128.From64(1), Output: 0},
+		},
+		Etching: &Etching{
+			Divisibility: ptr(uint8(7)),
+			Premine:      u128(8),
+			Rune:         &Rune{Value: uint128.From64(9)},
+			Spacers:      ptr(uint32(10)),
+			Symbol:       ptr('@'),
+			Terms: &Terms{
+				Cap:    u128(11),
+				Height: [2]*uint64{ptr(uint64(12)), ptr(uint64(13))},
+				Amount: u128(14),
+				Offset: [2]*uint64{ptr(uint64(15)), ptr(uint64(16))},
+			},
+			Turbo: true,
+		},
+		Mint:    &RuneId{Block: 17, Tx: 18},
+		Pointer: ptr(uint32(0)),
+	}
+	pkScript, err = runestone.Encipher()
+	require.NoError(t, err)
+
+	flags := FlagEtching.Mask().Or(FlagTerms.Mask()).Or(FlagTurbo.Mask())
+	want, err := txscript.NewScriptBuilder().AddOp(txscript.OP_RETURN).AddOp(txscript.OP_13).AddData(payload(t,
+		TagFlags, flags,
+		TagRune, 9,
+		TagDivisibility, 7,
+		TagSpacers, 10,
+		TagSymbol, int('@'),
+		TagPremine, 8,
+		TagAmount, 14,
+		TagCap, 11,
+		TagHeightStart, 12,
+		TagHeightEnd, 13,
+		TagOffsetStart, 15,
+		TagOffsetEnd, 16,
+		TagMint, 17,
+		TagMint, 18,
+		TagPointer, 0,
+		TagBody,
+		2, 3, 1, 0,
+		3, 6, 4, 1,
+	)).Script()
+	require.NoError(t, err)
+	require.Equal(t, want, pkScript)
+
+	// deciphering gives back the runestone with its edicts sorted
+	deciphered, cenotaph := Decipher(txWithOutputs(pkScript))
+	require.Nil(t, cenotaph)
+	runestone.Edicts[0], runestone.Edicts[1] = runestone.Edicts[1], runestone.Edicts[0]
+	require.Equal(t, runestone, deciphered)
+}
+
+func TestRunestone_EncipherChunks(t *testing.T) {
+	runestone := &Runestone{}
+	for i := uint32(0); i < 40; i++ {
+		runestone.Edicts = append(runestone.Edicts, Edict{ID: RuneId{Block: 840000, Tx: i}, Amount: uint128.Max})
+	}
+	pkScript, err := runestone.Encipher()
+	require.NoError(t, err)
+
+	// the body tag, a first edict of 24 bytes and 39 more of 22 bytes
+	var pushes []int
+	tokenizer := txscript.MakeScriptTokenizer(0, pkScript[2:])
+	for tokenizer.Next() {
+		pushes = append(pushes, len(tokenizer.Data()))
+	}
+	require.NoError(t, tokenizer.Err())
+	require.Equal(t, []int{520, 1 + 24 + 39*22 - 520}, pushes)
+
+	deciphered, cenotaph := Decipher(txWithOutputs(pkScript))
+	require.Nil(t, cenotaph)
+	require.Equal(t, runestone, deciphered)
+}
@@ -0,0 +1,127 @@
+package runes
+
+import (
+	"slices"
+
+	"github.com/btcsuite/btcd/wire"
+	"lukechampine.com/uint128"
+)
+
+// Balance is an amount of a rune held by an output.
+type Balance struct {
+	ID     RuneId
+	Amount uint128.Uint128
+}
+
+// Store holds the runes ledger an Indexer maintains. Methods looking up
+// missing state return nil or false without an error.
+type Store interface {
+	// Height returns the height of the last block indexed, or false before
+	// the first one.
+	Height() (uint64, bool, error)
+	SetHeight(height uint64) error
+
+	// Entry returns the rune etched as id, PutEntry adds or updates it.
+	Entry(id RuneId) (*RuneEntry, error)
+	PutEntry(id RuneId, entry *RuneEntry) error
+	// RuneID returns the ID of the rune etched with the name r.
+	RuneID(r Rune) (*RuneId, error)
+
+	// Balances returns the runes held by an unspent output, sorted by rune
+	// ID.
+	Balances(outpoint wire.OutPoint) ([]Balance, error)
+	PutBalances(outpoint wire.OutPoint, balances []Balance) error
+	DeleteBalances(outpoint wire.OutPoint) error
+
+	// TaprootHeight returns the height of the block that created an
+	// unspent taproot output, the confirmations of which tell whether an
+	// etching spending it commits to its rune in time.
+	TaprootHeight(outpoint wire.OutPoint) (uint64, bool, error)
+	PutTaprootHeight(outpoint wire.OutPoint, height uint64) error
+	DeleteTaprootHeight(outpoint wire.OutPoint) error
+}
+
+// MemoryStore is a Store kept in memory.
+type MemoryStore struct {
+	height   *uint64
+	entries  map[RuneId]*RuneEntry
+	ids      map[Rune]RuneId
+	balances map[wire.OutPoint][]Balance
+	taproot  map[wire.OutPoint]uint64
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries:  make(map[RuneId]*RuneEntry),
+		ids:      make(map[Rune]RuneId),
+		balances: make(map[wire.OutPoint][]Balance),
+		taproot:  make(map[wire.OutPoint]uint64),
+	}
+}
+
+func (s *MemoryStore) Height() (uint64, bool, error) {
+	if s.height == nil {
+		return 0, false, nil
+	}
+	return *s.height, true, nil
+}
+
+func (s *MemoryStore) SetHeight(height uint64) error {
+	s.height = &height
+	return nil
+}
+
+func (s *MemoryStore) Entry(id RuneId) (*RuneEntry, error) {
+	entry, ok := s.entries[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *entry
+	return &copied, nil
+}
+
+func (s *MemoryStore) PutEntry(id RuneId, entry *RuneEntry) error {
+	copied := *entry
+	s.entries[id] = &copied
+	s.ids[entry.SpacedRune.Rune] = id
+	return nil
+}
+
+func (s *MemoryStore) RuneID(r Rune) (*RuneId, error) {
+	id, ok := s.ids[r]
+	if !ok {
+		return nil, nil
+	}
+	return &id, nil
+}
+
+func (s *MemoryStore) Balances(outpoint wire.OutPoint) ([]Balance, error) {
+	return slices.Clone(s.balances[outpoint]), nil
+}
+
+func (s *MemoryStore) PutBalances(outpoint wire.OutPoint, balances []Balance) error {
+	s.balances[outpoint] = slices.Clone(balances)
+	return nil
+}
+
+func (s *MemoryStore) DeleteBalances(outpoint wire.OutPoint) error {
+	delete(s.balances, outpoint)
+	return nil
+}
+
+func (s *MemoryStore) TaprootHeight(outpoint wire.OutPoint) (uint64, bool, error) {
+	height, ok := s.taproot[outpoint]
+	return height, ok, nil
+}
+
+func (s *MemoryStore) PutTaprootHeight(outpoint wire.OutPoint, height uint64) error {
+	s.taproot[outpoint] = height
+	return nil
+}
+
+func (s *MemoryStore) DeleteTaprootHeight(outpoint wire.OutPoint) error {
+	delete(s.taproot, outpoint)
+	return nil
+}
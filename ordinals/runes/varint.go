@@ -0,0 +1,47 @@
+package runes
+
+import (
+	"errors"
+
+	"lukechampine.com/uint128"
+)
+
+// maxVarintLen is the length of the LEB128 encoding of the largest u128.
+const maxVarintLen = 19
+
+// AppendVarint appends the LEB128 encoding of n to buf: seven bits per
+// byte, least significant first, with the high bit set on every byte but
+// the last.
+func AppendVarint(buf []byte, n uint128.Uint128) []byte {
+	for !n.Rsh(7).IsZero() {
+		buf = append(buf, byte(n.Lo)|0x80)
+		n = n.Rsh(7)
+	}
+	return append(buf, byte(n.Lo))
+}
+
+// DecodeVarint decodes the LEB128 integer at the start of buf and returns
+// it with the number of bytes read.
+func DecodeVarint(buf []byte) (uint128.Uint128, int, error) {
+	var n uint128.Uint128
+	for i, b := range buf {
+		if i >= maxVarintLen {
+			return uint128.Zero, 0, ErrVarintOverlong
+		}
+		value := uint64(b & 0x7f)
+		if i == maxVarintLen-1 && value&0x7c != 0 {
+			return uint128.Zero, 0, ErrVarintOverflow
+		}
+		n = n.Or(uint128.From64(value).Lsh(uint(7 * i)))
+		if b&0x80 == 0 {
+			return n, i + 1, nil
+		}
+	}
+	return uint128.Zero, 0, ErrVarintUnterminated
+}
+
+var (
+	ErrVarintOverlong     = errors.New("varint too long")
+	ErrVarintOverflow     = errors.New("varint overflows u128")
+	ErrVarintUnterminated = errors.New("varint unterminated")
+)
@@ -0,0 +1,51 @@
+package runes
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"lukechampine.com/uint128"
+)
+
+func TestVarint(t *testing.T) {
+	for _, tt := range []struct {
+		n       uint128.Uint128
+		encoded []byte
+	}{
+		{uint128.Zero, []byte{0x00}},
+		{uint128.From64(1), []byte{0x01}},
+		{uint128.From64(127), []byte{0x7f}},
+		{uint128.From64(128), []byte{0x80, 0x01}},
+		{uint128.From64(255), []byte{0xff, 0x01}},
+		{uint128.From64(256), []byte{0x80, 0x02}},
+		{uint128.From64(16383), []byte{0xff, 0x7f}},
+		{uint128.From64(16384), []byte{0x80, 0x80, 0x01}},
+		{uint128.From64(16511), []byte{0xff, 0x80, 0x01}},
+		{uint128.From64(65535), []byte{0xff, 0xff, 0x03}},
+		{uint128.From64(1 << 32), []byte{0x80, 0x80, 0x80, 0x80, 0x10}},
+		{uint128.Max, append(bytes.Repeat([]byte{0xff}, 18), 0x03)},
+	} {
+		require.Equal(t, tt.encoded, AppendVarint(nil, tt.n), tt.n.String())
+		n, size, err := DecodeVarint(append(tt.encoded, 0xaa))
+		require.NoError(t, err)
+		require.Equal(t, tt.n, n)
+		require.Equal(t, len(tt.encoded), size)
+	}
+
+	for i := uint(0); i < 128; i++ {
+		n := uint128.From64(1).Lsh(i)
+		decoded, _, err := DecodeVarint(AppendVarint(nil, n))
+		require.NoError(t, err)
+		require.Equal(t, n, decoded)
+	}
+
+	_, _, err := DecodeVarint(append(bytes.Repeat([]byte{0x80}, 18), 0x04))
+	require.ErrorIs(t, err, ErrVarintOverflow)
+	_, _, err = DecodeVarint(append(bytes.Repeat([]byte{0x80}, 19), 0x00))
+	require.ErrorIs(t, err, ErrVarintOverlong)
+	_, _, err = DecodeVarint([]byte{0x80})
+	require.ErrorIs(t, err, ErrVarintUnterminated)
+	_, _, err = DecodeVarint(nil)
+	require.ErrorIs(t, err, ErrVarintUnterminated)
+}
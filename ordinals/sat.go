@@ -0,0 +1,169 @@
+package ordinals
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sat is the ordinal number of a satoshi, its position in the order sats
+// are mined in.
+type Sat uint64
+
+const (
+	subsidyHalvingInterval = 210000
+	diffChangeInterval     = 2016
+	cycleEpochs            = 6
+
+	// SatSupply is the number of sats that will ever be mined.
+	SatSupply Sat = 2099999997690000
+)
+
+// Rarity ranks sats by the events of the chain they are first of.
+type Rarity string
+
+const (
+	Common    Rarity = "common"    // any sat but the first of its block
+	Uncommon  Rarity = "uncommon"  // first sat of a block
+	Rare      Rarity = "rare"      // of a difficulty adjustment period
+	Epic      Rarity = "epic"      // of a halving epoch
+	Legendary Rarity = "legendary" // of a cycle, where both coincide
+	Mythic    Rarity = "mythic"    // of the genesis block
+)
+
+// subsidy returns the block subsidy of epoch.
+func subsidy(epoch uint64) uint64 {
+	if epoch >= 64 {
+		return 0
+	}
+	return 50_0000_0000 >> epoch
+}
+
+// epochStart returns the first sat mined in epoch.
+func epochStart(epoch uint64) Sat {
+	var sat Sat
+	for e := uint64(0); e < epoch && subsidy(e) > 0; e++ {
+		sat += Sat(subsidy(e) * subsidyHalvingInterval)
+	}
+	return sat
+}
+
+// FirstSat returns the first sat mined in the block at height, SatSupply
+// once the subsidy runs out.
+func FirstSat(height uint64) Sat {
+	epoch := height / subsidyHalvingInterval
+	return epochStart(epoch) + Sat(subsidy(epoch)*(height%subsidyHalvingInterval))
+}
+
+// epoch returns the halving epoch s is mined in and its first sat.
+func (s Sat) epoch() (uint64, Sat) {
+	var epoch uint64
+	start := Sat(0)
+	for {
+		next := start + Sat(subsidy(epoch)*subsidyHalvingInterval)
+		if s < next || subsidy(epoch) == 0 {
+			return epoch, start
+		}
+		epoch, start = epoch+1, next
+	}
+}
+
+// Height returns the height of the block s is mined in. Sats from
+// SatSupply on are never mined and get the height the subsidy runs out at.
+func (s Sat) Height() uint64 {
+	epoch, start := s.epoch()
+	if subsidy(epoch) == 0 {
+		return epoch * subsidyHalvingInterval
+	}
+	return epoch*subsidyHalvingInterval + uint64(s-start)/subsidy(epoch)
+}
+
+// Third returns the position of s in its block.
+func (s Sat) Third() uint64 {
+	epoch, start := s.epoch()
+	if subsidy(epoch) == 0 {
+		return 0
+	}
+	return uint64(s-start) % subsidy(epoch)
+}
+
+// Rarity returns the rarity of s.
+func (s Sat) Rarity() Rarity {
+	if s.Third() != 0 {
+		return Common
+	}
+	height := s.Height()
+	hour := height / (cycleEpochs * subsidyHalvingInterval)
+	minute := height % subsidyHalvingInterval
+	second := height % diffChangeInterval
+	switch {
+	case hour == 0 && minute == 0 && second == 0:
+		return Mythic
+	case minute == 0 && second == 0:
+		return Legendary
+	case minute == 0:
+		return Epic
+	case second == 0:
+		return Rare
+	default:
+		return Uncommon
+	}
+}
+
+// SatRange is the sats from Start up to End, excluded.
+type SatRange struct {
+	Start Sat
+	End   Sat
+}
+
+func (r SatRange) Len() uint64 {
+	return uint64(r.End - r.Start)
+}
+
+// RareSats returns the sats of r that aren't common, in order.
+func (r SatRange) RareSats() []Sat {
+	var sats []Sat
+	for height := r.Start.Height(); ; height++ {
+		sat := FirstSat(height)
+		if sat >= r.End || sat >= SatSupply {
+			return sats
+		}
+		if sat >= r.Start {
+			sats = append(sats, sat)
+		}
+	}
+}
+
+// AssignSatRanges hands the sats of the inputs, given as the ranges of
+// each, to outputs of values first in first out: the first output gets the
+// first sats of the first input, and so on. The sats left over are the fee.
+func AssignSatRanges(inputs [][]SatRange, values []int64) (outputs [][]SatRange, fee []SatRange, err error) {
+	var pending []SatRange
+	for _, ranges := range inputs {
+		pending = append(pending, ranges...)
+	}
+	outputs = make([][]SatRange, len(values))
+	for i, value := range values {
+		if value < 0 {
+			return nil, nil, fmt.Errorf("output %d has a negative value", i)
+		}
+		for left := uint64(value); left > 0; {
+			if len(pending) == 0 {
+				return nil, nil, errors.New("outputs spend more sats than the inputs hold")
+			}
+			r := pending[0]
+			if r.Len() == 0 {
+				pending = pending[1:]
+				continue
+			}
+			if r.Len() > left {
+				outputs[i] = append(outputs[i], SatRange{Start: r.Start, End: r.Start + Sat(left)})
+				pending[0].Start += Sat(left)
+				break
+			}
+			outputs[i] = append(outputs[i], r)
+			pending = pending[1:]
+			left -= r.Len()
+		}
+	}
+	return outputs, pending, nil
+}
@@ -0,0 +1,54 @@
+package ordinals
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSat(t *testing.T) {
+	for _, tt := range []struct {
+		sat    Sat
+		height uint64
+		rarity Rarity
+	}{
+		{0, 0, Mythic},
+		{1, 0, Common},
+		{FirstSat(1) - 1, 0, Common},
+		{FirstSat(1), 1, Uncommon},
+		{FirstSat(2016), 2016, Rare},
+		{FirstSat(210000), 210000, Epic},
+		{FirstSat(1260000), 1260000, Legendary},
+		{SatSupply - 1, 6929999, Uncommon}, // one sat blocks
+	} {
+		require.Equal(t, tt.height, tt.sat.Height(), "sat %d", tt.sat)
+		require.Equal(t, tt.rarity, tt.sat.Rarity(), "sat %d", tt.sat)
+	}
+	require.Equal(t, Sat(50_0000_0000*210000), FirstSat(210000))
+	require.Equal(t, SatSupply, FirstSat(6930000))
+	require.Equal(t, uint64(7), (FirstSat(840000) + 7).Third())
+}
+
+func TestSatRange_RareSats(t *testing.T) {
+	require.Equal(t, []Sat{FirstSat(1), FirstSat(2)}, SatRange{FirstSat(1) - 1, FirstSat(2) + 1}.RareSats())
+	require.Equal(t, []Sat{0}, SatRange{0, 10}.RareSats())
+	require.Empty(t, SatRange{1, FirstSat(1)}.RareSats())
+	require.Empty(t, SatRange{SatSupply, SatSupply + 10}.RareSats())
+}
+
+func TestAssignSatRanges(t *testing.T) {
+	outputs, fee, err := AssignSatRanges([][]SatRange{
+		{{0, 10}, {20, 25}},
+		{{100, 110}},
+	}, []int64{12, 5, 0})
+	require.NoError(t, err)
+	require.Equal(t, [][]SatRange{
+		{{0, 10}, {20, 22}},
+		{{22, 25}, {100, 102}},
+		nil,
+	}, outputs)
+	require.Equal(t, []SatRange{{102, 110}}, fee)
+
+	_, _, err = AssignSatRanges([][]SatRange{{{0, 10}}}, []int64{11})
+	require.Error(t, err)
+}
@@ -0,0 +1,151 @@
+package ordinals
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/gosuda/btctxbuilder/transaction"
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+// DustLimit is the smallest padding or excess output SendInscription makes,
+// the dust limit of P2PKH, the highest of the standard output types.
+const DustLimit = 546
+
+// InscriptionTransfer sends the inscription on the sat at Offset in Utxo,
+// an output of Owner, to To.
+type InscriptionTransfer struct {
+	Utxo   *types.Utxo
+	Owner  string
+	Offset uint64
+	To     string
+	// Postage is the value of the output the inscription goes to, at its
+	// first sat. DefaultPostage if 0.
+	Postage int64
+}
+
+func (t *InscriptionTransfer) postage() int64 {
+	if t.Postage == 0 {
+		return DefaultPostage
+	}
+	return t.Postage
+}
+
+// SendInscription builds the transfer of an inscription, which puts its
+// sat first in the output to transfer.To:
+//
+//   - the sats of Utxo before the inscription go back to the owner in a
+//     padding output in front. With fewer than DustLimit of them, a UTXO of
+//     fundAddress is spent first to make up the padding, and what the
+//     padding doesn't need of it goes back to fundAddress in an output
+//     ahead, unless that is less than DustLimit;
+//   - the sats after the postage go back to the owner, unless there are
+//     fewer than DustLimit of them, which then stay with the postage;
+//   - postage Utxo falls short of and the fee come from utxos of
+//     fundAddress, which gets the change. With a tracker, the outputs it
+//     protects are never spent.
+//
+// fee is expected in sat/vB. The PSBT is unsigned: the owner and the
+// funder each sign their inputs with transaction.SignTx.
+func SendInscription(
+	params *chaincfg.Params,
+	transfer *InscriptionTransfer,
+	utxos []*types.Utxo,
+	fundAddress string,
+	fee float64,
+	tracker *Tracker,
+) (*psbt.Packet, error) {
+	inscribed := transfer.Utxo
+	if inscribed == nil || inscribed.RawTx == nil {
+		return nil, errors.New("transfer needs the inscribed UTXO with its raw transaction")
+	}
+	if transfer.Offset >= uint64(inscribed.Value) {
+		return nil, fmt.Errorf("offset %d is past the %d sats of the UTXO", transfer.Offset, inscribed.Value)
+	}
+
+	builder := transaction.NewTxBuilder(params).
+		FeeRate(fee).
+		From(fundAddress).
+		Change(fundAddress).
+		Protect(inscribed.Txid, inscribed.Vout)
+	if tracker != nil {
+		tracker.Protect(builder)
+		utxos = slices.DeleteFunc(slices.Clone(utxos), func(u *types.Utxo) bool {
+			hash, err := chainhash.NewHashFromStr(u.Txid)
+			return err != nil || tracker.Protected(wire.OutPoint{Hash: *hash, Index: u.Vout})
+		})
+	}
+
+	padding := int64(transfer.Offset)
+	funded := padding > 0 && padding < DustLimit
+	if funded {
+		pad := padUtxo(utxos, inscribed, DustLimit-padding)
+		if pad == nil {
+			return nil, fmt.Errorf("no UTXO of %s pads the %d sats before the inscription", fundAddress, padding)
+		}
+		if err := builder.Inputs.AddInput(params, pad.RawTx, pad.Vout, pad.Value, fundAddress); err != nil {
+			return nil, fmt.Errorf("padding: %w", err)
+		}
+		utxos = slices.DeleteFunc(slices.Clone(utxos), func(u *types.Utxo) bool { return u == pad })
+		padding += pad.Value
+	}
+	var before uint64
+	for _, in := range builder.Inputs {
+		before += uint64(in.Amount)
+	}
+	if err := builder.Inputs.AddInput(params, inscribed.RawTx, inscribed.Vout, inscribed.Value, transfer.Owner); err != nil {
+		return nil, fmt.Errorf("inscribed utxo: %w", err)
+	}
+
+	recipient := 0
+	if refund := padding - DustLimit; funded && fundAddress != transfer.Owner && refund >= DustLimit {
+		builder.To(fundAddress, refund)
+		padding = DustLimit
+		recipient++
+	}
+	if padding > 0 {
+		builder.To(transfer.Owner, padding)
+		recipient++
+	}
+	postage := transfer.postage()
+	excess := inscribed.Value - int64(transfer.Offset) - postage
+	if excess >= DustLimit {
+		builder.To(transfer.To, postage).To(transfer.Owner, excess)
+	} else {
+		builder.To(transfer.To, postage+max(excess, 0))
+	}
+	pkt, err := builder.SelectUtxo(utxos).Build().Packet()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]int64, len(pkt.UnsignedTx.TxOut))
+	for i, out := range pkt.UnsignedTx.TxOut {
+		values[i] = out.Value
+	}
+	if vout, offset, ok := locate(values, before+transfer.Offset); !ok || vout != recipient || offset != 0 {
+		return nil, fmt.Errorf("inscription would land in output %d at offset %d", vout, offset)
+	}
+	return pkt, nil
+}
+
+// padUtxo returns the smallest of utxos worth at least value, other than
+// inscribed.
+func padUtxo(utxos []*types.Utxo, inscribed *types.Utxo, value int64) *types.Utxo {
+	var pad *types.Utxo
+	for _, u := range utxos {
+		if u.Txid == inscribed.Txid && u.Vout == inscribed.Vout {
+			continue
+		}
+		if u.Value >= value && (pad == nil || u.Value < pad.Value) {
+			pad = u
+		}
+	}
+	return pad
+}
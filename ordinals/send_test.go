@@ -0,0 +1,155 @@
+package ordinals
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosuda/btctxbuilder/script"
+	"github.com/gosuda/btctxbuilder/transaction"
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+func TestSendInscription(t *testing.T) {
+	const feeRate = 2
+	f := newInscribeFixture(t, feeRate)
+	params := f.inscriber.Params
+	prevOuts := f.prevOuts
+	track := func(tracker *Tracker, tx *wire.MsgTx) {
+		t.Helper()
+		require.NoError(t, tracker.Apply(tx, txscript.NewMultiPrevOutFetcher(prevOuts)))
+		for i, out := range tx.TxOut {
+			prevOuts[wire.OutPoint{Hash: tx.TxHash(), Index: uint32(i)}] = out
+		}
+	}
+
+	revealTxs, commitTx, err := f.inscriber.Inscribe(f.utxos, f.from, []*InscriptionData{
+		{ContentType: "text/plain", Body: []byte("sent"), RevealAddr: f.revealAddr, Postage: 20000},
+	}, f.funder.Sign, f.funder.PubKey())
+	require.NoError(t, err)
+	commit, reveal := extractTx(t, commitTx), extractTx(t, revealTxs[0])
+	tracker := NewTracker()
+	track(tracker, commit)
+	track(tracker, reveal)
+	id := script.InscriptionID{Txid: reveal.TxHash()}
+	inscribedAt := wire.OutPoint{Hash: reveal.TxHash()}
+	require.Equal(t, []LocatedInscription{{ID: id}}, tracker.Inscriptions(inscribedAt))
+	inscribed := &types.Utxo{Txid: reveal.TxID(), Vout: 0, Value: reveal.TxOut[0].Value, RawTx: reveal}
+
+	// the funder has the commit change, a small UTXO and one holding an
+	// uncommon sat, which is the largest but never spent
+	fundUtxo := func(value int64, sats *SatRange) *types.Utxo {
+		prevTx := wire.NewMsgTx(wire.TxVersion)
+		prevTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{byte(value)}}, nil, nil))
+		prevTx.AddTxOut(wire.NewTxOut(value, f.utxos[0].RawTx.TxOut[0].PkScript))
+		prevOuts[wire.OutPoint{Hash: prevTx.TxHash()}] = prevTx.TxOut[0]
+		if sats != nil {
+			tracker.SetRanges(wire.OutPoint{Hash: prevTx.TxHash()}, []SatRange{*sats})
+		}
+		return &types.Utxo{Txid: prevTx.TxID(), Vout: 0, Value: value, RawTx: prevTx}
+	}
+	change := uint32(len(commit.TxOut) - 1)
+	utxos := []*types.Utxo{
+		{Txid: commit.TxID(), Vout: change, Value: commit.TxOut[change].Value, RawTx: commit},
+		fundUtxo(1000, nil),
+		fundUtxo(500000, &SatRange{FirstSat(3) - 10, FirstSat(3) + 499990}),
+	}
+	rare := utxos[2]
+
+	for _, tt := range []struct {
+		name    string
+		offset  uint64
+		postage int64
+		// values of the refund to the funder, padding, recipient and excess
+		// outputs, 0 if none
+		outputs [4]int64
+		padded  bool
+	}{
+		{"at the start", 0, 10000, [4]int64{0, 0, 10000, 10000}, false},
+		{"small excess stays", 0, 19800, [4]int64{0, 0, 20000, 0}, false},
+		{"padding input", 300, 10000, [4]int64{754, 546, 10000, 9700}, true},
+		{"small refund stays", 50, 10000, [4]int64{0, 1050, 10000, 9950}, true},
+		{"postage topped up", 15000, 10000, [4]int64{0, 15000, 10000, 0}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			pkt, err := SendInscription(params, &InscriptionTransfer{
+				Utxo:    inscribed,
+				Owner:   f.revealAddr,
+				Offset:  tt.offset,
+				To:      f.from,
+				Postage: tt.postage,
+			}, utxos, f.from, feeRate, tracker)
+			require.NoError(t, err)
+
+			tx := pkt.UnsignedTx
+			inscribedIn := 0
+			if tt.padded {
+				inscribedIn = 1
+				require.Equal(t, utxos[1].RawTx.TxHash(), tx.TxIn[0].PreviousOutPoint.Hash)
+			}
+			require.Equal(t, inscribedAt, tx.TxIn[inscribedIn].PreviousOutPoint)
+			for _, in := range tx.TxIn {
+				require.NotEqual(t, rare.RawTx.TxHash(), in.PreviousOutPoint.Hash)
+			}
+			var outs []int64
+			for _, value := range tt.outputs {
+				if value != 0 {
+					outs = append(outs, value)
+				}
+			}
+			for i, value := range outs {
+				require.Equal(t, value, tx.TxOut[i].Value, "output %d", i)
+			}
+			// then the change of the funder
+			require.Len(t, tx.TxOut, len(outs)+1)
+
+			_, err = transaction.SignTx(params, pkt, f.owner.Sign, f.owner.PubKey())
+			require.NoError(t, err)
+			_, err = transaction.SignTx(params, pkt, f.funder.Sign, f.funder.PubKey())
+			require.NoError(t, err)
+			signed := extractTx(t, pkt)
+			require.GreaterOrEqual(t, executeTx(t, signed, prevOuts), feeRate*vSize(signed))
+
+			// the inscription sat starts the recipient output
+			moved := NewTracker()
+			moved.AddInscription(id, SatPoint{OutPoint: inscribedAt, Offset: tt.offset})
+			require.NoError(t, moved.Apply(signed, txscript.NewMultiPrevOutFetcher(prevOuts)))
+			recipient := 0
+			for _, value := range tt.outputs[:2] {
+				if value != 0 {
+					recipient++
+				}
+			}
+			require.Equal(t, []LocatedInscription{{ID: id}}, moved.Inscriptions(wire.OutPoint{Hash: signed.TxHash(), Index: uint32(recipient)}))
+		})
+	}
+
+	t.Run("large padding input refunded", func(t *testing.T) {
+		// without the small UTXO the commit change pads, the owner getting
+		// no more of it than the padding needs, and a larger one pays the fee
+		pkt, err := SendInscription(params, &InscriptionTransfer{
+			Utxo:    inscribed,
+			Owner:   f.revealAddr,
+			Offset:  300,
+			To:      f.from,
+			Postage: 10000,
+		}, []*types.Utxo{utxos[0], rare, fundUtxo(600000, nil)}, f.from, feeRate, tracker)
+		require.NoError(t, err)
+		tx := pkt.UnsignedTx
+		require.Equal(t, utxos[0].RawTx.TxHash(), tx.TxIn[0].PreviousOutPoint.Hash)
+		funderScript := utxos[0].RawTx.TxOut[utxos[0].Vout].PkScript
+		require.Equal(t, funderScript, tx.TxOut[0].PkScript)
+		require.Equal(t, utxos[0].Value+300-DustLimit, tx.TxOut[0].Value)
+		require.Equal(t, reveal.TxOut[0].PkScript, tx.TxOut[1].PkScript)
+		require.Equal(t, int64(DustLimit), tx.TxOut[1].Value)
+		require.Equal(t, int64(10000), tx.TxOut[2].Value)
+	})
+
+	t.Run("offset past the utxo", func(t *testing.T) {
+		_, err := SendInscription(params, &InscriptionTransfer{Utxo: inscribed, Owner: f.revealAddr, Offset: 20000, To: f.from}, utxos, f.from, feeRate, tracker)
+		require.Error(t, err)
+	})
+}
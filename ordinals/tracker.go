@@ -0,0 +1,192 @@
+package ordinals
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/gosuda/btctxbuilder/script"
+	"github.com/gosuda/btctxbuilder/transaction"
+)
+
+// SatPoint is the position of a sat: the output holding it and its offset
+// in the output.
+type SatPoint struct {
+	OutPoint wire.OutPoint
+	Offset   uint64
+}
+
+func (p SatPoint) String() string {
+	return fmt.Sprintf("%s:%d", p.OutPoint, p.Offset)
+}
+
+// LocatedInscription is an inscription and the offset of its sat in the
+// output holding it.
+type LocatedInscription struct {
+	ID     script.InscriptionID
+	Offset uint64
+}
+
+// Tracker follows the sat ranges and inscriptions of outputs through the
+// transactions spending them, so that spending can keep away from
+// inscribed and rare sats.
+type Tracker struct {
+	ranges       map[wire.OutPoint][]SatRange
+	inscriptions map[wire.OutPoint][]LocatedInscription
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		ranges:       make(map[wire.OutPoint][]SatRange),
+		inscriptions: make(map[wire.OutPoint][]LocatedInscription),
+	}
+}
+
+// SetRanges records the sat ranges of an output, e.g. from an ord server.
+func (t *Tracker) SetRanges(outpoint wire.OutPoint, ranges []SatRange) {
+	t.ranges[outpoint] = ranges
+}
+
+// Ranges returns the sat ranges of an output, nil if unknown.
+func (t *Tracker) Ranges(outpoint wire.OutPoint) []SatRange {
+	return t.ranges[outpoint]
+}
+
+// AddInscription records the inscription id on the sat at.
+func (t *Tracker) AddInscription(id script.InscriptionID, at SatPoint) {
+	t.inscriptions[at.OutPoint] = append(t.inscriptions[at.OutPoint], LocatedInscription{ID: id, Offset: at.Offset})
+}
+
+// Inscriptions returns the inscriptions held by an output.
+func (t *Tracker) Inscriptions(outpoint wire.OutPoint) []LocatedInscription {
+	return t.inscriptions[outpoint]
+}
+
+// Protected reports whether an output holds an inscription or a sat that
+// isn't common, and so shouldn't pay fees.
+func (t *Tracker) Protected(outpoint wire.OutPoint) bool {
+	if len(t.inscriptions[outpoint]) > 0 {
+		return true
+	}
+	for _, r := range t.ranges[outpoint] {
+		if len(r.RareSats()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Protect marks every protected output in b, which then never funds a
+// transaction with them.
+func (t *Tracker) Protect(b *transaction.TxBuilder) *transaction.TxBuilder {
+	for outpoint := range t.inscriptions {
+		b.Protect(outpoint.Hash.String(), outpoint.Index)
+	}
+	for outpoint := range t.ranges {
+		if t.Protected(outpoint) {
+			b.Protect(outpoint.Hash.String(), outpoint.Index)
+		}
+	}
+	return b
+}
+
+// Apply moves what the outputs tx spends hold to its outputs, first in
+// first out, and records the inscriptions tx reveals. Those go to the first
+// sat of the input of their envelope, or to their pointer if it falls in
+// the outputs. Inscriptions and sats beyond the outputs go to the miner
+// and are no longer tracked. Sat ranges are only carried over if known for
+// every input. prevOuts must know every output tx spends.
+func (t *Tracker) Apply(tx *wire.MsgTx, prevOuts txscript.PrevOutputFetcher) error {
+	values := make([]int64, len(tx.TxOut))
+	var outputTotal uint64
+	for i, out := range tx.TxOut {
+		values[i] = out.Value
+		outputTotal += uint64(out.Value)
+	}
+
+	// check everything up front so a failure leaves t as it was
+	inputValues := make([]uint64, len(tx.TxIn))
+	var inputTotal uint64
+	for i, in := range tx.TxIn {
+		prevOut := prevOuts.FetchPrevOutput(in.PreviousOutPoint)
+		if prevOut == nil {
+			return fmt.Errorf("input %d: unknown output %s", i, in.PreviousOutPoint)
+		}
+		inputValues[i] = uint64(prevOut.Value)
+		inputTotal += inputValues[i]
+	}
+	if outputTotal > inputTotal {
+		return fmt.Errorf("outputs spend %d sats of %d", outputTotal, inputTotal)
+	}
+
+	starts := make([]uint64, len(tx.TxIn))
+	var offset uint64
+	inputRanges := make([][]SatRange, 0, len(tx.TxIn))
+	allRanges := true
+	// offsets are in the sats of the whole transaction until located
+	var moved []LocatedInscription
+	for i, in := range tx.TxIn {
+		starts[i] = offset
+		for _, ins := range t.inscriptions[in.PreviousOutPoint] {
+			moved = append(moved, LocatedInscription{ID: ins.ID, Offset: offset + ins.Offset})
+		}
+		ranges, ok := t.ranges[in.PreviousOutPoint]
+		allRanges = allRanges && ok
+		inputRanges = append(inputRanges, ranges)
+		offset += inputValues[i]
+	}
+	var outputRanges [][]SatRange
+	if allRanges && len(tx.TxIn) > 0 {
+		var err error
+		if outputRanges, _, err = AssignSatRanges(inputRanges, values); err != nil {
+			return err
+		}
+	}
+
+	for _, revealed := range script.ParseInscriptions(tx, prevOuts) {
+		if revealed.Unbound {
+			continue
+		}
+		at := starts[revealed.Input]
+		if revealed.Pointer != nil && *revealed.Pointer < outputTotal {
+			at = *revealed.Pointer
+		}
+		moved = append(moved, LocatedInscription{ID: revealed.ID, Offset: at})
+	}
+
+	// nothing can fail from here on
+	for _, in := range tx.TxIn {
+		delete(t.inscriptions, in.PreviousOutPoint)
+		delete(t.ranges, in.PreviousOutPoint)
+	}
+	txHash := tx.TxHash()
+	for _, ins := range moved {
+		vout, offset, ok := locate(values, ins.Offset)
+		if !ok {
+			continue
+		}
+		t.AddInscription(ins.ID, SatPoint{OutPoint: wire.OutPoint{Hash: txHash, Index: uint32(vout)}, Offset: offset})
+	}
+
+	for vout, ranges := range outputRanges {
+		if len(ranges) > 0 {
+			t.ranges[wire.OutPoint{Hash: txHash, Index: uint32(vout)}] = ranges
+		}
+	}
+	return nil
+}
+
+// locate returns the output of values the sat at offset in the
+// transaction lands in and its offset there, or false if it goes to the
+// fee.
+func locate(values []int64, offset uint64) (int, uint64, bool) {
+	var start uint64
+	for vout, value := range values {
+		if offset < start+uint64(value) {
+			return vout, offset - start, true
+		}
+		start += uint64(value)
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,70 @@
+package ordinals
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosuda/btctxbuilder/script"
+)
+
+func TestTracker(t *testing.T) {
+	a := wire.OutPoint{Hash: chainhash.Hash{1}}
+	b := wire.OutPoint{Hash: chainhash.Hash{2}}
+	common := SatRange{FirstSat(1) + 10, FirstSat(1) + 1010}
+	uncommon := SatRange{FirstSat(2) - 500, FirstSat(2) + 500}
+	id := script.InscriptionID{Txid: chainhash.Hash{9}}
+
+	tracker := NewTracker()
+	tracker.SetRanges(a, []SatRange{common})
+	tracker.SetRanges(b, []SatRange{uncommon})
+	require.False(t, tracker.Protected(a))
+	require.True(t, tracker.Protected(b))
+	tracker.AddInscription(id, SatPoint{OutPoint: a, Offset: 600})
+	require.True(t, tracker.Protected(a))
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(&a, nil, nil))
+	tx.AddTxIn(wire.NewTxIn(&b, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(700, nil))
+	tx.AddTxOut(wire.NewTxOut(1200, nil))
+	prevOuts := txscript.NewMultiPrevOutFetcher(map[wire.OutPoint]*wire.TxOut{
+		a: wire.NewTxOut(1000, nil),
+		b: wire.NewTxOut(1000, nil),
+	})
+	require.NoError(t, tracker.Apply(tx, prevOuts))
+
+	out0, out1 := wire.OutPoint{Hash: tx.TxHash()}, wire.OutPoint{Hash: tx.TxHash(), Index: 1}
+	require.Nil(t, tracker.Inscriptions(a))
+	require.Nil(t, tracker.Ranges(a))
+	require.Equal(t, []LocatedInscription{{ID: id, Offset: 600}}, tracker.Inscriptions(out0))
+	require.Equal(t, []SatRange{{common.Start, common.Start + 700}}, tracker.Ranges(out0))
+	// the last 100 sats of b are the fee
+	require.Equal(t, []SatRange{{common.Start + 700, common.End}, {uncommon.Start, uncommon.Start + 900}}, tracker.Ranges(out1))
+	require.True(t, tracker.Protected(out1))
+
+	t.Run("unknown input", func(t *testing.T) {
+		spend := wire.NewMsgTx(wire.TxVersion)
+		spend.AddTxIn(wire.NewTxIn(&out0, nil, nil))
+		require.Error(t, tracker.Apply(spend, txscript.NewMultiPrevOutFetcher(nil)))
+		require.NotNil(t, tracker.Inscriptions(out0))
+	})
+
+	t.Run("ranges short of the value", func(t *testing.T) {
+		short := []SatRange{{common.Start, common.Start + 100}}
+		tracker.SetRanges(out0, short)
+		spend := wire.NewMsgTx(wire.TxVersion)
+		spend.AddTxIn(wire.NewTxIn(&out0, nil, nil))
+		spend.AddTxOut(wire.NewTxOut(700, nil))
+		prevOuts := txscript.NewMultiPrevOutFetcher(map[wire.OutPoint]*wire.TxOut{out0: tx.TxOut[0]})
+		require.Error(t, tracker.Apply(spend, prevOuts))
+
+		// the failure leaves the tracker as it was
+		require.Equal(t, []LocatedInscription{{ID: id, Offset: 600}}, tracker.Inscriptions(out0))
+		require.Equal(t, short, tracker.Ranges(out0))
+		require.Nil(t, tracker.Inscriptions(wire.OutPoint{Hash: spend.TxHash()}))
+	})
+}
@@ -1,9 +1,71 @@
-package ordinals
-
-const ()
-
-type InscriptionData struct {
-	ContentType string `json:"contentType"`
-	Body        []byte `json:"body"`
-	RevealAddr  string `json:"revealAddr"`
-}
+package ordinals
+
+import (
+	"github.com/gosuda/btctxbuilder/script"
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+const (
+	// DefaultPostage is the value of the output an inscription is revealed
+	// to when InscriptionData doesn't set one.
+	DefaultPostage = 10000
+)
+
+type InscriptionData struct {
+	ContentType string `json:"contentType"`
+	Body        []byte `json:"body"`
+	RevealAddr  string `json:"revealAddr"`
+	// Postage is the value of the reveal output, DefaultPostage if 0.
+	Postage int64 `json:"postage,omitempty"`
+
+	ContentEncoding string `json:"contentEncoding,omitempty"`
+	// Metadata is CBOR encoded.
+	Metadata     []byte `json:"metadata,omitempty"`
+	Metaprotocol string `json:"metaprotocol,omitempty"`
+	// Delegate is the id of an inscription whose content this one serves
+	// instead of its own.
+	Delegate string `json:"delegate,omitempty"`
+}
+
+// inscription returns the envelope content of d.
+func (d *InscriptionData) inscription() (*script.Inscription, error) {
+	ins := &script.Inscription{
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		Body:            d.Body,
+		Metadata:        d.Metadata,
+		Metaprotocol:    d.Metaprotocol,
+	}
+	if d.Delegate != "" {
+		delegate, err := script.ParseInscriptionID(d.Delegate)
+		if err != nil {
+			return nil, err
+		}
+		ins.Delegate = &delegate
+	}
+	return ins, nil
+}
+
+func (d *InscriptionData) postage() int64 {
+	if d.Postage == 0 {
+		return DefaultPostage
+	}
+	return d.Postage
+}
+
+// Parent is an inscription spent by a reveal transaction, which makes the
+// inscriptions it reveals children of it. The parent goes back to Address
+// in the first output of the reveal.
+type Parent struct {
+	ID string
+	// Utxo holds the parent inscription and belongs to Address. Spend is
+	// only needed for script outputs.
+	Utxo    *types.Utxo
+	Address string
+	Spend   *types.SpendInfo
+
+	// Sign and PubKey sign for Utxo, as for transaction.SignTx: for a
+	// taproot key path PubKey is the output key.
+	Sign   types.Signer
+	PubKey []byte
+}
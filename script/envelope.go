@@ -0,0 +1,342 @@
+package script
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Curse is the reason ord numbers an inscription as cursed. Since the
+// jubilee, block 824544 on mainnet, cursed inscriptions are numbered like
+// the others but keep their curse.
+type Curse string
+
+const (
+	CurseDuplicateField        Curse = "duplicate-field"
+	CurseIncompleteField       Curse = "incomplete-field"
+	CurseNotAtOffsetZero       Curse = "not-at-offset-zero"
+	CurseNotInFirstInput       Curse = "not-in-first-input"
+	CursePointer               Curse = "pointer"
+	CursePushnum               Curse = "pushnum"
+	CurseStutter               Curse = "stutter"
+	CurseUnrecognizedEvenField Curse = "unrecognized-even-field"
+)
+
+// EnvelopeField is a tag and value pair of an envelope.
+type EnvelopeField struct {
+	Tag   []byte
+	Value []byte
+}
+
+// Envelope is an inscription envelope
+//
+//	OP_FALSE OP_IF "ord" <tag> <value> ... OP_0 <body> ... OP_ENDIF
+//
+// found in the tapscript of a transaction input, decoded the way ord
+// decodes it. Body is nil without a body separator and empty for an empty
+// body. Invalid parents, delegates and pointers are left out.
+type Envelope struct {
+	// Input is the index of the input and Offset the index of the
+	// envelope among those of the input.
+	Input  int
+	Offset int
+	Inscription
+
+	// UnknownFields are the fields left once the known tags are decoded:
+	// unknown tags and the repeated values of tags taking a single value.
+	UnknownFields []EnvelopeField
+
+	DuplicateField        bool // a tag appears more than once
+	IncompleteField       bool // the last tag has no value
+	UnrecognizedEvenField bool // an unknown field has an even tag
+	Pushnum               bool // a push is made with OP_1NEGATE..OP_16
+	Stutter               bool // an OP_FALSE without envelope precedes it
+}
+
+// RevealedInscription is an inscription of a reveal transaction.
+type RevealedInscription struct {
+	ID InscriptionID
+	*Envelope
+
+	// Curse is empty for a blessed inscription. Reinscriptions are cursed
+	// too, but telling them apart takes an index of the inscribed sats.
+	Curse Curse
+	// Unbound inscriptions aren't carried by any sat: their input has no
+	// value or their envelope has an unrecognized even field.
+	Unbound bool
+}
+
+// ParseEnvelopes returns the envelopes of every input of tx, in order.
+// Following ord, any witness of two or more elements is read as a script
+// path spend, and the envelopes of a tapscript that doesn't parse are
+// ignored.
+func ParseEnvelopes(tx *wire.MsgTx) []*Envelope {
+	var envelopes []*Envelope
+	for i, in := range tx.TxIn {
+		tapscript := WitnessTapscript(in.Witness)
+		if tapscript == nil {
+			continue
+		}
+		inputEnvelopes, err := parseEnvelopes(tapscript, i)
+		if err != nil {
+			continue
+		}
+		envelopes = append(envelopes, inputEnvelopes...)
+	}
+	return envelopes
+}
+
+// ParseInscriptions returns the inscriptions revealed by tx with their
+// ids, numbered across all inputs, and curses. With prevOuts, inscriptions
+// in inputs without value are reported unbound.
+func ParseInscriptions(tx *wire.MsgTx, prevOuts txscript.PrevOutputFetcher) []*RevealedInscription {
+	txid := tx.TxHash()
+	envelopes := ParseEnvelopes(tx)
+	inscriptions := make([]*RevealedInscription, 0, len(envelopes))
+	for i, envelope := range envelopes {
+		ins := &RevealedInscription{
+			ID:       InscriptionID{Txid: txid, Index: uint32(i)},
+			Envelope: envelope,
+			Curse:    envelope.curse(),
+			Unbound:  envelope.UnrecognizedEvenField,
+		}
+		if prevOuts != nil {
+			prevOut := prevOuts.FetchPrevOutput(tx.TxIn[envelope.Input].PreviousOutPoint)
+			if prevOut == nil || prevOut.Value == 0 {
+				ins.Unbound = true
+			}
+		}
+		inscriptions = append(inscriptions, ins)
+	}
+	return inscriptions
+}
+
+// curse returns the first curse of e in the order ord checks them.
+func (e *Envelope) curse() Curse {
+	switch {
+	case e.UnrecognizedEvenField:
+		return CurseUnrecognizedEvenField
+	case e.DuplicateField:
+		return CurseDuplicateField
+	case e.IncompleteField:
+		return CurseIncompleteField
+	case e.Input != 0:
+		return CurseNotInFirstInput
+	case e.Offset != 0:
+		return CurseNotAtOffsetZero
+	case e.Pointer != nil:
+		return CursePointer
+	case e.Pushnum:
+		return CursePushnum
+	case e.Stutter:
+		return CurseStutter
+	}
+	return ""
+}
+
+// WitnessTapscript returns the tapscript of a script path spend: the
+// element before the control block, after removing the annex.
+func WitnessTapscript(witness wire.TxWitness) []byte {
+	n := len(witness)
+	if n >= 3 && len(witness[n-1]) > 0 && witness[n-1][0] == txscript.TaprootAnnexTag {
+		return witness[n-3]
+	}
+	if n >= 2 {
+		return witness[n-2]
+	}
+	return nil
+}
+
+type instruction struct {
+	op   byte
+	data []byte
+}
+
+// isPush reports whether in is a data push, OP_0 included.
+func (in instruction) isPush() bool {
+	return in.op <= txscript.OP_PUSHDATA4
+}
+
+// parseEnvelopes returns the envelopes of tapscript, the script of the
+// input at index input.
+func parseEnvelopes(tapscript []byte, input int) ([]*Envelope, error) {
+	var instructions []instruction
+	tokenizer := txscript.MakeScriptTokenizer(0, tapscript)
+	for tokenizer.Next() {
+		instructions = append(instructions, instruction{tokenizer.Opcode(), tokenizer.Data()})
+	}
+	if err := tokenizer.Err(); err != nil {
+		return nil, err
+	}
+
+	var envelopes []*Envelope
+	stuttered := false
+	for i := 0; i < len(instructions); {
+		in := instructions[i]
+		i++
+		if !in.isPush() || len(in.data) > 0 {
+			continue
+		}
+		payload, pushnum, next, stutter, ok := readEnvelope(instructions, i)
+		if ok {
+			envelope := newEnvelope(payload)
+			envelope.Input, envelope.Offset = input, len(envelopes)
+			envelope.Pushnum, envelope.Stutter = pushnum, stuttered
+			envelopes = append(envelopes, envelope)
+		} else {
+			stuttered = stutter
+		}
+		i = next
+	}
+	return envelopes, nil
+}
+
+// readEnvelope reads the envelope following an OP_FALSE, with instructions
+// starting at i after it, up to its OP_ENDIF. It returns the pushes of the
+// envelope after the protocol id and the index to resume scanning from.
+// When the envelope doesn't start there, stutter reports whether another
+// OP_FALSE follows, which the next envelope is cursed for.
+func readEnvelope(instructions []instruction, i int) (payload [][]byte, pushnum bool, next int, stutter bool, ok bool) {
+	emptyPushAt := func(j int) bool {
+		return j < len(instructions) && instructions[j].isPush() && len(instructions[j].data) == 0
+	}
+	if i >= len(instructions) || instructions[i].op != txscript.OP_IF {
+		return nil, false, i, emptyPushAt(i), false
+	}
+	i++
+	if i >= len(instructions) || !instructions[i].isPush() || string(instructions[i].data) != ORD_PREFIX {
+		return nil, false, i, emptyPushAt(i), false
+	}
+	for j := i + 1; j < len(instructions); j++ {
+		switch in := instructions[j]; {
+		case in.op == txscript.OP_ENDIF:
+			return payload, pushnum, j + 1, false, true
+		case in.isPush():
+			payload = append(payload, in.data)
+		case in.op == txscript.OP_1NEGATE:
+			pushnum = true
+			payload = append(payload, []byte{0x81})
+		case in.op >= txscript.OP_1 && in.op <= txscript.OP_16:
+			pushnum = true
+			payload = append(payload, []byte{in.op - txscript.OP_1 + 1})
+		default:
+			return nil, false, j + 1, false, false
+		}
+	}
+	return nil, false, len(instructions), false, false
+}
+
+// newEnvelope decodes the fields and the body of payload.
+func newEnvelope(payload [][]byte) *Envelope {
+	e := &Envelope{}
+	body := len(payload)
+	for i := 0; i < len(payload); i += 2 {
+		if len(payload[i]) == 0 {
+			body = i
+			break
+		}
+	}
+
+	// fields by tag, in order of appearance
+	var tags []string
+	fields := map[string][][]byte{}
+	for i := 0; i < body; i += 2 {
+		if i+1 == body {
+			e.IncompleteField = true
+			break
+		}
+		tag := string(payload[i])
+		if _, ok := fields[tag]; !ok {
+			tags = append(tags, tag)
+		}
+		fields[tag] = append(fields[tag], payload[i+1])
+	}
+	for _, values := range fields {
+		if len(values) > 1 {
+			e.DuplicateField = true
+		}
+	}
+
+	// take returns the first value of a tag, leaving the others.
+	take := func(tag byte) []byte {
+		values := fields[string([]byte{tag})]
+		if len(values) == 0 {
+			return nil
+		}
+		fields[string([]byte{tag})] = values[1:]
+		return values[0]
+	}
+	// takeAll returns every value of a tag.
+	takeAll := func(tag byte) [][]byte {
+		values := fields[string([]byte{tag})]
+		delete(fields, string([]byte{tag}))
+		return values
+	}
+
+	e.ContentEncoding = string(take(TagContentEncoding))
+	e.ContentType = string(take(TagContentType))
+	e.Delegate = inscriptionIDField(take(TagDelegate))
+	if metadata := takeAll(TagMetadata); len(metadata) > 0 {
+		e.Metadata = bytes.Join(metadata, nil)
+	}
+	e.Metaprotocol = string(take(TagMetaprotocol))
+	for _, value := range takeAll(TagParent) {
+		if parent := inscriptionIDField(value); parent != nil {
+			e.Parents = append(e.Parents, *parent)
+		}
+	}
+	if value := take(TagPointer); value != nil {
+		e.Pointer = pointerField(value)
+	}
+	e.Rune = take(TagRune)
+
+	for _, tag := range tags {
+		for _, value := range fields[tag] {
+			e.UnknownFields = append(e.UnknownFields, EnvelopeField{Tag: []byte(tag), Value: value})
+			if tag[0]%2 == 0 {
+				e.UnrecognizedEvenField = true
+			}
+		}
+	}
+
+	if body < len(payload) {
+		e.Body = bytes.Join(payload[body+1:], nil)
+		if e.Body == nil {
+			e.Body = []byte{}
+		}
+	}
+	return e
+}
+
+// inscriptionIDField decodes an id made by InscriptionID.Bytes. Indexes
+// with trailing zeros are invalid.
+func inscriptionIDField(value []byte) *InscriptionID {
+	if len(value) < chainhash.HashSize || len(value) > chainhash.HashSize+4 {
+		return nil
+	}
+	txid, index := value[:chainhash.HashSize], value[chainhash.HashSize:]
+	if len(index) > 0 && index[len(index)-1] == 0 {
+		return nil
+	}
+	id := &InscriptionID{}
+	copy(id.Txid[:], txid)
+	var le [4]byte
+	copy(le[:], index)
+	id.Index = binary.LittleEndian.Uint32(le[:])
+	return id
+}
+
+// pointerField decodes a little endian pointer, which is invalid if it
+// doesn't fit in 64 bits.
+func pointerField(value []byte) *uint64 {
+	if len(value) > 8 && len(bytes.TrimRight(value[8:], "\x00")) > 0 {
+		return nil
+	}
+	var le [8]byte
+	copy(le[:], value)
+	pointer := binary.LittleEndian.Uint64(le[:])
+	return &pointer
+}
@@ -0,0 +1,233 @@
+package script
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// revealTx spends one input per tapscript through the script path.
+func revealTx(tapscripts ...[]byte) *wire.MsgTx {
+	tx := wire.NewMsgTx(2)
+	for i, tapscript := range tapscripts {
+		in := wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{byte(i)}, 0), nil, nil)
+		in.Witness = wire.TxWitness{make([]byte, 64), tapscript, append([]byte{0xc0}, make([]byte, 32)...)}
+		tx.AddTxIn(in)
+	}
+	tx.AddTxOut(wire.NewTxOut(546, []byte{txscript.OP_1}))
+	return tx
+}
+
+// envelopeScript returns OP_FALSE OP_IF "ord" pushes... OP_ENDIF.
+func envelopeScript(pushes ...[]byte) []byte {
+	script := []byte{txscript.OP_FALSE, txscript.OP_IF}
+	script = pushData(script, []byte(ORD_PREFIX))
+	for _, push := range pushes {
+		script = pushData(script, push)
+	}
+	return append(script, txscript.OP_ENDIF)
+}
+
+func TestParseEnvelopes_RoundTrip(t *testing.T) {
+	parent, err := ParseInscriptionID("521f8eccffa4c41a3a7728dd012ea5a4a02feed81f41159231251ecf1e5c79dai1")
+	require.NoError(t, err)
+	pointer := uint64(1000)
+	ins := Inscription{
+		ContentType:     "text/html",
+		ContentEncoding: "br",
+		Body:            bytes.Repeat([]byte("<p>"), 400),
+		Pointer:         &pointer,
+		Parents:         []InscriptionID{parent, {Txid: chainhash.Hash{1}}},
+		Delegate:        &parent,
+		Metadata:        bytes.Repeat([]byte{0xa0}, MAX_CHUNK_SIZE+10),
+		Metaprotocol:    "brc-20",
+		Rune:            []byte{0x01, 0x02},
+	}
+	leaf, err := CreateInscriptionsScript(make([]byte, 32), []*Inscription{&ins, {ContentType: "text/plain"}})
+	require.NoError(t, err)
+
+	envelopes := ParseEnvelopes(revealTx(leaf))
+	require.Len(t, envelopes, 2)
+	// like ord, repeated metadata and parent tags count as duplicates
+	require.Equal(t, &Envelope{Inscription: ins, DuplicateField: true}, envelopes[0])
+	// without body separator there is no body at all
+	require.Equal(t, &Envelope{Offset: 1, Inscription: Inscription{ContentType: "text/plain"}}, envelopes[1])
+
+	contentType, body, err := GetInscriptionContent(revealTx(leaf))
+	require.NoError(t, err)
+	require.Equal(t, ins.ContentType, contentType)
+	require.Equal(t, ins.Body, body)
+}
+
+func TestParseEnvelopes_Fields(t *testing.T) {
+	tag := func(tag byte) []byte { return []byte{tag} }
+	pointer := func(v uint64) *uint64 { return &v }
+	longPointer := append(bytes.Repeat([]byte{0xff}, 8), 0, 1)
+	id := (&InscriptionID{Txid: chainhash.Hash{7}, Index: 256}).Bytes()
+
+	tests := []struct {
+		name   string
+		script []byte
+		want   *Envelope
+	}{
+		{
+			name:   "empty body",
+			script: envelopeScript(tag(TagContentType), []byte("text/plain"), nil),
+			want:   &Envelope{Inscription: Inscription{ContentType: "text/plain", Body: []byte{}}},
+		},
+		{
+			name:   "chunked body",
+			script: envelopeScript(nil, []byte("foo"), []byte("bar"), nil, []byte("baz")),
+			want:   &Envelope{Inscription: Inscription{Body: []byte("foobarbaz")}},
+		},
+		{
+			name:   "duplicate field",
+			script: envelopeScript(tag(TagContentType), []byte("a"), tag(TagContentType), []byte("b")),
+			want: &Envelope{
+				Inscription:    Inscription{ContentType: "a"},
+				UnknownFields:  []EnvelopeField{{Tag: tag(TagContentType), Value: []byte("b")}},
+				DuplicateField: true,
+			},
+		},
+		{
+			name:   "duplicate even field",
+			script: envelopeScript(tag(TagPointer), []byte{1}, tag(TagPointer), []byte{2}),
+			want: &Envelope{
+				Inscription:           Inscription{Pointer: pointer(1)},
+				UnknownFields:         []EnvelopeField{{Tag: tag(TagPointer), Value: []byte{2}}},
+				DuplicateField:        true,
+				UnrecognizedEvenField: true,
+			},
+		},
+		{
+			name:   "chunked metadata and parents",
+			script: envelopeScript(tag(TagMetadata), []byte{1}, tag(TagParent), id, tag(TagMetadata), []byte{2}, tag(TagParent), id[:31]),
+			want: &Envelope{
+				Inscription:    Inscription{Metadata: []byte{1, 2}, Parents: []InscriptionID{{Txid: chainhash.Hash{7}, Index: 256}}},
+				DuplicateField: true,
+			},
+		},
+		{
+			name:   "unknown fields",
+			script: envelopeScript([]byte{0x21}, []byte("odd"), []byte{0x22, 0x01}, []byte("even"), nil, []byte("body")),
+			want: &Envelope{
+				Inscription: Inscription{Body: []byte("body")},
+				UnknownFields: []EnvelopeField{
+					{Tag: []byte{0x21}, Value: []byte("odd")},
+					{Tag: []byte{0x22, 0x01}, Value: []byte("even")},
+				},
+				UnrecognizedEvenField: true,
+			},
+		},
+		{
+			name:   "incomplete field",
+			script: envelopeScript(tag(TagContentType), []byte("a"), tag(TagMetaprotocol)),
+			want:   &Envelope{Inscription: Inscription{ContentType: "a"}, IncompleteField: true},
+		},
+		{
+			name:   "invalid ids and pointer",
+			script: envelopeScript(tag(TagDelegate), append(id[:33:33], 0), tag(TagParent), id[:20], tag(TagPointer), longPointer),
+			want:   &Envelope{},
+		},
+		{
+			name:   "pointer with zero padding",
+			script: envelopeScript(tag(TagPointer), []byte{1, 0, 0, 0, 0, 0, 0, 0, 0, 0}),
+			want:   &Envelope{Inscription: Inscription{Pointer: pointer(1)}},
+		},
+		{
+			name: "pushnum",
+			script: []byte{txscript.OP_FALSE, txscript.OP_IF, 0x03, 'o', 'r', 'd',
+				txscript.OP_1, 0x01, 'a', txscript.OP_0, txscript.OP_16, txscript.OP_1NEGATE, txscript.OP_ENDIF},
+			want: &Envelope{Inscription: Inscription{ContentType: "a", Body: []byte{16, 0x81}}, Pushnum: true},
+		},
+		{
+			name:   "stutter",
+			script: append([]byte{txscript.OP_FALSE, txscript.OP_FALSE, txscript.OP_IF}, envelopeScript(nil)...),
+			want:   &Envelope{Inscription: Inscription{Body: []byte{}}, Stutter: true},
+		},
+		{
+			name:   "protocol id after failed start",
+			script: append([]byte{txscript.OP_FALSE, txscript.OP_IF, 0x03, 'b', 'a', 'd', txscript.OP_ENDIF}, envelopeScript(nil)...),
+			want:   &Envelope{Inscription: Inscription{Body: []byte{}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envelopes := ParseEnvelopes(revealTx(tt.script))
+			require.Len(t, envelopes, 1)
+			require.Equal(t, tt.want, envelopes[0])
+		})
+	}
+}
+
+func TestParseEnvelopes_Ignored(t *testing.T) {
+	for name, tx := range map[string]*wire.MsgTx{
+		"no envelope":       revealTx([]byte{txscript.OP_TRUE}),
+		"other protocol":    revealTx(append([]byte{txscript.OP_FALSE, txscript.OP_IF, 0x03, 'b', 'a', 'd'}, txscript.OP_ENDIF)),
+		"no endif":          revealTx(envelopeScript(nil)[:7]),
+		"opcode in payload": revealTx(append(envelopeScript(nil)[:7], txscript.OP_CHECKSIG, txscript.OP_ENDIF)),
+		// a truncated push makes the whole script invalid
+		"invalid script": revealTx(append(envelopeScript(nil), txscript.OP_PUSHDATA1, 0x10)),
+	} {
+		require.Empty(t, ParseEnvelopes(tx), name)
+	}
+
+	// a key path spend has no tapscript
+	tx := revealTx(envelopeScript(nil))
+	tx.TxIn[0].Witness = tx.TxIn[0].Witness[:1]
+	require.Empty(t, ParseEnvelopes(tx))
+
+	_, _, err := GetInscriptionContent(tx)
+	require.Error(t, err)
+}
+
+func TestParseEnvelopes_Annex(t *testing.T) {
+	tx := revealTx(envelopeScript(nil, []byte("x")))
+	tx.TxIn[0].Witness = append(tx.TxIn[0].Witness, []byte{txscript.TaprootAnnexTag, 0x01})
+	envelopes := ParseEnvelopes(tx)
+	require.Len(t, envelopes, 1)
+	require.Equal(t, []byte("x"), envelopes[0].Body)
+}
+
+func TestParseInscriptions(t *testing.T) {
+	plain := envelopeScript(nil, []byte("a"))
+	withPointer := envelopeScript([]byte{TagPointer}, []byte{1}, nil, []byte("b"))
+	tx := revealTx(
+		append(append([]byte{}, plain...), withPointer...),
+		plain,
+		envelopeScript([]byte{0x02, 0x02}, []byte("even")),
+	)
+	txid := tx.TxHash()
+
+	inscriptions := ParseInscriptions(tx, nil)
+	require.Len(t, inscriptions, 4)
+	for i, ins := range inscriptions {
+		require.Equal(t, InscriptionID{Txid: txid, Index: uint32(i)}, ins.ID)
+	}
+	require.Equal(t, Curse(""), inscriptions[0].Curse)
+	require.Equal(t, CurseNotAtOffsetZero, inscriptions[1].Curse)
+	require.Equal(t, CurseNotInFirstInput, inscriptions[2].Curse)
+	require.Equal(t, CurseUnrecognizedEvenField, inscriptions[3].Curse)
+	require.Equal(t, []bool{false, false, false, true}, []bool{
+		inscriptions[0].Unbound, inscriptions[1].Unbound, inscriptions[2].Unbound, inscriptions[3].Unbound,
+	})
+
+	// a pointer alone curses the first inscription too
+	inscriptions = ParseInscriptions(revealTx(withPointer), nil)
+	require.Equal(t, CursePointer, inscriptions[0].Curse)
+
+	// inscriptions of inputs without value are unbound
+	prevOuts := txscript.NewMultiPrevOutFetcher(map[wire.OutPoint]*wire.TxOut{
+		tx.TxIn[0].PreviousOutPoint: wire.NewTxOut(0, nil),
+		tx.TxIn[1].PreviousOutPoint: wire.NewTxOut(1000, nil),
+		tx.TxIn[2].PreviousOutPoint: wire.NewTxOut(1000, nil),
+	})
+	inscriptions = ParseInscriptions(tx, prevOuts)
+	require.Equal(t, []bool{true, true, false, true}, []bool{
+		inscriptions[0].Unbound, inscriptions[1].Unbound, inscriptions[2].Unbound, inscriptions[3].Unbound,
+	})
+}
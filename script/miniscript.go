@@ -1,387 +1,688 @@
-package script
-
-import (
-	"errors"
-
-	"github.com/btcsuite/btcd/txscript"
-)
-
-type NodeType string
-
-const (
-	Pk           NodeType = "pk"
-	Pkh          NodeType = "pkh"
-	Older        NodeType = "older"
-	After        NodeType = "after"
-	Thresh       NodeType = "thresh"
-	And          NodeType = "and_b"
-	Or           NodeType = "or_b"
-	Multi        NodeType = "multi"
-	CheckSig     NodeType = "checksig"
-	CheckSigHash NodeType = "checksighash"
-	Tr           NodeType = "taproot"
-	Leaf         NodeType = "leaf"
-)
-
-type Node interface {
-	Validate() error
-	ToScript(*txscript.ScriptBuilder)
-}
-
-// PkNode represents a public key argument
-type PkNode struct {
-	PubKey []byte
-}
-
-func (a *PkNode) Validate() error {
-	if len(a.PubKey) == 0 {
-		return errors.New("public key cannot be empty")
-	}
-	return nil
-}
-
-func (a *PkNode) FromScript(script []byte) error {
-	// A pay-to-compressed-pubkey script is of the form:
-	//   - OP_DATA_33 <33-byte compressed pubkey> OP_CHECKSIG
-	// All compressed secp256k1 public keys must start with 0x02 or 0x03.
-	if len(script) == 35 &&
-		script[34] == txscript.OP_CHECKSIG &&
-		script[0] == txscript.OP_DATA_33 &&
-		(script[1] == 0x02 || script[1] == 0x03) {
-		a.PubKey = script[1:34]
-		return nil
-	}
-
-	// A pay-to-uncompressed-pubkey script is of the form:
-	//   - OP_DATA_65 <65-byte uncompressed pubkey> OP_CHECKSIG
-	// All non-hybrid uncompressed secp256k1 public keys must start with 0x04.
-	// Hybrid uncompressed secp256k1 public keys start with 0x06 or 0x07:
-	//   - 0x06 => hybrid format for even Y coords
-	//   - 0x07 => hybrid format for odd Y coords
-	if len(script) == 67 &&
-		script[66] == txscript.OP_CHECKSIG &&
-		script[0] == txscript.OP_DATA_65 &&
-		(script[1] == 0x04 || script[1] == 0x06 || script[1] == 0x07) {
-		a.PubKey = script[1:66]
-		return nil
-	}
-	return errors.New("invalid public key script")
-}
-
-func (a *PkNode) ToScript(builder *txscript.ScriptBuilder) {
-	builder.AddData([]byte(a.PubKey)).AddOp(txscript.OP_CHECKSIG)
-}
-
-// PkhNode represents a public key hash argument
-type PkhNode struct {
-	Hash []byte
-}
-
-func (a *PkhNode) Validate() error {
-	if len(a.Hash) != 20 {
-		return errors.New("public key hash cannot be empty")
-	}
-	return nil
-}
-
-func (a *PkhNode) FromScript(script []byte) error {
-	if len(script) == 25 &&
-		script[0] == txscript.OP_DUP &&
-		script[1] == txscript.OP_HASH160 &&
-		script[2] == txscript.OP_DATA_20 &&
-		script[23] == txscript.OP_EQUALVERIFY &&
-		script[24] == txscript.OP_CHECKSIG {
-
-		a.Hash = script[3:23]
-		return nil
-	}
-	return errors.New("invalid public key hash script")
-}
-
-func (a *PkhNode) ToScript(builder *txscript.ScriptBuilder) {
-	builder.AddOp(txscript.OP_DUP).
-		AddOp(txscript.OP_HASH160).
-		AddData(a.Hash).
-		AddOp(txscript.OP_EQUALVERIFY).
-		AddOp(txscript.OP_CHECKSIG)
-}
-
-// AfterNod represents a locktime argument
-// if Time is less than 500000000, it is a block height
-// otherwise, it is a unix timestamp
-type AfterNode struct {
-	Time int64
-}
-
-func (a *AfterNode) Validate() error {
-	if a.Time <= 0 {
-		return errors.New("locktime must be positive")
-	}
-	return nil
-}
-
-func (a *AfterNode) FromScript(script []byte) error {
-	if len(script) >= 2 &&
-		script[len(script)-2] == txscript.OP_CHECKLOCKTIMEVERIFY &&
-		script[len(script)-1] == txscript.OP_DROP {
-
-		opcode := script[0]
-		data := script[1 : len(script)-2]
-		lockTime, _, err := DecodeInt64(opcode, data)
-		if err != nil {
-			return errors.New("invalid locktime value")
-		}
-		a.Time = lockTime
-		return nil
-	}
-	return errors.New("invalid after script")
-}
-
-func (a *AfterNode) ToScript(builder *txscript.ScriptBuilder) {
-	builder.AddInt64(a.Time).
-		AddOp(txscript.OP_CHECKLOCKTIMEVERIFY).
-		AddOp(txscript.OP_DROP)
-}
-
-// OlderNode represents a relative locktime argument
-type OlderNode struct {
-	Time int64
-}
-
-func (a *OlderNode) SetBlock(height int64) error {
-	if height <= 0 {
-		return errors.New("block count must be positive")
-	} else if height >= 0x80000000 {
-		return errors.New("block count exceeds maximum for blocks")
-	}
-	a.Time = height
-	return nil
-}
-
-func (a *OlderNode) SetTime(seconds int64) error {
-	if seconds <= 0 {
-		return errors.New("time must be positive")
-	}
-	timeUnits := seconds / 512
-	if seconds%512 != 0 {
-		return errors.New("time must be a multiple of 512 seconds")
-	}
-	a.Time = 0x80000000 | timeUnits
-	return nil
-}
-
-func (a *OlderNode) Validate() error {
-	if a.Time <= 0 {
-		return errors.New("relative locktime must be positive")
-	}
-	return nil
-}
-
-func (a *OlderNode) FromScript(script []byte) error {
-	if len(script) >= 2 &&
-		script[len(script)-2] == txscript.OP_CHECKSEQUENCEVERIFY &&
-		script[len(script)-1] == txscript.OP_DROP {
-
-		opcode := script[0]
-		data := script[1 : len(script)-2]
-		sequence, _, err := DecodeInt64(opcode, data)
-		if err != nil {
-			return errors.New("invalid sequence value")
-		}
-		a.Time = sequence
-		return nil
-	}
-	return errors.New("invalid older script")
-}
-
-func (a *OlderNode) ToScript(builder *txscript.ScriptBuilder) {
-	builder.AddInt64(a.Time).
-		AddOp(txscript.OP_CHECKSEQUENCEVERIFY).
-		AddOp(txscript.OP_DROP)
-}
-
-// MultiNode represents a multisig argument
-type MultiNode struct {
-	Required int
-	Keys     []string
-}
-
-func (a *MultiNode) Validate() error {
-	if a.Required <= 0 || len(a.Keys) < a.Required {
-		return errors.New("invalid multisig parameters")
-	}
-	return nil
-}
-
-// func (a *MultiNode) FromScript(script []byte) error {
-// 	if len(script) < 2 || script[len(script)-1] != txscript.OP_CHECKMULTISIG {
-// 		return errors.New("invalid multisig script")
-// 	}
-
-// 	// Parse required signatures
-// 	required, script, err := DecodeInt(script)
-// 	if err != nil {
-// 		return errors.New("failed to decode required signatures")
-// 	}
-// 	a.Required = int(required)
-
-// 	// Parse public keys
-// 	keys := []string{}
-// 	for len(script) > 1 {
-// 		keyLen := int(script[0])
-// 		if len(script) < 1+keyLen {
-// 			return errors.New("invalid key length in multisig script")
-// 		}
-// 		keys = append(keys, string(script[1:1+keyLen]))
-// 		script = script[1+keyLen:]
-// 	}
-
-// 	// Parse total keys
-// 	totalKeys, _, err := DecodeInt(script)
-// 	if err != nil || len(keys) != int(totalKeys) {
-// 		return errors.New("total keys mismatch in multisig script")
-// 	}
-// 	a.Keys = keys
-
-// 	return nil
-// }
-
-func (a *MultiNode) ToScript(builder *txscript.ScriptBuilder) {
-	builder.AddInt64(int64(a.Required))
-	for _, key := range a.Keys {
-		builder.AddData([]byte(key))
-	}
-	builder.AddInt64(int64(len(a.Keys))).AddOp(txscript.OP_CHECKMULTISIG)
-}
-
-// ThreshNode represents a threshold condition
-type ThreshNode struct {
-	Threshold int
-	Children  []Node
-}
-
-func (a *ThreshNode) Validate() error {
-	if a.Threshold <= 0 || len(a.Children) < a.Threshold {
-		return errors.New("invalid threshold parameters")
-	}
-	for _, child := range a.Children {
-		if err := child.Validate(); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-// func (a *ThreshNode) FromScript(script []byte) error {
-// 	threshold, remaining, err := DecodeInt(script)
-// 	if err != nil {
-// 		return errors.New("failed to decode threshold")
-// 	}
-// 	a.Threshold = int(threshold)
-
-// 	children := []Node{}
-// 	for len(remaining) > 0 {
-// 		child, rest, err := parseChildNode(remaining)
-// 		if err != nil {
-// 			return err
-// 		}
-// 		children = append(children, child)
-// 		remaining = rest
-// 	}
-// 	a.Children = children
-// 	return nil
-// }
-
-func (a *ThreshNode) ToScript(builder *txscript.ScriptBuilder) {
-	for _, child := range a.Children {
-		child.ToScript(builder)
-	}
-	builder.AddInt64(int64(a.Threshold))
-}
-
-// CompositeNode represents a logical combination ( and, or ) of child nodes
-type CompositeNode struct {
-	Children []Node
-}
-
-func (a *CompositeNode) Validate() error {
-	if len(a.Children) < 2 {
-		return errors.New("composite nodes require at least two children")
-	}
-	for _, child := range a.Children {
-		if err := child.Validate(); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-// func (a *CompositeNode) FromScript(script []byte) error {
-// 	children := []Node{}
-// 	for len(script) > 0 {
-// 		child, remaining, err := parseChildNode(script)
-// 		if err != nil {
-// 			return err
-// 		}
-// 		children = append(children, child)
-// 		script = remaining
-// 	}
-// 	a.Children = children
-// 	return nil
-// }
-
-func (a *CompositeNode) ToScript(builder *txscript.ScriptBuilder) {
-	for _, child := range a.Children {
-		child.ToScript(builder)
-	}
-}
-
-// TrArgs represents a Taproot script
-type TrArgs struct {
-	InternalKey string
-	MerkleRoot  string
-}
-
-func (a *TrArgs) Validate() error {
-	if a.InternalKey == "" {
-		return errors.New("internal key cannot be empty")
-	}
-	// MerkleRoot can be empty for a single-leaf Taproot
-	return nil
-}
-
-func (a *TrArgs) ToScript(builder *txscript.ScriptBuilder) {
-	builder.AddOp(txscript.OP_1).AddData([]byte(a.InternalKey))
-	if a.MerkleRoot != "" {
-		builder.AddData([]byte(a.MerkleRoot))
-	}
-}
-
-// LeafArgs represents a Taproot leaf node
-type LeafArgs struct {
-	Script  string                        // Script for this leaf node
-	Version txscript.TapscriptLeafVersion // Leaf version (default: 0xc0 for Taproot v1)
-}
-
-func (a *LeafArgs) Validate() error {
-	if a.Script == "" {
-		return errors.New("leaf script cannot be empty")
-	}
-	if a.Version < txscript.BaseLeafVersion {
-		return errors.New("invalid leaf version")
-	}
-	return nil
-}
-
-func (a *LeafArgs) FromScript(script []byte) error {
-	if len(script) < 1 {
-		return errors.New("invalid leaf script")
-	}
-	a.Version = txscript.TapscriptLeafVersion(script[0])
-	a.Script = string(script[1:])
-	return nil
-}
-
-func (a *LeafArgs) ToScript(builder *txscript.ScriptBuilder) {
-	builder.AddOp(byte(a.Version)).AddData([]byte(a.Script))
-}
+package script
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+type NodeType string
+
+const (
+	Pk           NodeType = "pk"
+	Pkh          NodeType = "pkh"
+	Older        NodeType = "older"
+	After        NodeType = "after"
+	Thresh       NodeType = "thresh"
+	And          NodeType = "and_b"
+	Or           NodeType = "or_b"
+	Multi        NodeType = "multi"
+	CheckSig     NodeType = "checksig"
+	CheckSigHash NodeType = "checksighash"
+	Tr           NodeType = "taproot"
+	Leaf         NodeType = "leaf"
+)
+
+type Node interface {
+	Validate() error
+	ToScript(*txscript.ScriptBuilder)
+}
+
+// PkNode represents a public key argument
+type PkNode struct {
+	PubKey []byte
+}
+
+func (a *PkNode) Validate() error {
+	if len(a.PubKey) == 0 {
+		return errors.New("public key cannot be empty")
+	}
+	return nil
+}
+
+func (a *PkNode) FromScript(script []byte) error {
+	// A pay-to-compressed-pubkey script is of the form:
+	//   - OP_DATA_33 <33-byte compressed pubkey> OP_CHECKSIG
+	// All compressed secp256k1 public keys must start with 0x02 or 0x03.
+	if len(script) == 35 &&
+		script[34] == txscript.OP_CHECKSIG &&
+		script[0] == txscript.OP_DATA_33 &&
+		(script[1] == 0x02 || script[1] == 0x03) {
+		a.PubKey = script[1:34]
+		return nil
+	}
+
+	// A pay-to-uncompressed-pubkey script is of the form:
+	//   - OP_DATA_65 <65-byte uncompressed pubkey> OP_CHECKSIG
+	// All non-hybrid uncompressed secp256k1 public keys must start with 0x04.
+	// Hybrid uncompressed secp256k1 public keys start with 0x06 or 0x07:
+	//   - 0x06 => hybrid format for even Y coords
+	//   - 0x07 => hybrid format for odd Y coords
+	if len(script) == 67 &&
+		script[66] == txscript.OP_CHECKSIG &&
+		script[0] == txscript.OP_DATA_65 &&
+		(script[1] == 0x04 || script[1] == 0x06 || script[1] == 0x07) {
+		a.PubKey = script[1:66]
+		return nil
+	}
+	return errors.New("invalid public key script")
+}
+
+func (a *PkNode) ToScript(builder *txscript.ScriptBuilder) {
+	builder.AddData([]byte(a.PubKey)).AddOp(txscript.OP_CHECKSIG)
+}
+
+// PkhNode represents a public key hash argument
+type PkhNode struct {
+	Hash []byte
+}
+
+func (a *PkhNode) Validate() error {
+	if len(a.Hash) != 20 {
+		return errors.New("public key hash cannot be empty")
+	}
+	return nil
+}
+
+func (a *PkhNode) FromScript(script []byte) error {
+	if len(script) == 25 &&
+		script[0] == txscript.OP_DUP &&
+		script[1] == txscript.OP_HASH160 &&
+		script[2] == txscript.OP_DATA_20 &&
+		script[23] == txscript.OP_EQUALVERIFY &&
+		script[24] == txscript.OP_CHECKSIG {
+
+		a.Hash = script[3:23]
+		return nil
+	}
+	return errors.New("invalid public key hash script")
+}
+
+func (a *PkhNode) ToScript(builder *txscript.ScriptBuilder) {
+	builder.AddOp(txscript.OP_DUP).
+		AddOp(txscript.OP_HASH160).
+		AddData(a.Hash).
+		AddOp(txscript.OP_EQUALVERIFY).
+		AddOp(txscript.OP_CHECKSIG)
+}
+
+// AfterNod represents a locktime argument
+// if Time is less than 500000000, it is a block height
+// otherwise, it is a unix timestamp
+type AfterNode struct {
+	Time int64
+}
+
+func (a *AfterNode) Validate() error {
+	if a.Time <= 0 {
+		return errors.New("locktime must be positive")
+	}
+	return nil
+}
+
+func (a *AfterNode) FromScript(script []byte) error {
+	if len(script) >= 2 &&
+		script[len(script)-2] == txscript.OP_CHECKLOCKTIMEVERIFY &&
+		script[len(script)-1] == txscript.OP_DROP {
+
+		opcode := script[0]
+		data := script[1 : len(script)-2]
+		lockTime, _, err := DecodeInt64(opcode, data)
+		if err != nil {
+			return errors.New("invalid locktime value")
+		}
+		a.Time = lockTime
+		return nil
+	}
+	return errors.New("invalid after script")
+}
+
+func (a *AfterNode) ToScript(builder *txscript.ScriptBuilder) {
+	builder.AddInt64(a.Time).
+		AddOp(txscript.OP_CHECKLOCKTIMEVERIFY).
+		AddOp(txscript.OP_DROP)
+}
+
+// OlderNode represents a relative locktime argument
+type OlderNode struct {
+	Time int64
+}
+
+func (a *OlderNode) SetBlock(height int64) error {
+	if height <= 0 {
+		return errors.New("block count must be positive")
+	} else if height >= 0x80000000 {
+		return errors.New("block count exceeds maximum for blocks")
+	}
+	a.Time = height
+	return nil
+}
+
+func (a *OlderNode) SetTime(seconds int64) error {
+	if seconds <= 0 {
+		return errors.New("time must be positive")
+	}
+	timeUnits := seconds / 512
+	if seconds%512 != 0 {
+		return errors.New("time must be a multiple of 512 seconds")
+	}
+	a.Time = 0x80000000 | timeUnits
+	return nil
+}
+
+func (a *OlderNode) Validate() error {
+	if a.Time <= 0 {
+		return errors.New("relative locktime must be positive")
+	}
+	return nil
+}
+
+func (a *OlderNode) FromScript(script []byte) error {
+	if len(script) >= 2 &&
+		script[len(script)-2] == txscript.OP_CHECKSEQUENCEVERIFY &&
+		script[len(script)-1] == txscript.OP_DROP {
+
+		opcode := script[0]
+		data := script[1 : len(script)-2]
+		sequence, _, err := DecodeInt64(opcode, data)
+		if err != nil {
+			return errors.New("invalid sequence value")
+		}
+		a.Time = sequence
+		return nil
+	}
+	return errors.New("invalid older script")
+}
+
+func (a *OlderNode) ToScript(builder *txscript.ScriptBuilder) {
+	builder.AddInt64(a.Time).
+		AddOp(txscript.OP_CHECKSEQUENCEVERIFY).
+		AddOp(txscript.OP_DROP)
+}
+
+// MultiNode represents a multisig argument
+type MultiNode struct {
+	Required int
+	Keys     []string
+}
+
+func (a *MultiNode) Validate() error {
+	if a.Required <= 0 || len(a.Keys) < a.Required {
+		return errors.New("invalid multisig parameters")
+	}
+	return nil
+}
+
+// func (a *MultiNode) FromScript(script []byte) error {
+// 	if len(script) < 2 || script[len(script)-1] != txscript.OP_CHECKMULTISIG {
+// 		return errors.New("invalid multisig script")
+// 	}
+
+// 	// Parse required signatures
+// 	required, script, err := DecodeInt(script)
+// 	if err != nil {
+// 		return errors.New("failed to decode required signatures")
+// 	}
+// 	a.Required = int(required)
+
+// 	// Parse public keys
+// 	keys := []string{}
+// 	for len(script) > 1 {
+// 		keyLen := int(script[0])
+// 		if len(script) < 1+keyLen {
+// 			return errors.New("invalid key length in multisig script")
+// 		}
+// 		keys = append(keys, string(script[1:1+keyLen]))
+// 		script = script[1+keyLen:]
+// 	}
+
+// 	// Parse total keys
+// 	totalKeys, _, err := DecodeInt(script)
+// 	if err != nil || len(keys) != int(totalKeys) {
+// 		return errors.New("total keys mismatch in multisig script")
+// 	}
+// 	a.Keys = keys
+
+// 	return nil
+// }
+
+func (a *MultiNode) ToScript(builder *txscript.ScriptBuilder) {
+	builder.AddInt64(int64(a.Required))
+	for _, key := range a.Keys {
+		builder.AddData([]byte(key))
+	}
+	builder.AddInt64(int64(len(a.Keys))).AddOp(txscript.OP_CHECKMULTISIG)
+}
+
+// ThreshNode represents a threshold condition
+type ThreshNode struct {
+	Threshold int
+	Children  []Node
+}
+
+func (a *ThreshNode) Validate() error {
+	if a.Threshold <= 0 || len(a.Children) < a.Threshold {
+		return errors.New("invalid threshold parameters")
+	}
+	for _, child := range a.Children {
+		if err := child.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// func (a *ThreshNode) FromScript(script []byte) error {
+// 	threshold, remaining, err := DecodeInt(script)
+// 	if err != nil {
+// 		return errors.New("failed to decode threshold")
+// 	}
+// 	a.Threshold = int(threshold)
+
+// 	children := []Node{}
+// 	for len(remaining) > 0 {
+// 		child, rest, err := parseChildNode(remaining)
+// 		if err != nil {
+// 			return err
+// 		}
+// 		children = append(children, child)
+// 		remaining = rest
+// 	}
+// 	a.Children = children
+// 	return nil
+// }
+
+func (a *ThreshNode) ToScript(builder *txscript.ScriptBuilder) {
+	for _, child := range a.Children {
+		child.ToScript(builder)
+	}
+	builder.AddInt64(int64(a.Threshold))
+}
+
+// CompositeNode represents a logical combination ( and, or ) of child nodes
+type CompositeNode struct {
+	Children []Node
+}
+
+func (a *CompositeNode) Validate() error {
+	if len(a.Children) < 2 {
+		return errors.New("composite nodes require at least two children")
+	}
+	for _, child := range a.Children {
+		if err := child.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// func (a *CompositeNode) FromScript(script []byte) error {
+// 	children := []Node{}
+// 	for len(script) > 0 {
+// 		child, remaining, err := parseChildNode(script)
+// 		if err != nil {
+// 			return err
+// 		}
+// 		children = append(children, child)
+// 		script = remaining
+// 	}
+// 	a.Children = children
+// 	return nil
+// }
+
+func (a *CompositeNode) ToScript(builder *txscript.ScriptBuilder) {
+	for _, child := range a.Children {
+		child.ToScript(builder)
+	}
+}
+
+// TrArgs represents a Taproot script
+type TrArgs struct {
+	InternalKey string
+	MerkleRoot  string
+}
+
+func (a *TrArgs) Validate() error {
+	if a.InternalKey == "" {
+		return errors.New("internal key cannot be empty")
+	}
+	// MerkleRoot can be empty for a single-leaf Taproot
+	return nil
+}
+
+func (a *TrArgs) ToScript(builder *txscript.ScriptBuilder) {
+	builder.AddOp(txscript.OP_1).AddData([]byte(a.InternalKey))
+	if a.MerkleRoot != "" {
+		builder.AddData([]byte(a.MerkleRoot))
+	}
+}
+
+// LeafArgs represents a Taproot leaf node
+type LeafArgs struct {
+	Script  string                        // Script for this leaf node
+	Version txscript.TapscriptLeafVersion // Leaf version (default: 0xc0 for Taproot v1)
+}
+
+func (a *LeafArgs) Validate() error {
+	if a.Script == "" {
+		return errors.New("leaf script cannot be empty")
+	}
+	if a.Version < txscript.BaseLeafVersion {
+		return errors.New("invalid leaf version")
+	}
+	return nil
+}
+
+func (a *LeafArgs) FromScript(script []byte) error {
+	if len(script) < 1 {
+		return errors.New("invalid leaf script")
+	}
+	a.Version = txscript.TapscriptLeafVersion(script[0])
+	a.Script = string(script[1:])
+	return nil
+}
+
+func (a *LeafArgs) ToScript(builder *txscript.ScriptBuilder) {
+	builder.AddOp(byte(a.Version)).AddData([]byte(a.Script))
+}
+
+// Miniscript is a node of a miniscript expression tree. Unlike the fixed
+// nodes above it covers the whole language, carries its type and can
+// compute witnesses. Build one with ParseMiniscript or DecodeMiniscript.
+type Miniscript struct {
+	Fragment Fragment
+	// K is the threshold of thresh, multi and multi_a, and the lock value of
+	// older and after.
+	K uint32
+	// Keys are the public keys of pk_k, pk_h, multi and multi_a.
+	Keys [][]byte
+	// Hash is the digest of a hash fragment. A pk_h decoded from script
+	// keeps its key hash here, as the key itself is not in the script.
+	Hash []byte
+	Subs []*Miniscript
+
+	ctx MiniscriptContext
+	typ MiniscriptType
+	ops opsCount
+}
+
+// newMiniscript builds a node and type checks it against its
+// subexpressions.
+func newMiniscript(ctx MiniscriptContext, frag Fragment, k uint32, keys [][]byte, hash []byte, subs ...*Miniscript) (*Miniscript, error) {
+	m := &Miniscript{Fragment: frag, K: k, Keys: keys, Hash: hash, Subs: subs, ctx: ctx}
+	if err := m.checkArgs(); err != nil {
+		return nil, err
+	}
+	types := make([]MiniscriptType, len(subs))
+	for i, sub := range subs {
+		types[i] = sub.typ
+	}
+	m.typ = computeType(ctx, frag, k, types)
+	if m.typ == 0 {
+		return nil, fmt.Errorf("%s is not a valid miniscript expression", m)
+	}
+	m.ops = calcOps(m)
+	return m, nil
+}
+
+func (m *Miniscript) checkArgs() error {
+	switch m.Fragment {
+	case FragPkK:
+		return checkMiniscriptKey(m.ctx, m.Keys[0])
+	case FragPkH:
+		if len(m.Keys) == 0 {
+			if len(m.Hash) != 20 {
+				return errors.New("pk_h needs a key or a 20-byte key hash")
+			}
+			return nil
+		}
+		return checkMiniscriptKey(m.ctx, m.Keys[0])
+	case FragOlder, FragAfter:
+		if m.K < 1 || m.K >= 0x80000000 {
+			return fmt.Errorf("%s value %d out of range", m.Fragment, m.K)
+		}
+	case FragSha256, FragHash256, FragRipemd160, FragHash160:
+		if len(m.Hash) != m.Fragment.hashLen() {
+			return fmt.Errorf("%s needs a %d-byte hash", m.Fragment, m.Fragment.hashLen())
+		}
+	case FragThresh:
+		if m.K < 1 || int(m.K) > len(m.Subs) {
+			return fmt.Errorf("invalid thresh threshold %d of %d", m.K, len(m.Subs))
+		}
+	case FragMulti, FragMultiA:
+		limit := MaxPubKeysPerMulti
+		if m.Fragment == FragMultiA {
+			limit = MaxPubKeysPerMultiA
+		}
+		if (m.Fragment == FragMulti) != (m.ctx == P2WSHContext) {
+			return fmt.Errorf("%s is not available in %s", m.Fragment, m.ctx)
+		}
+		if m.K < 1 || int(m.K) > len(m.Keys) || len(m.Keys) > limit {
+			return fmt.Errorf("invalid %s threshold %d of %d keys", m.Fragment, m.K, len(m.Keys))
+		}
+		for _, key := range m.Keys {
+			if err := checkMiniscriptKey(m.ctx, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkMiniscriptKey(ctx MiniscriptContext, key []byte) error {
+	if len(key) != ctx.keyLen() {
+		return fmt.Errorf("%s keys must be %d bytes, got %d", ctx, ctx.keyLen(), len(key))
+	}
+	var err error
+	if ctx == TapscriptContext {
+		_, err = schnorr.ParsePubKey(key)
+	} else {
+		_, err = btcec.ParsePubKey(key)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid key %x: %w", key, err)
+	}
+	return nil
+}
+
+// Context returns the script context m was built for.
+func (m *Miniscript) Context() MiniscriptContext { return m.ctx }
+
+// Type returns the type properties of m.
+func (m *Miniscript) Type() MiniscriptType { return m.typ }
+
+// Validate checks that m is usable as a whole script: of type B, within the
+// resource limits of its context, free of duplicate keys and timelock
+// mixing, and always spent with a signature and a non-malleable witness.
+func (m *Miniscript) Validate() error {
+	if !m.typ.Has("B") {
+		return errors.New("top level miniscript must be of type B")
+	}
+	script, err := m.Script()
+	if err != nil {
+		return err
+	}
+	if m.ctx == P2WSHContext {
+		if len(script) > MaxStandardP2WSHScriptSize {
+			return fmt.Errorf("script size %d exceeds %d bytes", len(script), MaxStandardP2WSHScriptSize)
+		}
+		if m.ops.sat.valid && m.ops.count+m.ops.sat.v > MaxOpsPerScript {
+			return fmt.Errorf("satisfaction executes %d opcodes, more than %d", m.ops.count+m.ops.sat.v, MaxOpsPerScript)
+		}
+	}
+	if !m.typ.Has("m") {
+		return errors.New("miniscript is malleable")
+	}
+	if !m.typ.Has("s") {
+		return errors.New("miniscript can be spent without a signature")
+	}
+	if !m.typ.Has("k") {
+		return errors.New("miniscript mixes height and time locks")
+	}
+	seen := map[string]bool{}
+	var dup error
+	m.walk(func(n *Miniscript) {
+		for _, key := range n.Keys {
+			if seen[string(key)] && dup == nil {
+				dup = fmt.Errorf("duplicate key %x", key)
+			}
+			seen[string(key)] = true
+		}
+	})
+	return dup
+}
+
+func (m *Miniscript) walk(fn func(*Miniscript)) {
+	fn(m)
+	for _, sub := range m.Subs {
+		sub.walk(fn)
+	}
+}
+
+// Script returns the script m compiles to.
+func (m *Miniscript) Script() ([]byte, error) {
+	builder := txscript.NewScriptBuilder()
+	m.ToScript(builder)
+	return builder.Script()
+}
+
+func (m *Miniscript) ToScript(builder *txscript.ScriptBuilder) {
+	m.build(builder, false)
+}
+
+// build appends the script of m. With verify set the result is consumed by
+// OP_VERIFY, which merges into a trailing OP_EQUAL, OP_CHECKSIG,
+// OP_CHECKMULTISIG or OP_NUMEQUAL wherever the type has no x property.
+func (m *Miniscript) build(b *txscript.ScriptBuilder, verify bool) {
+	pick := func(op, verifyOp byte) byte {
+		if verify {
+			return verifyOp
+		}
+		return op
+	}
+
+	switch m.Fragment {
+	case FragJust0:
+		b.AddOp(txscript.OP_0)
+	case FragJust1:
+		b.AddOp(txscript.OP_1)
+	case FragPkK:
+		b.AddData(m.Keys[0])
+	case FragPkH:
+		b.AddOp(txscript.OP_DUP).AddOp(txscript.OP_HASH160).
+			AddData(m.keyHash()).AddOp(txscript.OP_EQUALVERIFY)
+	case FragOlder:
+		b.AddInt64(int64(m.K)).AddOp(txscript.OP_CHECKSEQUENCEVERIFY)
+	case FragAfter:
+		b.AddInt64(int64(m.K)).AddOp(txscript.OP_CHECKLOCKTIMEVERIFY)
+	case FragSha256, FragHash256, FragRipemd160, FragHash160:
+		b.AddOp(txscript.OP_SIZE).AddInt64(32).AddOp(txscript.OP_EQUALVERIFY).
+			AddOp(hashOpcodes[m.Fragment]).AddData(m.Hash).
+			AddOp(pick(txscript.OP_EQUAL, txscript.OP_EQUALVERIFY))
+	case FragWrapA:
+		b.AddOp(txscript.OP_TOALTSTACK)
+		m.Subs[0].build(b, false)
+		b.AddOp(txscript.OP_FROMALTSTACK)
+	case FragWrapS:
+		b.AddOp(txscript.OP_SWAP)
+		m.Subs[0].build(b, verify)
+	case FragWrapC:
+		m.Subs[0].build(b, false)
+		b.AddOp(pick(txscript.OP_CHECKSIG, txscript.OP_CHECKSIGVERIFY))
+	case FragWrapD:
+		b.AddOp(txscript.OP_DUP).AddOp(txscript.OP_IF)
+		m.Subs[0].build(b, false)
+		b.AddOp(txscript.OP_ENDIF)
+	case FragWrapV:
+		expensive := m.Subs[0].typ.Has("x")
+		m.Subs[0].build(b, !expensive)
+		if expensive {
+			b.AddOp(txscript.OP_VERIFY)
+		}
+	case FragWrapJ:
+		b.AddOp(txscript.OP_SIZE).AddOp(txscript.OP_0NOTEQUAL).AddOp(txscript.OP_IF)
+		m.Subs[0].build(b, false)
+		b.AddOp(txscript.OP_ENDIF)
+	case FragWrapN:
+		m.Subs[0].build(b, false)
+		b.AddOp(txscript.OP_0NOTEQUAL)
+	case FragAndV:
+		m.Subs[0].build(b, false)
+		m.Subs[1].build(b, verify)
+	case FragAndB:
+		m.Subs[0].build(b, false)
+		m.Subs[1].build(b, false)
+		b.AddOp(txscript.OP_BOOLAND)
+	case FragOrB:
+		m.Subs[0].build(b, false)
+		m.Subs[1].build(b, false)
+		b.AddOp(txscript.OP_BOOLOR)
+	case FragOrC:
+		m.Subs[0].build(b, false)
+		b.AddOp(txscript.OP_NOTIF)
+		m.Subs[1].build(b, false)
+		b.AddOp(txscript.OP_ENDIF)
+	case FragOrD:
+		m.Subs[0].build(b, false)
+		b.AddOp(txscript.OP_IFDUP).AddOp(txscript.OP_NOTIF)
+		m.Subs[1].build(b, false)
+		b.AddOp(txscript.OP_ENDIF)
+	case FragOrI:
+		b.AddOp(txscript.OP_IF)
+		m.Subs[0].build(b, false)
+		b.AddOp(txscript.OP_ELSE)
+		m.Subs[1].build(b, false)
+		b.AddOp(txscript.OP_ENDIF)
+	case FragAndOr:
+		m.Subs[0].build(b, false)
+		b.AddOp(txscript.OP_NOTIF)
+		m.Subs[2].build(b, false)
+		b.AddOp(txscript.OP_ELSE)
+		m.Subs[1].build(b, false)
+		b.AddOp(txscript.OP_ENDIF)
+	case FragThresh:
+		for i, sub := range m.Subs {
+			sub.build(b, false)
+			if i > 0 {
+				b.AddOp(txscript.OP_ADD)
+			}
+		}
+		b.AddInt64(int64(m.K)).AddOp(pick(txscript.OP_EQUAL, txscript.OP_EQUALVERIFY))
+	case FragMulti:
+		b.AddInt64(int64(m.K))
+		for _, key := range m.Keys {
+			b.AddData(key)
+		}
+		b.AddInt64(int64(len(m.Keys))).
+			AddOp(pick(txscript.OP_CHECKMULTISIG, txscript.OP_CHECKMULTISIGVERIFY))
+	case FragMultiA:
+		for i, key := range m.Keys {
+			b.AddData(key)
+			if i == 0 {
+				b.AddOp(txscript.OP_CHECKSIG)
+			} else {
+				b.AddOp(txscript.OP_CHECKSIGADD)
+			}
+		}
+		b.AddInt64(int64(m.K)).AddOp(pick(txscript.OP_NUMEQUAL, txscript.OP_NUMEQUALVERIFY))
+	}
+}
+
+var hashOpcodes = map[Fragment]byte{
+	FragSha256:    txscript.OP_SHA256,
+	FragHash256:   txscript.OP_HASH256,
+	FragRipemd160: txscript.OP_RIPEMD160,
+	FragHash160:   txscript.OP_HASH160,
+}
+
+// keyHash returns the HASH160 a pk_h fragment checks against.
+func (m *Miniscript) keyHash() []byte {
+	if len(m.Keys) == 0 {
+		return m.Hash
+	}
+	return btcutil.Hash160(m.Keys[0])
+}
@@ -0,0 +1,463 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// msToken is a script opcode with its push data.
+type msToken struct {
+	op   byte
+	data []byte
+}
+
+// decomposeScript splits script into tokens, expanding the VERIFY opcodes
+// into their plain form followed by OP_VERIFY, and rejects non-minimal
+// pushes, which miniscript never produces.
+func decomposeScript(script []byte) ([]msToken, error) {
+	var tokens []msToken
+	tokenizer := txscript.MakeScriptTokenizer(0, script)
+	for tokenizer.Next() {
+		op, data := tokenizer.Opcode(), tokenizer.Data()
+		if op > txscript.OP_0 && op <= txscript.OP_PUSHDATA4 && !isMinimalPush(op, data) {
+			return nil, fmt.Errorf("non-minimal push of %d bytes", len(data))
+		}
+		switch op {
+		case txscript.OP_EQUALVERIFY:
+			tokens = append(tokens, msToken{op: txscript.OP_EQUAL}, msToken{op: txscript.OP_VERIFY})
+		case txscript.OP_CHECKSIGVERIFY:
+			tokens = append(tokens, msToken{op: txscript.OP_CHECKSIG}, msToken{op: txscript.OP_VERIFY})
+		case txscript.OP_CHECKMULTISIGVERIFY:
+			tokens = append(tokens, msToken{op: txscript.OP_CHECKMULTISIG}, msToken{op: txscript.OP_VERIFY})
+		case txscript.OP_NUMEQUALVERIFY:
+			tokens = append(tokens, msToken{op: txscript.OP_NUMEQUAL}, msToken{op: txscript.OP_VERIFY})
+		default:
+			tokens = append(tokens, msToken{op: op, data: data})
+		}
+	}
+	if err := tokenizer.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func isMinimalPush(op byte, data []byte) bool {
+	switch {
+	case len(data) == 0:
+		return op == txscript.OP_0
+	case len(data) == 1 && (data[0] >= 1 && data[0] <= 16 || data[0] == 0x81):
+		return false
+	case len(data) <= 75:
+		return int(op) == len(data)
+	case len(data) <= 255:
+		return op == txscript.OP_PUSHDATA1
+	case len(data) <= 65535:
+		return op == txscript.OP_PUSHDATA2
+	}
+	return true
+}
+
+// scriptNumber reads a token as a minimally encoded script number of up to
+// 4 bytes.
+func scriptNumber(t msToken) (int64, bool) {
+	switch {
+	case t.op == txscript.OP_0:
+		return 0, true
+	case t.op >= txscript.OP_1 && t.op <= txscript.OP_16:
+		return int64(t.op-txscript.OP_1) + 1, true
+	case t.op > txscript.OP_PUSHDATA4 || len(t.data) == 0 || len(t.data) > 4:
+		return 0, false
+	}
+	// The last byte may only be 0x00 or 0x80 when it carries the sign of a
+	// preceding byte that uses its top bit.
+	last := t.data[len(t.data)-1]
+	if last&0x7f == 0 && (len(t.data) == 1 || t.data[len(t.data)-2]&0x80 == 0) {
+		return 0, false
+	}
+	n, err := decodeScriptNum(t.data)
+	return n, err == nil
+}
+
+type decodeState uint8
+
+const (
+	decodeSingleBKV decodeState = iota // a single B, K or V expression
+	decodeBKV                          // an and_v chain of them
+	decodeW                            // a W expression
+	decodeMaybeAndV
+	decodeAndV
+	decodeAndB
+	decodeOrB
+	decodeOrC
+	decodeOrD
+	decodeAndOr
+	decodeThreshW
+	decodeThreshE
+	decodeEndIf
+	decodeEndIfNotIf
+	decodeEndIfElse
+	decodeSwap
+	decodeAlt
+	decodeCheck
+	decodeDupIf
+	decodeVerify
+	decodeNonZero
+	decodeZeroNotEqual
+)
+
+type decodeTask struct {
+	state decodeState
+	n, k  int
+}
+
+// DecodeMiniscript recovers the miniscript expression a script was compiled
+// from. Scripts that are not miniscript, or whose expression is ill typed or
+// not of type B, are rejected. A pk_h key cannot be recovered from its hash,
+// so decoded pk_h fragments carry only Hash.
+//
+// Several expressions can compile to the same script, e.g. and_v chains
+// nest either way; the result is one of them.
+func DecodeMiniscript(script []byte, ctx MiniscriptContext) (*Miniscript, error) {
+	tokens, err := decomposeScript(script)
+	if err != nil {
+		return nil, err
+	}
+	// Miniscript decodes right to left.
+	slices.Reverse(tokens)
+	d := &miniscriptDecoder{ctx: ctx, in: tokens}
+	m, err := d.decode()
+	if err != nil {
+		return nil, fmt.Errorf("not a miniscript: %w", err)
+	}
+	return m, nil
+}
+
+type miniscriptDecoder struct {
+	ctx         MiniscriptContext
+	in          []msToken
+	constructed []*Miniscript
+}
+
+var errUnexpected = errors.New("unexpected opcode")
+
+// has reports whether at least n tokens are left.
+func (d *miniscriptDecoder) has(n int) bool { return len(d.in) >= n }
+
+func (d *miniscriptDecoder) op(i int) byte { return d.in[i].op }
+
+func (d *miniscriptDecoder) push(m *Miniscript, err error) error {
+	if err != nil {
+		return err
+	}
+	d.constructed = append(d.constructed, m)
+	return nil
+}
+
+func (d *miniscriptDecoder) pop() *Miniscript {
+	m := d.constructed[len(d.constructed)-1]
+	d.constructed = d.constructed[:len(d.constructed)-1]
+	return m
+}
+
+// wrapBack replaces the last constructed expression with frag applied to it.
+func (d *miniscriptDecoder) wrapBack(frag Fragment) error {
+	if len(d.constructed) == 0 {
+		return errUnexpected
+	}
+	return d.push(newMiniscript(d.ctx, frag, 0, nil, nil, d.pop()))
+}
+
+// buildBack combines the last two constructed expressions. As decoding runs
+// backwards, the last one is the left subexpression.
+func (d *miniscriptDecoder) buildBack(frag Fragment) error {
+	if len(d.constructed) < 2 {
+		return errUnexpected
+	}
+	x := d.pop()
+	y := d.pop()
+	return d.push(newMiniscript(d.ctx, frag, 0, nil, nil, x, y))
+}
+
+func (d *miniscriptDecoder) decode() (*Miniscript, error) {
+	// The top level is of type B, so it cannot be a W expression.
+	todo := []decodeTask{{state: decodeBKV}}
+	for len(todo) > 0 {
+		task := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+
+		var err error
+		switch task.state {
+		case decodeSingleBKV:
+			todo, err = d.single(todo)
+		case decodeBKV:
+			todo = append(todo, decodeTask{state: decodeMaybeAndV}, decodeTask{state: decodeSingleBKV})
+		case decodeW:
+			if !d.has(1) {
+				return nil, errors.New("script ended early")
+			}
+			if d.op(0) == txscript.OP_FROMALTSTACK {
+				d.in = d.in[1:]
+				todo = append(todo, decodeTask{state: decodeAlt})
+			} else {
+				todo = append(todo, decodeTask{state: decodeSwap})
+			}
+			todo = append(todo, decodeTask{state: decodeBKV})
+		case decodeMaybeAndV:
+			// These opcodes cannot end an expression, so they cannot be
+			// the end of the left side of an and_v either.
+			if d.has(1) {
+				switch d.op(0) {
+				case txscript.OP_IF, txscript.OP_ELSE, txscript.OP_NOTIF, txscript.OP_TOALTSTACK, txscript.OP_SWAP:
+				default:
+					todo = append(todo, decodeTask{state: decodeAndV}, decodeTask{state: decodeBKV})
+				}
+			}
+		case decodeSwap:
+			if !d.has(1) || d.op(0) != txscript.OP_SWAP {
+				return nil, errUnexpected
+			}
+			d.in = d.in[1:]
+			err = d.wrapBack(FragWrapS)
+		case decodeAlt:
+			if !d.has(1) || d.op(0) != txscript.OP_TOALTSTACK {
+				return nil, errUnexpected
+			}
+			d.in = d.in[1:]
+			err = d.wrapBack(FragWrapA)
+		case decodeCheck:
+			err = d.wrapBack(FragWrapC)
+		case decodeDupIf:
+			err = d.wrapBack(FragWrapD)
+		case decodeVerify:
+			err = d.wrapBack(FragWrapV)
+		case decodeNonZero:
+			err = d.wrapBack(FragWrapJ)
+		case decodeZeroNotEqual:
+			err = d.wrapBack(FragWrapN)
+		case decodeAndV:
+			err = d.buildBack(FragAndV)
+		case decodeAndB:
+			err = d.buildBack(FragAndB)
+		case decodeOrB:
+			err = d.buildBack(FragOrB)
+		case decodeOrC:
+			err = d.buildBack(FragOrC)
+		case decodeOrD:
+			err = d.buildBack(FragOrD)
+		case decodeAndOr:
+			if len(d.constructed) < 3 {
+				return nil, errUnexpected
+			}
+			x := d.pop()
+			z := d.pop()
+			y := d.pop()
+			err = d.push(newMiniscript(d.ctx, FragAndOr, 0, nil, nil, x, y, z))
+		case decodeThreshW:
+			if !d.has(1) {
+				return nil, errors.New("script ended early")
+			}
+			if d.op(0) == txscript.OP_ADD {
+				d.in = d.in[1:]
+				todo = append(todo, decodeTask{state: decodeThreshW, n: task.n + 1, k: task.k}, decodeTask{state: decodeW})
+			} else {
+				// The first subexpression is d, so it cannot be an and_v.
+				todo = append(todo, decodeTask{state: decodeThreshE, n: task.n + 1, k: task.k}, decodeTask{state: decodeSingleBKV})
+			}
+		case decodeThreshE:
+			if task.k < 1 || task.k > task.n || len(d.constructed) < task.n {
+				return nil, errors.New("invalid thresh")
+			}
+			subs := make([]*Miniscript, task.n)
+			for i := range subs {
+				subs[i] = d.pop()
+			}
+			err = d.push(newMiniscript(d.ctx, FragThresh, uint32(task.k), nil, nil, subs...))
+		case decodeEndIf:
+			switch {
+			case !d.has(1):
+				return nil, errors.New("script ended early")
+			case d.op(0) == txscript.OP_ELSE:
+				// andor or or_i
+				d.in = d.in[1:]
+				todo = append(todo, decodeTask{state: decodeEndIfElse}, decodeTask{state: decodeBKV})
+			case d.op(0) == txscript.OP_IF && d.has(2) && d.op(1) == txscript.OP_DUP:
+				d.in = d.in[2:]
+				todo = append(todo, decodeTask{state: decodeDupIf})
+			case d.op(0) == txscript.OP_IF && d.has(3) && d.op(1) == txscript.OP_0NOTEQUAL && d.op(2) == txscript.OP_SIZE:
+				d.in = d.in[3:]
+				todo = append(todo, decodeTask{state: decodeNonZero})
+			case d.op(0) == txscript.OP_NOTIF:
+				// or_c or or_d
+				d.in = d.in[1:]
+				todo = append(todo, decodeTask{state: decodeEndIfNotIf})
+			default:
+				return nil, errUnexpected
+			}
+		case decodeEndIfNotIf:
+			if !d.has(1) {
+				return nil, errors.New("script ended early")
+			}
+			if d.op(0) == txscript.OP_IFDUP {
+				d.in = d.in[1:]
+				todo = append(todo, decodeTask{state: decodeOrD})
+			} else {
+				todo = append(todo, decodeTask{state: decodeOrC})
+			}
+			// The left side of or_c and or_d is d, so it cannot be an and_v.
+			todo = append(todo, decodeTask{state: decodeSingleBKV})
+		case decodeEndIfElse:
+			switch {
+			case !d.has(1):
+				return nil, errors.New("script ended early")
+			case d.op(0) == txscript.OP_IF:
+				d.in = d.in[1:]
+				err = d.buildBack(FragOrI)
+			case d.op(0) == txscript.OP_NOTIF:
+				d.in = d.in[1:]
+				// The condition of andor is d, so it cannot be an and_v.
+				todo = append(todo, decodeTask{state: decodeAndOr}, decodeTask{state: decodeSingleBKV})
+			default:
+				return nil, errUnexpected
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(d.in) != 0 || len(d.constructed) != 1 {
+		return nil, errors.New("trailing script data")
+	}
+	m := d.constructed[0]
+	if !m.typ.Has("B") {
+		return nil, fmt.Errorf("top level expression is of type %s, not B", m.typ)
+	}
+	return m, nil
+}
+
+// single decodes the end of a single expression, pushing a leaf fragment
+// or scheduling the parts of a composite one.
+func (d *miniscriptDecoder) single(todo []decodeTask) ([]decodeTask, error) {
+	if !d.has(1) {
+		return nil, errors.New("script ended early")
+	}
+	in := d.in
+	switch {
+	case in[0].op == txscript.OP_1:
+		d.in = in[1:]
+		return todo, d.push(newMiniscript(d.ctx, FragJust1, 0, nil, nil))
+	case in[0].op == txscript.OP_0:
+		d.in = in[1:]
+		return todo, d.push(newMiniscript(d.ctx, FragJust0, 0, nil, nil))
+	case len(in[0].data) == 33 || len(in[0].data) == 32:
+		d.in = in[1:]
+		return todo, d.push(newMiniscript(d.ctx, FragPkK, 0, [][]byte{in[0].data}, nil))
+	case d.has(5) && in[0].op == txscript.OP_VERIFY && in[1].op == txscript.OP_EQUAL &&
+		len(in[2].data) == 20 && in[3].op == txscript.OP_HASH160 && in[4].op == txscript.OP_DUP:
+		d.in = in[5:]
+		return todo, d.push(newMiniscript(d.ctx, FragPkH, 0, nil, in[2].data))
+	}
+
+	if d.has(2) && (in[0].op == txscript.OP_CHECKSEQUENCEVERIFY || in[0].op == txscript.OP_CHECKLOCKTIMEVERIFY) {
+		n, ok := scriptNumber(in[1])
+		if !ok || n < 1 || n > 0x7fffffff {
+			return nil, errors.New("invalid timelock")
+		}
+		frag := FragOlder
+		if in[0].op == txscript.OP_CHECKLOCKTIMEVERIFY {
+			frag = FragAfter
+		}
+		d.in = in[2:]
+		return todo, d.push(newMiniscript(d.ctx, frag, uint32(n), nil, nil))
+	}
+
+	if d.has(7) && in[0].op == txscript.OP_EQUAL && in[3].op == txscript.OP_VERIFY &&
+		in[4].op == txscript.OP_EQUAL && in[6].op == txscript.OP_SIZE {
+		if size, ok := scriptNumber(in[5]); ok && size == 32 {
+			for frag, op := range hashOpcodes {
+				if in[2].op == op && len(in[1].data) == frag.hashLen() {
+					d.in = in[7:]
+					return todo, d.push(newMiniscript(d.ctx, frag, 0, nil, in[1].data))
+				}
+			}
+		}
+	}
+
+	if d.has(3) && in[0].op == txscript.OP_CHECKMULTISIG {
+		n, ok := scriptNumber(in[1])
+		if !ok || n < 1 || n > MaxPubKeysPerMulti || !d.has(3+int(n)) {
+			return nil, errors.New("invalid multi")
+		}
+		keys := make([][]byte, n)
+		for i := range keys {
+			keys[i] = in[2+i].data
+		}
+		k, ok := scriptNumber(in[2+n])
+		if !ok {
+			return nil, errors.New("invalid multi threshold")
+		}
+		slices.Reverse(keys)
+		d.in = in[3+n:]
+		return todo, d.push(newMiniscript(d.ctx, FragMulti, uint32(k), keys, nil))
+	}
+
+	if d.has(4) && in[0].op == txscript.OP_NUMEQUAL && d.ctx == TapscriptContext {
+		k, ok := scriptNumber(in[1])
+		if !ok || k < 1 || k > MaxPubKeysPerMultiA {
+			return nil, errors.New("invalid multi_a threshold")
+		}
+		var keys [][]byte
+		for pos := 2; ; pos += 2 {
+			if !d.has(pos+2) || len(keys) >= MaxPubKeysPerMultiA {
+				return nil, errors.New("invalid multi_a")
+			}
+			op := in[pos].op
+			if op != txscript.OP_CHECKSIGADD && op != txscript.OP_CHECKSIG {
+				return nil, errors.New("invalid multi_a")
+			}
+			keys = append(keys, in[pos+1].data)
+			if op == txscript.OP_CHECKSIG {
+				break
+			}
+		}
+		slices.Reverse(keys)
+		d.in = in[2+2*len(keys):]
+		return todo, d.push(newMiniscript(d.ctx, FragMultiA, uint32(k), keys, nil))
+	}
+
+	// and_v commutes with the following wrappers, e.g. c:and_v(X,Y) is the
+	// script of and_v(X,c:Y), so they only take a single expression.
+	switch in[0].op {
+	case txscript.OP_CHECKSIG:
+		d.in = in[1:]
+		return append(todo, decodeTask{state: decodeCheck}, decodeTask{state: decodeSingleBKV}), nil
+	case txscript.OP_VERIFY:
+		d.in = in[1:]
+		return append(todo, decodeTask{state: decodeVerify}, decodeTask{state: decodeSingleBKV}), nil
+	case txscript.OP_0NOTEQUAL:
+		d.in = in[1:]
+		return append(todo, decodeTask{state: decodeZeroNotEqual}, decodeTask{state: decodeSingleBKV}), nil
+	case txscript.OP_ENDIF:
+		d.in = in[1:]
+		return append(todo, decodeTask{state: decodeEndIf}, decodeTask{state: decodeBKV}), nil
+	case txscript.OP_BOOLAND, txscript.OP_BOOLOR:
+		// or_b(and_v(X,Y),Z) and and_v(X,or_b(Y,Z)) share a script and
+		// only the latter is valid, so the and_v stays outside.
+		state := decodeAndB
+		if in[0].op == txscript.OP_BOOLOR {
+			state = decodeOrB
+		}
+		d.in = in[1:]
+		return append(todo, decodeTask{state: state}, decodeTask{state: decodeSingleBKV}, decodeTask{state: decodeW}), nil
+	}
+	if d.has(3) && in[0].op == txscript.OP_EQUAL {
+		if k, ok := scriptNumber(in[1]); ok && k >= 1 {
+			d.in = in[2:]
+			return append(todo, decodeTask{state: decodeThreshW, k: int(k)}), nil
+		}
+	}
+	return nil, errUnexpected
+}
@@ -0,0 +1,375 @@
+package script
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// KeyResolver maps a key name in a miniscript expression, such as A in
+// pk(A), to its public key.
+type KeyResolver func(name string) ([]byte, error)
+
+// ParseMiniscript parses a miniscript expression such as
+// "and_v(v:pk(K),older(144))" for the given context. Keys are resolved with
+// keys, or read as hex when keys is nil: 33-byte compressed keys for P2WSH
+// and 32-byte x-only keys for tapscript.
+//
+// The expression must be well typed and of type B. Whether it is also safe
+// to use (non-malleable, signature protected, within limits) is checked by
+// Validate.
+func ParseMiniscript(expr string, ctx MiniscriptContext, keys KeyResolver) (*Miniscript, error) {
+	if keys == nil {
+		keys = func(name string) ([]byte, error) { return hex.DecodeString(name) }
+	}
+	p := &miniscriptParser{ctx: ctx, keys: keys}
+	m, err := p.parse(strings.TrimSpace(expr))
+	if err != nil {
+		return nil, err
+	}
+	if !m.typ.Has("B") {
+		return nil, fmt.Errorf("%s is of type %s, not B", expr, m.typ)
+	}
+	return m, nil
+}
+
+type miniscriptParser struct {
+	ctx  MiniscriptContext
+	keys KeyResolver
+}
+
+func (p *miniscriptParser) parse(s string) (*Miniscript, error) {
+	name, args, err := splitFragment(s)
+	if err != nil {
+		return nil, err
+	}
+
+	// Wrappers, e.g. "sc:" in sc:pk_k(K), apply right to left.
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		wrappers := name[:i]
+		if wrappers == "" {
+			return nil, fmt.Errorf("empty wrapper in %q", s)
+		}
+		m, err := p.parse(s[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		for j := len(wrappers) - 1; j >= 0; j-- {
+			if m, err = p.wrap(wrappers[j], m); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	}
+
+	argc := func(n int) error {
+		if len(args) != n {
+			return fmt.Errorf("%s takes %d arguments, got %d", name, n, len(args))
+		}
+		return nil
+	}
+	subs := func(args []string) ([]*Miniscript, error) {
+		ms := make([]*Miniscript, len(args))
+		for i, arg := range args {
+			m, err := p.parse(arg)
+			if err != nil {
+				return nil, err
+			}
+			ms[i] = m
+		}
+		return ms, nil
+	}
+
+	switch name {
+	case "0", "1":
+		if args != nil {
+			return nil, fmt.Errorf("%s takes no arguments", name)
+		}
+		if name == "0" {
+			return p.just(FragJust0)
+		}
+		return p.just(FragJust1)
+
+	case "pk_k", "pk_h", "pk", "pkh":
+		if err := argc(1); err != nil {
+			return nil, err
+		}
+		key, err := p.keys(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", args[0], err)
+		}
+		frag := FragPkK
+		if name == "pk_h" || name == "pkh" {
+			frag = FragPkH
+		}
+		m, err := newMiniscript(p.ctx, frag, 0, [][]byte{key}, nil)
+		if err != nil || name == "pk_k" || name == "pk_h" {
+			return m, err
+		}
+		return newMiniscript(p.ctx, FragWrapC, 0, nil, nil, m)
+
+	case "expr_raw_pkh":
+		if err := argc(1); err != nil {
+			return nil, err
+		}
+		hash, err := hex.DecodeString(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid key hash %q: %w", args[0], err)
+		}
+		return newMiniscript(p.ctx, FragPkH, 0, nil, hash)
+
+	case "older", "after":
+		if err := argc(1); err != nil {
+			return nil, err
+		}
+		n, err := parseMiniscriptNumber(args[0])
+		if err != nil {
+			return nil, err
+		}
+		frag := FragOlder
+		if name == "after" {
+			frag = FragAfter
+		}
+		return newMiniscript(p.ctx, frag, n, nil, nil)
+
+	case "sha256", "hash256", "ripemd160", "hash160":
+		if err := argc(1); err != nil {
+			return nil, err
+		}
+		hash, err := hex.DecodeString(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s hash %q: %w", name, args[0], err)
+		}
+		return newMiniscript(p.ctx, hashFragments[name], 0, nil, hash)
+
+	case "and_v", "and_b", "or_b", "or_c", "or_d", "or_i", "and_n", "andor":
+		n := 2
+		if name == "andor" {
+			n = 3
+		}
+		if err := argc(n); err != nil {
+			return nil, err
+		}
+		ms, err := subs(args)
+		if err != nil {
+			return nil, err
+		}
+		if name == "and_n" {
+			zero, err := p.just(FragJust0)
+			if err != nil {
+				return nil, err
+			}
+			return newMiniscript(p.ctx, FragAndOr, 0, nil, nil, ms[0], ms[1], zero)
+		}
+		return newMiniscript(p.ctx, binaryFragments[name], 0, nil, nil, ms...)
+
+	case "thresh", "multi", "multi_a":
+		if len(args) < 2 {
+			return nil, fmt.Errorf("%s needs a threshold and at least one argument", name)
+		}
+		k, err := parseMiniscriptNumber(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if name == "thresh" {
+			ms, err := subs(args[1:])
+			if err != nil {
+				return nil, err
+			}
+			return newMiniscript(p.ctx, FragThresh, k, nil, nil, ms...)
+		}
+		keys := make([][]byte, len(args)-1)
+		for i, arg := range args[1:] {
+			if keys[i], err = p.keys(arg); err != nil {
+				return nil, fmt.Errorf("key %q: %w", arg, err)
+			}
+		}
+		frag := FragMulti
+		if name == "multi_a" {
+			frag = FragMultiA
+		}
+		return newMiniscript(p.ctx, frag, k, keys, nil)
+	}
+	return nil, fmt.Errorf("unknown miniscript fragment %q", name)
+}
+
+func (p *miniscriptParser) just(frag Fragment) (*Miniscript, error) {
+	return newMiniscript(p.ctx, frag, 0, nil, nil)
+}
+
+func (p *miniscriptParser) wrap(w byte, m *Miniscript) (*Miniscript, error) {
+	switch w {
+	case 't':
+		one, err := p.just(FragJust1)
+		if err != nil {
+			return nil, err
+		}
+		return newMiniscript(p.ctx, FragAndV, 0, nil, nil, m, one)
+	case 'l', 'u':
+		zero, err := p.just(FragJust0)
+		if err != nil {
+			return nil, err
+		}
+		if w == 'l' {
+			return newMiniscript(p.ctx, FragOrI, 0, nil, nil, zero, m)
+		}
+		return newMiniscript(p.ctx, FragOrI, 0, nil, nil, m, zero)
+	}
+	frag, ok := wrapperFragments[w]
+	if !ok {
+		return nil, fmt.Errorf("unknown miniscript wrapper %q", w)
+	}
+	return newMiniscript(p.ctx, frag, 0, nil, nil, m)
+}
+
+var hashFragments = map[string]Fragment{
+	"sha256":    FragSha256,
+	"hash256":   FragHash256,
+	"ripemd160": FragRipemd160,
+	"hash160":   FragHash160,
+}
+
+var binaryFragments = map[string]Fragment{
+	"and_v": FragAndV,
+	"and_b": FragAndB,
+	"or_b":  FragOrB,
+	"or_c":  FragOrC,
+	"or_d":  FragOrD,
+	"or_i":  FragOrI,
+	"andor": FragAndOr,
+}
+
+var wrapperFragments = map[byte]Fragment{
+	'a': FragWrapA,
+	's': FragWrapS,
+	'c': FragWrapC,
+	'd': FragWrapD,
+	'v': FragWrapV,
+	'j': FragWrapJ,
+	'n': FragWrapN,
+}
+
+// splitFragment splits "name(arg1,arg2)" into its name and top level
+// arguments. args is nil when there are no parentheses.
+func splitFragment(s string) (name string, args []string, err error) {
+	open := strings.IndexByte(s, '(')
+	if open < 0 {
+		if strings.ContainsAny(s, "),") || s == "" {
+			return "", nil, fmt.Errorf("invalid miniscript expression %q", s)
+		}
+		return s, nil, nil
+	}
+	if !strings.HasSuffix(s, ")") {
+		return "", nil, fmt.Errorf("missing closing parenthesis in %q", s)
+	}
+
+	depth, start := 0, open+1
+	body := s[:len(s)-1]
+	for i := open + 1; i < len(body); i++ {
+		switch body[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return "", nil, fmt.Errorf("unbalanced parentheses in %q", s)
+			}
+		case ',':
+			if depth == 0 {
+				args = append(args, body[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return "", nil, fmt.Errorf("unbalanced parentheses in %q", s)
+	}
+	args = append(args, body[start:])
+	for _, arg := range args {
+		if arg == "" {
+			return "", nil, fmt.Errorf("empty argument in %q", s)
+		}
+	}
+	return s[:open], args, nil
+}
+
+func parseMiniscriptNumber(s string) (uint32, error) {
+	if s == "" || s[0] == '+' || (len(s) > 1 && s[0] == '0') {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, errors.New("invalid number " + strconv.Quote(s))
+	}
+	return uint32(n), nil
+}
+
+// String returns the miniscript expression of m with keys in hex, using the
+// pk, pkh, and_n, t:, l: and u: shorthands where they apply.
+func (m *Miniscript) String() string {
+	return m.str(false)
+}
+
+// str formats m. wrapped is set below a wrapper, where a fragment name
+// needs a leading colon.
+func (m *Miniscript) str(wrapped bool) string {
+	prefix := ""
+	if wrapped {
+		prefix = ":"
+	}
+
+	switch m.Fragment {
+	case FragWrapC:
+		sub := m.Subs[0]
+		switch {
+		case sub.Fragment == FragPkK:
+			return prefix + "pk(" + hex.EncodeToString(sub.Keys[0]) + ")"
+		case sub.Fragment == FragPkH && len(sub.Keys) > 0:
+			return prefix + "pkh(" + hex.EncodeToString(sub.Keys[0]) + ")"
+		}
+		return "c" + sub.str(true)
+	case FragWrapA, FragWrapS, FragWrapD, FragWrapV, FragWrapJ, FragWrapN:
+		return m.Fragment.String() + m.Subs[0].str(true)
+	case FragAndV:
+		if m.Subs[1].Fragment == FragJust1 {
+			return "t" + m.Subs[0].str(true)
+		}
+	case FragOrI:
+		if m.Subs[0].Fragment == FragJust0 {
+			return "l" + m.Subs[1].str(true)
+		}
+		if m.Subs[1].Fragment == FragJust0 {
+			return "u" + m.Subs[0].str(true)
+		}
+	}
+
+	args := make([]string, 0, len(m.Subs)+len(m.Keys)+1)
+	switch m.Fragment {
+	case FragJust0, FragJust1:
+		return prefix + m.Fragment.String()
+	case FragPkK, FragPkH:
+		if len(m.Keys) == 0 {
+			return prefix + "expr_raw_pkh(" + hex.EncodeToString(m.Hash) + ")"
+		}
+		args = append(args, hex.EncodeToString(m.Keys[0]))
+	case FragOlder, FragAfter:
+		args = append(args, strconv.FormatUint(uint64(m.K), 10))
+	case FragSha256, FragHash256, FragRipemd160, FragHash160:
+		args = append(args, hex.EncodeToString(m.Hash))
+	case FragAndOr:
+		if m.Subs[2].Fragment == FragJust0 {
+			return prefix + "and_n(" + m.Subs[0].str(false) + "," + m.Subs[1].str(false) + ")"
+		}
+	case FragThresh, FragMulti, FragMultiA:
+		args = append(args, strconv.FormatUint(uint64(m.K), 10))
+		for _, key := range m.Keys {
+			args = append(args, hex.EncodeToString(key))
+		}
+	}
+	for _, sub := range m.Subs {
+		args = append(args, sub.str(false))
+	}
+	return prefix + m.Fragment.String() + "(" + strings.Join(args, ",") + ")"
+}
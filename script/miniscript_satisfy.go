@@ -0,0 +1,370 @@
+package script
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"golang.org/x/crypto/ripemd160"
+)
+
+var (
+	// ErrUnsatisfiable is returned when the available signatures, preimages
+	// and timelocks are not enough to satisfy a miniscript.
+	ErrUnsatisfiable = errors.New("miniscript cannot be satisfied with the available data")
+	// ErrMalleableSatisfaction is returned when the only satisfactions found
+	// could be changed by a third party, or need no signature at all.
+	ErrMalleableSatisfaction = errors.New("miniscript has no non-malleable satisfaction")
+)
+
+// Satisfier supplies the data a miniscript witness is made of.
+type Satisfier interface {
+	// Sign returns the signature for pubKey as it goes on the witness stack,
+	// i.e. including the sighash byte where there is one.
+	Sign(pubKey []byte) ([]byte, bool)
+	// PubKeyByHash returns the key whose HASH160 is hash. It is only needed
+	// for pk_h fragments decoded from script.
+	PubKeyByHash(hash []byte) ([]byte, bool)
+	// Preimage returns the 32-byte preimage of hash under the hash function
+	// of frag.
+	Preimage(frag Fragment, hash []byte) ([]byte, bool)
+	// CheckOlder reports whether the spending input satisfies older(n).
+	CheckOlder(n uint32) bool
+	// CheckAfter reports whether the spending transaction satisfies
+	// after(n).
+	CheckAfter(n uint32) bool
+}
+
+// StaticSatisfier is a Satisfier over data collected in advance.
+type StaticSatisfier struct {
+	// Signatures are keyed by the hex public key as it appears in the
+	// script.
+	Signatures map[string][]byte
+	// Preimages are matched against every hash fragment.
+	Preimages [][]byte
+
+	// TxVersion and LockTime are those of the spending transaction and
+	// Sequence that of the spending input.
+	TxVersion int32
+	LockTime  uint32
+	Sequence  uint32
+}
+
+func (s *StaticSatisfier) Sign(pubKey []byte) ([]byte, bool) {
+	sig, ok := s.Signatures[hex.EncodeToString(pubKey)]
+	return sig, ok
+}
+
+func (s *StaticSatisfier) PubKeyByHash(hash []byte) ([]byte, bool) {
+	for k := range s.Signatures {
+		key, err := hex.DecodeString(k)
+		if err == nil && bytes.Equal(btcutil.Hash160(key), hash) {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+func (s *StaticSatisfier) Preimage(frag Fragment, hash []byte) ([]byte, bool) {
+	for _, preimage := range s.Preimages {
+		if len(preimage) == 32 && bytes.Equal(miniscriptHash(frag, preimage), hash) {
+			return preimage, true
+		}
+	}
+	return nil, false
+}
+
+// CheckOlder applies BIP68: the input sequence must enable relative locks
+// and hold a lock of the same unit that is at least n.
+func (s *StaticSatisfier) CheckOlder(n uint32) bool {
+	const mask = wire.SequenceLockTimeIsSeconds | wire.SequenceLockTimeMask
+	if s.TxVersion < 2 || s.Sequence&wire.SequenceLockTimeDisabled != 0 {
+		return false
+	}
+	seq, n := s.Sequence&mask, n&mask
+	if (seq < wire.SequenceLockTimeIsSeconds) != (n < wire.SequenceLockTimeIsSeconds) {
+		return false
+	}
+	return n <= seq
+}
+
+// CheckAfter applies BIP65: the lock time must be of the same unit as n and
+// at least n, and the input must not be final.
+func (s *StaticSatisfier) CheckAfter(n uint32) bool {
+	if s.Sequence == wire.MaxTxInSequenceNum {
+		return false
+	}
+	if (s.LockTime < txscript.LockTimeThreshold) != (n < txscript.LockTimeThreshold) {
+		return false
+	}
+	return n <= s.LockTime
+}
+
+func miniscriptHash(frag Fragment, data []byte) []byte {
+	switch frag {
+	case FragSha256:
+		h := sha256.Sum256(data)
+		return h[:]
+	case FragHash256:
+		return chainhash.DoubleHashB(data)
+	case FragRipemd160:
+		h := ripemd160.New()
+		h.Write(data)
+		return h.Sum(nil)
+	case FragHash160:
+		return btcutil.Hash160(data)
+	}
+	return nil
+}
+
+// witnessStack is a candidate witness for an expression, bottom item first.
+type witnessStack struct {
+	available bool
+	hasSig    bool
+	malleable bool
+	size      int
+	items     [][]byte
+}
+
+func pushItem(item []byte) witnessStack {
+	return witnessStack{available: true, size: len(item) + 1, items: [][]byte{item}}
+}
+
+var (
+	emptyStack   = witnessStack{available: true}
+	invalidStack = witnessStack{}
+)
+
+func zeroStack() witnessStack { return pushItem([]byte{}) }
+
+func oneStack() witnessStack { return pushItem([]byte{1}) }
+
+func (w witnessStack) withSig() witnessStack {
+	w.hasSig = true
+	return w
+}
+
+func (w witnessStack) setMalleable() witnessStack {
+	w.malleable = true
+	return w
+}
+
+func (w witnessStack) availableIf(ok bool) witnessStack {
+	w.available = w.available && ok
+	return w
+}
+
+// and puts b above a: a is consumed by what executes last.
+func (a witnessStack) and(b witnessStack) witnessStack {
+	return witnessStack{
+		available: a.available && b.available,
+		hasSig:    a.hasSig || b.hasSig,
+		malleable: a.malleable || b.malleable,
+		size:      a.size + b.size,
+		items:     append(append(make([][]byte, 0, len(a.items)+len(b.items)), a.items...), b.items...),
+	}
+}
+
+// or picks the better of two alternative stacks. A third party can swap a
+// stack without a signature for any other valid one, so choosing between
+// two signature-free stacks is malleable.
+func (a witnessStack) or(b witnessStack) witnessStack {
+	switch {
+	case !a.available:
+		return b
+	case !b.available:
+		return a
+	case !a.hasSig && b.hasSig:
+		return a
+	case !b.hasSig && a.hasSig:
+		return b
+	case !a.hasSig && !b.hasSig:
+		a.malleable, b.malleable = true, true
+	case b.malleable && !a.malleable:
+		return a
+	case a.malleable && !b.malleable:
+		return b
+	}
+	if a.size <= b.size {
+		return a
+	}
+	return b
+}
+
+// satisfactions are the best satisfying and dissatisfying stacks of an
+// expression.
+type satisfactions struct {
+	sat, dsat witnessStack
+}
+
+// Satisfy returns the smallest non-malleable witness stack satisfying m,
+// bottom item first and without the script itself (and, in tapscript, the
+// control block). Like Bitcoin Core, it only returns witnesses that include
+// a signature.
+func (m *Miniscript) Satisfy(s Satisfier) ([][]byte, error) {
+	res := m.satisfy(s)
+	switch {
+	case !res.sat.available:
+		return nil, ErrUnsatisfiable
+	case res.sat.malleable || !res.sat.hasSig:
+		return nil, ErrMalleableSatisfaction
+	}
+	return res.sat.items, nil
+}
+
+func (m *Miniscript) satisfy(s Satisfier) satisfactions {
+	subs := make([]satisfactions, len(m.Subs))
+	for i, sub := range m.Subs {
+		subs[i] = sub.satisfy(s)
+	}
+	sign := func(key []byte) witnessStack {
+		sig, ok := s.Sign(key)
+		return pushItem(sig).withSig().availableIf(ok)
+	}
+
+	switch m.Fragment {
+	case FragJust0:
+		return satisfactions{sat: invalidStack, dsat: emptyStack}
+	case FragJust1:
+		return satisfactions{sat: emptyStack, dsat: invalidStack}
+	case FragPkK:
+		return satisfactions{sat: sign(m.Keys[0]), dsat: zeroStack()}
+	case FragPkH:
+		var key []byte
+		if len(m.Keys) > 0 {
+			key = m.Keys[0]
+		} else if key, _ = s.PubKeyByHash(m.Hash); key == nil {
+			return satisfactions{}
+		}
+		return satisfactions{
+			sat:  sign(key).and(pushItem(key)),
+			dsat: zeroStack().and(pushItem(key)),
+		}
+	case FragOlder:
+		return satisfactions{sat: emptyStack.availableIf(s.CheckOlder(m.K)), dsat: invalidStack}
+	case FragAfter:
+		return satisfactions{sat: emptyStack.availableIf(s.CheckAfter(m.K)), dsat: invalidStack}
+	case FragSha256, FragHash256, FragRipemd160, FragHash160:
+		preimage, ok := s.Preimage(m.Fragment, m.Hash)
+		return satisfactions{
+			sat:  pushItem(preimage).availableIf(ok),
+			dsat: pushItem(make([]byte, 32)).setMalleable(),
+		}
+	case FragWrapA, FragWrapS, FragWrapC, FragWrapN:
+		return subs[0]
+	case FragWrapD:
+		return satisfactions{sat: subs[0].sat.and(oneStack()), dsat: zeroStack()}
+	case FragWrapJ:
+		x := subs[0]
+		// Any dissatisfaction of X with a non-zero top item would dissatisfy
+		// j:X too, so the zero is malleable unless X cannot be dissatisfied
+		// without a signature.
+		dsat := zeroStack()
+		if x.dsat.available && !x.dsat.hasSig {
+			dsat = dsat.setMalleable()
+		}
+		return satisfactions{sat: x.sat, dsat: dsat}
+	case FragWrapV:
+		return satisfactions{sat: subs[0].sat, dsat: invalidStack}
+	case FragAndV:
+		x, y := subs[0], subs[1]
+		return satisfactions{sat: y.sat.and(x.sat), dsat: y.dsat.and(x.sat)}
+	case FragAndB:
+		x, y := subs[0], subs[1]
+		return satisfactions{
+			sat: y.sat.and(x.sat),
+			dsat: y.dsat.and(x.dsat).
+				or(y.sat.and(x.dsat).setMalleable()).
+				or(y.dsat.and(x.sat).setMalleable()),
+		}
+	case FragOrB:
+		x, z := subs[0], subs[1]
+		return satisfactions{
+			sat: z.dsat.and(x.sat).
+				or(z.sat.and(x.dsat)).
+				or(z.sat.and(x.sat).setMalleable()),
+			dsat: z.dsat.and(x.dsat),
+		}
+	case FragOrC:
+		x, z := subs[0], subs[1]
+		return satisfactions{sat: x.sat.or(z.sat.and(x.dsat)), dsat: invalidStack}
+	case FragOrD:
+		x, z := subs[0], subs[1]
+		return satisfactions{sat: x.sat.or(z.sat.and(x.dsat)), dsat: z.dsat.and(x.dsat)}
+	case FragOrI:
+		x, z := subs[0], subs[1]
+		return satisfactions{
+			sat:  x.sat.and(oneStack()).or(z.sat.and(zeroStack())),
+			dsat: x.dsat.and(oneStack()).or(z.dsat.and(zeroStack())),
+		}
+	case FragAndOr:
+		x, y, z := subs[0], subs[1], subs[2]
+		return satisfactions{
+			sat:  y.sat.and(x.sat).or(z.sat.and(x.dsat)),
+			dsat: y.dsat.and(x.sat).or(z.dsat.and(x.dsat)),
+		}
+	case FragMulti:
+		// sats[j] is the best stack with j signatures among the keys seen so
+		// far. It starts with the extra item OP_CHECKMULTISIG pops.
+		sats := []witnessStack{zeroStack()}
+		for _, key := range m.Keys {
+			sig := sign(key)
+			next := []witnessStack{sats[0]}
+			for j := 1; j < len(sats); j++ {
+				next = append(next, sats[j].or(sats[j-1].and(sig)))
+			}
+			next = append(next, sats[len(sats)-1].and(sig))
+			sats = next
+		}
+		dsat := zeroStack()
+		for i := uint32(0); i < m.K; i++ {
+			dsat = dsat.and(zeroStack())
+		}
+		return satisfactions{sat: sats[m.K], dsat: dsat}
+	case FragMultiA:
+		// The signature for the first key goes on top, so walk the keys
+		// backwards, with an empty item for each key that does not sign.
+		sats := []witnessStack{emptyStack}
+		for i := len(m.Keys) - 1; i >= 0; i-- {
+			sig := sign(m.Keys[i])
+			next := []witnessStack{sats[0].and(zeroStack())}
+			for j := 1; j < len(sats); j++ {
+				next = append(next, sats[j].and(zeroStack()).or(sats[j-1].and(sig)))
+			}
+			next = append(next, sats[len(sats)-1].and(sig))
+			sats = next
+		}
+		return satisfactions{sat: sats[m.K], dsat: sats[0]}
+	case FragThresh:
+		// sats[j] is the best stack satisfying j of the last subexpressions
+		// seen; walking backwards leaves the first one on top.
+		sats := []witnessStack{emptyStack}
+		for i := len(subs) - 1; i >= 0; i-- {
+			res := subs[i]
+			next := []witnessStack{sats[0].and(res.dsat)}
+			for j := 1; j < len(sats); j++ {
+				next = append(next, sats[j].and(res.dsat).or(sats[j-1].and(res.sat)))
+			}
+			next = append(next, sats[len(sats)-1].and(res.sat))
+			sats = next
+		}
+		// Satisfying any other number than k dissatisfies the thresh, but
+		// only the all-dissatisfied stack is not malleable.
+		dsat := invalidStack
+		for i := range sats {
+			if i != 0 && i != int(m.K) {
+				sats[i] = sats[i].setMalleable()
+			}
+			if i != int(m.K) {
+				dsat = dsat.or(sats[i])
+			}
+		}
+		return satisfactions{sat: sats[m.K], dsat: dsat}
+	}
+	return satisfactions{}
+}
@@ -1,162 +1,502 @@
-package script
-
-import (
-	"fmt"
-	"testing"
-	"time"
-
-	"github.com/benma/miniscript-go"
-	"github.com/btcsuite/btcd/btcec/v2"
-	"github.com/btcsuite/btcd/btcutil"
-	"github.com/btcsuite/btcd/txscript"
-	"github.com/stretchr/testify/require"
-)
-
-func TestPkNode(t *testing.T) {
-	for _, test := range []struct {
-		compressed bool
-	}{
-		{true},
-		{false},
-	} {
-		privKey, err := btcec.NewPrivateKey()
-		require.NoError(t, err)
-		var pubKey []byte
-		if test.compressed {
-			pubKey = privKey.PubKey().SerializeCompressed()
-		} else {
-			pubKey = privKey.PubKey().SerializeUncompressed()
-		}
-
-		pkNode := &PkNode{PubKey: pubKey}
-
-		// create script from public key
-		builder := txscript.NewScriptBuilder()
-		pkNode.ToScript(builder)
-		script, err := builder.Script()
-		require.NoError(t, err)
-
-		// recover public key from script
-		pkNodeNew := &PkNode{}
-		err = pkNodeNew.FromScript(script)
-		require.NoError(t, err)
-
-		require.Equal(t, pubKey, pkNodeNew.PubKey)
-	}
-}
-
-func TestPkhNode(t *testing.T) {
-	for _, test := range []struct {
-		compressed bool
-	}{
-		{true},
-		{false},
-	} {
-		privKey, err := btcec.NewPrivateKey()
-		require.NoError(t, err)
-		var pubKeyHash []byte
-		if test.compressed {
-			pubKey := privKey.PubKey().SerializeCompressed()
-			pubKeyHash = btcutil.Hash160(pubKey)
-		} else {
-			pubKey := privKey.PubKey().SerializeUncompressed()
-			pubKeyHash = btcutil.Hash160(pubKey)
-		}
-		pkhNode := &PkhNode{Hash: pubKeyHash}
-
-		// create script from public key
-		builder := txscript.NewScriptBuilder()
-		pkhNode.ToScript(builder)
-		script, err := builder.Script()
-		require.NoError(t, err)
-
-		// recover public key from script
-		pkhNodeNew := &PkhNode{}
-		err = pkhNodeNew.FromScript(script)
-		require.NoError(t, err)
-
-		require.Equal(t, pubKeyHash, pkhNodeNew.Hash)
-	}
-}
-
-func TestAfterNode(t *testing.T) {
-	for _, test := range []struct {
-		time int64
-	}{
-		{850000},                  // block height
-		{time.Now().UTC().Unix()}, // unix timestamp
-		{time.Now().UTC().AddDate(0, 0, -1).Unix()},
-		{time.Now().UTC().AddDate(0, 0, 1).Unix()},
-	} {
-		afterNode := &AfterNode{Time: test.time}
-
-		// create to script
-		builder := txscript.NewScriptBuilder()
-		afterNode.ToScript(builder)
-		script, err := builder.Script()
-		require.NoError(t, err)
-
-		// recover from script
-		afterNodeNew := &AfterNode{}
-		err = afterNodeNew.FromScript(script)
-		require.NoError(t, err)
-
-		require.Equal(t, test.time, afterNodeNew.Time)
-	}
-}
-
-func TestOlderNode(t *testing.T) {
-	for _, test := range []struct {
-		blockHeight int64
-		timeSecond  int64
-	}{
-		{100, 0},  // 100 block height later
-		{0, 2048}, // 2048 seconds later
-	} {
-		olderNode := &OlderNode{}
-		if test.blockHeight > 0 {
-			err := olderNode.SetBlock(test.blockHeight)
-			require.NoError(t, err)
-		} else {
-			err := olderNode.SetTime(test.timeSecond)
-			require.NoError(t, err)
-		}
-
-		// create to script
-		builder := txscript.NewScriptBuilder()
-		olderNode.ToScript(builder)
-		script, err := builder.Script()
-		require.NoError(t, err)
-
-		// recover from script
-		olderNodeNew := &OlderNode{}
-		err = olderNodeNew.FromScript(script)
-		require.NoError(t, err)
-
-		require.Equal(t, olderNode.Time, olderNodeNew.Time)
-	}
-}
-
-func TestMakeMiniscript(t *testing.T) {
-	privKey, err := btcec.NewPrivateKey()
-	require.NoError(t, err)
-	pubKey := privKey.PubKey().SerializeCompressed()
-
-	mini := fmt.Sprintf("pk_h(%x)", pubKey)
-	ast, err := miniscript.Parse(mini)
-	require.NoError(t, err)
-
-	err = ast.ApplyVars(func(identifier string) ([]byte, error) {
-		// Provide the public key hash when requested
-		if identifier == fmt.Sprintf("%x", pubKey) {
-			return pubKey, nil
-		}
-		// Return nil if no matching identifier
-		return nil, fmt.Errorf("unknown identifier: %s", identifier)
-	})
-	require.NoError(t, err)
-
-	script, err := ast.Script()
-	require.NoError(t, err)
-	fmt.Println(script)
-}
+package script
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/benma/miniscript-go"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPkNode(t *testing.T) {
+	for _, test := range []struct {
+		compressed bool
+	}{
+		{true},
+		{false},
+	} {
+		privKey, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+		var pubKey []byte
+		if test.compressed {
+			pubKey = privKey.PubKey().SerializeCompressed()
+		} else {
+			pubKey = privKey.PubKey().SerializeUncompressed()
+		}
+
+		pkNode := &PkNode{PubKey: pubKey}
+
+		// create script from public key
+		builder := txscript.NewScriptBuilder()
+		pkNode.ToScript(builder)
+		script, err := builder.Script()
+		require.NoError(t, err)
+
+		// recover public key from script
+		pkNodeNew := &PkNode{}
+		err = pkNodeNew.FromScript(script)
+		require.NoError(t, err)
+
+		require.Equal(t, pubKey, pkNodeNew.PubKey)
+	}
+}
+
+func TestPkhNode(t *testing.T) {
+	for _, test := range []struct {
+		compressed bool
+	}{
+		{true},
+		{false},
+	} {
+		privKey, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+		var pubKeyHash []byte
+		if test.compressed {
+			pubKey := privKey.PubKey().SerializeCompressed()
+			pubKeyHash = btcutil.Hash160(pubKey)
+		} else {
+			pubKey := privKey.PubKey().SerializeUncompressed()
+			pubKeyHash = btcutil.Hash160(pubKey)
+		}
+		pkhNode := &PkhNode{Hash: pubKeyHash}
+
+		// create script from public key
+		builder := txscript.NewScriptBuilder()
+		pkhNode.ToScript(builder)
+		script, err := builder.Script()
+		require.NoError(t, err)
+
+		// recover public key from script
+		pkhNodeNew := &PkhNode{}
+		err = pkhNodeNew.FromScript(script)
+		require.NoError(t, err)
+
+		require.Equal(t, pubKeyHash, pkhNodeNew.Hash)
+	}
+}
+
+func TestAfterNode(t *testing.T) {
+	for _, test := range []struct {
+		time int64
+	}{
+		{850000},                  // block height
+		{time.Now().UTC().Unix()}, // unix timestamp
+		{time.Now().UTC().AddDate(0, 0, -1).Unix()},
+		{time.Now().UTC().AddDate(0, 0, 1).Unix()},
+	} {
+		afterNode := &AfterNode{Time: test.time}
+
+		// create to script
+		builder := txscript.NewScriptBuilder()
+		afterNode.ToScript(builder)
+		script, err := builder.Script()
+		require.NoError(t, err)
+
+		// recover from script
+		afterNodeNew := &AfterNode{}
+		err = afterNodeNew.FromScript(script)
+		require.NoError(t, err)
+
+		require.Equal(t, test.time, afterNodeNew.Time)
+	}
+}
+
+func TestOlderNode(t *testing.T) {
+	for _, test := range []struct {
+		blockHeight int64
+		timeSecond  int64
+	}{
+		{100, 0},  // 100 block height later
+		{0, 2048}, // 2048 seconds later
+	} {
+		olderNode := &OlderNode{}
+		if test.blockHeight > 0 {
+			err := olderNode.SetBlock(test.blockHeight)
+			require.NoError(t, err)
+		} else {
+			err := olderNode.SetTime(test.timeSecond)
+			require.NoError(t, err)
+		}
+
+		// create to script
+		builder := txscript.NewScriptBuilder()
+		olderNode.ToScript(builder)
+		script, err := builder.Script()
+		require.NoError(t, err)
+
+		// recover from script
+		olderNodeNew := &OlderNode{}
+		err = olderNodeNew.FromScript(script)
+		require.NoError(t, err)
+
+		require.Equal(t, olderNode.Time, olderNodeNew.Time)
+	}
+}
+
+func TestMakeMiniscript(t *testing.T) {
+	privKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	pubKey := privKey.PubKey().SerializeCompressed()
+
+	mini := fmt.Sprintf("pk_h(%x)", pubKey)
+	ast, err := miniscript.Parse(mini)
+	require.NoError(t, err)
+
+	err = ast.ApplyVars(func(identifier string) ([]byte, error) {
+		// Provide the public key hash when requested
+		if identifier == fmt.Sprintf("%x", pubKey) {
+			return pubKey, nil
+		}
+		// Return nil if no matching identifier
+		return nil, fmt.Errorf("unknown identifier: %s", identifier)
+	})
+	require.NoError(t, err)
+
+	script, err := ast.Script()
+	require.NoError(t, err)
+	fmt.Println(script)
+}
+
+// miniscriptKeys returns deterministic keys for the names used in test
+// vectors, compressed or x-only.
+func miniscriptKeys(xonly bool) (map[string]*btcec.PrivateKey, KeyResolver) {
+	privs := map[string]*btcec.PrivateKey{}
+	resolve := func(name string) ([]byte, error) {
+		priv, ok := privs[name]
+		if !ok {
+			seed := sha256.Sum256([]byte(name))
+			priv, _ = btcec.PrivKeyFromBytes(seed[:])
+			privs[name] = priv
+		}
+		if xonly {
+			return schnorr.SerializePubKey(priv.PubKey()), nil
+		}
+		return priv.PubKey().SerializeCompressed(), nil
+	}
+	return privs, resolve
+}
+
+// basicProps keeps the properties the alloy vectors list.
+func basicProps(t MiniscriptType) string {
+	var props []byte
+	for _, c := range "BVKWzondufems" {
+		if t.Has(string(c)) {
+			props = append(props, byte(c))
+		}
+	}
+	slices.Sort(props)
+	return string(props)
+}
+
+func TestParseMiniscript(t *testing.T) {
+	_, resolve := miniscriptKeys(false)
+	for _, test := range []struct {
+		expr  string
+		props string
+	}{
+		{"or_d(or_d(multi(2,A,B,C),or_d(multi(2,D,E,F),multi(2,G,I,J))),multi(2,K,L,M))", "Bdusem"},
+		{"andor(multi(2,A,B,C),or_d(multi(2,D,E,F),sha256(926a54995ca48600920a19bf7bc502ca5f2f7d07e6f804c4f00ebf0325084dbc)),c:pk_h(G))", "Bdusem"},
+		{"andor(c:pk_k(A),after(500000001),j:multi(2,B,C,D))", "Bsdm"},
+		{"jc:and_v(v:after(1),pk_k(A))", "Bndousm"},
+		{"and_b(ndvn:after(500000001),a:multi(2,A,B,C))", "Bndusm"},
+		{"or_i(0,and_v(v:after(1),or_i(0,c:pk_h(A))))", "Bsdum"},
+		{"and_b(multi(2,A,B,C),sjc:pk_k(D))", "Bndusm"},
+		{"and_b(and_v(v:multi(2,A,B,C),multi(2,D,E,F)),a:multi(2,G,I,J))", "Bfnusm"},
+		{"andor(sha256(926a54995ca48600920a19bf7bc502ca5f2f7d07e6f804c4f00ebf0325084dbc),n:after(1),after(1))", "oBf"},
+		{"and_v(or_c(sha256(926a54995ca48600920a19bf7bc502ca5f2f7d07e6f804c4f00ebf0325084dbc),v:sha256(926a54995ca48600920a19bf7bc502ca5f2f7d07e6f804c4f00ebf0325084dbc)),1)", "Bfu"},
+		{"thresh(2,c:pk_k(A),sc:pk_k(B),a:pkh(C))", "Bdemsu"},
+		{"and_v(v:pk(A),older(144))", "Bfmnos"},
+	} {
+		m, err := ParseMiniscript(test.expr, P2WSHContext, resolve)
+		require.NoError(t, err, test.expr)
+		require.Equal(t, basicProps(mst(test.props)), basicProps(m.Type()), test.expr)
+
+		// compare the script with the miniscript-go implementation
+		ast, err := miniscript.Parse(test.expr)
+		require.NoError(t, err, test.expr)
+		require.NoError(t, ast.ApplyVars(func(name string) ([]byte, error) {
+			if len(name) == 64 {
+				return nil, nil
+			}
+			return resolve(name)
+		}))
+		want, err := ast.Script()
+		require.NoError(t, err)
+		script, err := m.Script()
+		require.NoError(t, err)
+		require.Equal(t, want, script, test.expr)
+
+		// the string form and the script decode back to the same script
+		again, err := ParseMiniscript(m.String(), P2WSHContext, nil)
+		require.NoError(t, err, m.String())
+		againScript, err := again.Script()
+		require.NoError(t, err)
+		require.Equal(t, script, againScript)
+
+		decoded, err := DecodeMiniscript(script, P2WSHContext)
+		require.NoError(t, err, test.expr)
+		decodedScript, err := decoded.Script()
+		require.NoError(t, err)
+		require.Equal(t, script, decodedScript, test.expr)
+		require.Equal(t, basicProps(m.Type()), basicProps(decoded.Type()))
+	}
+}
+
+func TestParseMiniscript_Invalid(t *testing.T) {
+	_, resolve := miniscriptKeys(false)
+	for _, expr := range []string{
+		"or_b(or_i(0,sha256(926a54995ca48600920a19bf7bc502ca5f2f7d07e6f804c4f00ebf0325084dbc)),after(1))",
+		"or_b(s:pk_h(A),after(500000001))",
+		"or_d(j:multi(2,A,B,C),pk_k(D))",
+		"dc:sha256(926a54995ca48600920a19bf7bc502ca5f2f7d07e6f804c4f00ebf0325084dbc)",
+		"thresh(2,after(1),after(1),s:pk_k(A))",
+		"j:or_b(pk_h(A),sha256(926a54995ca48600920a19bf7bc502ca5f2f7d07e6f804c4f00ebf0325084dbc))",
+		"pk_k(A)",                 // type K at top level
+		"multi_a(1,A)",            // tapscript only
+		"thresh(3,pk(A),s:pk(B))", // threshold above the number of subexpressions
+		"older(0)",
+		"and_v(v:pk(A),older(144)",
+		"unknown(A)",
+	} {
+		_, err := ParseMiniscript(expr, P2WSHContext, resolve)
+		require.Error(t, err, expr)
+	}
+}
+
+func TestMiniscript_Validate(t *testing.T) {
+	_, resolve := miniscriptKeys(false)
+	for _, test := range []struct {
+		expr string
+		err  string
+	}{
+		{"and_v(v:pk(A),older(144))", ""},
+		{"or_d(pk(A),and_v(v:pkh(B),after(800000)))", ""},
+		{"or_d(or_d(j:multi(2,A,B,C),multi(2,D,E,F)),j:multi(2,G,I,J))", "malleable"},
+		{"andor(j:multi(2,A,B,C),jc:pk_k(D),multi(2,E,F,G))", "malleable"},
+		{"and_v(v:after(1),andor(multi(2,A,B,C),after(500000001),multi(2,D,E,F)))", "mixes"},
+		{"older(144)", "without a signature"},
+		{"and_v(v:pk(A),pk(A))", "duplicate key"},
+	} {
+		m, err := ParseMiniscript(test.expr, P2WSHContext, resolve)
+		require.NoError(t, err, test.expr)
+		err = m.Validate()
+		if test.err == "" {
+			require.NoError(t, err, test.expr)
+		} else {
+			require.ErrorContains(t, err, test.err, test.expr)
+		}
+	}
+}
+
+func TestMiniscript_Tapscript(t *testing.T) {
+	_, resolve := miniscriptKeys(true)
+
+	m, err := ParseMiniscript("and_v(v:multi_a(2,A,B,C),older(10))", TapscriptContext, resolve)
+	require.NoError(t, err)
+	require.NoError(t, m.Validate())
+	script, err := m.Script()
+	require.NoError(t, err)
+
+	decoded, err := DecodeMiniscript(script, TapscriptContext)
+	require.NoError(t, err)
+	require.Equal(t, m.String(), decoded.String())
+
+	// multi is P2WSH only and tapscript keys are x-only
+	_, err = ParseMiniscript("multi(1,A)", TapscriptContext, resolve)
+	require.Error(t, err)
+	_, resolveCompressed := miniscriptKeys(false)
+	_, err = ParseMiniscript("pk(A)", TapscriptContext, resolveCompressed)
+	require.Error(t, err)
+
+	// d: has u only in tapscript, as thresh requires
+	_, err = ParseMiniscript("thresh(2,pk(A),sdv:older(144))", P2WSHContext, resolveCompressed)
+	require.Error(t, err)
+	tap, err := ParseMiniscript("thresh(2,pk(A),sdv:older(144))", TapscriptContext, resolve)
+	require.NoError(t, err)
+	require.True(t, tap.Subs[1].Subs[0].Type().Has("u"))
+}
+
+// spendTx returns a version 2 transaction spending a single prevout.
+func spendTx(sequence, lockTime uint32) *wire.MsgTx {
+	tx := wire.NewMsgTx(2)
+	tx.LockTime = lockTime
+	tx.AddTxIn(&wire.TxIn{PreviousOutPoint: wire.OutPoint{Index: 0}, Sequence: sequence})
+	tx.AddTxOut(&wire.TxOut{Value: 90000, PkScript: []byte{txscript.OP_TRUE}})
+	return tx
+}
+
+func executeWitness(t *testing.T, tx *wire.MsgTx, prevOut *wire.TxOut) error {
+	fetcher := txscript.NewCannedPrevOutputFetcher(prevOut.PkScript, prevOut.Value)
+	vm, err := txscript.NewEngine(prevOut.PkScript, tx, 0, txscript.StandardVerifyFlags,
+		nil, txscript.NewTxSigHashes(tx, fetcher), prevOut.Value, fetcher)
+	require.NoError(t, err)
+	return vm.Execute()
+}
+
+func TestMiniscript_SatisfyP2WSH(t *testing.T) {
+	privs, resolve := miniscriptKeys(false)
+	m, err := ParseMiniscript("or_d(pk(A),and_v(v:pkh(B),older(144)))", P2WSHContext, resolve)
+	require.NoError(t, err)
+	require.NoError(t, m.Validate())
+	witnessScript, err := m.Script()
+	require.NoError(t, err)
+	scriptHash := sha256.Sum256(witnessScript)
+	pkScript, err := txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(scriptHash[:]).Script()
+	require.NoError(t, err)
+	prevOut := wire.NewTxOut(100000, pkScript)
+
+	sign := func(tx *wire.MsgTx, names ...string) *StaticSatisfier {
+		fetcher := txscript.NewCannedPrevOutputFetcher(pkScript, prevOut.Value)
+		sigHashes := txscript.NewTxSigHashes(tx, fetcher)
+		s := &StaticSatisfier{Signatures: map[string][]byte{}, TxVersion: tx.Version, LockTime: tx.LockTime, Sequence: tx.TxIn[0].Sequence}
+		for _, name := range names {
+			sig, err := txscript.RawTxInWitnessSignature(tx, sigHashes, 0, prevOut.Value, witnessScript, txscript.SigHashAll, privs[name])
+			require.NoError(t, err)
+			key, _ := resolve(name)
+			s.Signatures[hex.EncodeToString(key)] = sig
+		}
+		return s
+	}
+
+	// A alone
+	tx := spendTx(wire.MaxTxInSequenceNum, 0)
+	stack, err := m.Satisfy(sign(tx, "A"))
+	require.NoError(t, err)
+	tx.TxIn[0].Witness = append(stack, witnessScript)
+	require.NoError(t, executeWitness(t, tx, prevOut))
+
+	// B before the relative lock expires
+	tx = spendTx(100, 0)
+	_, err = m.Satisfy(sign(tx, "B"))
+	require.ErrorIs(t, err, ErrUnsatisfiable)
+
+	// B after it
+	tx = spendTx(144, 0)
+	stack, err = m.Satisfy(sign(tx, "B"))
+	require.NoError(t, err)
+	tx.TxIn[0].Witness = append(stack, witnessScript)
+	require.NoError(t, executeWitness(t, tx, prevOut))
+
+	// the same through a decoded script, where pkh only has the key hash
+	decoded, err := DecodeMiniscript(witnessScript, P2WSHContext)
+	require.NoError(t, err)
+	stack, err = decoded.Satisfy(sign(tx, "B"))
+	require.NoError(t, err)
+	tx.TxIn[0].Witness = append(stack, witnessScript)
+	require.NoError(t, executeWitness(t, tx, prevOut))
+}
+
+func TestMiniscript_SatisfyHashAndMulti(t *testing.T) {
+	privs, resolve := miniscriptKeys(false)
+	preimage := bytes.Repeat([]byte{7}, 32)
+	digest := sha256.Sum256(preimage)
+	expr := fmt.Sprintf("andor(multi(2,A,B,C),sha256(%x),and_v(v:pk(D),after(900000)))", digest)
+	m, err := ParseMiniscript(expr, P2WSHContext, resolve)
+	require.NoError(t, err)
+	require.NoError(t, m.Validate())
+	witnessScript, err := m.Script()
+	require.NoError(t, err)
+	scriptHash := sha256.Sum256(witnessScript)
+	pkScript, err := txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(scriptHash[:]).Script()
+	require.NoError(t, err)
+	prevOut := wire.NewTxOut(100000, pkScript)
+
+	for _, test := range []struct {
+		signers  []string
+		preimage bool
+		lockTime uint32
+		ok       bool
+	}{
+		{[]string{"A", "C"}, true, 0, true},
+		{[]string{"A", "B", "C"}, true, 0, true},
+		{[]string{"A", "C"}, false, 0, false},
+		{[]string{"A"}, true, 0, false},
+		{[]string{"D"}, false, 900000, true},
+		{[]string{"D"}, false, 899999, false},
+	} {
+		tx := spendTx(0xfffffffe, test.lockTime)
+		fetcher := txscript.NewCannedPrevOutputFetcher(pkScript, prevOut.Value)
+		sigHashes := txscript.NewTxSigHashes(tx, fetcher)
+		s := &StaticSatisfier{Signatures: map[string][]byte{}, TxVersion: tx.Version, LockTime: tx.LockTime, Sequence: tx.TxIn[0].Sequence}
+		for _, name := range test.signers {
+			sig, err := txscript.RawTxInWitnessSignature(tx, sigHashes, 0, prevOut.Value, witnessScript, txscript.SigHashAll, privs[name])
+			require.NoError(t, err)
+			key, _ := resolve(name)
+			s.Signatures[hex.EncodeToString(key)] = sig
+		}
+		if test.preimage {
+			s.Preimages = [][]byte{preimage}
+		}
+
+		stack, err := m.Satisfy(s)
+		if !test.ok {
+			require.Error(t, err, test)
+			continue
+		}
+		require.NoError(t, err, test)
+		tx.TxIn[0].Witness = append(stack, witnessScript)
+		require.NoError(t, executeWitness(t, tx, prevOut), test)
+	}
+}
+
+func TestMiniscript_SatisfyTapscript(t *testing.T) {
+	privs, resolve := miniscriptKeys(true)
+	m, err := ParseMiniscript("or_d(multi_a(2,A,B,C),and_v(v:pk(D),older(12)))", TapscriptContext, resolve)
+	require.NoError(t, err)
+	require.NoError(t, m.Validate())
+	leafScript, err := m.Script()
+	require.NoError(t, err)
+
+	internal, _ := btcec.PrivKeyFromBytes(bytes.Repeat([]byte{1}, 32))
+	leaf := txscript.NewBaseTapLeaf(leafScript)
+	tree := txscript.AssembleTaprootScriptTree(leaf)
+	rootHash := tree.RootNode.TapHash()
+	outputKey := txscript.ComputeTaprootOutputKey(internal.PubKey(), rootHash[:])
+	pkScript, err := txscript.PayToTaprootScript(outputKey)
+	require.NoError(t, err)
+	ctrl := tree.LeafMerkleProofs[0].ToControlBlock(internal.PubKey())
+	controlBlock, err := ctrl.ToBytes()
+	require.NoError(t, err)
+	prevOut := wire.NewTxOut(100000, pkScript)
+
+	for _, test := range []struct {
+		signers  []string
+		sequence uint32
+		ok       bool
+	}{
+		{[]string{"A", "C"}, 0, true},
+		{[]string{"B"}, 0, false},
+		{[]string{"D"}, 12, true},
+		{[]string{"D"}, 11, false},
+	} {
+		tx := spendTx(test.sequence, 0)
+		fetcher := txscript.NewCannedPrevOutputFetcher(pkScript, prevOut.Value)
+		sigHashes := txscript.NewTxSigHashes(tx, fetcher)
+		s := &StaticSatisfier{Signatures: map[string][]byte{}, TxVersion: tx.Version, Sequence: test.sequence}
+		for _, name := range test.signers {
+			sig, err := txscript.RawTxInTapscriptSignature(tx, sigHashes, 0, prevOut.Value, pkScript, leaf, txscript.SigHashDefault, privs[name])
+			require.NoError(t, err)
+			key, _ := resolve(name)
+			s.Signatures[hex.EncodeToString(key)] = sig
+		}
+
+		stack, err := m.Satisfy(s)
+		if !test.ok {
+			require.ErrorIs(t, err, ErrUnsatisfiable, test)
+			continue
+		}
+		require.NoError(t, err, test)
+		tx.TxIn[0].Witness = append(stack, leafScript, controlBlock)
+		require.NoError(t, executeWitness(t, tx, prevOut), test)
+	}
+}
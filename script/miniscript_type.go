@@ -0,0 +1,480 @@
+package script
+
+import (
+	"strings"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// MiniscriptContext is the script context a miniscript is written for. It
+// decides the key format, which multisig fragment is available and the
+// resource limits that apply.
+type MiniscriptContext uint8
+
+const (
+	// P2WSHContext is a segwit v0 witness script: 33-byte compressed keys,
+	// multi() and the 201 non-push opcode limit.
+	P2WSHContext MiniscriptContext = iota
+	// TapscriptContext is a BIP342 leaf script: 32-byte x-only keys and
+	// multi_a().
+	TapscriptContext
+)
+
+func (c MiniscriptContext) String() string {
+	if c == TapscriptContext {
+		return "tapscript"
+	}
+	return "p2wsh"
+}
+
+func (c MiniscriptContext) keyLen() int {
+	if c == TapscriptContext {
+		return 32
+	}
+	return 33
+}
+
+const (
+	// MaxStandardP2WSHScriptSize is the largest witness script relayed by
+	// default.
+	MaxStandardP2WSHScriptSize = 3600
+	// MaxOpsPerScript is the consensus limit of executed non-push opcodes in
+	// a P2WSH script. Tapscript has no such limit.
+	MaxOpsPerScript = 201
+	// MaxPubKeysPerMulti is the key limit of multi().
+	MaxPubKeysPerMulti = 20
+	// MaxPubKeysPerMultiA is the key limit of multi_a().
+	MaxPubKeysPerMultiA = 999
+)
+
+// Fragment identifies a miniscript fragment. Syntactic sugar such as pk(),
+// pkh(), and_n() or the t:, l: and u: wrappers is expanded while parsing and
+// has no fragment of its own.
+type Fragment uint8
+
+const (
+	FragJust0     Fragment = iota // 0
+	FragJust1                     // 1
+	FragPkK                       // pk_k(key)
+	FragPkH                       // pk_h(key)
+	FragOlder                     // older(n)
+	FragAfter                     // after(n)
+	FragSha256                    // sha256(h)
+	FragHash256                   // hash256(h)
+	FragRipemd160                 // ripemd160(h)
+	FragHash160                   // hash160(h)
+	FragWrapA                     // a:X
+	FragWrapS                     // s:X
+	FragWrapC                     // c:X
+	FragWrapD                     // d:X
+	FragWrapV                     // v:X
+	FragWrapJ                     // j:X
+	FragWrapN                     // n:X
+	FragAndV                      // and_v(X,Y)
+	FragAndB                      // and_b(X,Y)
+	FragOrB                       // or_b(X,Z)
+	FragOrC                       // or_c(X,Z)
+	FragOrD                       // or_d(X,Z)
+	FragOrI                       // or_i(X,Z)
+	FragAndOr                     // andor(X,Y,Z)
+	FragThresh                    // thresh(k,X1,...,Xn)
+	FragMulti                     // multi(k,key1,...,keyn)
+	FragMultiA                    // multi_a(k,key1,...,keyn)
+)
+
+var fragmentNames = [...]string{
+	"0", "1", "pk_k", "pk_h", "older", "after",
+	"sha256", "hash256", "ripemd160", "hash160",
+	"a", "s", "c", "d", "v", "j", "n",
+	"and_v", "and_b", "or_b", "or_c", "or_d", "or_i", "andor",
+	"thresh", "multi", "multi_a",
+}
+
+func (f Fragment) String() string {
+	if int(f) < len(fragmentNames) {
+		return fragmentNames[f]
+	}
+	return "unknown"
+}
+
+func (f Fragment) isHash() bool {
+	return f >= FragSha256 && f <= FragHash160
+}
+
+func (f Fragment) hashLen() int {
+	if f == FragRipemd160 || f == FragHash160 {
+		return 20
+	}
+	return 32
+}
+
+// MiniscriptType is the set of type properties of a miniscript expression:
+// exactly one basic type (B, V, K or W), the modifiers z, o, n, d and u, the
+// malleability properties e, f, s and m, and the timelock bookkeeping x, g,
+// h, i, j and k. The letters follow the miniscript specification.
+type MiniscriptType uint32
+
+const miniscriptTypeLetters = "BVKWzondufesmxghijk"
+
+// mst builds a type from its letters.
+func mst(props string) MiniscriptType {
+	var t MiniscriptType
+	for _, c := range props {
+		t |= 1 << strings.IndexRune(miniscriptTypeLetters, c)
+	}
+	return t
+}
+
+// Has reports whether t has every property in props, e.g. t.Has("Bms").
+func (t MiniscriptType) Has(props string) bool {
+	for _, c := range props {
+		if !strings.ContainsRune(miniscriptTypeLetters, c) {
+			return false
+		}
+	}
+	return t.is(mst(props))
+}
+
+func (t MiniscriptType) String() string {
+	var sb strings.Builder
+	for i, c := range miniscriptTypeLetters {
+		if t&(1<<i) != 0 {
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
+
+func (t MiniscriptType) is(props MiniscriptType) bool { return t&props == props }
+
+func (t MiniscriptType) when(cond bool) MiniscriptType {
+	if cond {
+		return t
+	}
+	return 0
+}
+
+// sanitize drops a type that does not have exactly one basic type.
+func (t MiniscriptType) sanitize() MiniscriptType {
+	n := 0
+	for _, b := range "BVKW" {
+		if t.Has(string(b)) {
+			n++
+		}
+	}
+	if n != 1 {
+		return 0
+	}
+	return t
+}
+
+// timelockConflict reports whether x and y combine a height lock with a time
+// lock of the same kind (relative or absolute).
+func timelockConflict(x, y MiniscriptType) bool {
+	return (x.Has("g") && y.Has("h")) || (x.Has("h") && y.Has("g")) ||
+		(x.Has("i") && y.Has("j")) || (x.Has("j") && y.Has("i"))
+}
+
+// computeType derives the type of a fragment from the types of its
+// subexpressions, following the correctness and malleability rules of the
+// miniscript specification. It returns 0 for an ill-typed expression.
+func computeType(ctx MiniscriptContext, frag Fragment, k uint32, subs []MiniscriptType) MiniscriptType {
+	var x, y, z MiniscriptType
+	if len(subs) > 0 {
+		x = subs[0]
+	}
+	if len(subs) > 1 {
+		y = subs[1]
+	}
+	if len(subs) > 2 {
+		z = subs[2]
+	}
+
+	var t MiniscriptType
+	switch frag {
+	case FragPkK:
+		t = mst("Konudemsxk")
+	case FragPkH:
+		t = mst("Knudemsxk")
+	case FragOlder:
+		t = mst("g").when(k&wire.SequenceLockTimeIsSeconds != 0) |
+			mst("h").when(k&wire.SequenceLockTimeIsSeconds == 0) |
+			mst("Bzfmxk")
+	case FragAfter:
+		t = mst("i").when(k >= txscript.LockTimeThreshold) |
+			mst("j").when(k < txscript.LockTimeThreshold) |
+			mst("Bzfmxk")
+	case FragSha256, FragHash256, FragRipemd160, FragHash160:
+		t = mst("Bonudmk")
+	case FragJust1:
+		t = mst("Bzufmxk")
+	case FragJust0:
+		t = mst("Bzudemsxk")
+	case FragWrapA:
+		t = mst("W").when(x.Has("B")) |
+			x&mst("ghijk") |
+			x&mst("udfems") |
+			mst("x")
+	case FragWrapS:
+		t = mst("W").when(x.Has("Bo")) |
+			x&mst("ghijk") |
+			x&mst("udfemsx")
+	case FragWrapC:
+		t = mst("B").when(x.Has("K")) |
+			x&mst("ghijk") |
+			x&mst("ondfem") |
+			mst("us")
+	case FragWrapD:
+		// d: only has u in tapscript, where MINIMALIF is a consensus rule.
+		t = mst("B").when(x.Has("Vz")) |
+			mst("o").when(x.Has("z")) |
+			mst("e").when(x.Has("f")) |
+			x&mst("ghijk") |
+			x&mst("ms") |
+			mst("u").when(ctx == TapscriptContext) |
+			mst("ndx")
+	case FragWrapV:
+		t = mst("V").when(x.Has("B")) |
+			x&mst("ghijk") |
+			x&mst("zonms") |
+			mst("fx")
+	case FragWrapJ:
+		t = mst("B").when(x.Has("Bn")) |
+			mst("e").when(x.Has("f")) |
+			x&mst("ghijk") |
+			x&mst("oums") |
+			mst("ndx")
+	case FragWrapN:
+		t = x&mst("ghijk") |
+			x&mst("Bzondfems") |
+			mst("ux")
+	case FragAndV:
+		t = (y & mst("KVB")).when(x.Has("V")) |
+			x&mst("n") | (y & mst("n")).when(x.Has("z")) |
+			((x | y) & mst("o")).when((x | y).Has("z")) |
+			x&y&mst("dmz") |
+			(x|y)&mst("s") |
+			mst("f").when(y.Has("f") || x.Has("s")) |
+			y&mst("ux") |
+			(x|y)&mst("ghij") |
+			mst("k").when((x&y).Has("k") && !timelockConflict(x, y))
+	case FragAndB:
+		t = (x & mst("B")).when(y.Has("W")) |
+			((x | y) & mst("o")).when((x | y).Has("z")) |
+			x&mst("n") | (y & mst("n")).when(x.Has("z")) |
+			(x & y & mst("e")).when((x & y).Has("s")) |
+			x&y&mst("dzm") |
+			mst("f").when((x&y).Has("f") || x.Has("sf") || y.Has("sf")) |
+			(x|y)&mst("s") |
+			mst("ux") |
+			(x|y)&mst("ghij") |
+			mst("k").when((x&y).Has("k") && !timelockConflict(x, y))
+	case FragOrB:
+		t = mst("B").when(x.Has("Bd") && y.Has("Wd")) |
+			((x | y) & mst("o")).when((x | y).Has("z")) |
+			(x & y & mst("m")).when((x|y).Has("s") && (x&y).Has("e")) |
+			x&y&mst("zse") |
+			mst("dux") |
+			(x|y)&mst("ghij") |
+			x&y&mst("k")
+	case FragOrD:
+		t = (y & mst("B")).when(x.Has("Bdu")) |
+			(x & mst("o")).when(y.Has("z")) |
+			(x & y & mst("m")).when(x.Has("e") && (x|y).Has("s")) |
+			x&y&mst("zs") |
+			y&mst("ufde") |
+			mst("x") |
+			(x|y)&mst("ghij") |
+			x&y&mst("k")
+	case FragOrC:
+		t = (y & mst("V")).when(x.Has("Bdu")) |
+			(x & mst("o")).when(y.Has("z")) |
+			(x & y & mst("m")).when(x.Has("e") && (x|y).Has("s")) |
+			x&y&mst("zs") |
+			mst("fx") |
+			(x|y)&mst("ghij") |
+			x&y&mst("k")
+	case FragOrI:
+		t = x&y&mst("VBKufs") |
+			mst("o").when((x & y).Has("z")) |
+			((x | y) & mst("e")).when((x | y).Has("f")) |
+			(x & y & mst("m")).when((x | y).Has("s")) |
+			(x|y)&mst("d") |
+			mst("x") |
+			(x|y)&mst("ghij") |
+			x&y&mst("k")
+	case FragAndOr:
+		t = (y & z & mst("BKV")).when(x.Has("Bdu")) |
+			x&y&z&mst("z") |
+			((x | (y & z)) & mst("o")).when((x | (y & z)).Has("z")) |
+			y&z&mst("u") |
+			(z & mst("f")).when(x.Has("s") || y.Has("f")) |
+			z&mst("d") |
+			(z & mst("e")).when(x.Has("s") || y.Has("f")) |
+			(x & y & z & mst("m")).when(x.Has("e") && (x|y|z).Has("s")) |
+			z&(x|y)&mst("s") |
+			mst("x") |
+			(x|y|z)&mst("ghij") |
+			mst("k").when((x&y&z).Has("k") && !timelockConflict(x, y))
+	case FragMulti:
+		t = mst("Bnudemsk")
+	case FragMultiA:
+		t = mst("Budemsk")
+	case FragThresh:
+		allE, allM := true, true
+		args, numS := 0, 0
+		acc := mst("k")
+		for i, st := range subs {
+			want := "Wdu"
+			if i == 0 {
+				want = "Bdu"
+			}
+			if !st.Has(want) {
+				return 0
+			}
+			allE = allE && st.Has("e")
+			allM = allM && st.Has("m")
+			if st.Has("s") {
+				numS++
+			}
+			switch {
+			case st.Has("z"):
+			case st.Has("o"):
+				args++
+			default:
+				args += 2
+			}
+			// Mixing only matters when two different subexpressions have to
+			// be satisfied together.
+			keep := (acc & st).Has("k") && (k <= 1 || !timelockConflict(acc, st))
+			acc = acc&mst("ghij") | st&mst("ghij") | mst("k").when(keep)
+		}
+		n := len(subs)
+		t = mst("Bdu") |
+			mst("z").when(args == 0) |
+			mst("o").when(args == 1) |
+			mst("e").when(allE && numS == n) |
+			mst("m").when(allE && allM && numS >= n-int(k)) |
+			mst("s").when(numS >= n-int(k)+1) |
+			acc
+	}
+	return t.sanitize()
+}
+
+// -----------------------------------------------------------------------------
+// opcode count
+// -----------------------------------------------------------------------------
+
+// maxInt is a count that may not exist, e.g. the ops of a satisfaction that
+// cannot happen.
+type maxInt struct {
+	valid bool
+	v     uint32
+}
+
+func someInt(v uint32) maxInt { return maxInt{valid: true, v: v} }
+
+func (a maxInt) plus(b maxInt) maxInt {
+	if !a.valid || !b.valid {
+		return maxInt{}
+	}
+	return someInt(a.v + b.v)
+}
+
+func (a maxInt) or(b maxInt) maxInt {
+	switch {
+	case !a.valid:
+		return b
+	case !b.valid:
+		return a
+	case a.v >= b.v:
+		return a
+	}
+	return b
+}
+
+// opsCount holds the non-push opcodes of an expression: count is the static
+// number in the script and sat/dsat the extra ones executed by
+// OP_CHECKMULTISIG when satisfying or dissatisfying it.
+type opsCount struct {
+	count     uint32
+	sat, dsat maxInt
+}
+
+func calcOps(m *Miniscript) opsCount {
+	var x, y, z opsCount
+	if len(m.Subs) > 0 {
+		x = m.Subs[0].ops
+	}
+	if len(m.Subs) > 1 {
+		y = m.Subs[1].ops
+	}
+	if len(m.Subs) > 2 {
+		z = m.Subs[2].ops
+	}
+
+	switch m.Fragment {
+	case FragJust1:
+		return opsCount{0, someInt(0), maxInt{}}
+	case FragJust0:
+		return opsCount{0, maxInt{}, someInt(0)}
+	case FragPkK:
+		return opsCount{0, someInt(0), someInt(0)}
+	case FragPkH:
+		return opsCount{3, someInt(0), someInt(0)}
+	case FragOlder, FragAfter:
+		return opsCount{1, someInt(0), maxInt{}}
+	case FragSha256, FragHash256, FragRipemd160, FragHash160:
+		return opsCount{4, someInt(0), maxInt{}}
+	case FragWrapA:
+		return opsCount{2 + x.count, x.sat, x.dsat}
+	case FragWrapS, FragWrapC, FragWrapN:
+		return opsCount{1 + x.count, x.sat, x.dsat}
+	case FragWrapD:
+		return opsCount{3 + x.count, x.sat, someInt(0)}
+	case FragWrapJ:
+		return opsCount{4 + x.count, x.sat, someInt(0)}
+	case FragWrapV:
+		count := x.count
+		if m.Subs[0].typ.Has("x") {
+			count++
+		}
+		return opsCount{count, x.sat, maxInt{}}
+	case FragAndV:
+		return opsCount{x.count + y.count, x.sat.plus(y.sat), maxInt{}}
+	case FragAndB:
+		return opsCount{1 + x.count + y.count, x.sat.plus(y.sat), x.dsat.plus(y.dsat)}
+	case FragOrB:
+		return opsCount{1 + x.count + y.count,
+			x.sat.plus(y.dsat).or(x.dsat.plus(y.sat)), x.dsat.plus(y.dsat)}
+	case FragOrD:
+		return opsCount{3 + x.count + y.count, x.sat.or(y.sat.plus(x.dsat)), x.dsat.plus(y.dsat)}
+	case FragOrC:
+		return opsCount{2 + x.count + y.count, x.sat.or(y.sat.plus(x.dsat)), maxInt{}}
+	case FragOrI:
+		return opsCount{3 + x.count + y.count, x.sat.or(y.sat), x.dsat.or(y.dsat)}
+	case FragAndOr:
+		return opsCount{3 + x.count + y.count + z.count,
+			y.sat.plus(x.sat).or(x.dsat.plus(z.sat)), x.dsat.plus(z.dsat)}
+	case FragMulti:
+		n := uint32(len(m.Keys))
+		return opsCount{1, someInt(n), someInt(n)}
+	case FragMultiA:
+		return opsCount{uint32(len(m.Keys)) + 1, someInt(0), someInt(0)}
+	case FragThresh:
+		var count uint32
+		sats := []maxInt{someInt(0)}
+		for _, sub := range m.Subs {
+			count += sub.ops.count + 1
+			next := []maxInt{sats[0].plus(sub.ops.dsat)}
+			for j := 1; j < len(sats); j++ {
+				next = append(next, sats[j].plus(sub.ops.dsat).or(sats[j-1].plus(sub.ops.sat)))
+			}
+			next = append(next, sats[len(sats)-1].plus(sub.ops.sat))
+			sats = next
+		}
+		return opsCount{count, sats[m.K], sats[0]}
+	}
+	return opsCount{}
+}
@@ -1,59 +1,196 @@
-package script
-
-import (
-	"github.com/btcsuite/btcd/btcutil"
-	"github.com/btcsuite/btcd/txscript"
-
-	"github.com/gosuda/btctxbuilder/types"
-)
-
-func EncodeMultiSigScript(network types.Network, pubKeys [][]byte, nRequired int) ([]byte, error) {
-	if nRequired <= 1 {
-		nRequired = len(pubKeys) - 1
-	}
-
-	addrPubKeys := make([]*btcutil.AddressPubKey, 0, len(pubKeys))
-	for _, pubKey := range pubKeys {
-		addrPubkey, err := btcutil.NewAddressPubKey(pubKey, types.GetParams(network))
-		if err != nil {
-			return nil, err
-		}
-		addrPubKeys = append(addrPubKeys, addrPubkey)
-	}
-
-	script, err := txscript.MultiSigScript(addrPubKeys, nRequired)
-	if err != nil {
-		return nil, err
-	}
-	return script, nil
-}
-
-const (
-	ScriptVersion = 0
-)
-
-func DecodeMultiSigScript(script []byte) ([][]byte, error) {
-	tokenizer := txscript.MakeScriptTokenizer(ScriptVersion, script)
-
-	var pubkeys [][]byte = make([][]byte, 0, 16)
-	var nRequired int = 0
-	for tokenizer.Next() {
-		op := tokenizer.Opcode()
-		data := tokenizer.Data()
-
-		// parse nRequired opcode for multisig
-		if nRequired == 0 && op >= txscript.OP_1 && op <= txscript.OP_16 {
-			nRequired = int(op - txscript.OP_1 + 1)
-			continue
-		}
-
-		if len(data) > 0 && (op == txscript.OP_DATA_33 || op == txscript.OP_DATA_65) {
-			pubkeys = append(pubkeys, data)
-		}
-	}
-	if err := tokenizer.Err(); err != nil {
-		return nil, err
-	}
-
-	return pubkeys, nil
-}
+package script
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
+
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+// EncodeMultiSigScript returns the m-of-n script
+//
+//	<m> <key_1> ... <key_n> <n> OP_CHECKMULTISIG
+//
+// with the keys in the order given.
+func EncodeMultiSigScript(network types.Network, pubKeys [][]byte, nRequired int) ([]byte, error) {
+	if len(pubKeys) == 0 || len(pubKeys) > MaxPubKeysPerMulti {
+		return nil, fmt.Errorf("multisig needs 1 to %d keys, got %d", MaxPubKeysPerMulti, len(pubKeys))
+	}
+	if nRequired < 1 || nRequired > len(pubKeys) {
+		return nil, fmt.Errorf("invalid threshold %d for %d keys", nRequired, len(pubKeys))
+	}
+
+	addrPubKeys := make([]*btcutil.AddressPubKey, 0, len(pubKeys))
+	for _, pubKey := range pubKeys {
+		addrPubkey, err := btcutil.NewAddressPubKey(pubKey, types.GetParams(network))
+		if err != nil {
+			return nil, err
+		}
+		addrPubKeys = append(addrPubKeys, addrPubkey)
+	}
+
+	script, err := txscript.MultiSigScript(addrPubKeys, nRequired)
+	if err != nil {
+		return nil, err
+	}
+	return script, nil
+}
+
+// EncodeSortedMultiSigScript is EncodeMultiSigScript with the keys sorted
+// as BIP67 requires, so every cosigner derives the same script whatever
+// order the keys were exchanged in.
+func EncodeSortedMultiSigScript(network types.Network, pubKeys [][]byte, nRequired int) ([]byte, error) {
+	sorted, err := SortPubKeys(pubKeys)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeMultiSigScript(network, sorted, nRequired)
+}
+
+// SortPubKeys returns a copy of pubKeys in BIP67 order, lexicographic over
+// the compressed encoding. BIP67 only allows compressed keys.
+func SortPubKeys(pubKeys [][]byte) ([][]byte, error) {
+	for i, key := range pubKeys {
+		if len(key) != btcec.PubKeyBytesLenCompressed {
+			return nil, fmt.Errorf("key %d is not compressed", i)
+		}
+	}
+	sorted := slices.Clone(pubKeys)
+	slices.SortFunc(sorted, bytes.Compare)
+	return sorted, nil
+}
+
+const (
+	ScriptVersion = 0
+)
+
+// DecodeMultiSigScript extracts the keys of a script made by
+// EncodeMultiSigScript. ParseMultiSigScript returns the threshold too.
+func DecodeMultiSigScript(script []byte) ([][]byte, error) {
+	pubKeys, _, err := ParseMultiSigScript(script)
+	return pubKeys, err
+}
+
+// ParseMultiSigScript extracts the keys and the threshold of a script made
+// by EncodeMultiSigScript.
+func ParseMultiSigScript(script []byte) (pubKeys [][]byte, nRequired int, err error) {
+	type token struct {
+		op   byte
+		data []byte
+	}
+	var tokens []token
+	tokenizer := txscript.MakeScriptTokenizer(ScriptVersion, script)
+	for tokenizer.Next() {
+		tokens = append(tokens, token{tokenizer.Opcode(), tokenizer.Data()})
+	}
+	if err := tokenizer.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	invalid := errors.New("not a multisig script")
+	if len(tokens) < 4 || tokens[len(tokens)-1].op != txscript.OP_CHECKMULTISIG {
+		return nil, 0, invalid
+	}
+	// counts up to 16 are small integer opcodes, above minimal pushes of
+	// script numbers
+	number := func(t token) int {
+		if t.op == txscript.OP_0 {
+			return 0
+		}
+		if t.op >= txscript.OP_1 && t.op <= txscript.OP_16 {
+			return int(t.op-txscript.OP_1) + 1
+		}
+		if t.op > txscript.OP_PUSHDATA4 {
+			return -1
+		}
+		n, err := txscript.MakeScriptNum(t.data, true, 4)
+		if err != nil {
+			return -1
+		}
+		return int(n)
+	}
+	nRequired = number(tokens[0])
+	nKeys := number(tokens[len(tokens)-2])
+	if nKeys != len(tokens)-3 {
+		return nil, 0, invalid
+	}
+	for _, t := range tokens[1 : len(tokens)-2] {
+		if t.op != txscript.OP_DATA_33 && t.op != txscript.OP_DATA_65 {
+			return nil, 0, invalid
+		}
+		pubKeys = append(pubKeys, t.data)
+	}
+	if nRequired < 1 || nRequired > nKeys {
+		return nil, 0, fmt.Errorf("invalid threshold %d for %d keys", nRequired, nKeys)
+	}
+	return pubKeys, nRequired, nil
+}
+
+// MultiSigOutput is a legacy multisig output together with the scripts
+// needed to spend it.
+type MultiSigOutput struct {
+	Address  btcutil.Address
+	PkScript []byte
+	AddrType types.AddrType
+
+	// MultiSigScript is the script executed on spending: the redeem script
+	// of P2SH, or the witness script of P2WSH, nested or not.
+	MultiSigScript []byte
+	// RedeemScript is the witness program of a nested P2WSH output.
+	RedeemScript []byte
+}
+
+// NewMultiSig wraps multiSigScript, from EncodeMultiSigScript or
+// EncodeSortedMultiSigScript, in an output of addrType: P2SH, P2WSH or
+// P2WSH_NESTED.
+func NewMultiSig(network types.Network, addrType types.AddrType, multiSigScript []byte) (*MultiSigOutput, error) {
+	if _, _, err := ParseMultiSigScript(multiSigScript); err != nil {
+		return nil, err
+	}
+	params := types.GetParams(network)
+	out := &MultiSigOutput{AddrType: addrType, MultiSigScript: multiSigScript}
+
+	var err error
+	switch addrType {
+	case types.P2SH:
+		if len(multiSigScript) > txscript.MaxScriptElementSize {
+			return nil, fmt.Errorf("redeem script of %d bytes exceeds %d", len(multiSigScript), txscript.MaxScriptElementSize)
+		}
+		out.Address, err = btcutil.NewAddressScriptHash(multiSigScript, params)
+	case types.P2WSH:
+		hash := sha256.Sum256(multiSigScript)
+		out.Address, err = btcutil.NewAddressWitnessScriptHash(hash[:], params)
+	case types.P2WSH_NESTED:
+		hash := sha256.Sum256(multiSigScript)
+		out.RedeemScript, err = txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(hash[:]).Script()
+		if err != nil {
+			return nil, err
+		}
+		out.Address, err = btcutil.NewAddressScriptHash(out.RedeemScript, params)
+	default:
+		return nil, fmt.Errorf("multisig can't be wrapped in %s", addrType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	out.PkScript, err = txscript.PayToAddrScript(out.Address)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SpendInfo returns the spend info of the output.
+func (o *MultiSigOutput) SpendInfo() *types.SpendInfo {
+	if o.AddrType == types.P2SH {
+		return &types.SpendInfo{RedeemScript: o.MultiSigScript}
+	}
+	return &types.SpendInfo{RedeemScript: o.RedeemScript, WitnessScript: o.MultiSigScript}
+}
@@ -0,0 +1,132 @@
+package script
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+func TestEncodeMultiSigScript(t *testing.T) {
+	_, resolve := miniscriptKeys(false)
+	a, err := resolve("A")
+	require.NoError(t, err)
+	b, err := resolve("B")
+	require.NoError(t, err)
+
+	// 1-of-2 used to be turned into n-1
+	s, err := EncodeMultiSigScript(types.BTC, [][]byte{a, b}, 1)
+	require.NoError(t, err)
+	keys, nRequired, err := ParseMultiSigScript(s)
+	require.NoError(t, err)
+	require.Equal(t, 1, nRequired)
+	require.Equal(t, [][]byte{a, b}, keys)
+
+	for _, n := range []int{0, 3, -1} {
+		_, err = EncodeMultiSigScript(types.BTC, [][]byte{a, b}, n)
+		require.Error(t, err)
+	}
+
+	_, _, err = ParseMultiSigScript(append([]byte{txscript.OP_1}, s[1:len(s)-2]...))
+	require.Error(t, err)
+	p2pkh, err := hex.DecodeString("76a914000000000000000000000000000000000000000088ac")
+	require.NoError(t, err)
+	_, _, err = ParseMultiSigScript(p2pkh)
+	require.Error(t, err)
+}
+
+func TestEncodeMultiSigScript_MoreThan16Keys(t *testing.T) {
+	for _, n := range []int{17, 20} {
+		var keys [][]byte
+		for i := range n {
+			priv, _ := btcec.PrivKeyFromBytes([]byte{byte(i + 1)})
+			keys = append(keys, priv.PubKey().SerializeCompressed())
+		}
+		// the counts are pushed as script numbers
+		s, err := EncodeMultiSigScript(types.BTC, keys, 17)
+		require.NoError(t, err)
+		require.Equal(t, []byte{txscript.OP_DATA_1, 17}, s[:2])
+		require.Equal(t, []byte{txscript.OP_DATA_1, byte(n), txscript.OP_CHECKMULTISIG}, s[len(s)-3:])
+
+		decoded, nRequired, err := ParseMultiSigScript(s)
+		require.NoError(t, err)
+		require.Equal(t, 17, nRequired)
+		require.Equal(t, keys, decoded)
+		decoded, err = DecodeMultiSigScript(s)
+		require.NoError(t, err)
+		require.Equal(t, keys, decoded)
+
+		out, err := NewMultiSig(types.BTC, types.P2WSH, s)
+		require.NoError(t, err)
+		require.Equal(t, s, out.SpendInfo().WitnessScript)
+		// too large for a redeem script
+		_, err = NewMultiSig(types.BTC, types.P2SH, s)
+		require.Error(t, err)
+	}
+}
+
+func TestEncodeSortedMultiSigScript(t *testing.T) {
+	// BIP67 test vector 1
+	k1, _ := hex.DecodeString("02ff12471208c14bd580709cb2358d98975247d8765f92bc25eab3b2763ed605f8")
+	k2, _ := hex.DecodeString("02fe6f0a5a297eb38c391581c4413e084773ea23954d93f7753db7dc0adc188b2f")
+	for _, keys := range [][][]byte{{k1, k2}, {k2, k1}} {
+		s, err := EncodeSortedMultiSigScript(types.BTC, keys, 2)
+		require.NoError(t, err)
+		require.Equal(t, "522102fe6f0a5a297eb38c391581c4413e084773ea23954d93f7753db7dc0adc188b2f2102ff12471208c14bd580709cb2358d98975247d8765f92bc25eab3b2763ed605f852ae", hex.EncodeToString(s))
+
+		out, err := NewMultiSig(types.BTC, types.P2SH, s)
+		require.NoError(t, err)
+		require.Equal(t, "39bgKC7RFbpoCRbtD5KEdkYKtNyhpsNa3Z", out.Address.EncodeAddress())
+	}
+	// the input order is left alone
+	keys := [][]byte{k1, k2}
+	sorted, err := SortPubKeys(keys)
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{k2, k1}, sorted)
+	require.Equal(t, [][]byte{k1, k2}, keys)
+
+	uncompressed, _ := hex.DecodeString("04" + "ff12471208c14bd580709cb2358d98975247d8765f92bc25eab3b2763ed605f8" + "ff12471208c14bd580709cb2358d98975247d8765f92bc25eab3b2763ed605f8")
+	_, err = SortPubKeys([][]byte{k1, uncompressed})
+	require.Error(t, err)
+}
+
+func TestNewMultiSig(t *testing.T) {
+	_, resolve := miniscriptKeys(false)
+	a, err := resolve("A")
+	require.NoError(t, err)
+	b, err := resolve("B")
+	require.NoError(t, err)
+	s, err := EncodeSortedMultiSigScript(types.BTC_Signet, [][]byte{a, b}, 2)
+	require.NoError(t, err)
+
+	for addrType, class := range map[types.AddrType]txscript.ScriptClass{
+		types.P2SH:         txscript.ScriptHashTy,
+		types.P2WSH:        txscript.WitnessV0ScriptHashTy,
+		types.P2WSH_NESTED: txscript.ScriptHashTy,
+	} {
+		out, err := NewMultiSig(types.BTC_Signet, addrType, s)
+		require.NoError(t, err)
+		require.Equal(t, class, txscript.GetScriptClass(out.PkScript))
+		spend := out.SpendInfo()
+		switch addrType {
+		case types.P2SH:
+			require.Equal(t, s, spend.RedeemScript)
+			require.Nil(t, spend.WitnessScript)
+		case types.P2WSH:
+			require.Nil(t, spend.RedeemScript)
+			require.Equal(t, s, spend.WitnessScript)
+		case types.P2WSH_NESTED:
+			require.True(t, txscript.IsPayToWitnessScriptHash(spend.RedeemScript))
+			require.Equal(t, s, spend.WitnessScript)
+		}
+	}
+
+	_, err = NewMultiSig(types.BTC_Signet, types.P2TR, s)
+	require.Error(t, err)
+	_, err = NewMultiSig(types.BTC_Signet, types.P2SH, []byte{txscript.OP_TRUE})
+	require.Error(t, err)
+}
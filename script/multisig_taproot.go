@@ -1,48 +1,268 @@
-package script
-
-/*
-// EncodeTaprootMultiSigScript creates a Taproot address for a multisig setup.
-func EncodeTaprootMultiSigScript(network types.Network, pubKeys [][]byte, nRequired int) ([]byte, error) {
-	if len(pubKeys) < nRequired {
-		return nil, errors.New("number of required signatures cannot exceed number of public keys")
-	}
-
-	tapLeafScripts := make([]txscript.TapLeaf, len(pubKeys))
-	for i, pubKey := range pubKeys {
-		tapLeafScripts[i] = txscript.NewTapLeaf(txscript.BaseLeafVersion, pubKey)
-	}
-
-	// Build the Taproot script tree
-	taprootTree := txscript.AssembleTaprootScriptTree(tapLeafScripts...)
-
-	// Compute Taproot output key
-	outputKey := taprootTree.RootNode.TapHash()
-	_ = outputKey
-	return nil, nil
-	// Return the witness program (segwit v1)
-	// return txscript.NewScriptBuilder().AddData(outputKey).Script()
-}
-
-// DecodeTaprootMultiSigScript decodes the Taproot multisig script and extracts keys.
-func DecodeTaprootMultiSigScript(script []byte) ([][]byte, error) {
-	// Check if script is a valid witness program
-	if len(script) < 2 || script[0] != txscript.OP_0 {
-		return nil, errors.New("invalid Taproot script")
-	}
-
-	// Parse the Taproot output key
-	outputKey := script[1:]
-	_, tapTree, err := txscript.ParseTaprootOutputKey(outputKey)
-	if err != nil {
-		return nil, err
-	}
-
-	// Extract public keys from the TapTree leaves
-	var pubKeys [][]byte
-	for _, leaf := range tapTree.Leaves {
-		pubKeys = append(pubKeys, leaf.Script) // Each leaf script holds the public key
-	}
-
-	return pubKeys, nil
-}
-*/
+package script
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/txscript"
+
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+// TaprootMultiSigLayout selects how a k-of-n policy is laid out in the
+// script tree.
+type TaprootMultiSigLayout int
+
+const (
+	// TaprootMultiSigCheckSigAdd puts the policy in a single
+	// OP_CHECKSIGADD leaf that reveals all n keys when spent.
+	TaprootMultiSigCheckSigAdd TaprootMultiSigLayout = iota
+	// TaprootMultiSigCombinations uses one k-of-k leaf per combination of
+	// signers. A spend reveals only the keys that signed and carries no
+	// empty signatures, at the cost of C(n,k) leaves, so it suits small k.
+	TaprootMultiSigCombinations
+)
+
+// maxTaprootMultiSigLeaves bounds the number of combinations generated for
+// TaprootMultiSigCombinations.
+const maxTaprootMultiSigLeaves = 1000
+
+// EncodeTaprootMultiSigScript returns the k-of-n tapscript leaf
+//
+//	<key_1> OP_CHECKSIG <key_2> OP_CHECKSIGADD ... <key_n> OP_CHECKSIGADD <k> OP_NUMEQUAL
+//
+// Keys may be x-only or compressed and keep the order given.
+func EncodeTaprootMultiSigScript(pubKeys [][]byte, nRequired int) ([]byte, error) {
+	keys, err := taprootMultiSigKeys(pubKeys, nRequired)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := txscript.NewScriptBuilder()
+	for i, key := range keys {
+		builder.AddData(key)
+		if i == 0 {
+			builder.AddOp(txscript.OP_CHECKSIG)
+		} else {
+			builder.AddOp(txscript.OP_CHECKSIGADD)
+		}
+	}
+	return builder.AddInt64(int64(nRequired)).AddOp(txscript.OP_NUMEQUAL).Script()
+}
+
+// encodeTaprootAllSigScript returns the k-of-k leaf
+// <key_1> OP_CHECKSIGVERIFY ... <key_k> OP_CHECKSIG.
+func encodeTaprootAllSigScript(keys [][]byte) ([]byte, error) {
+	builder := txscript.NewScriptBuilder()
+	for i, key := range keys {
+		builder.AddData(key)
+		if i < len(keys)-1 {
+			builder.AddOp(txscript.OP_CHECKSIGVERIFY)
+		} else {
+			builder.AddOp(txscript.OP_CHECKSIG)
+		}
+	}
+	return builder.Script()
+}
+
+// DecodeTaprootMultiSigScript extracts the x-only keys and the threshold of
+// a multisig leaf script, either the OP_CHECKSIGADD form or a k-of-k leaf
+// of TaprootMultiSigCombinations.
+//
+// The keys of a taproot output cannot be recovered from its pkScript
+// (OP_1 <output key>), only from the leaf scripts it commits to.
+func DecodeTaprootMultiSigScript(script []byte) (pubKeys [][]byte, nRequired int, err error) {
+	if txscript.IsPayToTaproot(script) {
+		return nil, 0, errors.New("script is a taproot output, decode one of its leaf scripts instead")
+	}
+
+	type token struct {
+		op   byte
+		data []byte
+	}
+	var tokens []token
+	tokenizer := txscript.MakeScriptTokenizer(ScriptVersion, script)
+	for tokenizer.Next() {
+		tokens = append(tokens, token{tokenizer.Opcode(), tokenizer.Data()})
+	}
+	if err := tokenizer.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	invalid := errors.New("not a taproot multisig script")
+	var n int
+	for n = 0; 2*n+1 < len(tokens) && tokens[2*n].op == txscript.OP_DATA_32; n++ {
+		pubKeys = append(pubKeys, tokens[2*n].data)
+	}
+	if n == 0 {
+		return nil, 0, invalid
+	}
+
+	switch len(tokens) {
+	case 2 * n:
+		// <key> OP_CHECKSIGVERIFY ... <key> OP_CHECKSIG
+		for i := 0; i < n; i++ {
+			want := byte(txscript.OP_CHECKSIGVERIFY)
+			if i == n-1 {
+				want = txscript.OP_CHECKSIG
+			}
+			if tokens[2*i+1].op != want {
+				return nil, 0, invalid
+			}
+		}
+		return pubKeys, n, nil
+
+	case 2*n + 2:
+		// <key> OP_CHECKSIG <key> OP_CHECKSIGADD ... <k> OP_NUMEQUAL
+		for i := 0; i < n; i++ {
+			want := byte(txscript.OP_CHECKSIGADD)
+			if i == 0 {
+				want = txscript.OP_CHECKSIG
+			}
+			if tokens[2*i+1].op != want {
+				return nil, 0, invalid
+			}
+		}
+		if tokens[2*n+1].op != txscript.OP_NUMEQUAL {
+			return nil, 0, invalid
+		}
+		switch k := tokens[2*n]; {
+		case k.op >= txscript.OP_1 && k.op <= txscript.OP_16:
+			nRequired = int(k.op-txscript.OP_1) + 1
+		case k.op >= txscript.OP_DATA_1 && k.op <= txscript.OP_DATA_2:
+			num, err := txscript.MakeScriptNum(k.data, true, 2)
+			if err != nil {
+				return nil, 0, err
+			}
+			nRequired = int(num.Int32())
+		default:
+			return nil, 0, invalid
+		}
+		if nRequired < 1 || nRequired > n {
+			return nil, 0, fmt.Errorf("invalid threshold %d for %d keys", nRequired, n)
+		}
+		return pubKeys, nRequired, nil
+	}
+	return nil, 0, invalid
+}
+
+// TaprootMultiSigTree returns the script tree of a k-of-n policy.
+func TaprootMultiSigTree(pubKeys [][]byte, nRequired int, layout TaprootMultiSigLayout) (*TapTree, error) {
+	keys, err := taprootMultiSigKeys(pubKeys, nRequired)
+	if err != nil {
+		return nil, err
+	}
+
+	switch layout {
+	case TaprootMultiSigCheckSigAdd:
+		leaf, err := EncodeTaprootMultiSigScript(keys, nRequired)
+		if err != nil {
+			return nil, err
+		}
+		return NewTapTree(TapLeaf{Script: leaf})
+
+	case TaprootMultiSigCombinations:
+		if count := binomial(len(keys), nRequired); count > maxTaprootMultiSigLeaves {
+			return nil, fmt.Errorf("%d-of-%d needs %d leaves, more than %d", nRequired, len(keys), count, maxTaprootMultiSigLeaves)
+		}
+		var leaves []TapLeaf
+		var combErr error
+		combinations(len(keys), nRequired, func(idx []int) {
+			signers := make([][]byte, len(idx))
+			for i, j := range idx {
+				signers[i] = keys[j]
+			}
+			leaf, err := encodeTaprootAllSigScript(signers)
+			if err != nil {
+				combErr = err
+				return
+			}
+			leaves = append(leaves, TapLeaf{Script: leaf})
+		})
+		if combErr != nil {
+			return nil, combErr
+		}
+		return NewTapTree(leaves...)
+	}
+	return nil, fmt.Errorf("unknown taproot multisig layout %d", layout)
+}
+
+// NewTaprootMultiSig returns the taproot output of a k-of-n policy on
+// network. A nil internalKey disables the key path with the NUMS key.
+func NewTaprootMultiSig(network types.Network, internalKey []byte, pubKeys [][]byte, nRequired int, layout TaprootMultiSigLayout) (*TaprootOutput, error) {
+	tree, err := TaprootMultiSigTree(pubKeys, nRequired, layout)
+	if err != nil {
+		return nil, err
+	}
+	if internalKey == nil {
+		if internalKey, err = NUMSInternalKey(nil); err != nil {
+			return nil, err
+		}
+	}
+	return tree.Output(internalKey, types.GetParams(network))
+}
+
+// taprootMultiSigKeys validates a k-of-n policy and returns its keys in
+// x-only form.
+func taprootMultiSigKeys(pubKeys [][]byte, nRequired int) ([][]byte, error) {
+	if len(pubKeys) == 0 || len(pubKeys) > MaxPubKeysPerMultiA {
+		return nil, fmt.Errorf("taproot multisig needs 1 to %d keys, got %d", MaxPubKeysPerMultiA, len(pubKeys))
+	}
+	if nRequired < 1 || nRequired > len(pubKeys) {
+		return nil, fmt.Errorf("invalid threshold %d for %d keys", nRequired, len(pubKeys))
+	}
+
+	keys := make([][]byte, len(pubKeys))
+	for i, key := range pubKeys {
+		if len(key) == btcec.PubKeyBytesLenCompressed {
+			key = key[1:]
+		}
+		if _, err := schnorr.ParsePubKey(key); err != nil {
+			return nil, fmt.Errorf("key %d: %w", i, err)
+		}
+		for _, seen := range keys[:i] {
+			if bytes.Equal(seen, key) {
+				return nil, fmt.Errorf("duplicate key %x", key)
+			}
+		}
+		keys[i] = key
+	}
+	return keys, nil
+}
+
+func binomial(n, k int) int {
+	c := 1
+	for i := 0; i < k; i++ {
+		c = c * (n - i) / (i + 1)
+		if c > maxTaprootMultiSigLeaves {
+			return c
+		}
+	}
+	return c
+}
+
+// combinations calls fn with every k-element subset of 0..n-1 in
+// lexicographic order.
+func combinations(n, k int, fn func([]int)) {
+	idx := make([]int, k)
+	for i := range idx {
+		idx[i] = i
+	}
+	for {
+		fn(idx)
+		i := k - 1
+		for i >= 0 && idx[i] == n-k+i {
+			i--
+		}
+		if i < 0 {
+			return
+		}
+		idx[i]++
+		for j := i + 1; j < k; j++ {
+			idx[j] = idx[j-1] + 1
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package script
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+func taprootMultiSigTestKeys(t *testing.T, n int) (names []string, keys [][]byte) {
+	t.Helper()
+	_, resolve := miniscriptKeys(true)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("K%d", i)
+		key, err := resolve(name)
+		require.NoError(t, err)
+		names = append(names, name)
+		keys = append(keys, key)
+	}
+	return names, keys
+}
+
+func TestEncodeTaprootMultiSigScript(t *testing.T) {
+	_, resolve := miniscriptKeys(true)
+	for _, tc := range []struct{ k, n int }{{1, 1}, {2, 3}, {3, 3}, {17, 20}} {
+		t.Run(fmt.Sprintf("%d-of-%d", tc.k, tc.n), func(t *testing.T) {
+			names, keys := taprootMultiSigTestKeys(t, tc.n)
+			leaf, err := EncodeTaprootMultiSigScript(keys, tc.k)
+			require.NoError(t, err)
+
+			// the leaf is the miniscript multi_a fragment
+			m, err := ParseMiniscript(fmt.Sprintf("multi_a(%d,%s)", tc.k, strings.Join(names, ",")), TapscriptContext, resolve)
+			require.NoError(t, err)
+			want, err := m.Script()
+			require.NoError(t, err)
+			require.Equal(t, want, leaf)
+
+			gotKeys, k, err := DecodeTaprootMultiSigScript(leaf)
+			require.NoError(t, err)
+			require.Equal(t, tc.k, k)
+			require.Equal(t, keys, gotKeys)
+		})
+	}
+
+	// compressed keys are stored x-only
+	_, keys := taprootMultiSigTestKeys(t, 2)
+	compressed := [][]byte{append([]byte{0x02}, keys[0]...), keys[1]}
+	leaf, err := EncodeTaprootMultiSigScript(compressed, 1)
+	require.NoError(t, err)
+	gotKeys, _, err := DecodeTaprootMultiSigScript(leaf)
+	require.NoError(t, err)
+	require.Equal(t, keys, gotKeys)
+}
+
+func TestEncodeTaprootMultiSigScript_Invalid(t *testing.T) {
+	_, keys := taprootMultiSigTestKeys(t, 3)
+	for _, tc := range []struct {
+		keys [][]byte
+		k    int
+	}{
+		{nil, 1},
+		{keys, 0},
+		{keys, 4},
+		{[][]byte{keys[0], keys[0]}, 1},
+		{[][]byte{keys[0][:31]}, 1},
+	} {
+		_, err := EncodeTaprootMultiSigScript(tc.keys, tc.k)
+		require.Error(t, err)
+	}
+
+	for _, s := range [][]byte{
+		nil,
+		{txscript.OP_1, txscript.OP_DATA_32},
+		append([]byte{txscript.OP_1, txscript.OP_DATA_32}, keys[0]...),
+		append(append([]byte{txscript.OP_DATA_32}, keys[0]...), txscript.OP_CHECKSIGVERIFY),
+		append(append([]byte{txscript.OP_DATA_32}, keys[0]...), txscript.OP_CHECKSIG, txscript.OP_2, txscript.OP_NUMEQUAL),
+	} {
+		_, _, err := DecodeTaprootMultiSigScript(s)
+		require.Error(t, err, "%x", s)
+	}
+}
+
+func TestTaprootMultiSigTree(t *testing.T) {
+	_, keys := taprootMultiSigTestKeys(t, 4)
+
+	tree, err := TaprootMultiSigTree(keys, 2, TaprootMultiSigCheckSigAdd)
+	require.NoError(t, err)
+	require.Len(t, tree.Leaves, 1)
+
+	tree, err = TaprootMultiSigTree(keys, 2, TaprootMultiSigCombinations)
+	require.NoError(t, err)
+	require.Len(t, tree.Leaves, 6)
+	seen := map[string]bool{}
+	for _, leaf := range tree.Leaves {
+		leafKeys, k, err := DecodeTaprootMultiSigScript(leaf.Script)
+		require.NoError(t, err)
+		require.Equal(t, 2, k)
+		require.Len(t, leafKeys, 2)
+		seen[fmt.Sprintf("%x", leafKeys)] = true
+	}
+	require.Len(t, seen, 6)
+
+	_, keys = taprootMultiSigTestKeys(t, 20)
+	_, err = TaprootMultiSigTree(keys, 10, TaprootMultiSigCombinations)
+	require.Error(t, err)
+}
+
+func TestNewTaprootMultiSig(t *testing.T) {
+	_, keys := taprootMultiSigTestKeys(t, 3)
+	for net, prefix := range map[types.Network]string{
+		types.BTC:               "bc1p",
+		types.BTC_Testnet3:      "tb1p",
+		types.BTC_Signet:        "tb1p",
+		types.BTC_Regressionnet: "bcrt1p",
+	} {
+		out, err := NewTaprootMultiSig(net, nil, keys, 2, TaprootMultiSigCheckSigAdd)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(out.Address.EncodeAddress(), prefix), out.Address.EncodeAddress())
+		nums, err := NUMSInternalKey(nil)
+		require.NoError(t, err)
+		require.Equal(t, nums, out.InternalKey)
+	}
+
+	out, err := NewTaprootMultiSig(types.BTC, keys[0], keys, 2, TaprootMultiSigCombinations)
+	require.NoError(t, err)
+	require.Equal(t, keys[0], out.InternalKey)
+	require.Len(t, out.LeafScripts, 3)
+}
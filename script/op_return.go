@@ -1,15 +1,21 @@
-package script
-
-import "github.com/btcsuite/btcd/txscript"
-
-func OpReturnScript(data []byte) (pkScript []byte, err error) {
-	return txscript.NullDataScript(data)
-}
-
-func RuneStoneScript(data []byte) (pkScript []byte, err error) {
-	return txscript.NewScriptBuilder().
-		AddOp(txscript.OP_RETURN). // OP_RETURN
-		AddOp(txscript.OP_13).     // Runestone Magic Number
-		AddData(data).
-		Script()
-}
+package script
+
+import "github.com/btcsuite/btcd/txscript"
+
+func OpReturnScript(data []byte) (pkScript []byte, err error) {
+	return txscript.NullDataScript(data)
+}
+
+// RuneStoneScript returns OP_RETURN OP_13 followed by data in pushes of at
+// most MAX_CHUNK_SIZE bytes. One byte pushes are never shortened to
+// OP_1..OP_16, which would make the runestone a cenotaph.
+func RuneStoneScript(data []byte) (pkScript []byte, err error) {
+	pkScript = []byte{
+		txscript.OP_RETURN,
+		txscript.OP_13, // Runestone Magic Number
+	}
+	for _, chunk := range chunks(data) {
+		pkScript = pushData(pkScript, chunk)
+	}
+	return pkScript, nil
+}
@@ -1,126 +1,244 @@
-package script
-
-import (
-	"bytes"
-	"encoding/hex"
-	"errors"
-	"strings"
-
-	"github.com/btcsuite/btcd/txscript"
-	"github.com/btcsuite/btcd/wire"
-)
-
-const (
-	MAX_CHUNK_SIZE   = 520
-	ORD_PREFIX       = "ord"
-	ORD_PREFIX_BYTES = "6f7264"
-	DUST_SATOSHI     = 546
-)
-
-func CreateInscriptionScript(pubkey []byte, contentType string, fileBytes []byte, inscriptionAddData []byte) ([]byte, error) {
-	builder := txscript.NewScriptBuilder()
-	builder.AddData(pubkey) //push schnorr pubkey
-	builder.AddOp(txscript.OP_CHECKSIG)
-	// Ordinals script
-	if len(inscriptionAddData) > 0 {
-		builder.AddData(inscriptionAddData)
-		builder.AddOp(txscript.OP_DROP)
-	}
-	builder.AddOp(txscript.OP_FALSE)
-	builder.AddOp(txscript.OP_IF)
-	builder.AddData([]byte(ORD_PREFIX))
-	builder.AddOp(txscript.OP_DATA_1)
-	builder.AddOp(txscript.OP_DATA_1)
-	builder.AddData([]byte(contentType))
-	builder.AddOp(txscript.OP_0)
-	data, err := builder.Script()
-	if err != nil {
-		return nil, err
-	}
-
-	// append file
-	bodySize := len(fileBytes)
-	for i := 0; i < bodySize; i += MAX_CHUNK_SIZE {
-		end := i + MAX_CHUNK_SIZE
-		if end > bodySize {
-			end = bodySize
-		}
-		builder.AddFullData(fileBytes[i:end])
-	}
-	data = append(data, txscript.OP_ENDIF)
-	return data, err
-}
-
-func CreateCommitmentScript(pubkey []byte, commitment []byte) ([]byte, error) {
-	builder := txscript.NewScriptBuilder()
-
-	builder.AddData(pubkey)
-	builder.AddOp(txscript.OP_CHECKSIG)
-	builder.AddOp(txscript.OP_FALSE)
-	builder.AddOp(txscript.OP_IF)
-	builder.AddData(commitment)
-	builder.AddOp(txscript.OP_ENDIF)
-
-	return builder.Script()
-}
-
-func GetOrdinalsContent(tapScript []byte) (mime string, content []byte, err error) {
-	scriptStr, err := txscript.DisasmString(tapScript)
-	if err != nil {
-		return "", nil, err
-	}
-	start := false
-	scriptStrArray := strings.Split(scriptStr, " ")
-	contentHex := ""
-	for i := 0; i < len(scriptStrArray); i++ {
-		if scriptStrArray[i] == ORD_PREFIX_BYTES {
-			start = true
-			mimeBytes, _ := hex.DecodeString(scriptStrArray[i+2])
-			mime = string(mimeBytes)
-			i = i + 4
-		}
-		if i < len(scriptStrArray) {
-			if start {
-				contentHex = contentHex + scriptStrArray[i]
-			}
-			if scriptStrArray[i] == "OP_ENDIF" {
-				break
-			}
-		}
-	}
-	contentBytes, _ := hex.DecodeString(contentHex)
-	return mime, contentBytes, nil
-}
-
-func IsOrdinalsScript(script []byte) bool {
-	if bytes.Contains(script, ordiBytes) && script[len(script)-1] == txscript.OP_ENDIF {
-		return true
-	}
-	return false
-}
-
-func GetInscriptionContent(tx *wire.MsgTx) (contentType string, content []byte, err error) {
-	for _, txIn := range tx.TxIn {
-		if IsTapScript(txIn.Witness) {
-			if IsOrdinalsScript(txIn.Witness[1]) {
-				contentType, data, err := GetOrdinalsContent(txIn.Witness[1])
-				if err != nil {
-					return "", nil, err
-				}
-				return contentType, data, nil
-			}
-		}
-	}
-	return "", nil, errors.New("no ordinals script found")
-}
-
-var ordiBytes []byte
-
-func init() {
-	builder := txscript.NewScriptBuilder()
-	builder.AddOp(txscript.OP_FALSE)
-	builder.AddOp(txscript.OP_IF)
-	builder.AddData([]byte("ord"))
-	builder.AddOp(txscript.OP_DATA_1)
-	ordiBytes, _ = builder.Script()
-}
+package script
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	MAX_CHUNK_SIZE   = 520
+	ORD_PREFIX       = "ord"
+	ORD_PREFIX_BYTES = "6f7264"
+	DUST_SATOSHI     = 546
+)
+
+// Tags of the fields of an inscription envelope. Even tags are
+// mandatory for indexers to understand, odd ones may be ignored.
+const (
+	TagContentType     = 1
+	TagPointer         = 2
+	TagParent          = 3
+	TagMetadata        = 5
+	TagMetaprotocol    = 7
+	TagContentEncoding = 9
+	TagDelegate        = 11
+	TagRune            = 13
+)
+
+// InscriptionID identifies an inscription by its reveal transaction and
+// its index among the inscriptions of that transaction.
+type InscriptionID struct {
+	Txid  chainhash.Hash
+	Index uint32
+}
+
+// ParseInscriptionID parses the "<txid>i<index>" form of an inscription
+// id.
+func ParseInscriptionID(s string) (InscriptionID, error) {
+	txid, index, ok := strings.Cut(s, "i")
+	if !ok {
+		return InscriptionID{}, fmt.Errorf("invalid inscription id %q", s)
+	}
+	hash, err := chainhash.NewHashFromStr(txid)
+	if err != nil || len(txid) != 2*chainhash.HashSize {
+		return InscriptionID{}, fmt.Errorf("invalid inscription id %q", s)
+	}
+	n, err := strconv.ParseUint(index, 10, 32)
+	if err != nil {
+		return InscriptionID{}, fmt.Errorf("invalid inscription id %q: %w", s, err)
+	}
+	return InscriptionID{Txid: *hash, Index: uint32(n)}, nil
+}
+
+func (id InscriptionID) String() string {
+	return fmt.Sprintf("%si%d", id.Txid, id.Index)
+}
+
+// Bytes returns the envelope encoding of id: the txid in serialized byte
+// order followed by the little endian index without trailing zeros.
+func (id InscriptionID) Bytes() []byte {
+	return append(id.Txid[:len(id.Txid):len(id.Txid)], trimLE(uint64(id.Index))...)
+}
+
+// Inscription is the content of one inscription envelope.
+type Inscription struct {
+	ContentType     string
+	ContentEncoding string
+	Body            []byte
+
+	// Pointer is the offset, in sats among the reveal outputs, of the sat
+	// to inscribe instead of the first sat of the input.
+	Pointer *uint64
+	// Parents are the inscriptions this one is a child of. The reveal
+	// transaction must spend them for the link to be valid.
+	Parents  []InscriptionID
+	Delegate *InscriptionID
+	// Metadata is CBOR encoded.
+	Metadata     []byte
+	Metaprotocol string
+	// Rune is the commitment of a rune etched along with the inscription.
+	Rune []byte
+}
+
+// Envelope returns the envelope of the inscription,
+//
+//	OP_FALSE OP_IF "ord" <tag> <value> ... OP_0 <body> OP_ENDIF
+//
+// with values and body split in pushes of at most MAX_CHUNK_SIZE bytes.
+// Pushes are never shortened to OP_1..OP_16, which indexers reject.
+func (ins *Inscription) Envelope() []byte {
+	envelope := []byte{txscript.OP_FALSE, txscript.OP_IF}
+	envelope = pushData(envelope, []byte(ORD_PREFIX))
+	field := func(tag byte, value []byte) {
+		envelope = pushData(envelope, []byte{tag})
+		envelope = pushData(envelope, value)
+	}
+	if ins.ContentType != "" {
+		field(TagContentType, []byte(ins.ContentType))
+	}
+	if ins.Pointer != nil {
+		field(TagPointer, trimLE(*ins.Pointer))
+	}
+	for _, parent := range ins.Parents {
+		field(TagParent, parent.Bytes())
+	}
+	if ins.Delegate != nil {
+		field(TagDelegate, ins.Delegate.Bytes())
+	}
+	for _, chunk := range chunks(ins.Metadata) {
+		field(TagMetadata, chunk)
+	}
+	if ins.Metaprotocol != "" {
+		field(TagMetaprotocol, []byte(ins.Metaprotocol))
+	}
+	if ins.ContentEncoding != "" {
+		field(TagContentEncoding, []byte(ins.ContentEncoding))
+	}
+	if len(ins.Rune) > 0 {
+		field(TagRune, ins.Rune)
+	}
+	if len(ins.Body) > 0 {
+		envelope = append(envelope, txscript.OP_0)
+		for _, chunk := range chunks(ins.Body) {
+			envelope = pushData(envelope, chunk)
+		}
+	}
+	return append(envelope, txscript.OP_ENDIF)
+}
+
+// CreateInscriptionsScript returns the tapscript leaf locking the
+// envelopes of inscriptions, in order, to the x-only pubkey.
+func CreateInscriptionsScript(pubkey []byte, inscriptions []*Inscription) ([]byte, error) {
+	if len(inscriptions) == 0 {
+		return nil, errors.New("no inscriptions")
+	}
+	script, err := txscript.NewScriptBuilder().AddData(pubkey).AddOp(txscript.OP_CHECKSIG).Script()
+	if err != nil {
+		return nil, err
+	}
+	for _, ins := range inscriptions {
+		script = append(script, ins.Envelope()...)
+	}
+	return script, nil
+}
+
+func CreateInscriptionScript(pubkey []byte, contentType string, fileBytes []byte, inscriptionAddData []byte) ([]byte, error) {
+	builder := txscript.NewScriptBuilder()
+	builder.AddData(pubkey) //push schnorr pubkey
+	builder.AddOp(txscript.OP_CHECKSIG)
+	// Ordinals script
+	if len(inscriptionAddData) > 0 {
+		builder.AddData(inscriptionAddData)
+		builder.AddOp(txscript.OP_DROP)
+	}
+	data, err := builder.Script()
+	if err != nil {
+		return nil, err
+	}
+	ins := &Inscription{ContentType: contentType, Body: fileBytes}
+	return append(data, ins.Envelope()...), nil
+}
+
+// pushData appends a push of data using the smallest push opcode, but
+// never OP_1..OP_16.
+func pushData(script []byte, data []byte) []byte {
+	switch n := len(data); {
+	case n == 0:
+		return append(script, txscript.OP_0)
+	case n < txscript.OP_PUSHDATA1:
+		script = append(script, byte(n))
+	case n <= 0xff:
+		script = append(script, txscript.OP_PUSHDATA1, byte(n))
+	default:
+		script = append(script, txscript.OP_PUSHDATA2, byte(n), byte(n>>8))
+	}
+	return append(script, data...)
+}
+
+// chunks splits data in pieces of at most MAX_CHUNK_SIZE bytes.
+func chunks(data []byte) [][]byte {
+	var pieces [][]byte
+	for len(data) > MAX_CHUNK_SIZE {
+		pieces = append(pieces, data[:MAX_CHUNK_SIZE])
+		data = data[MAX_CHUNK_SIZE:]
+	}
+	if len(data) > 0 {
+		pieces = append(pieces, data)
+	}
+	return pieces
+}
+
+// trimLE returns v in little endian without trailing zero bytes.
+func trimLE(v uint64) []byte {
+	b := binary.LittleEndian.AppendUint64(nil, v)
+	return bytes.TrimRight(b, "\x00")
+}
+
+func CreateCommitmentScript(pubkey []byte, commitment []byte) ([]byte, error) {
+	builder := txscript.NewScriptBuilder()
+
+	builder.AddData(pubkey)
+	builder.AddOp(txscript.OP_CHECKSIG)
+	builder.AddOp(txscript.OP_FALSE)
+	builder.AddOp(txscript.OP_IF)
+	builder.AddData(commitment)
+	builder.AddOp(txscript.OP_ENDIF)
+
+	return builder.Script()
+}
+
+// GetOrdinalsContent returns the content type and body of the first
+// inscription envelope in tapScript.
+func GetOrdinalsContent(tapScript []byte) (mime string, content []byte, err error) {
+	envelopes, err := parseEnvelopes(tapScript, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(envelopes) == 0 {
+		return "", nil, errors.New("no inscription envelope found")
+	}
+	return envelopes[0].ContentType, envelopes[0].Body, nil
+}
+
+func IsOrdinalsScript(script []byte) bool {
+	envelopes, err := parseEnvelopes(script, 0)
+	return err == nil && len(envelopes) > 0
+}
+
+// GetInscriptionContent returns the content type and body of the first
+// inscription revealed by tx.
+func GetInscriptionContent(tx *wire.MsgTx) (contentType string, content []byte, err error) {
+	envelopes := ParseEnvelopes(tx)
+	if len(envelopes) == 0 {
+		return "", nil, errors.New("no ordinals script found")
+	}
+	return envelopes[0].ContentType, envelopes[0].Body, nil
+}
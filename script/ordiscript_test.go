@@ -0,0 +1,82 @@
+package script
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInscriptionID(t *testing.T) {
+	const s = "521f8eccffa4c41a3a7728dd012ea5a4a02feed81f41159231251ecf1e5c79dai258"
+	id, err := ParseInscriptionID(s)
+	require.NoError(t, err)
+	require.Equal(t, uint32(258), id.Index)
+	require.Equal(t, s, id.String())
+
+	// serialized txid byte order, then the index without trailing zeros
+	txid, err := hex.DecodeString("da795c1ecf1e25319215411fd8ee2fa0a4a52e01dd28773a1ac4a4ffcc8e1f52")
+	require.NoError(t, err)
+	require.Equal(t, append(txid, 0x02, 0x01), id.Bytes())
+	id.Index = 0
+	require.Equal(t, txid, id.Bytes())
+
+	for _, invalid := range []string{"", "i0", s[:64], s[:64] + "i", s[:64] + "i-1", s[:63] + "i0"} {
+		_, err := ParseInscriptionID(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestInscription_Envelope(t *testing.T) {
+	parent, err := ParseInscriptionID("521f8eccffa4c41a3a7728dd012ea5a4a02feed81f41159231251ecf1e5c79dai0")
+	require.NoError(t, err)
+	pointer := uint64(1)
+	ins := &Inscription{
+		ContentType:     "text/plain",
+		ContentEncoding: "br",
+		Body:            bytes.Repeat([]byte{0x01}, MAX_CHUNK_SIZE+1),
+		Pointer:         &pointer,
+		Parents:         []InscriptionID{parent},
+		Metadata:        []byte{0xa1, 0x61, 0x61, 0x01}, // {"a": 1}
+		Metaprotocol:    "brc-721",
+	}
+
+	want := []byte{0x00, 0x63, 0x03, 'o', 'r', 'd'}
+	want = append(want, 0x01, TagContentType, 0x0a)
+	want = append(want, "text/plain"...)
+	// one byte values are pushed as data, not as OP_1
+	want = append(want, 0x01, TagPointer, 0x01, 0x01)
+	want = append(want, 0x01, TagParent, 0x20)
+	want = append(want, parent.Txid[:]...)
+	want = append(want, 0x01, TagMetadata, 0x04, 0xa1, 0x61, 0x61, 0x01)
+	want = append(want, 0x01, TagMetaprotocol, 0x07)
+	want = append(want, "brc-721"...)
+	want = append(want, 0x01, TagContentEncoding, 0x02, 'b', 'r')
+	want = append(want, 0x00, 0x4d, 0x08, 0x02)
+	want = append(want, ins.Body[:MAX_CHUNK_SIZE]...)
+	want = append(want, 0x01, 0x01, 0x68)
+	require.Equal(t, want, ins.Envelope())
+
+	// a zero pointer is an empty push
+	pointer = 0
+	envelope := (&Inscription{Pointer: &pointer}).Envelope()
+	require.Equal(t, []byte{0x00, 0x63, 0x03, 'o', 'r', 'd', 0x01, TagPointer, 0x00, 0x68}, envelope)
+}
+
+func TestCreateInscriptionsScript(t *testing.T) {
+	pubkey := bytes.Repeat([]byte{0x02}, 32)
+	first := &Inscription{ContentType: "text/plain", Body: []byte("a")}
+	second := &Inscription{ContentType: "text/plain", Body: []byte("b")}
+	leaf, err := CreateInscriptionsScript(pubkey, []*Inscription{first, second})
+	require.NoError(t, err)
+
+	want := append([]byte{0x20}, pubkey...)
+	want = append(want, 0xac)
+	want = append(want, first.Envelope()...)
+	want = append(want, second.Envelope()...)
+	require.Equal(t, want, leaf)
+
+	_, err = CreateInscriptionsScript(pubkey, nil)
+	require.Error(t, err)
+}
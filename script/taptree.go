@@ -0,0 +1,261 @@
+package script
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+// numsKeyX is the x coordinate of the BIP341 point H, the SHA256 of the
+// uncompressed generator. Nobody knows its discrete logarithm, so an
+// output using it as internal key can only be spent through its scripts.
+const numsKeyX = "50929b74c1a04954b78b4b6035e97a5e078a5a0f28ec96d547bfee9ace803ac0"
+
+// NUMSInternalKey returns the x-only internal key of a provably unspendable
+// key path. With a non-nil r the key is H + rG instead, which hides that
+// the key path is disabled from everyone who doesn't know r.
+func NUMSInternalKey(r []byte) ([]byte, error) {
+	raw, _ := hex.DecodeString(numsKeyX)
+	h, err := schnorr.ParsePubKey(raw)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return raw, nil
+	}
+
+	var scalar btcec.ModNScalar
+	if len(r) != 32 || scalar.SetByteSlice(r) || scalar.IsZero() {
+		return nil, errors.New("NUMS tweak must be a non-zero 32-byte scalar")
+	}
+	var hPoint, rG, sum btcec.JacobianPoint
+	h.AsJacobian(&hPoint)
+	btcec.ScalarBaseMultNonConst(&scalar, &rG)
+	btcec.AddNonConst(&hPoint, &rG, &sum)
+	sum.ToAffine()
+	return schnorr.SerializePubKey(btcec.NewPublicKey(&sum.X, &sum.Y)), nil
+}
+
+// TapLeaf is a leaf script of a taproot script tree. Weight is how likely
+// the leaf is to be spent relative to the others: heavier leaves sit closer
+// to the root and get shorter control blocks. A zero weight counts as 1 and
+// a zero LeafVersion means txscript.BaseLeafVersion.
+type TapLeaf struct {
+	Script      []byte
+	Weight      int
+	LeafVersion txscript.TapscriptLeafVersion
+}
+
+func (l TapLeaf) version() txscript.TapscriptLeafVersion {
+	if l.LeafVersion == 0 {
+		return txscript.BaseLeafVersion
+	}
+	return l.LeafVersion
+}
+
+// TapTree is a taproot script tree laid out by Huffman coding of the leaf
+// weights, which minimizes the expected control block size.
+type TapTree struct {
+	Leaves []TapLeaf
+
+	root   chainhash.Hash
+	hashes []chainhash.Hash
+	proofs [][]byte // sibling hashes from each leaf up to the root
+}
+
+// NewTapTree builds the tree for leaves. Leaves of equal weight are paired
+// in the order given, so equal weights give a balanced tree.
+func NewTapTree(leaves ...TapLeaf) (*TapTree, error) {
+	if len(leaves) == 0 {
+		return nil, errors.New("taproot tree needs at least one leaf")
+	}
+
+	t := &TapTree{
+		Leaves: leaves,
+		hashes: make([]chainhash.Hash, len(leaves)),
+		proofs: make([][]byte, len(leaves)),
+	}
+	nodes := make(tapNodeHeap, len(leaves))
+	for i, leaf := range leaves {
+		if leaf.Weight < 0 {
+			return nil, fmt.Errorf("leaf %d has negative weight %d", i, leaf.Weight)
+		}
+		if leaf.version()&^txscript.TaprootLeafMask != 0 {
+			return nil, fmt.Errorf("leaf %d has invalid leaf version %#x", i, leaf.LeafVersion)
+		}
+		t.hashes[i] = txscript.NewTapLeaf(leaf.version(), leaf.Script).TapHash()
+		nodes[i] = &tapNode{weight: max(leaf.Weight, 1), seq: i, hash: t.hashes[i], leaves: []int{i}}
+	}
+	heap.Init(&nodes)
+
+	for seq := len(leaves); nodes.Len() > 1; seq++ {
+		a := heap.Pop(&nodes).(*tapNode)
+		b := heap.Pop(&nodes).(*tapNode)
+		for _, i := range a.leaves {
+			t.proofs[i] = append(t.proofs[i], b.hash[:]...)
+		}
+		for _, i := range b.leaves {
+			t.proofs[i] = append(t.proofs[i], a.hash[:]...)
+		}
+		heap.Push(&nodes, &tapNode{
+			weight: a.weight + b.weight,
+			seq:    seq,
+			hash:   tapBranchHash(a.hash, b.hash),
+			leaves: append(a.leaves, b.leaves...),
+		})
+	}
+	for i, proof := range t.proofs {
+		if len(proof)/chainhash.HashSize > txscript.ControlBlockMaxNodeCount {
+			return nil, fmt.Errorf("leaf %d is deeper than %d", i, txscript.ControlBlockMaxNodeCount)
+		}
+	}
+	t.root = nodes[0].hash
+	return t, nil
+}
+
+// MerkleRoot returns the root hash of the tree.
+func (t *TapTree) MerkleRoot() []byte {
+	return append([]byte(nil), t.root[:]...)
+}
+
+// LeafHash returns the tapleaf hash of leaf i.
+func (t *TapTree) LeafHash(i int) []byte {
+	return append([]byte(nil), t.hashes[i][:]...)
+}
+
+// Depth returns the number of branches between leaf i and the root.
+func (t *TapTree) Depth(i int) int {
+	return len(t.proofs[i]) / chainhash.HashSize
+}
+
+// TaprootOutput is a taproot output committing to a script tree, with
+// everything needed to fill in the PSBT taproot fields of an input spending
+// it.
+type TaprootOutput struct {
+	Address     btcutil.Address
+	PkScript    []byte
+	InternalKey []byte // x-only
+	OutputKey   []byte // x-only
+	MerkleRoot  []byte
+
+	// LeafScripts holds each leaf with its control block, in the order of
+	// TapTree.Leaves.
+	LeafScripts []*psbt.TaprootTapLeafScript
+}
+
+// Output tweaks internalKey, x-only or compressed, with the merkle root of
+// the tree and returns the resulting output for net.
+func (t *TapTree) Output(internalKey []byte, net *chaincfg.Params) (*TaprootOutput, error) {
+	if len(internalKey) == btcec.PubKeyBytesLenCompressed {
+		internalKey = internalKey[1:]
+	}
+	internal, err := schnorr.ParsePubKey(internalKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid internal key: %w", err)
+	}
+
+	outputKey := txscript.ComputeTaprootOutputKey(internal, t.root[:])
+	outputKeyBytes := schnorr.SerializePubKey(outputKey)
+	addr, err := btcutil.NewAddressTaproot(outputKeyBytes, net)
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &TaprootOutput{
+		Address:     addr,
+		PkScript:    pkScript,
+		InternalKey: schnorr.SerializePubKey(internal),
+		OutputKey:   outputKeyBytes,
+		MerkleRoot:  t.MerkleRoot(),
+	}
+	for i, leaf := range t.Leaves {
+		cb := txscript.ControlBlock{
+			InternalKey:     internal,
+			OutputKeyYIsOdd: outputKey.SerializeCompressed()[0] == 0x03,
+			LeafVersion:     leaf.version(),
+			InclusionProof:  t.proofs[i],
+		}
+		cbBytes, err := cb.ToBytes()
+		if err != nil {
+			return nil, err
+		}
+		out.LeafScripts = append(out.LeafScripts, &psbt.TaprootTapLeafScript{
+			ControlBlock: cbBytes,
+			Script:       leaf.Script,
+			LeafVersion:  leaf.version(),
+		})
+	}
+	return out, nil
+}
+
+// ControlBlock returns the serialized control block of leaf i.
+func (o *TaprootOutput) ControlBlock(i int) []byte {
+	return o.LeafScripts[i].ControlBlock
+}
+
+// SpendInfo returns the spend info of the output for the given leaves, or
+// for all of them when none are given.
+func (o *TaprootOutput) SpendInfo(leaves ...int) *types.SpendInfo {
+	spend := &types.SpendInfo{
+		TaprootInternalKey: o.InternalKey,
+		TaprootMerkleRoot:  o.MerkleRoot,
+		TaprootLeafScripts: o.LeafScripts,
+	}
+	if len(leaves) > 0 {
+		spend.TaprootLeafScripts = make([]*psbt.TaprootTapLeafScript, len(leaves))
+		for i, leaf := range leaves {
+			spend.TaprootLeafScripts[i] = o.LeafScripts[leaf]
+		}
+	}
+	return spend
+}
+
+// tapBranchHash hashes two children in lexicographic order as BIP341
+// requires.
+func tapBranchHash(a, b chainhash.Hash) chainhash.Hash {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+	return *chainhash.TaggedHash(chainhash.TagTapBranch, a[:], b[:])
+}
+
+type tapNode struct {
+	weight int
+	seq    int // creation order, breaks ties between equal weights
+	hash   chainhash.Hash
+	leaves []int
+}
+
+type tapNodeHeap []*tapNode
+
+func (h tapNodeHeap) Len() int { return len(h) }
+func (h tapNodeHeap) Less(i, j int) bool {
+	if h[i].weight != h[j].weight {
+		return h[i].weight < h[j].weight
+	}
+	return h[i].seq < h[j].seq
+}
+func (h tapNodeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *tapNodeHeap) Push(x any)   { *h = append(*h, x.(*tapNode)) }
+func (h *tapNodeHeap) Pop() any {
+	old := *h
+	n := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return n
+}
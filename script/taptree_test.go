@@ -0,0 +1,120 @@
+package script
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/stretchr/testify/require"
+)
+
+func tapLeafScripts(t *testing.T, n int) [][]byte {
+	t.Helper()
+	_, keys := miniscriptKeys(true)
+	scripts := make([][]byte, n)
+	for i := range scripts {
+		key, err := keys(string(rune('A' + i)))
+		require.NoError(t, err)
+		scripts[i], err = txscript.NewScriptBuilder().AddData(key).AddOp(txscript.OP_CHECKSIG).Script()
+		require.NoError(t, err)
+	}
+	return scripts
+}
+
+func TestTapTree_Balanced(t *testing.T) {
+	scripts := tapLeafScripts(t, 4)
+	leaves := make([]TapLeaf, len(scripts))
+	want := make([]txscript.TapLeaf, len(scripts))
+	for i, s := range scripts {
+		leaves[i] = TapLeaf{Script: s}
+		want[i] = txscript.NewBaseTapLeaf(s)
+	}
+
+	tree, err := NewTapTree(leaves...)
+	require.NoError(t, err)
+	root := txscript.AssembleTaprootScriptTree(want...).RootNode.TapHash()
+	require.Equal(t, root[:], tree.MerkleRoot())
+	for i := range leaves {
+		require.Equal(t, 2, tree.Depth(i))
+	}
+}
+
+func TestTapTree_Weighted(t *testing.T) {
+	scripts := tapLeafScripts(t, 5)
+	tree, err := NewTapTree(
+		TapLeaf{Script: scripts[0], Weight: 1},
+		TapLeaf{Script: scripts[1], Weight: 1},
+		TapLeaf{Script: scripts[2], Weight: 2},
+		TapLeaf{Script: scripts[3], Weight: 4},
+		TapLeaf{Script: scripts[4], Weight: 8},
+	)
+	require.NoError(t, err)
+	for i, depth := range []int{4, 4, 3, 2, 1} {
+		require.Equal(t, depth, tree.Depth(i), "leaf %d", i)
+	}
+
+	internal, err := NUMSInternalKey(nil)
+	require.NoError(t, err)
+	out, err := tree.Output(internal, &chaincfg.SigNetParams)
+	require.NoError(t, err)
+	require.Equal(t, internal, out.InternalKey)
+	require.Equal(t, tree.MerkleRoot(), out.MerkleRoot)
+	require.Equal(t, out.OutputKey, out.PkScript[2:])
+
+	outputKey, err := schnorr.ParsePubKey(out.OutputKey)
+	require.NoError(t, err)
+	for i, leaf := range out.LeafScripts {
+		require.Equal(t, scripts[i], leaf.Script)
+		require.Len(t, out.ControlBlock(i), txscript.ControlBlockBaseSize+32*tree.Depth(i))
+		cb, err := txscript.ParseControlBlock(out.ControlBlock(i))
+		require.NoError(t, err)
+		require.NoError(t, txscript.VerifyTaprootLeafCommitment(cb, out.OutputKey, leaf.Script))
+		leafHash := txscript.NewBaseTapLeaf(leaf.Script).TapHash()
+		require.Equal(t, leafHash[:], tree.LeafHash(i))
+		require.True(t, outputKey.IsEqual(txscript.ComputeTaprootOutputKey(cb.InternalKey, cb.RootHash(leaf.Script))))
+	}
+
+	spend := out.SpendInfo(4)
+	require.Equal(t, internal, spend.TaprootInternalKey)
+	require.Equal(t, out.MerkleRoot, spend.TaprootMerkleRoot)
+	require.Equal(t, out.LeafScripts[4:], spend.TaprootLeafScripts)
+	require.Len(t, out.SpendInfo().TaprootLeafScripts, 5)
+}
+
+func TestTapTree_Invalid(t *testing.T) {
+	_, err := NewTapTree()
+	require.Error(t, err)
+	_, err = NewTapTree(TapLeaf{Script: []byte{txscript.OP_TRUE}, Weight: -1})
+	require.Error(t, err)
+	_, err = NewTapTree(TapLeaf{Script: []byte{txscript.OP_TRUE}, LeafVersion: 0xc1})
+	require.Error(t, err)
+
+	tree, err := NewTapTree(TapLeaf{Script: []byte{txscript.OP_TRUE}})
+	require.NoError(t, err)
+	require.Equal(t, 0, tree.Depth(0))
+	_, err = tree.Output(make([]byte, 32), &chaincfg.SigNetParams)
+	require.Error(t, err)
+}
+
+func TestNUMSInternalKey(t *testing.T) {
+	h, err := NUMSInternalKey(nil)
+	require.NoError(t, err)
+	require.Equal(t, "50929b74c1a04954b78b4b6035e97a5e078a5a0f28ec96d547bfee9ace803ac0", hex.EncodeToString(h))
+
+	r := bytes.Repeat([]byte{0x01}, 32)
+	tweaked, err := NUMSInternalKey(r)
+	require.NoError(t, err)
+	require.Len(t, tweaked, 32)
+	require.NotEqual(t, h, tweaked)
+	again, err := NUMSInternalKey(r)
+	require.NoError(t, err)
+	require.Equal(t, tweaked, again)
+
+	_, err = NUMSInternalKey(make([]byte, 32))
+	require.Error(t, err)
+	_, err = NUMSInternalKey([]byte{1})
+	require.Error(t, err)
+}
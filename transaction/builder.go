@@ -1,219 +1,485 @@
-package transaction
-
-import (
-	"errors"
-	"fmt"
-
-	"github.com/btcsuite/btcd/btcutil/psbt"
-	"github.com/btcsuite/btcd/chaincfg"
-	"github.com/btcsuite/btcd/wire"
-
-	"github.com/gosuda/btctxbuilder/types"
-	"github.com/gosuda/btctxbuilder/utils"
-)
-
-// -----------------------------------------------------------------------------
-// TxBuilder
-// -----------------------------------------------------------------------------
-
-type TxBuilder struct {
-	params     *chaincfg.Params
-	feeRate    float64
-	fromAddr   string
-	changeAddr string
-	utxos      []*types.Utxo
-	Inputs     TxInputs
-	Outputs    TxOutputs
-	pkt        *psbt.Packet
-
-	errs []error
-}
-
-// -----------------------------------------------------------------------------
-// ctor / error handling
-// -----------------------------------------------------------------------------
-
-func NewTxBuilder(params *chaincfg.Params) *TxBuilder {
-	return &TxBuilder{params: params}
-}
-
-func (b *TxBuilder) addErr(err error) {
-	if err != nil {
-		b.errs = append(b.errs, err)
-	}
-}
-
-func (b *TxBuilder) Err() error {
-	if len(b.errs) == 0 {
-		return nil
-	}
-	return errors.Join(b.errs...)
-}
-
-func (b *TxBuilder) OK() bool { return b.Err() == nil }
-
-// -----------------------------------------------------------------------------
-// setters
-// -----------------------------------------------------------------------------
-
-func (b *TxBuilder) From(addr string) *TxBuilder {
-	if b.OK() {
-		b.fromAddr = addr
-	}
-	return b
-}
-
-func (b *TxBuilder) Change(addr string) *TxBuilder {
-	if b.OK() {
-		b.changeAddr = addr
-	}
-	return b
-}
-
-func (b *TxBuilder) FeeRate(feeRate float64) *TxBuilder {
-	if b.OK() {
-		b.feeRate = feeRate
-	}
-	return b
-}
-
-func (b *TxBuilder) To(addr string, amt int64) *TxBuilder {
-	if b.OK() {
-		b.addErr(b.Outputs.AddOutputTransfer(b.params, addr, amt))
-	}
-	return b
-}
-
-func (b *TxBuilder) ToMap(balance map[string]int64) *TxBuilder {
-	for addr, amt := range balance {
-		b.To(addr, amt)
-	}
-	return b
-}
-
-// -----------------------------------------------------------------------------
-// transitions (mutating builder)
-// -----------------------------------------------------------------------------
-
-// SelectUtxo picks UTXOs to cover Outputs (fee finalized in Build).
-func (b *TxBuilder) SelectUtxo(utxos []*types.Utxo) *TxBuilder {
-	if !b.OK() {
-		return b
-	}
-	amountTotal := int(b.Outputs.AmountTotal())
-
-	selected, unselected, ok := utils.SelectUtxo(utxos, amountTotal, func(u *types.Utxo) int {
-		return int(u.Value)
-	})
-	if !ok {
-		b.addErr(fmt.Errorf("insufficient balance | need : %v", amountTotal))
-		return b
-	}
-
-	for _, u := range selected {
-		b.addErr(b.Inputs.AddInput(b.params, u.RawTx, u.Vout, u.Value, b.fromAddr))
-	}
-	b.utxos = unselected
-
-	if b.changeAddr == "" {
-		b.changeAddr = b.fromAddr
-	}
-	return b
-}
-
-// -----------------------------------------------------------------------------
-// build / sign
-// -----------------------------------------------------------------------------
-
-func (b *TxBuilder) Build() *TxBuilder {
-	if !b.OK() {
-		return b
-	}
-
-	msg := wire.NewMsgTx(wire.TxVersion)
-
-	ins, err := b.Inputs.ToWire()
-	if err != nil {
-		b.addErr(err)
-		return b
-	}
-	for _, in := range ins {
-		msg.AddTxIn(in)
-	}
-
-	outs, err := b.Outputs.ToWire()
-	if err != nil {
-		b.addErr(err)
-		return b
-	}
-	for _, out := range outs {
-		msg.AddTxOut(out)
-	}
-
-	// finalize fee + add change
-	if b.changeAddr != "" {
-		if err := FundRawTransaction(
-			b.params,
-			msg,
-			&b.Inputs,
-			b.Outputs,
-			b.changeAddr,
-			b.feeRate,
-			b.utxos,
-			b.fromAddr,
-		); err != nil {
-			b.addErr(err)
-			return b
-		}
-	}
-
-	pkt, err := psbt.NewFromUnsignedTx(msg)
-	if err != nil {
-		b.addErr(err)
-		return b
-	}
-	if err := DecorateTxInputs(pkt, b.Inputs); err != nil {
-		b.addErr(err)
-		return b
-	}
-
-	b.pkt = pkt
-	return b
-}
-
-func (b *TxBuilder) SignWith(sign types.Signer, pubkey []byte) *TxBuilder {
-	if !b.OK() {
-		return b
-	}
-	if sign == nil {
-		b.addErr(fmt.Errorf("no signer provided"))
-		return b
-	}
-	pkt, err := SignTx(b.params, b.pkt, sign, pubkey)
-	if err != nil {
-		b.addErr(err)
-		return b
-	}
-	b.pkt = pkt
-	return b
-}
-
-func (b *TxBuilder) Packet() (*psbt.Packet, error) {
-	if err := b.Err(); err != nil {
-		return nil, err
-	}
-	if b.pkt == nil {
-		return nil, fmt.Errorf("no packet: call Build() first")
-	}
-	return b.pkt, nil
-}
-
-func (b *TxBuilder) RawTx() ([]byte, error) {
-	if err := b.Err(); err != nil {
-		return nil, err
-	}
-	if b.pkt == nil || b.pkt.UnsignedTx == nil {
-		return nil, fmt.Errorf("no unsigned tx: call Build() first")
-	}
-	return types.EncodePsbtToRawTx(b.pkt)
-}
+package transaction
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/gosuda/btctxbuilder/script"
+	"github.com/gosuda/btctxbuilder/types"
+	"github.com/gosuda/btctxbuilder/utils"
+)
+
+// -----------------------------------------------------------------------------
+// TxBuilder
+// -----------------------------------------------------------------------------
+
+type TxBuilder struct {
+	params     *chaincfg.Params
+	feeRate    float64
+	fromAddr   string
+	changeAddr string
+	utxos      []*types.Utxo
+	selector   utils.CoinSelector
+	rbf        bool
+	spends     map[string]*types.SpendInfo // by pkScript
+	sigHash    txscript.SigHashType
+	sigHashes  map[wire.OutPoint]txscript.SigHashType
+	sequences  map[wire.OutPoint]uint32
+	protected  map[wire.OutPoint]bool
+	Inputs     TxInputs
+	Outputs    TxOutputs
+	pkt        *psbt.Packet
+
+	errs []error
+}
+
+// -----------------------------------------------------------------------------
+// ctor / error handling
+// -----------------------------------------------------------------------------
+
+func NewTxBuilder(params *chaincfg.Params) *TxBuilder {
+	return &TxBuilder{params: params}
+}
+
+func (b *TxBuilder) addErr(err error) {
+	if err != nil {
+		b.errs = append(b.errs, err)
+	}
+}
+
+func (b *TxBuilder) Err() error {
+	if len(b.errs) == 0 {
+		return nil
+	}
+	return errors.Join(b.errs...)
+}
+
+func (b *TxBuilder) OK() bool { return b.Err() == nil }
+
+// -----------------------------------------------------------------------------
+// setters
+// -----------------------------------------------------------------------------
+
+func (b *TxBuilder) From(addr string) *TxBuilder {
+	if b.OK() {
+		b.fromAddr = addr
+	}
+	return b
+}
+
+func (b *TxBuilder) Change(addr string) *TxBuilder {
+	if b.OK() {
+		b.changeAddr = addr
+	}
+	return b
+}
+
+func (b *TxBuilder) FeeRate(feeRate float64) *TxBuilder {
+	if b.OK() {
+		b.feeRate = feeRate
+	}
+	return b
+}
+
+// CoinSelector sets the strategy SelectUtxo uses. The default is
+// utils.LargestFirst.
+func (b *TxBuilder) CoinSelector(selector utils.CoinSelector) *TxBuilder {
+	if b.OK() {
+		b.selector = selector
+	}
+	return b
+}
+
+// RBF signals BIP125 replaceability on every input so the transaction can be
+// fee bumped later with BumpFee.
+func (b *TxBuilder) RBF() *TxBuilder {
+	if b.OK() {
+		b.rbf = true
+	}
+	return b
+}
+
+// KeyOrigin records the BIP32 origin of the key behind addr. Inputs spending
+// from addr and outputs paying to it, change included, then carry derivation
+// info in the PSBT.
+func (b *TxBuilder) KeyOrigin(addr string, origin *types.KeyOrigin) *TxBuilder {
+	return b.SpendInfo(addr, &types.SpendInfo{Origins: []*types.KeyOrigin{origin}})
+}
+
+// SpendInfo records the scripts and key origins needed to spend from addr,
+// e.g. from a descriptor. Inputs spending from addr and outputs paying to it
+// carry them in the PSBT.
+func (b *TxBuilder) SpendInfo(addr string, info *types.SpendInfo) *TxBuilder {
+	if !b.OK() {
+		return b
+	}
+	btcAddr, _, err := types.DecodeAddress(addr, b.params)
+	if err != nil {
+		b.addErr(err)
+		return b
+	}
+	pkScript, err := script.EncodeTransferScript(btcAddr)
+	if err != nil {
+		b.addErr(err)
+		return b
+	}
+	if b.spends == nil {
+		b.spends = make(map[string]*types.SpendInfo)
+	}
+	b.spends[string(pkScript)] = info
+	return b
+}
+
+// SigHashType sets the sighash type every input is signed with, unless
+// InputSigHashType overrides it. Without one, inputs sign with SigHashAll,
+// or SigHashDefault for taproot.
+func (b *TxBuilder) SigHashType(hashType txscript.SigHashType) *TxBuilder {
+	if b.OK() {
+		b.sigHash = hashType
+	}
+	return b
+}
+
+// InputSigHashType sets the sighash type of the input spending txid:vout.
+// An input signed with SigHashSingle commits to the output of the same
+// index only, and with SigHashAnyOneCanPay leaves the other inputs open, so
+// e.g. SigHashSingle|SigHashAnyOneCanPay lets a seller sign an offer that
+// buyers complete with their own inputs and outputs.
+func (b *TxBuilder) InputSigHashType(txid string, vout uint32, hashType txscript.SigHashType) *TxBuilder {
+	if !b.OK() {
+		return b
+	}
+	hash, err := chainhash.NewHashFromStr(txid)
+	if err != nil {
+		b.addErr(err)
+		return b
+	}
+	if b.sigHashes == nil {
+		b.sigHashes = make(map[wire.OutPoint]txscript.SigHashType)
+	}
+	b.sigHashes[*wire.NewOutPoint(hash, vout)] = hashType
+	return b
+}
+
+// InputSequence sets the nSequence of the input spending txid:vout, e.g. a
+// BIP68 relative lock made with blockchain.LockTimeToSequence. Relative
+// locks only apply from transaction version 2, which Build then uses.
+func (b *TxBuilder) InputSequence(txid string, vout uint32, sequence uint32) *TxBuilder {
+	if !b.OK() {
+		return b
+	}
+	hash, err := chainhash.NewHashFromStr(txid)
+	if err != nil {
+		b.addErr(err)
+		return b
+	}
+	if b.sequences == nil {
+		b.sequences = make(map[wire.OutPoint]uint32)
+	}
+	b.sequences[*wire.NewOutPoint(hash, vout)] = sequence
+	return b
+}
+
+// Protect keeps the output txid:vout, e.g. one holding an inscription or a
+// rare sat, out of the UTXOs SelectUtxo and Build fund the transaction
+// with. Inputs added to Inputs directly still spend it.
+func (b *TxBuilder) Protect(txid string, vout uint32) *TxBuilder {
+	if !b.OK() {
+		return b
+	}
+	hash, err := chainhash.NewHashFromStr(txid)
+	if err != nil {
+		b.addErr(err)
+		return b
+	}
+	if b.protected == nil {
+		b.protected = make(map[wire.OutPoint]bool)
+	}
+	b.protected[*wire.NewOutPoint(hash, vout)] = true
+	return b
+}
+
+func (b *TxBuilder) To(addr string, amt int64) *TxBuilder {
+	if b.OK() {
+		b.addErr(b.Outputs.AddOutputTransfer(b.params, addr, amt))
+	}
+	return b
+}
+
+func (b *TxBuilder) ToMap(balance map[string]int64) *TxBuilder {
+	for addr, amt := range balance {
+		b.To(addr, amt)
+	}
+	return b
+}
+
+// -----------------------------------------------------------------------------
+// transitions (mutating builder)
+// -----------------------------------------------------------------------------
+
+// SelectUtxo picks UTXOs to cover Outputs (fee finalized in Build).
+// Each UTXO is valued at its effective value, i.e. net of the fee for
+// spending it at the builder's fee rate.
+func (b *TxBuilder) SelectUtxo(utxos []*types.Utxo) *TxBuilder {
+	if !b.OK() {
+		return b
+	}
+	if b.changeAddr == "" {
+		b.changeAddr = b.fromAddr
+	}
+	utxos = b.unprotected(utxos)
+
+	fromAddr, _, err := types.DecodeAddress(b.fromAddr, b.params)
+	if err != nil {
+		b.addErr(fmt.Errorf("decode from address: %w", err))
+		return b
+	}
+	fromScript, err := script.EncodeTransferScript(fromAddr)
+	if err != nil {
+		b.addErr(err)
+		return b
+	}
+	changeAddr, _, err := types.DecodeAddress(b.changeAddr, b.params)
+	if err != nil {
+		b.addErr(fmt.Errorf("decode change address: %w", err))
+		return b
+	}
+	costOfChange, err := CostOfChange(b.feeRate, changeAddr)
+	if err != nil {
+		b.addErr(err)
+		return b
+	}
+
+	outs, err := b.Outputs.ToWire()
+	if err != nil {
+		b.addErr(err)
+		return b
+	}
+	inputSize := InputVirtualSize(fromScript)
+	if weight := scriptInputWeight(b.spends[string(fromScript)]); weight > 0 {
+		inputSize = (weight + blockchain.WitnessScaleFactor - 1) / blockchain.WitnessScaleFactor
+	}
+	inputFee := FeeForVirtualSize(b.feeRate, inputSize)
+	target := b.Outputs.AmountTotal() + FeeForVirtualSize(b.feeRate, estimateVirtualSize(b.Inputs, outs, 0))
+	for _, in := range b.Inputs {
+		target -= in.Amount
+	}
+	if target <= 0 {
+		// inputs added up front already cover everything
+		b.utxos = utxos
+		return b
+	}
+
+	selector := b.selector
+	if selector == nil {
+		selector = utils.LargestFirst{}
+	}
+	selected, unselected, ok := utils.SelectWith(selector, utxos, int64(target), int64(costOfChange), func(u *types.Utxo) int64 {
+		return u.Value - int64(inputFee)
+	})
+	if !ok {
+		b.addErr(fmt.Errorf("insufficient balance | need : %v", target))
+		return b
+	}
+
+	for _, u := range selected {
+		b.addErr(b.Inputs.AddInput(b.params, u.RawTx, u.Vout, u.Value, b.fromAddr))
+	}
+	b.utxos = unselected
+	return b
+}
+
+// unprotected returns the utxos not marked with Protect.
+func (b *TxBuilder) unprotected(utxos []*types.Utxo) []*types.Utxo {
+	if len(b.protected) == 0 {
+		return utxos
+	}
+	kept := make([]*types.Utxo, 0, len(utxos))
+	for _, u := range utxos {
+		hash, err := chainhash.NewHashFromStr(u.Txid)
+		if err == nil && b.protected[*wire.NewOutPoint(hash, u.Vout)] {
+			continue
+		}
+		kept = append(kept, u)
+	}
+	return kept
+}
+
+// -----------------------------------------------------------------------------
+// build / sign
+// -----------------------------------------------------------------------------
+
+func (b *TxBuilder) Build() *TxBuilder {
+	if !b.OK() {
+		return b
+	}
+
+	msg := wire.NewMsgTx(wire.TxVersion)
+
+	ins, err := b.Inputs.ToWire()
+	if err != nil {
+		b.addErr(err)
+		return b
+	}
+	for _, in := range ins {
+		msg.AddTxIn(in)
+	}
+
+	outs, err := b.Outputs.ToWire()
+	if err != nil {
+		b.addErr(err)
+		return b
+	}
+	for _, out := range outs {
+		msg.AddTxOut(out)
+	}
+
+	// spend info also sizes script inputs for the fee estimate
+	b.attachSpends()
+
+	// finalize fee + add change
+	if b.changeAddr != "" {
+		if err := FundRawTransaction(
+			b.params,
+			msg,
+			&b.Inputs,
+			b.Outputs,
+			b.changeAddr,
+			b.feeRate,
+			b.utxos,
+			b.fromAddr,
+		); err != nil {
+			b.addErr(err)
+			return b
+		}
+	}
+
+	if b.rbf {
+		for _, in := range msg.TxIn {
+			in.Sequence = RBFSequence
+		}
+	}
+	for i, in := range b.Inputs {
+		sequence, ok := b.sequences[in.OutPoint()]
+		if !ok {
+			continue
+		}
+		msg.TxIn[i].Sequence = sequence
+		if sequence&wire.SequenceLockTimeDisabled == 0 {
+			msg.Version = 2
+		}
+	}
+
+	if err := b.attachSigHashes(len(msg.TxOut)); err != nil {
+		b.addErr(err)
+		return b
+	}
+
+	pkt, err := psbt.NewFromUnsignedTx(msg)
+	if err != nil {
+		b.addErr(err)
+		return b
+	}
+	b.attachSpends()
+	if err := DecorateTxInputs(pkt, b.Inputs); err != nil {
+		b.addErr(err)
+		return b
+	}
+	outputs := make(TxOutputs, 0, len(msg.TxOut))
+	for i, out := range msg.TxOut {
+		spend := b.spends[string(out.PkScript)]
+		if i < len(b.Outputs) && b.Outputs[i].Spend != nil {
+			spend = b.Outputs[i].Spend
+		}
+		outputs = append(outputs, &TxOutput{
+			Amount:   btcutil.Amount(out.Value),
+			PkScript: out.PkScript,
+			Spend:    spend,
+		})
+	}
+	if err := DecorateTxOutputs(pkt, outputs); err != nil {
+		b.addErr(err)
+		return b
+	}
+
+	b.pkt = pkt
+	return b
+}
+
+// attachSpends sets the spend info recorded with SpendInfo on the inputs
+// that don't have one yet.
+func (b *TxBuilder) attachSpends() {
+	for _, in := range b.Inputs {
+		if in.Spend == nil && in.prevVout != nil {
+			in.SetSpend(b.spends[string(in.prevVout.PkScript)])
+		}
+	}
+}
+
+// attachSigHashes sets the sighash types recorded with SigHashType and
+// InputSigHashType on the inputs, checking each fits its input and that
+// SigHashSingle inputs have an output to commit to.
+func (b *TxBuilder) attachSigHashes(numOutputs int) error {
+	for i, in := range b.Inputs {
+		hashType, ok := b.sigHashes[in.OutPoint()]
+		if !ok {
+			hashType = b.sigHash
+		}
+		if hashType == txscript.SigHashDefault {
+			continue
+		}
+		if !ValidSigHashType(hashType, in.AddrType == types.P2TR) {
+			return fmt.Errorf("input %d: invalid sighash type %#x for %s", i, byte(hashType), in.AddrType)
+		}
+		if hashType&sigHashMask == txscript.SigHashSingle && i >= numOutputs {
+			return fmt.Errorf("input %d: SIGHASH_SINGLE without a matching output", i)
+		}
+		in.SigHashType = hashType
+	}
+	return nil
+}
+
+func (b *TxBuilder) SignWith(sign types.Signer, pubkey []byte) *TxBuilder {
+	if !b.OK() {
+		return b
+	}
+	if sign == nil {
+		b.addErr(fmt.Errorf("no signer provided"))
+		return b
+	}
+	pkt, err := SignTx(b.params, b.pkt, sign, pubkey)
+	if err != nil {
+		b.addErr(err)
+		return b
+	}
+	b.pkt = pkt
+	return b
+}
+
+func (b *TxBuilder) Packet() (*psbt.Packet, error) {
+	if err := b.Err(); err != nil {
+		return nil, err
+	}
+	if b.pkt == nil {
+		return nil, fmt.Errorf("no packet: call Build() first")
+	}
+	return b.pkt, nil
+}
+
+func (b *TxBuilder) RawTx() ([]byte, error) {
+	if err := b.Err(); err != nil {
+		return nil, err
+	}
+	if b.pkt == nil || b.pkt.UnsignedTx == nil {
+		return nil, fmt.Errorf("no unsigned tx: call Build() first")
+	}
+	return types.EncodePsbtToRawTx(b.pkt)
+}
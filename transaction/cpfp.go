@@ -0,0 +1,103 @@
+package transaction
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcwallet/wallet/txrules"
+
+	"github.com/gosuda/btctxbuilder/script"
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+// CPFPChildFee returns the fee a child of childVSize vbytes has to pay for
+// (parentFee+childFee)/(parentVSize+childVSize) to reach feeRate sat/vB.
+// The child never pays less than feeRate for its own vsize, so it does not
+// end up stuck if the parent confirms on its own.
+func CPFPChildFee(parentFee btcutil.Amount, parentVSize int, childVSize int, feeRate float64) btcutil.Amount {
+	fee := FeeForVirtualSize(feeRate, parentVSize+childVSize) - parentFee
+	return max(fee, FeeForVirtualSize(feeRate, childVSize))
+}
+
+// NewCPFPTx builds an unsigned child transaction spending output vout of
+// parent to toAddr, paying the fee that lifts the package of both to feeRate
+// sat/vB. parentFee and parentVSize describe the parent as relayed.
+//
+// When the parent output cannot cover that fee and still leave a non-dust
+// output, confirmed utxos are added as extra inputs, largest first.
+func NewCPFPTx(
+	params *chaincfg.Params,
+	parent *wire.MsgTx,
+	parentFee btcutil.Amount,
+	parentVSize int,
+	vout uint32,
+	toAddr string,
+	feeRate float64,
+	utxos []*types.Utxo,
+) (*psbt.Packet, error) {
+	if int(vout) >= len(parent.TxOut) {
+		return nil, fmt.Errorf("parent has no output %d", vout)
+	}
+	prevOut := parent.TxOut[vout]
+	parentAddr, err := addressFromPkScript(prevOut.PkScript, params)
+	if err != nil {
+		return nil, fmt.Errorf("parent output %d: %w", vout, err)
+	}
+
+	toBTC, _, err := types.DecodeAddress(toAddr, params)
+	if err != nil {
+		return nil, fmt.Errorf("decode to address: %w", err)
+	}
+	toScript, err := script.EncodeTransferScript(toBTC)
+	if err != nil {
+		return nil, err
+	}
+
+	b := NewTxBuilder(params).FeeRate(feeRate)
+	if err := b.Inputs.AddInput(params, parent, vout, prevOut.Value, parentAddr); err != nil {
+		return nil, err
+	}
+
+	parentHash := parent.TxHash()
+	var pool []*types.Utxo
+	for _, u := range utxos {
+		if u.RawTx == nil || !u.Status.Confirmed || u.RawTx.TxHash() == parentHash {
+			continue
+		}
+		pool = append(pool, u)
+	}
+	sort.SliceStable(pool, func(i, j int) bool {
+		return pool[i].Value > pool[j].Value
+	})
+
+	var out *wire.TxOut
+	for {
+		out = wire.NewTxOut(0, toScript)
+		childVSize := estimateVirtualSize(b.Inputs, []*wire.TxOut{out}, 0)
+		fee := CPFPChildFee(parentFee, parentVSize, childVSize, feeRate)
+		out.Value = int64(b.Inputs.AmountTotal() - fee)
+		if out.Value > 0 && !txrules.IsDustOutput(out, txrules.DefaultRelayFeePerKb) {
+			break
+		}
+		if len(pool) == 0 {
+			return nil, fmt.Errorf("insufficient balance: have=%v, child fee=%v", b.Inputs.AmountTotal(), fee)
+		}
+
+		u := pool[0]
+		pool = pool[1:]
+		addr, err := addressFromPkScript(u.RawTx.TxOut[u.Vout].PkScript, params)
+		if err != nil {
+			return nil, fmt.Errorf("utxo %s:%d: %w", u.Txid, u.Vout, err)
+		}
+		if err := b.Inputs.AddInput(params, u.RawTx, u.Vout, u.Value, addr); err != nil {
+			return nil, err
+		}
+	}
+
+	// Without a change address Build keeps the fee computed above as is.
+	return b.To(toAddr, out.Value).Build().Packet()
+}
@@ -0,0 +1,86 @@
+package transaction
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+func TestCPFPChildFee(t *testing.T) {
+	// the child makes up for the parent
+	require.Equal(t, btcutil.Amount(2000*10-500), CPFPChildFee(500, 1000, 1000, 10))
+	// the parent already pays enough, the child still pays its own way
+	require.Equal(t, btcutil.Amount(1000*10), CPFPChildFee(50000, 1000, 1000, 10))
+}
+
+func TestNewCPFPTx(t *testing.T) {
+	params := types.GetParams(types.BTC_Signet)
+	from := "tb1q307vt2zz3f66hhs90p0le2pp6r53tvyqnzsy42"
+	to := "n368zCWREFiRRX7icJRBb6n8nMsjJjNVK8"
+	signer, err := types.NewECDSASigner("887ad33f247a7df59f1bf61b6aa69ab2a537c0708d7d6fb6614e10511fca377b")
+	require.NoError(t, err)
+
+	buildParent := func(utxo *types.Utxo) (*wire.MsgTx, btcutil.Amount, int) {
+		pkt, err := NewTxBuilder(params).
+			FeeRate(1).
+			From(from).
+			To(to, 20000).
+			SelectUtxo([]*types.Utxo{utxo}).
+			Build().
+			SignWith(signer.Sign, signer.PubKey()).
+			Packet()
+		require.NoError(t, err)
+		fee := packetFee(t, pkt)
+		tx, err := psbt.Extract(pkt)
+		require.NoError(t, err)
+		require.Len(t, tx.TxOut, 2)
+		return tx, fee, int(mempool.GetTxVirtualSize(btcutil.NewTx(tx)))
+	}
+	packageRate := func(parentFee btcutil.Amount, parentVSize int, child *psbt.Packet, childInputs int) float64 {
+		var ins TxInputs
+		for range childInputs {
+			require.NoError(t, ins.AddInput(params, child.Inputs[0].NonWitnessUtxo, 0, 0, from))
+		}
+		childVSize := estimateVirtualSize(ins, child.UnsignedTx.TxOut, 0)
+		return float64(parentFee+packetFee(t, child)) / float64(parentVSize+childVSize)
+	}
+
+	utxos := newTestUtxos(t, params, from, 50000, 21500, 80000)
+	utxos[2].Status.Confirmed = true
+
+	// the change output of the parent pays for the child
+	parent, parentFee, parentVSize := buildParent(utxos[0])
+	child, err := NewCPFPTx(params, parent, parentFee, parentVSize, 1, from, 20, utxos)
+	require.NoError(t, err)
+	require.Len(t, child.UnsignedTx.TxIn, 1)
+	require.Len(t, child.UnsignedTx.TxOut, 1)
+	require.Equal(t, parent.TxHash(), child.UnsignedTx.TxIn[0].PreviousOutPoint.Hash)
+	require.GreaterOrEqual(t, packageRate(parentFee, parentVSize, child, 1), 20.0)
+
+	signed, err := SignTx(params, child, signer.Sign, signer.PubKey())
+	require.NoError(t, err)
+	_, err = types.EncodePsbtToRawTx(signed)
+	require.NoError(t, err)
+
+	// the change output is too small, a confirmed UTXO is added
+	parent, parentFee, parentVSize = buildParent(utxos[1])
+	child, err = NewCPFPTx(params, parent, parentFee, parentVSize, 1, from, 20, utxos)
+	require.NoError(t, err)
+	require.Len(t, child.UnsignedTx.TxIn, 2)
+	require.Equal(t, utxos[2].RawTx.TxHash(), child.UnsignedTx.TxIn[1].PreviousOutPoint.Hash)
+	require.GreaterOrEqual(t, packageRate(parentFee, parentVSize, child, 2), 20.0)
+
+	// without confirmed UTXOs there is nothing to add
+	utxos[2].Status.Confirmed = false
+	_, err = NewCPFPTx(params, parent, parentFee, parentVSize, 1, from, 20, utxos)
+	require.Error(t, err)
+
+	_, err = NewCPFPTx(params, parent, parentFee, parentVSize, 2, from, 20, utxos)
+	require.Error(t, err)
+}
@@ -1,147 +1,274 @@
-package transaction
-
-import (
-	"fmt"
-
-	"github.com/btcsuite/btcd/btcutil"
-	"github.com/btcsuite/btcd/chaincfg"
-	"github.com/btcsuite/btcd/chaincfg/chainhash"
-	"github.com/btcsuite/btcd/wire"
-	"github.com/btcsuite/btcwallet/wallet/txrules"
-	"github.com/btcsuite/btcwallet/wallet/txsizes"
-
-	"github.com/gosuda/btctxbuilder/script"
-	"github.com/gosuda/btctxbuilder/types"
-	"github.com/gosuda/btctxbuilder/utils"
-)
-
-func FundRawTransaction(
-	params *chaincfg.Params,
-	msgTx *wire.MsgTx,
-	inputs *TxInputs,
-	outputs TxOutputs,
-	changeAddr string,
-	feeRate float64,
-	utxoPool []*types.Utxo,
-	fromAddr string,
-) error {
-	changeBTC, err := btcutil.DecodeAddress(changeAddr, params)
-	if err != nil {
-		return fmt.Errorf("decode change address: %w", err)
-	}
-
-	changeScript, err := script.EncodeTransferScript(changeBTC)
-	if err != nil {
-		return fmt.Errorf("encode change script: %w", err)
-	}
-
-	addWireInput := func(raw *wire.MsgTx, vout uint32) error {
-		txid, err := chainhash.NewHashFromStr(raw.TxID())
-		if err != nil {
-			return err
-		}
-		msgTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(txid, vout), nil, nil))
-		return nil
-	}
-
-	userOuts := make([]*wire.TxOut, len(msgTx.TxOut))
-	copy(userOuts, msgTx.TxOut)
-
-	fee, err := EstimateTxFee(feeRate, *inputs, userOuts, changeBTC)
-	if err != nil {
-		return fmt.Errorf("estimate fee: %w", err)
-	}
-	inTotal := inputs.AmountTotal()
-	outTotal := outputs.AmountTotal()
-	fund := inTotal - outTotal - fee
-
-	for fund < 0 {
-		deficit := -fund
-		selected, rest, ok := utils.SelectUtxo(utxoPool, int(deficit), func(u *types.Utxo) int {
-			return int(u.Value)
-		})
-		if !ok {
-			return fmt.Errorf("insufficient balance: have=%v, need=%v (fee=%v)",
-				inTotal, outTotal+fee, fee)
-		}
-		for _, u := range selected {
-			if err := inputs.AddInput(params, u.RawTx, u.Vout, u.Value, fromAddr); err != nil {
-				return fmt.Errorf("add input: %w", err)
-			}
-			if err := addWireInput(u.RawTx, u.Vout); err != nil {
-				return fmt.Errorf("add txin: %w", err)
-			}
-			inTotal += btcutil.Amount(u.Value)
-		}
-		utxoPool = rest
-
-		fee, err = EstimateTxFee(feeRate, *inputs, userOuts, changeBTC)
-		if err != nil {
-			return fmt.Errorf("re-estimate fee: %w", err)
-		}
-		fund = inTotal - outTotal - fee
-	}
-
-	if fund > 0 {
-		changeOut := wire.NewTxOut(int64(fund), changeScript)
-		if changeOut.Value > 0 {
-			msgTx.AddTxOut(changeOut)
-		}
-	}
-
-	return nil
-}
-
-func EstimateTxFee(feeRate float64, ins TxInputs, outs []*wire.TxOut, fundAddress btcutil.Address) (btcutil.Amount, error) {
-	feeRatePerKb := btcutil.Amount(feeRate) * 1000
-	vSize, err := EstimateTxVirtualSize(ins, outs, fundAddress)
-	if err != nil {
-		return 0, err
-	}
-	estimateFee := txrules.FeeForSerializeSize(feeRatePerKb, vSize)
-	return estimateFee, nil
-}
-
-func EstimateTxVirtualSize(ins TxInputs, outs []*wire.TxOut, fundAddress btcutil.Address) (vSize int, err error) {
-	// TODO : Add support for p2sh, p2wsh
-	var nested, p2wpkh, p2tr, p2pkh int
-	for _, in := range ins {
-		switch types.GetAddressType(in.Address) {
-		case types.P2PKH:
-			p2pkh++
-		case types.P2WPKH:
-			p2wpkh++
-		case types.P2WPKH_NESTED, types.P2WSH_NESTED:
-			nested++
-		case types.P2TR:
-			p2tr++
-		}
-	}
-	fundScriptSize, err := GetFundScriptSize(fundAddress)
-	if err != nil {
-		return 0, err
-	}
-
-	vSize = txsizes.EstimateVirtualSize(p2pkh, p2tr, p2wpkh, nested, outs, fundScriptSize)
-	return vSize, nil
-}
-
-func GetFundScriptSize(fundAddress btcutil.Address) (int, error) {
-	// Determine the script type and size
-	switch fundAddress.(type) {
-	case *btcutil.AddressPubKey: // P2PK
-		return 35, nil // [33-byte PubKey] OP_CHECKSIG
-	case *btcutil.AddressPubKeyHash: // P2PKH
-		return 25, nil // OP_DUP OP_HASH160 [20-byte HASH] OP_EQUALVERIFY OP_CHECKSIG
-	case *btcutil.AddressScriptHash: // P2SH
-		return 23, nil // OP_HASH160 [20-byte HASH] OP_EQUAL
-	case *btcutil.AddressWitnessPubKeyHash: // P2WPKH
-		return 22, nil // OP_0 [20-byte HASH]
-	case *btcutil.AddressWitnessScriptHash: // P2WSH
-		return 34, nil // OP_0 [32-byte HASH]
-	case *btcutil.AddressTaproot: // P2TR
-		return 34, nil // OP_1 [32-byte Taproot PubKey]
-	default:
-		return 0, fmt.Errorf("unsupported address type: %T", fundAddress)
-	}
-}
+package transaction
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcwallet/wallet/txrules"
+	"github.com/btcsuite/btcwallet/wallet/txsizes"
+
+	"github.com/gosuda/btctxbuilder/script"
+	"github.com/gosuda/btctxbuilder/types"
+	"github.com/gosuda/btctxbuilder/utils"
+)
+
+func FundRawTransaction(
+	params *chaincfg.Params,
+	msgTx *wire.MsgTx,
+	inputs *TxInputs,
+	outputs TxOutputs,
+	changeAddr string,
+	feeRate float64,
+	utxoPool []*types.Utxo,
+	fromAddr string,
+) error {
+	changeBTC, err := btcutil.DecodeAddress(changeAddr, params)
+	if err != nil {
+		return fmt.Errorf("decode change address: %w", err)
+	}
+
+	changeScript, err := script.EncodeTransferScript(changeBTC)
+	if err != nil {
+		return fmt.Errorf("encode change script: %w", err)
+	}
+
+	addWireInput := func(raw *wire.MsgTx, vout uint32) error {
+		txid, err := chainhash.NewHashFromStr(raw.TxID())
+		if err != nil {
+			return err
+		}
+		msgTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(txid, vout), nil, nil))
+		return nil
+	}
+
+	userOuts := make([]*wire.TxOut, len(msgTx.TxOut))
+	copy(userOuts, msgTx.TxOut)
+
+	changeCost, err := CostOfChange(feeRate, changeBTC)
+	if err != nil {
+		return fmt.Errorf("estimate cost of change: %w", err)
+	}
+	inTotal := inputs.AmountTotal()
+	outTotal := outputs.AmountTotal()
+
+	for {
+		// Without change, whatever is left over goes to the miner. That is
+		// preferable whenever a change output would cost more than it saves.
+		feeNoChange := FeeForVirtualSize(feeRate, estimateVirtualSize(*inputs, userOuts, 0))
+		excess := inTotal - outTotal - feeNoChange
+		if excess >= 0 && excess < changeCost {
+			return nil
+		}
+
+		fee, err := EstimateTxFee(feeRate, *inputs, userOuts, changeBTC)
+		if err != nil {
+			return fmt.Errorf("estimate fee: %w", err)
+		}
+		fund := inTotal - outTotal - fee
+		if fund >= 0 {
+			changeOut := wire.NewTxOut(int64(fund), changeScript)
+			if changeOut.Value > 0 && !txrules.IsDustOutput(changeOut, txrules.DefaultRelayFeePerKb) {
+				msgTx.AddTxOut(changeOut)
+			}
+			return nil
+		}
+
+		deficit := -fund
+		selected, rest, ok := utils.SelectUtxo(utxoPool, int(deficit), func(u *types.Utxo) int {
+			return int(u.Value)
+		})
+		if !ok {
+			return fmt.Errorf("insufficient balance: have=%v, need=%v (fee=%v)",
+				inTotal, outTotal+fee, fee)
+		}
+		for _, u := range selected {
+			if err := inputs.AddInput(params, u.RawTx, u.Vout, u.Value, fromAddr); err != nil {
+				return fmt.Errorf("add input: %w", err)
+			}
+			if err := addWireInput(u.RawTx, u.Vout); err != nil {
+				return fmt.Errorf("add txin: %w", err)
+			}
+			inTotal += btcutil.Amount(u.Value)
+		}
+		utxoPool = rest
+	}
+}
+
+func EstimateTxFee(feeRate float64, ins TxInputs, outs []*wire.TxOut, fundAddress btcutil.Address) (btcutil.Amount, error) {
+	vSize, err := EstimateTxVirtualSize(ins, outs, fundAddress)
+	if err != nil {
+		return 0, err
+	}
+	return FeeForVirtualSize(feeRate, vSize), nil
+}
+
+// FeeForVirtualSize returns the fee for vSize virtual bytes at feeRate sat/vB.
+func FeeForVirtualSize(feeRate float64, vSize int) btcutil.Amount {
+	feeRatePerKb := btcutil.Amount(feeRate * 1000)
+	return txrules.FeeForSerializeSize(feeRatePerKb, vSize)
+}
+
+func EstimateTxVirtualSize(ins TxInputs, outs []*wire.TxOut, fundAddress btcutil.Address) (vSize int, err error) {
+	fundScriptSize, err := GetFundScriptSize(fundAddress)
+	if err != nil {
+		return 0, err
+	}
+	return estimateVirtualSize(ins, outs, fundScriptSize), nil
+}
+
+func estimateVirtualSize(ins TxInputs, outs []*wire.TxOut, changeScriptSize int) int {
+	var nested, p2wpkh, p2tr, p2pkh int
+	var scriptWeight int
+	for _, in := range ins {
+		// AddrType knows about nested segwit, the address alone doesn't
+		addrType := in.AddrType
+		if addrType == types.Invalid {
+			addrType = types.GetAddressType(in.Address)
+		}
+		switch addrType {
+		case types.P2PKH:
+			p2pkh++
+		case types.P2WPKH:
+			p2wpkh++
+		case types.P2WPKH_NESTED:
+			nested++
+		case types.P2TR:
+			p2tr++
+		case types.P2SH, types.P2WSH, types.P2WSH_NESTED:
+			if weight := scriptInputWeight(in.Spend); weight > 0 {
+				scriptWeight += weight
+			} else if addrType != types.P2WSH {
+				// without scripts, a P2SH output is taken for the
+				// P2SH-P2WPKH one of a single key wallet
+				nested++
+			}
+		}
+	}
+	vSize := txsizes.EstimateVirtualSize(p2pkh, p2tr, p2wpkh, nested, outs, changeScriptSize)
+	return vSize + (scriptWeight+blockchain.WitnessScaleFactor-1)/blockchain.WitnessScaleFactor
+}
+
+// scriptInputWeight returns the weight of an input spending a P2SH, P2WSH
+// or P2SH-P2WSH output with spend, or 0 if spend doesn't describe one.
+// Signatures are assumed to be 73 bytes, the DER maximum with sighash type,
+// and segwit inputs are charged the 2 weight units of the segwit marker and
+// flag. Witness scripts other than multisig are sized by their smallest
+// miniscript satisfaction.
+func scriptInputWeight(spend *types.SpendInfo) int {
+	if spend == nil {
+		return 0
+	}
+	const outpointSequence = 32 + 4 + 4
+	pushSize := func(n int) int {
+		size, _ := txscript.NewScriptBuilder().AddData(make([]byte, n)).Script()
+		return len(size)
+	}
+
+	if spend.WitnessScript != nil {
+		stack, ok := witnessStackSize(spend.WitnessScript)
+		if !ok {
+			return 0
+		}
+		witness := stack + wire.VarIntSerializeSize(uint64(len(spend.WitnessScript))) + len(spend.WitnessScript)
+		sigScript := 0
+		if spend.RedeemScript != nil {
+			sigScript = pushSize(len(spend.RedeemScript))
+		}
+		base := outpointSequence + wire.VarIntSerializeSize(uint64(sigScript)) + sigScript
+		return base*blockchain.WitnessScaleFactor + witness + 2
+	}
+
+	_, nRequired, err := script.ParseMultiSigScript(spend.RedeemScript)
+	if err != nil {
+		return 0
+	}
+	sigScript := 1 + nRequired*(1+73) + pushSize(len(spend.RedeemScript))
+	return (outpointSequence + wire.VarIntSerializeSize(uint64(sigScript)) + sigScript) * blockchain.WitnessScaleFactor
+}
+
+// witnessStackSize returns the serialized size of the witness items, the
+// item count included, that satisfy witnessScript.
+func witnessStackSize(witnessScript []byte) (int, bool) {
+	var stack [][]byte
+	if _, nRequired, err := script.ParseMultiSigScript(witnessScript); err == nil {
+		stack = make([][]byte, nRequired+1)
+		for i := range stack[1:] {
+			stack[i+1] = make([]byte, 73)
+		}
+	} else {
+		ms, err := script.DecodeMiniscript(witnessScript, script.P2WSHContext)
+		if err != nil {
+			return 0, false
+		}
+		if stack, err = ms.Satisfy(sizingSatisfier{}); err != nil {
+			return 0, false
+		}
+	}
+	size := wire.VarIntSerializeSize(uint64(len(stack) + 1))
+	for _, item := range stack {
+		size += wire.VarIntSerializeSize(uint64(len(item))) + len(item)
+	}
+	return size, true
+}
+
+// sizingSatisfier satisfies any miniscript with placeholders of the
+// largest size the real data can have.
+type sizingSatisfier struct{}
+
+func (sizingSatisfier) Sign([]byte) ([]byte, bool) { return make([]byte, 73), true }
+
+func (sizingSatisfier) PubKeyByHash([]byte) ([]byte, bool) {
+	return make([]byte, btcec.PubKeyBytesLenCompressed), true
+}
+
+func (sizingSatisfier) Preimage(script.Fragment, []byte) ([]byte, bool) {
+	return make([]byte, 32), true
+}
+
+func (sizingSatisfier) CheckOlder(uint32) bool { return true }
+
+func (sizingSatisfier) CheckAfter(uint32) bool { return true }
+
+// InputVirtualSize returns the virtual size spending a single-key output with
+// the given pkScript adds to a transaction.
+func InputVirtualSize(pkScript []byte) int {
+	return txsizes.GetMinInputVirtualSize(pkScript)
+}
+
+// CostOfChange returns the fee for adding a change output to changeAddress
+// plus the fee for spending it later at the same rate.
+func CostOfChange(feeRate float64, changeAddress btcutil.Address) (btcutil.Amount, error) {
+	changeScript, err := script.EncodeTransferScript(changeAddress)
+	if err != nil {
+		return 0, err
+	}
+	outputSize := wire.NewTxOut(0, changeScript).SerializeSize()
+	return FeeForVirtualSize(feeRate, outputSize) +
+		FeeForVirtualSize(feeRate, InputVirtualSize(changeScript)), nil
+}
+
+func GetFundScriptSize(fundAddress btcutil.Address) (int, error) {
+	// Determine the script type and size
+	switch fundAddress.(type) {
+	case *btcutil.AddressPubKey: // P2PK
+		return 35, nil // [33-byte PubKey] OP_CHECKSIG
+	case *btcutil.AddressPubKeyHash: // P2PKH
+		return 25, nil // OP_DUP OP_HASH160 [20-byte HASH] OP_EQUALVERIFY OP_CHECKSIG
+	case *btcutil.AddressScriptHash: // P2SH
+		return 23, nil // OP_HASH160 [20-byte HASH] OP_EQUAL
+	case *btcutil.AddressWitnessPubKeyHash: // P2WPKH
+		return 22, nil // OP_0 [20-byte HASH]
+	case *btcutil.AddressWitnessScriptHash: // P2WSH
+		return 34, nil // OP_0 [32-byte HASH]
+	case *btcutil.AddressTaproot: // P2TR
+		return 34, nil // OP_1 [32-byte Taproot PubKey]
+	default:
+		return 0, fmt.Errorf("unsupported address type: %T", fundAddress)
+	}
+}
@@ -1 +1,29 @@
-package transaction
+package transaction
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosuda/btctxbuilder/script"
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+func TestScriptInputWeight_LargeMultiSig(t *testing.T) {
+	var keys [][]byte
+	for i := range 20 {
+		priv, _ := btcec.PrivKeyFromBytes([]byte{byte(i + 1)})
+		keys = append(keys, priv.PubKey().SerializeCompressed())
+	}
+	small, err := script.EncodeMultiSigScript(types.BTC, keys[:16], 16)
+	require.NoError(t, err)
+	large, err := script.EncodeMultiSigScript(types.BTC, keys, 20)
+	require.NoError(t, err)
+
+	// a 20-of-20 P2WSH input weighs 4 signatures and keys more than 16-of-16
+	smallWeight := scriptInputWeight(&types.SpendInfo{WitnessScript: small})
+	largeWeight := scriptInputWeight(&types.SpendInfo{WitnessScript: large})
+	require.Positive(t, smallWeight)
+	require.Equal(t, smallWeight+4*(1+73)+4*(1+33)+2, largeWeight)
+}
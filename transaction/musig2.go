@@ -0,0 +1,259 @@
+package transaction
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"slices"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr/musig2"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/txscript"
+
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+// BIP373 input fields. btcutil/psbt doesn't know them, so they travel in
+// PInput.Unknowns with the key type as first key byte.
+const (
+	psbtInMuSig2ParticipantPubKeys = 0x1a // <aggregate key> -> <participant keys>
+	psbtInMuSig2PubNonce           = 0x1b // <participant key> <aggregate key> -> <public nonce>
+	psbtInMuSig2PartialSig         = 0x1c // <participant key> <aggregate key> -> <partial signature>
+)
+
+// muSig2Input is the MuSig2 key path state of a PSBT input.
+type muSig2Input struct {
+	aggKey       []byte // 33-byte, before the taproot tweak
+	participants [][]byte
+	nonces       map[string][]byte // by participant key
+	partialSigs  map[string][]byte // by participant key
+}
+
+// readMuSig2Input collects the MuSig2 fields of in. It returns nil when the
+// input has no participants field. Fields for script path spends, which
+// carry a leaf hash, are ignored.
+func readMuSig2Input(in *psbt.PInput) (*muSig2Input, error) {
+	var m *muSig2Input
+	for _, u := range in.Unknowns {
+		if len(u.Key) == 1+btcec.PubKeyBytesLenCompressed && u.Key[0] == psbtInMuSig2ParticipantPubKeys {
+			if m != nil {
+				return nil, fmt.Errorf("more than one MuSig2 aggregate key")
+			}
+			if len(u.Value) == 0 || len(u.Value)%btcec.PubKeyBytesLenCompressed != 0 {
+				return nil, fmt.Errorf("invalid MuSig2 participants field")
+			}
+			m = &muSig2Input{aggKey: u.Key[1:], nonces: map[string][]byte{}, partialSigs: map[string][]byte{}}
+			for key := range slices.Chunk(u.Value, btcec.PubKeyBytesLenCompressed) {
+				m.participants = append(m.participants, key)
+			}
+		}
+	}
+	if m == nil {
+		return nil, nil
+	}
+
+	for _, u := range in.Unknowns {
+		if len(u.Key) != 1+2*btcec.PubKeyBytesLenCompressed ||
+			!bytes.Equal(u.Key[1+btcec.PubKeyBytesLenCompressed:], m.aggKey) {
+			continue
+		}
+		participant := string(u.Key[1 : 1+btcec.PubKeyBytesLenCompressed])
+		switch u.Key[0] {
+		case psbtInMuSig2PubNonce:
+			if len(u.Value) != musig2.PubNonceSize {
+				return nil, fmt.Errorf("invalid MuSig2 public nonce")
+			}
+			m.nonces[participant] = u.Value
+		case psbtInMuSig2PartialSig:
+			if len(u.Value) != 32 {
+				return nil, fmt.Errorf("invalid MuSig2 partial signature")
+			}
+			m.partialSigs[participant] = u.Value
+		}
+	}
+	return m, nil
+}
+
+func (m *muSig2Input) has(pubkey []byte) bool {
+	return slices.ContainsFunc(m.participants, func(p []byte) bool { return bytes.Equal(p, pubkey) })
+}
+
+// nonceList returns the public nonces in participant order, or nil while
+// some are missing.
+func (m *muSig2Input) nonceList() [][]byte {
+	return m.byParticipant(m.nonces)
+}
+
+// partialSigList returns the partial signatures in participant order, or
+// nil while some are missing.
+func (m *muSig2Input) partialSigList() [][]byte {
+	return m.byParticipant(m.partialSigs)
+}
+
+func (m *muSig2Input) byParticipant(values map[string][]byte) [][]byte {
+	list := make([][]byte, len(m.participants))
+	for i, p := range m.participants {
+		v, ok := values[string(p)]
+		if !ok {
+			return nil
+		}
+		list[i] = v
+	}
+	return list
+}
+
+// setUnknown adds or replaces the unknown field with the given key.
+func setUnknown(in *psbt.PInput, key, value []byte) {
+	for _, u := range in.Unknowns {
+		if bytes.Equal(u.Key, key) {
+			u.Value = value
+			return
+		}
+	}
+	in.Unknowns = append(in.Unknowns, &psbt.Unknown{Key: key, Value: value})
+}
+
+// AddMuSig2Participants marks input i as a MuSig2 key path spend by
+// pubKeys. The aggregate key must match the output being spent.
+func AddMuSig2Participants(packet *psbt.Packet, i int, pubKeys [][]byte) error {
+	if i < 0 || i >= len(packet.Inputs) {
+		return fmt.Errorf("input %d out of range", i)
+	}
+	if err := addMuSig2Participants(&packet.Inputs[i], pubKeys); err != nil {
+		return fmt.Errorf("input %d: %w", i, err)
+	}
+	return nil
+}
+
+func addMuSig2Participants(in *psbt.PInput, pubKeys [][]byte) error {
+	if in.WitnessUtxo == nil || !txscript.IsPayToTaproot(in.WitnessUtxo.PkScript) {
+		return fmt.Errorf("MuSig2 needs a taproot witness utxo")
+	}
+	aggKey, err := types.MuSig2AggregateKey(pubKeys)
+	if err != nil {
+		return err
+	}
+	outputKey, err := types.MuSig2OutputKey(pubKeys, merkleRoot(in))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(outputKey, in.WitnessUtxo.PkScript[2:]) {
+		return fmt.Errorf("MuSig2 participants don't match the output key")
+	}
+
+	key := append([]byte{psbtInMuSig2ParticipantPubKeys}, aggKey.SerializeCompressed()...)
+	setUnknown(in, key, bytes.Join(pubKeys, nil))
+	if len(in.TaprootInternalKey) == 0 {
+		in.TaprootInternalKey = schnorr.SerializePubKey(aggKey)
+	}
+	return nil
+}
+
+// AddMuSig2Nonces runs the first MuSig2 round for signer: it adds a public
+// nonce to every input signer participates in.
+func AddMuSig2Nonces(packet *psbt.Packet, signer *types.MuSig2Signer) error {
+	return forMuSig2Inputs(packet, signer, func(i int, m *muSig2Input, msg []byte) error {
+		nonce, err := signer.GenNonce(muSig2SessionID(packet, i, m), m.participants, msg)
+		if err != nil {
+			return err
+		}
+		key := append(append([]byte{psbtInMuSig2PubNonce}, signer.PubKey()...), m.aggKey...)
+		setUnknown(&packet.Inputs[i], key, nonce)
+		return nil
+	})
+}
+
+// AddMuSig2PartialSigs runs the second MuSig2 round for signer once the
+// public nonces of all participants are in the packet.
+func AddMuSig2PartialSigs(packet *psbt.Packet, signer *types.MuSig2Signer) error {
+	return forMuSig2Inputs(packet, signer, func(i int, m *muSig2Input, msg []byte) error {
+		nonces := m.nonceList()
+		if nonces == nil {
+			return fmt.Errorf("input %d: missing MuSig2 public nonces", i)
+		}
+		aggNonce, err := types.MuSig2AggregateNonces(nonces)
+		if err != nil {
+			return err
+		}
+		sig, err := signer.Sign(muSig2SessionID(packet, i, m), aggNonce, m.participants, merkleRoot(&packet.Inputs[i]), msg)
+		if err != nil {
+			return fmt.Errorf("input %d: %w", i, err)
+		}
+		key := append(append([]byte{psbtInMuSig2PartialSig}, signer.PubKey()...), m.aggKey...)
+		setUnknown(&packet.Inputs[i], key, sig)
+		return nil
+	})
+}
+
+func forMuSig2Inputs(packet *psbt.Packet, signer *types.MuSig2Signer, fn func(i int, m *muSig2Input, msg []byte) error) error {
+	prevOutputFetcher := PsbtPrevOutputFetcher(packet)
+	sigHashes := txscript.NewTxSigHashes(packet.UnsignedTx, prevOutputFetcher)
+	for i := range packet.Inputs {
+		m, err := readMuSig2Input(&packet.Inputs[i])
+		if err != nil {
+			return fmt.Errorf("input %d: %w", i, err)
+		}
+		if m == nil || !m.has(signer.PubKey()) {
+			continue
+		}
+		msg, err := txscript.CalcTaprootSignatureHash(sigHashes, packet.Inputs[i].SighashType, packet.UnsignedTx, i, prevOutputFetcher)
+		if err != nil {
+			return err
+		}
+		if err := fn(i, m, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aggregateMuSig2 turns the partial signatures of input i into its key
+// spend signature once every participant has signed. It reports whether
+// input i is a MuSig2 input at all.
+func aggregateMuSig2(packet *psbt.Packet, i int, sigHashes *txscript.TxSigHashes, prevOutFetcher txscript.PrevOutputFetcher) (isMuSig2 bool, err error) {
+	in := &packet.Inputs[i]
+	m, err := readMuSig2Input(in)
+	if err != nil || m == nil {
+		return false, err
+	}
+	nonces, sigs := m.nonceList(), m.partialSigList()
+	if nonces == nil || sigs == nil {
+		return true, nil
+	}
+
+	aggNonce, err := types.MuSig2AggregateNonces(nonces)
+	if err != nil {
+		return false, err
+	}
+	msg, err := txscript.CalcTaprootSignatureHash(sigHashes, in.SighashType, packet.UnsignedTx, i, prevOutFetcher)
+	if err != nil {
+		return false, err
+	}
+	for j, p := range m.participants {
+		if err := types.VerifyMuSig2PartialSig(sigs[j], nonces[j], aggNonce, m.participants, p, merkleRoot(in), msg); err != nil {
+			return true, err
+		}
+	}
+	sig, err := types.AggregateMuSig2PartialSigs(sigs, aggNonce, m.participants, merkleRoot(in), msg)
+	if err != nil {
+		return true, err
+	}
+	in.TaprootKeySpendSig = withSigHashType(sig, in.SighashType)
+	return true, nil
+}
+
+// muSig2SessionID ties a signer's nonce to one input of one transaction.
+func muSig2SessionID(packet *psbt.Packet, i int, m *muSig2Input) []byte {
+	txid := packet.UnsignedTx.TxHash()
+	id := binary.LittleEndian.AppendUint32(txid[:], uint32(i))
+	return append(id, m.aggKey...)
+}
+
+func merkleRoot(in *psbt.PInput) []byte {
+	if len(in.TaprootMerkleRoot) == 0 {
+		return nil
+	}
+	return in.TaprootMerkleRoot
+}
@@ -0,0 +1,98 @@
+package transaction
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosuda/btctxbuilder/types"
+	"github.com/gosuda/btctxbuilder/utils"
+)
+
+func TestMuSig2KeySpend(t *testing.T) {
+	params := types.GetParams(types.BTC_Signet)
+	to := "n368zCWREFiRRX7icJRBb6n8nMsjJjNVK8"
+
+	signers := make([]*types.MuSig2Signer, 3)
+	keys := make([][]byte, 3)
+	for i := range signers {
+		seed := sha256.Sum256([]byte(fmt.Sprintf("participant %d", i)))
+		var err error
+		signers[i], err = types.NewMuSig2Signer(fmt.Sprintf("%x", seed))
+		require.NoError(t, err)
+		keys[i] = signers[i].PubKey()
+	}
+	outputKey, err := types.MuSig2OutputKey(keys, nil)
+	require.NoError(t, err)
+	addr, err := btcutil.NewAddressTaproot(outputKey, params)
+	require.NoError(t, err)
+	from := addr.EncodeAddress()
+
+	build := func(t *testing.T) (*psbt.Packet, []*types.Utxo) {
+		utxos := newTestUtxos(t, params, from, 40000, 30000)
+		packet, err := NewTxBuilder(params).
+			FeeRate(2).
+			From(from).
+			SpendInfo(from, &types.SpendInfo{MuSig2PubKeys: keys}).
+			To(to, 50000).
+			CoinSelector(utils.Knapsack{}).
+			SelectUtxo(utxos).
+			Build().
+			Packet()
+		require.NoError(t, err)
+		require.Len(t, packet.Inputs, 2)
+		return packet, utxos
+	}
+
+	t.Run("shared packet", func(t *testing.T) {
+		packet, utxos := build(t)
+		for _, s := range signers {
+			require.NoError(t, AddMuSig2Nonces(packet, s))
+		}
+		for _, s := range signers[:2] {
+			require.NoError(t, AddMuSig2PartialSigs(packet, s))
+		}
+
+		// not every participant has signed yet
+		signed, err := SignTx(params, packet, nil, nil)
+		require.NoError(t, err)
+		require.Empty(t, signed.Inputs[0].FinalScriptWitness)
+
+		require.NoError(t, AddMuSig2PartialSigs(packet, signers[2]))
+		signed, err = SignTx(params, packet, nil, nil)
+		require.NoError(t, err)
+		executeInputs(t, signed, utxos)
+	})
+
+	t.Run("combined packets", func(t *testing.T) {
+		packet, utxos := build(t)
+		copies := func(round func(*psbt.Packet, *types.MuSig2Signer) error) []*psbt.Packet {
+			var packets []*psbt.Packet
+			for _, s := range signers {
+				raw, err := types.EncodePsbt(packet)
+				require.NoError(t, err)
+				copied, err := types.DecodePsbt(raw)
+				require.NoError(t, err)
+				require.NoError(t, round(copied, s))
+				packets = append(packets, copied)
+			}
+			return packets
+		}
+
+		packet, err := CombineTx(copies(AddMuSig2Nonces)...)
+		require.NoError(t, err)
+		combined, err := CombineTx(copies(AddMuSig2PartialSigs)...)
+		require.NoError(t, err)
+		executeInputs(t, combined, utxos)
+	})
+
+	t.Run("wrong participants", func(t *testing.T) {
+		packet, _ := build(t)
+		require.Error(t, AddMuSig2Participants(packet, 0, keys[:2]))
+		require.NoError(t, AddMuSig2Participants(packet, 0, keys))
+	})
+}
@@ -0,0 +1,224 @@
+package transaction
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/gosuda/btctxbuilder/script"
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+const (
+	// RBFSequence is the nSequence TxBuilder.RBF sets on every input. Any
+	// value below 0xfffffffe signals replaceability (BIP125 rule 1) while
+	// keeping nLockTime enforced.
+	RBFSequence = wire.MaxTxInSequenceNum - 2
+
+	// IncrementalRelayFee is the default -incrementalrelayfee of Bitcoin
+	// Core in sat/vB: a replacement has to pay at least this much per vbyte
+	// on top of the fee of the transaction it replaces.
+	IncrementalRelayFee = 1.0
+
+	maxBumpAttempts = 16
+)
+
+var ErrNotReplaceable = errors.New("transaction does not signal BIP125 replaceability")
+
+// SignalsRBF reports whether any input of tx opts in to replacement.
+func SignalsRBF(tx *wire.MsgTx) bool {
+	for _, in := range tx.TxIn {
+		if in.Sequence <= RBFSequence {
+			return true
+		}
+	}
+	return false
+}
+
+// BumpFee builds an unsigned BIP125 replacement of tx paying at least feeRate
+// sat/vB. tx may be signed or unsigned.
+//
+// utxos must contain the outputs spent by tx; any other entry is a candidate
+// for an additional input and is only used when confirmed. The fee increase
+// comes out of the change output first, the last output paying changeAddr;
+// earlier outputs paying changeAddr are payments and kept. When the change is
+// not enough, more inputs are added (assumed to belong to the same address as
+// the first input of tx) and a fresh change output is created. The
+// replacement pays a higher fee and fee rate than tx, and the difference
+// covers its own vsize at IncrementalRelayFee. Inputs of tx keep their
+// nSequence when it signals replaceability; every other input gets
+// RBFSequence.
+func BumpFee(
+	params *chaincfg.Params,
+	tx *wire.MsgTx,
+	utxos []*types.Utxo,
+	changeAddr string,
+	feeRate float64,
+) (*psbt.Packet, error) {
+	if !SignalsRBF(tx) {
+		return nil, ErrNotReplaceable
+	}
+
+	changeBTC, _, err := types.DecodeAddress(changeAddr, params)
+	if err != nil {
+		return nil, fmt.Errorf("decode change address: %w", err)
+	}
+	changeScript, err := script.EncodeTransferScript(changeBTC)
+	if err != nil {
+		return nil, fmt.Errorf("encode change script: %w", err)
+	}
+
+	// Split the UTXO set into the inputs of tx and the pool to fund from.
+	byOutpoint := make(map[wire.OutPoint]*types.Utxo, len(utxos))
+	for _, u := range utxos {
+		if u.RawTx == nil {
+			return nil, fmt.Errorf("utxo %s:%d: missing raw tx", u.Txid, u.Vout)
+		}
+		byOutpoint[wire.OutPoint{Hash: u.RawTx.TxHash(), Index: u.Vout}] = u
+	}
+
+	var inputs TxInputs
+	var fromAddr string
+	for i, in := range tx.TxIn {
+		u, ok := byOutpoint[in.PreviousOutPoint]
+		if !ok {
+			return nil, fmt.Errorf("input %d: utxo %s not provided", i, in.PreviousOutPoint)
+		}
+		delete(byOutpoint, in.PreviousOutPoint)
+
+		addr, err := addressFromPkScript(u.RawTx.TxOut[u.Vout].PkScript, params)
+		if err != nil {
+			return nil, fmt.Errorf("input %d: %w", i, err)
+		}
+		if fromAddr == "" {
+			fromAddr = addr
+		}
+		if err := inputs.AddInput(params, u.RawTx, u.Vout, u.Value, addr); err != nil {
+			return nil, fmt.Errorf("input %d: %w", i, err)
+		}
+	}
+
+	var pool []*types.Utxo
+	for _, u := range utxos {
+		op := wire.OutPoint{Hash: u.RawTx.TxHash(), Index: u.Vout}
+		if _, ok := byOutpoint[op]; ok && u.Status.Confirmed {
+			pool = append(pool, u)
+		}
+	}
+
+	// Only the last output paying changeAddr is change, as the builder
+	// appends it after the payments; any other is a payment and kept as is.
+	change := -1
+	for i, out := range tx.TxOut {
+		if bytes.Equal(out.PkScript, changeScript) {
+			change = i
+		}
+	}
+	var outputs TxOutputs
+	var outTotal btcutil.Amount
+	for i, out := range tx.TxOut {
+		outTotal += btcutil.Amount(out.Value)
+		if i == change {
+			continue
+		}
+		outputs.AddOutputPkScript(out.PkScript, out.Value)
+	}
+
+	oldFee := inputs.AmountTotal() - outTotal
+	if oldFee < 0 {
+		return nil, fmt.Errorf("outputs exceed inputs by %v", -oldFee)
+	}
+	oldVSize := txVirtualSize(tx, inputs)
+	oldRate := float64(oldFee) / float64(oldVSize)
+
+	rate := max(feeRate, oldRate+IncrementalRelayFee)
+	for attempt := 0; attempt < maxBumpAttempts; attempt++ {
+		bumpInputs := make(TxInputs, len(inputs))
+		copy(bumpInputs, inputs)
+
+		msg := wire.NewMsgTx(tx.Version)
+		msg.LockTime = tx.LockTime
+		ins, err := bumpInputs.ToWire()
+		if err != nil {
+			return nil, err
+		}
+		for _, in := range ins {
+			msg.AddTxIn(in)
+		}
+		outs, err := outputs.ToWire()
+		if err != nil {
+			return nil, err
+		}
+		for _, out := range outs {
+			msg.AddTxOut(out)
+		}
+
+		if err := FundRawTransaction(params, msg, &bumpInputs, outputs, changeAddr, rate, pool, fromAddr); err != nil {
+			return nil, err
+		}
+		// Inputs of tx keep their nSequence, which may carry a BIP68
+		// relative lock, as long as it signals replaceability; added
+		// inputs signal it as well.
+		for i, in := range msg.TxIn {
+			in.Sequence = RBFSequence
+			if i < len(tx.TxIn) && tx.TxIn[i].Sequence <= RBFSequence {
+				in.Sequence = tx.TxIn[i].Sequence
+			}
+		}
+
+		var newOutTotal btcutil.Amount
+		for _, out := range msg.TxOut {
+			newOutTotal += btcutil.Amount(out.Value)
+		}
+		newFee := bumpInputs.AmountTotal() - newOutTotal
+		newVSize := estimateVirtualSize(bumpInputs, msg.TxOut, 0)
+		if err := checkReplacement(oldFee, oldRate, newFee, newVSize); err != nil {
+			// Dropping the change output can shrink the replacement enough
+			// to fall short of the absolute fee; try again a bit higher.
+			rate += IncrementalRelayFee
+			continue
+		}
+
+		pkt, err := psbt.NewFromUnsignedTx(msg)
+		if err != nil {
+			return nil, err
+		}
+		if err := DecorateTxInputs(pkt, bumpInputs); err != nil {
+			return nil, err
+		}
+		return pkt, nil
+	}
+	return nil, fmt.Errorf("no replacement satisfying BIP125 found up to %.2f sat/vB", rate)
+}
+
+// checkReplacement verifies BIP125 rules 3 and 4 plus the fee rate increase
+// Bitcoin Core requires of a replacement.
+func checkReplacement(oldFee btcutil.Amount, oldRate float64, newFee btcutil.Amount, newVSize int) error {
+	if newFee < oldFee {
+		return fmt.Errorf("replacement fee %v below original fee %v", newFee, oldFee)
+	}
+	if relay := FeeForVirtualSize(IncrementalRelayFee, newVSize); newFee-oldFee < relay {
+		return fmt.Errorf("fee increase %v below incremental relay fee %v", newFee-oldFee, relay)
+	}
+	if newRate := float64(newFee) / float64(newVSize); newRate <= oldRate {
+		return fmt.Errorf("replacement fee rate %.2f not above original %.2f", newRate, oldRate)
+	}
+	return nil
+}
+
+// txVirtualSize returns the vsize of tx, estimated from its inputs when tx
+// carries no signatures yet.
+func txVirtualSize(tx *wire.MsgTx, inputs TxInputs) int {
+	for _, in := range tx.TxIn {
+		if len(in.SignatureScript) > 0 || len(in.Witness) > 0 {
+			return int(mempool.GetTxVirtualSize(btcutil.NewTx(tx)))
+		}
+	}
+	return estimateVirtualSize(inputs, tx.TxOut, 0)
+}
@@ -0,0 +1,166 @@
+package transaction
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+func packetFee(t *testing.T, pkt *psbt.Packet) btcutil.Amount {
+	t.Helper()
+	in, err := psbt.SumUtxoInputValues(pkt)
+	require.NoError(t, err)
+	var out int64
+	for _, o := range pkt.UnsignedTx.TxOut {
+		out += o.Value
+	}
+	return btcutil.Amount(in - out)
+}
+
+func TestBumpFee(t *testing.T) {
+	params := types.GetParams(types.BTC_Signet)
+	from := "tb1q307vt2zz3f66hhs90p0le2pp6r53tvyqnzsy42"
+	to := "n368zCWREFiRRX7icJRBb6n8nMsjJjNVK8"
+	signer, err := types.NewECDSASigner("887ad33f247a7df59f1bf61b6aa69ab2a537c0708d7d6fb6614e10511fca377b")
+	require.NoError(t, err)
+
+	utxos := newTestUtxos(t, params, from, 50000, 80000)
+	utxos[1].Status.Confirmed = true
+
+	build := NewTxBuilder(params).
+		FeeRate(2).
+		From(from).
+		To(to, 20000).
+		RBF().
+		SelectUtxo(utxos[:1]).
+		Build().
+		SignWith(signer.Sign, signer.PubKey())
+	require.NoError(t, build.Err())
+	original, err := build.Packet()
+	require.NoError(t, err)
+	originalFee := packetFee(t, original)
+	signedTx, err := psbt.Extract(original)
+	require.NoError(t, err)
+	require.True(t, SignalsRBF(signedTx))
+
+	// shrinking the change is enough
+	bumped, err := BumpFee(params, signedTx, utxos, from, 10)
+	require.NoError(t, err)
+	require.Len(t, bumped.UnsignedTx.TxIn, 1)
+	require.Len(t, bumped.UnsignedTx.TxOut, 2)
+	require.Equal(t, int64(20000), bumped.UnsignedTx.TxOut[0].Value)
+	for _, in := range bumped.UnsignedTx.TxIn {
+		require.Equal(t, uint32(RBFSequence), in.Sequence)
+	}
+	bumpedFee := packetFee(t, bumped)
+	require.Greater(t, bumpedFee, originalFee)
+	vsize := estimateVirtualSize(TxInputs{build.Inputs[0]}, bumped.UnsignedTx.TxOut, 0)
+	require.GreaterOrEqual(t, float64(bumpedFee)/float64(vsize), 10.0)
+
+	signed, err := SignTx(params, bumped, signer.Sign, signer.PubKey())
+	require.NoError(t, err)
+	_, err = types.EncodePsbtToRawTx(signed)
+	require.NoError(t, err)
+
+	// the change cannot absorb the bump, a confirmed UTXO is added
+	bumped, err = BumpFee(params, signedTx, utxos, from, 400)
+	require.NoError(t, err)
+	require.Len(t, bumped.UnsignedTx.TxIn, 2)
+	require.Equal(t, utxos[1].RawTx.TxHash(), bumped.UnsignedTx.TxIn[1].PreviousOutPoint.Hash)
+	require.Greater(t, packetFee(t, bumped), originalFee)
+
+	// unconfirmed UTXOs are never added
+	utxos[1].Status.Confirmed = false
+	_, err = BumpFee(params, signedTx, utxos, from, 400)
+	require.Error(t, err)
+}
+
+func TestBumpFee_KeepsSequence(t *testing.T) {
+	params := types.GetParams(types.BTC_Signet)
+	from := "tb1q307vt2zz3f66hhs90p0le2pp6r53tvyqnzsy42"
+	to := "n368zCWREFiRRX7icJRBb6n8nMsjJjNVK8"
+	utxos := newTestUtxos(t, params, from, 50000, 80000)
+	utxos[1].Status.Confirmed = true
+
+	// a relative lock of 10 blocks also signals replaceability
+	const csv = 10
+	build := NewTxBuilder(params).
+		FeeRate(2).
+		From(from).
+		To(to, 20000).
+		SelectUtxo(utxos[:1]).
+		InputSequence(utxos[0].Txid, utxos[0].Vout, csv).
+		Build()
+	require.NoError(t, build.Err())
+	original, err := build.Packet()
+	require.NoError(t, err)
+	require.Equal(t, uint32(csv), original.UnsignedTx.TxIn[0].Sequence)
+
+	// shrinking the change is enough
+	bumped, err := BumpFee(params, original.UnsignedTx, utxos, from, 10)
+	require.NoError(t, err)
+	require.Len(t, bumped.UnsignedTx.TxIn, 1)
+	require.Equal(t, uint32(csv), bumped.UnsignedTx.TxIn[0].Sequence)
+
+	// the added input signals replaceability, the original keeps its lock
+	bumped, err = BumpFee(params, original.UnsignedTx, utxos, from, 400)
+	require.NoError(t, err)
+	require.Len(t, bumped.UnsignedTx.TxIn, 2)
+	require.Equal(t, uint32(csv), bumped.UnsignedTx.TxIn[0].Sequence)
+	require.Equal(t, uint32(RBFSequence), bumped.UnsignedTx.TxIn[1].Sequence)
+}
+
+func TestBumpFee_NotReplaceable(t *testing.T) {
+	params := types.GetParams(types.BTC_Signet)
+	from := "tb1q307vt2zz3f66hhs90p0le2pp6r53tvyqnzsy42"
+	utxos := newTestUtxos(t, params, from, 50000)
+
+	build := NewTxBuilder(params).
+		FeeRate(2).
+		From(from).
+		To(from, 20000).
+		SelectUtxo(utxos).
+		Build()
+	require.NoError(t, build.Err())
+	pkt, err := build.Packet()
+	require.NoError(t, err)
+	require.Equal(t, wire.MaxTxInSequenceNum, pkt.UnsignedTx.TxIn[0].Sequence)
+
+	_, err = BumpFee(params, pkt.UnsignedTx, utxos, from, 10)
+	require.ErrorIs(t, err, ErrNotReplaceable)
+}
+
+func TestBumpFee_PaymentToChangeAddress(t *testing.T) {
+	params := types.GetParams(types.BTC_Signet)
+	from := "tb1q307vt2zz3f66hhs90p0le2pp6r53tvyqnzsy42"
+	to := "n368zCWREFiRRX7icJRBb6n8nMsjJjNVK8"
+	utxos := newTestUtxos(t, params, from, 50000)
+
+	build := NewTxBuilder(params).
+		FeeRate(2).
+		From(from).
+		To(from, 20000).
+		To(to, 10000).
+		RBF().
+		SelectUtxo(utxos).
+		Build()
+	require.NoError(t, build.Err())
+	original, err := build.Packet()
+	require.NoError(t, err)
+	require.Len(t, original.UnsignedTx.TxOut, 3)
+	change := original.UnsignedTx.TxOut[2].Value
+
+	// the payment to from is kept, only the change pays the bump
+	bumped, err := BumpFee(params, original.UnsignedTx, utxos, from, 10)
+	require.NoError(t, err)
+	require.Len(t, bumped.UnsignedTx.TxOut, 3)
+	require.Equal(t, int64(20000), bumped.UnsignedTx.TxOut[0].Value)
+	require.Equal(t, int64(10000), bumped.UnsignedTx.TxOut[1].Value)
+	require.Less(t, bumped.UnsignedTx.TxOut[2].Value, change)
+	require.Greater(t, packetFee(t, bumped), packetFee(t, original))
+}
@@ -0,0 +1,140 @@
+package transaction
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"lukechampine.com/uint128"
+
+	"github.com/gosuda/btctxbuilder/ordinals/runes"
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+// RunePostage is the value of the outputs receiving runes, above the dust
+// limit of every standard output type.
+const RunePostage = 546
+
+// RuneTransfer sends Amount of the rune ID to Address. A zero Amount sends
+// all of the rune not transferred by earlier edicts.
+type RuneTransfer struct {
+	Address string
+	ID      runes.RuneId
+	Amount  uint128.Uint128
+}
+
+// NewRunestoneEdictTx spends the runeUtxos of fromAddress and transfers
+// rune balances with one edict per transfer, to outputs in the order of
+// transfers right after the runestone output. The runes left over go back
+// to fromAddress in the next output, with the sats of runeUtxos beyond the
+// postage of the transfers. The fee is paid from utxos of fundAddress,
+// which gets the change, and runeUtxos are never picked for it. fee is
+// expected in sat/vB.
+//
+// The PSBT is unsigned: the owners of fromAddress and fundAddress each sign
+// their inputs with SignTx.
+func NewRunestoneEdictTx(
+	params *chaincfg.Params,
+	runeUtxos []*types.Utxo,
+	fromAddress string,
+	transfers []RuneTransfer,
+	utxos []*types.Utxo,
+	fundAddress string,
+	fee float64,
+) (*psbt.Packet, error) {
+	if len(runeUtxos) == 0 {
+		return nil, errors.New("no rune utxos")
+	}
+	if len(transfers) == 0 {
+		return nil, errors.New("no rune transfers")
+	}
+
+	runestone := &runes.Runestone{}
+	for i, transfer := range transfers {
+		runestone.Edicts = append(runestone.Edicts, runes.Edict{
+			ID:     transfer.ID,
+			Amount: transfer.Amount,
+			Output: uint32(i + 1),
+		})
+	}
+	change := uint32(len(transfers) + 1)
+	runestone.Pointer = &change
+
+	builder := NewTxBuilder(params).
+		FeeRate(fee).
+		From(fundAddress).
+		Change(fundAddress)
+	var runeValue int64
+	for _, u := range runeUtxos {
+		runeValue += u.Value
+		if err := builder.Inputs.AddInput(params, u.RawTx, u.Vout, u.Value, fromAddress); err != nil {
+			return nil, fmt.Errorf("rune utxo %s:%d: %w", u.Txid, u.Vout, err)
+		}
+	}
+	if err := addRunestone(builder, runestone); err != nil {
+		return nil, err
+	}
+	for _, transfer := range transfers {
+		builder.To(transfer.Address, RunePostage)
+	}
+	return builder.
+		To(fromAddress, max(RunePostage, runeValue-int64(len(transfers))*RunePostage)).
+		SelectUtxo(excludeUtxos(utxos, runeUtxos)).
+		Build().
+		Packet()
+}
+
+// NewRuneMintTx mints the rune id to toAddress, in the output right after
+// the runestone, and pays the fee from utxos of fundAddress, which gets the
+// change. The terms of the rune set the amount; a mint outside them is
+// ignored by indexers, fee included. fee is expected in sat/vB.
+//
+// The PSBT is unsigned; the owner of fundAddress signs it with SignTx.
+func NewRuneMintTx(
+	params *chaincfg.Params,
+	id runes.RuneId,
+	toAddress string,
+	utxos []*types.Utxo,
+	fundAddress string,
+	fee float64,
+) (*psbt.Packet, error) {
+	pointer := uint32(1)
+	builder := NewTxBuilder(params).
+		FeeRate(fee).
+		From(fundAddress).
+		Change(fundAddress)
+	if err := addRunestone(builder, &runes.Runestone{Mint: &id, Pointer: &pointer}); err != nil {
+		return nil, err
+	}
+	return builder.
+		To(toAddress, RunePostage).
+		SelectUtxo(utxos).
+		Build().
+		Packet()
+}
+
+// addRunestone adds the OP_RETURN output of runestone to the outputs of b.
+func addRunestone(b *TxBuilder, runestone *runes.Runestone) error {
+	pkScript, err := runestone.Encipher()
+	if err != nil {
+		return fmt.Errorf("runestone: %w", err)
+	}
+	b.Outputs.AddOutputPkScript(pkScript, 0)
+	return nil
+}
+
+// excludeUtxos returns the utxos that aren't in exclude.
+func excludeUtxos(utxos, exclude []*types.Utxo) []*types.Utxo {
+	excluded := make(map[string]bool, len(exclude))
+	for _, u := range exclude {
+		excluded[fmt.Sprintf("%s:%d", u.Txid, u.Vout)] = true
+	}
+	kept := make([]*types.Utxo, 0, len(utxos))
+	for _, u := range utxos {
+		if !excluded[fmt.Sprintf("%s:%d", u.Txid, u.Vout)] {
+			kept = append(kept, u)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,122 @@
+package transaction
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+	"lukechampine.com/uint128"
+
+	"github.com/gosuda/btctxbuilder/ordinals/runes"
+	"github.com/gosuda/btctxbuilder/script"
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+// newRuneWallets derives n P2WPKH wallets.
+func newRuneWallets(t *testing.T, params *chaincfg.Params, n int) ([]*types.ECDSASigner, []string) {
+	t.Helper()
+	master, err := types.NewHDKeyFromSeed(make([]byte, 32), params)
+	require.NoError(t, err)
+	signers := make([]*types.ECDSASigner, n)
+	addrs := make([]string, n)
+	for i := range signers {
+		key, err := master.DerivePath(fmt.Sprintf("m/84'/1'/0'/0/%d", i))
+		require.NoError(t, err)
+		signers[i], err = key.ECDSASigner()
+		require.NoError(t, err)
+		addrs[i], err = types.PubKeyToAddr(signers[i].PubKey(), types.P2WPKH, params)
+		require.NoError(t, err)
+	}
+	return signers, addrs
+}
+
+func TestNewRunestoneEdictTx(t *testing.T) {
+	params := types.GetParams(types.BTC_Signet)
+	signers, addrs := newRuneWallets(t, params, 4)
+	owner, funder := signers[0], signers[1]
+	from, fund, alice, bob := addrs[0], addrs[1], addrs[2], addrs[3]
+
+	runeUtxos := newTestUtxos(t, params, from, RunePostage, 10000)
+	fundUtxos := newTestUtxos(t, params, fund, 20000)
+	id := runes.RuneId{Block: 840000, Tx: 7}
+	other := runes.RuneId{Block: 840000, Tx: 3}
+	transfers := []RuneTransfer{
+		{Address: alice, ID: id, Amount: uint128.From64(500)},
+		{Address: bob, ID: other, Amount: uint128.From64(42)},
+	}
+
+	// the rune UTXOs never fund the fee, even when offered
+	pkt, err := NewRunestoneEdictTx(params, runeUtxos, from, transfers, append(fundUtxos, runeUtxos...), fund, 5)
+	require.NoError(t, err)
+	tx := pkt.UnsignedTx
+	require.Len(t, tx.TxIn, 3)
+	for i, u := range append(runeUtxos, fundUtxos...) {
+		require.Equal(t, wire.OutPoint{Hash: u.RawTx.TxHash(), Index: u.Vout}, tx.TxIn[i].PreviousOutPoint)
+	}
+
+	// runestone, the transfers in order, the rune change, then the change
+	require.Len(t, tx.TxOut, 5)
+	require.Zero(t, tx.TxOut[0].Value)
+	for i, addr := range []string{alice, bob, from, fund} {
+		decoded, _, err := types.DecodeAddress(addr, params)
+		require.NoError(t, err)
+		pkScript, err := script.EncodeTransferScript(decoded)
+		require.NoError(t, err)
+		require.Equal(t, pkScript, tx.TxOut[i+1].PkScript)
+	}
+	// the rune sats go back with the runes, the fee is on the funder
+	require.EqualValues(t, RunePostage, tx.TxOut[1].Value)
+	require.EqualValues(t, RunePostage, tx.TxOut[2].Value)
+	require.EqualValues(t, 10000-RunePostage, tx.TxOut[3].Value)
+
+	runestone, cenotaph := runes.Decipher(tx)
+	require.Nil(t, cenotaph)
+	change := uint32(3)
+	require.Equal(t, &runes.Runestone{
+		// edicts are sorted by rune ID
+		Edicts: []runes.Edict{
+			{ID: other, Amount: uint128.From64(42), Output: 2},
+			{ID: id, Amount: uint128.From64(500), Output: 1},
+		},
+		Pointer: &change,
+	}, runestone)
+
+	// each owner signs their own inputs only
+	_, err = SignTx(params, pkt, owner.Sign, owner.PubKey())
+	require.NoError(t, err)
+	require.NotNil(t, pkt.Inputs[0].FinalScriptWitness)
+	require.Nil(t, pkt.Inputs[2].FinalScriptWitness)
+	_, err = SignTx(params, pkt, funder.Sign, funder.PubKey())
+	require.NoError(t, err)
+	executeInputs(t, pkt, append(runeUtxos, fundUtxos...))
+
+	t.Run("no transfers", func(t *testing.T) {
+		_, err := NewRunestoneEdictTx(params, runeUtxos, from, nil, fundUtxos, fund, 5)
+		require.Error(t, err)
+	})
+}
+
+func TestNewRuneMintTx(t *testing.T) {
+	params := types.GetParams(types.BTC_Signet)
+	signers, addrs := newRuneWallets(t, params, 1)
+	signer, fund := signers[0], addrs[0]
+	to := "n368zCWREFiRRX7icJRBb6n8nMsjJjNVK8"
+	utxos := newTestUtxos(t, params, fund, 10000)
+	id := runes.RuneId{Block: 840000, Tx: 7}
+
+	pkt, err := NewRuneMintTx(params, id, to, utxos, fund, 2)
+	require.NoError(t, err)
+	require.Len(t, pkt.UnsignedTx.TxOut, 3)
+	require.EqualValues(t, RunePostage, pkt.UnsignedTx.TxOut[1].Value)
+
+	runestone, cenotaph := runes.Decipher(pkt.UnsignedTx)
+	require.Nil(t, cenotaph)
+	pointer := uint32(1)
+	require.Equal(t, &runes.Runestone{Mint: &id, Pointer: &pointer}, runestone)
+
+	_, err = SignTx(params, pkt, signer.Sign, signer.PubKey())
+	require.NoError(t, err)
+	executeInputs(t, pkt, utxos)
+}
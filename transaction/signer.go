@@ -1,639 +1,1207 @@
-package transaction
-
-import (
-	"bytes"
-	"crypto/sha256"
-	"encoding/binary"
-	"encoding/hex"
-	"fmt"
-	"io"
-
-	"github.com/btcsuite/btcd/btcec/v2"
-	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
-	"github.com/btcsuite/btcd/btcec/v2/schnorr"
-	"github.com/btcsuite/btcd/btcutil/psbt"
-	"github.com/btcsuite/btcd/chaincfg"
-	"github.com/btcsuite/btcd/chaincfg/chainhash"
-	"github.com/btcsuite/btcd/txscript"
-	"github.com/btcsuite/btcd/wire"
-	"github.com/decred/dcrd/dcrec/secp256k1/v4"
-
-	"github.com/gosuda/btctxbuilder/types"
-)
-
-// signTx signs a PSBT packet using the provided signer and public key.
-func SignTx(chain *chaincfg.Params, packet *psbt.Packet, sign types.Signer, pubkey []byte) (*psbt.Packet, error) {
-	err := psbt.InputsReadyToSign(packet)
-	if err != nil {
-		return nil, err
-	}
-
-	updater, err := psbt.NewUpdater(packet)
-	if err != nil {
-		return nil, err
-	}
-
-	prevOutputFetcher := PsbtPrevOutputFetcher(packet)
-	for i, input := range packet.Inputs {
-		// Extract previous transaction output information
-		if input.WitnessUtxo == nil && input.NonWitnessUtxo == nil {
-			return nil, fmt.Errorf("missing input UTXO information for input %d", i)
-		}
-
-		var prevOutValue int64
-		var pkScript []byte
-		if input.WitnessUtxo != nil {
-			updater.AddInWitnessUtxo(input.WitnessUtxo, i)
-			prevOutValue = input.WitnessUtxo.Value
-			pkScript = input.WitnessUtxo.PkScript
-		} else if input.NonWitnessUtxo != nil {
-			index := packet.UnsignedTx.TxIn[i].PreviousOutPoint.Index
-			prevOut := input.NonWitnessUtxo.TxOut[index]
-			prevOutValue = prevOut.Value
-			pkScript = prevOut.PkScript
-		}
-
-		scriptClass, _, _, err := txscript.ExtractPkScriptAddrs(pkScript, chain)
-		if err != nil {
-			return nil, err
-		}
-
-		switch scriptClass {
-		case txscript.WitnessV1TaprootTy: // P2TR
-			err = signInputP2TR(updater, i, pkScript, sign, prevOutputFetcher)
-		case txscript.PubKeyTy: // P2PK
-			err = signInputP2PK(updater, i, pkScript, sign)
-		case txscript.PubKeyHashTy: // P2PKH
-			err = signInputP2PKH(updater, i, pkScript, sign, pubkey)
-		case txscript.ScriptHashTy: // P2SH
-			err = signInputP2SH(updater, input.RedeemScript, i, pkScript, sign, pubkey)
-		case txscript.WitnessV0PubKeyHashTy: // P2WPKH
-			err = signInputP2WPKH(updater, i, pkScript, prevOutValue, prevOutputFetcher, sign, pubkey)
-		case txscript.WitnessV0ScriptHashTy: // P2WSH
-			err = signInputP2WSH(updater, i, pkScript, input.WitnessScript, prevOutValue, prevOutputFetcher, sign, pubkey)
-		case txscript.MultiSigTy: // Multisig
-			panic("not supported yet")
-		case txscript.NullDataTy: // OP_RETURN
-
-		default:
-			return nil, fmt.Errorf("unsupported script type")
-		}
-		if err != nil {
-			return nil, err
-		}
-		_, err = psbt.MaybeFinalize(packet, i)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	return packet, nil
-}
-
-func signInputP2PK(updater *psbt.Updater, i int, prevPkScript []byte, sign types.Signer) error {
-	// TODO : hashtype always all in p2pk
-	hashType := txscript.SigHashAll
-	if err := updater.AddInSighashType(hashType, i); err != nil {
-		return err
-	}
-	signature, err := RawTxInSignature(updater.Upsbt.UnsignedTx, i, prevPkScript, hashType, sign)
-	if err != nil {
-		return err
-	}
-	scriptSig, err := txscript.NewScriptBuilder().AddData(signature).Script()
-	if err != nil {
-		return err
-	}
-	updater.Upsbt.Inputs[i].FinalScriptSig = scriptSig
-	return nil
-}
-
-func signInputP2PKH(updater *psbt.Updater, i int, prevPkScript []byte, sign types.Signer, pubkey []byte) error {
-	// TODO : hashtype always all in p2pkh
-	hashType := txscript.SigHashAll
-	if err := updater.AddInSighashType(hashType, i); err != nil {
-		return err
-	}
-
-	signature, err := RawTxInSignature(updater.Upsbt.UnsignedTx, i, prevPkScript, txscript.SigHashAll, sign)
-	if err != nil {
-		return err
-	}
-
-	if signOutcome, err := updater.Sign(i, signature, pubkey, nil, nil); err != nil {
-		return err
-	} else if signOutcome != psbt.SignSuccesful {
-		return fmt.Errorf("signing failed, code: %d", signOutcome)
-	}
-
-	return nil
-}
-
-func signInputP2SH(updater *psbt.Updater, redeemScript []byte, i int, prevPkScript []byte, sign types.Signer, pubkey []byte) error {
-	hashType := txscript.SigHashAll
-	if err := updater.AddInSighashType(hashType, i); err != nil {
-		return err
-	}
-
-	// valid RedeemScript
-	if !ValidRedeemSignature(redeemScript, prevPkScript) {
-		return fmt.Errorf("invalid redeem script")
-	}
-
-	if txscript.IsWitnessProgram(redeemScript) {
-		return fmt.Errorf("redeemScript is a witness program (nested segwit). Use a P2WPKH/WSH path with amount-aware signing")
-	}
-
-	signature, err := RawTxInSignature(updater.Upsbt.UnsignedTx, i, redeemScript, hashType, sign)
-	if err != nil {
-		return err
-	}
-	signOutcome, err := updater.Sign(i, signature, pubkey, redeemScript, nil)
-	if err != nil {
-		return fmt.Errorf("failed to sign PSBT input: %v", err)
-	} else if signOutcome != psbt.SignSuccesful {
-		return fmt.Errorf("signing was not successful, outcome: %v", signOutcome)
-	}
-	return nil
-}
-
-func signInputP2WPKH(updater *psbt.Updater, i int, prevPkScript []byte, amount int64, prevOutFetcher *txscript.MultiPrevOutFetcher, sign types.Signer, pubkey []byte) error {
-	// TODO : hashtype always all in p2wpkh
-	hashType := txscript.SigHashAll
-	if err := updater.AddInSighashType(hashType, i); err != nil {
-		return err
-	}
-
-	signature, err := RawTxInWitnessSignature(updater.Upsbt.UnsignedTx, txscript.NewTxSigHashes(updater.Upsbt.UnsignedTx, prevOutFetcher), i, amount, prevPkScript, hashType, sign)
-	if err != nil {
-		return err
-	}
-	if signOutcome, err := updater.Sign(i, signature, pubkey, nil, nil); err != nil {
-		return err
-	} else if signOutcome != psbt.SignSuccesful {
-		return fmt.Errorf("signing failed, code: %d", signOutcome)
-	}
-	return nil
-}
-
-func signInputP2WSH(updater *psbt.Updater, i int, prevPkScript []byte, witnessScript []byte, amount int64, prevOutFetcher *txscript.MultiPrevOutFetcher, sign types.Signer, pubkey []byte) error {
-	const hashType = txscript.SigHashAll
-	if err := updater.AddInSighashType(hashType, i); err != nil {
-		return err
-	}
-	ver, program, err := txscript.ExtractWitnessProgramInfo(prevPkScript)
-	if err != nil {
-		return err
-	} else if ver != 0 || len(program) != 32 {
-		return fmt.Errorf("input %d: prevPkScript is not P2WSH (v0,witLen=32)", i)
-	}
-
-	h := sha256.Sum256(witnessScript)
-	if !bytes.Equal(program, h[:]) {
-		return fmt.Errorf("input %d: witnessScript hash mismatch (pkScript != sha256(witnessScript))", i)
-	}
-
-	sigHashes := txscript.NewTxSigHashes(updater.Upsbt.UnsignedTx, prevOutFetcher)
-	signature, err := RawTxInWitnessSignature(updater.Upsbt.UnsignedTx, sigHashes, i, amount, witnessScript, hashType, sign)
-	if err != nil {
-		return err
-	}
-	if signOutcome, err := updater.Sign(i, signature, pubkey, nil, witnessScript); err != nil {
-		return err
-	} else if signOutcome != psbt.SignSuccesful {
-		return fmt.Errorf("signing failed, code: %d", signOutcome)
-	}
-	return nil
-}
-
-func signInputP2TR(updater *psbt.Updater, i int, prevPkScript []byte, sign types.Signer, prevOutFetcher txscript.PrevOutputFetcher) error {
-	var err error
-
-	sigHashes := txscript.NewTxSigHashes(updater.Upsbt.UnsignedTx, prevOutFetcher)
-
-	// TODO : hashtype always default in taproot
-	hashType := txscript.SigHashDefault
-	err = updater.AddInSighashType(hashType, i)
-	if err != nil {
-		return err
-	}
-	msgHash, err := txscript.CalcTaprootSignatureHash(sigHashes, hashType, updater.Upsbt.UnsignedTx, i, prevOutFetcher)
-	if err != nil {
-		return err
-	}
-	signature, err := sign(msgHash)
-	if err != nil {
-		return err
-	}
-	updater.Upsbt.Inputs[i].TaprootKeySpendSig = signature
-	return nil
-}
-
-func CheckDuplicateOfUpdater(updater *psbt.Updater, index int) {
-	signatures := updater.Upsbt.Inputs[index].TaprootScriptSpendSig
-	m := map[string]*psbt.TaprootScriptSpendSig{}
-	signs := make([]*psbt.TaprootScriptSpendSig, 0)
-	for _, v := range signatures {
-		key := append(v.XOnlyPubKey, v.LeafHash...)
-		keyHex := hex.EncodeToString(key)
-		_, ok := m[keyHex]
-		if !ok {
-			m[keyHex] = v
-			signs = append(signs, v)
-		}
-	}
-	updater.Upsbt.Inputs[index].TaprootScriptSpendSig = signs
-}
-
-const (
-	witnessV0PubKeyHashLen = 22
-
-	sigHashMask = 0x1f
-)
-
-// RawTxInSignature returns the serialized ECDSA signature for the input idx of
-// the given transaction, with hashType appended to it.
-func RawTxInSignature(tx *wire.MsgTx, idx int, subScript []byte,
-	hashType txscript.SigHashType, sign types.Signer) ([]byte, error) {
-
-	hash, err := txscript.CalcSignatureHash(subScript, hashType, tx, idx)
-	if err != nil {
-		return nil, err
-	}
-	signature, err := sign(hash)
-	if err != nil {
-		return nil, err
-	}
-
-	return append(signature, byte(hashType)), nil
-}
-
-// RawTxInWitnessSignature returns the serialized ECDA signature for the input
-// idx of the given transaction, with the hashType appended to it. This
-// function is identical to RawTxInSignature, however the signature generated
-// signs a new sighash digest defined in BIP0143.
-func RawTxInWitnessSignature(tx *wire.MsgTx, sigHashes *txscript.TxSigHashes, idx int,
-	amt int64, subScript []byte, hashType txscript.SigHashType, sign types.Signer) ([]byte, error) {
-
-	msgHash, err := calcWitnessSignatureHashRaw(subScript, sigHashes, hashType, tx, idx, amt)
-	if err != nil {
-		return nil, err
-	}
-
-	signature, err := sign(msgHash)
-	if err != nil {
-		return nil, err
-	}
-
-	return append(signature, byte(hashType)), nil
-}
-
-// calcWitnessSignatureHashRaw computes the sighash digest of a transaction's
-// segwit input using the new, optimized digest calculation algorithm defined
-// in BIP0143: https://github.com/bitcoin/bips/blob/master/bip-0143.mediawiki.
-// This function makes use of pre-calculated sighash fragments stored within
-// the passed HashCache to eliminate duplicate hashing computations when
-// calculating the final digest, reducing the complexity from O(N^2) to O(N).
-// Additionally, signatures now cover the input value of the referenced unspent
-// output. This allows offline, or hardware wallets to compute the exact amount
-// being spent, in addition to the final transaction fee. In the case the
-// wallet if fed an invalid input amount, the real sighash will differ causing
-// the produced signature to be invalid.
-func calcWitnessSignatureHashRaw(subScript []byte, sigHashes *txscript.TxSigHashes,
-	hashType txscript.SigHashType, tx *wire.MsgTx, idx int, amt int64) ([]byte, error) {
-
-	// As a sanity check, ensure the passed input index for the transaction
-	// is valid.
-	//
-	// TODO(roasbeef): check needs to be lifted elsewhere?
-	if idx > len(tx.TxIn)-1 {
-		return nil, fmt.Errorf("idx %d but %d txins", idx, len(tx.TxIn))
-	}
-
-	sigHashBytes := chainhash.DoubleHashRaw(func(w io.Writer) error {
-		var scratch [8]byte
-
-		// First write out, then encode the transaction's version
-		// number.
-		binary.LittleEndian.PutUint32(scratch[:], uint32(tx.Version))
-		w.Write(scratch[:4])
-
-		// Next write out the possibly pre-calculated hashes for the
-		// sequence numbers of all inputs, and the hashes of the
-		// previous outs for all outputs.
-		var zeroHash chainhash.Hash
-
-		// If anyone can pay isn't active, then we can use the cached
-		// hashPrevOuts, otherwise we just write zeroes for the prev
-		// outs.
-		if hashType&txscript.SigHashAnyOneCanPay == 0 {
-			w.Write(sigHashes.HashPrevOutsV0[:])
-		} else {
-			w.Write(zeroHash[:])
-		}
-
-		// If the sighash isn't anyone can pay, single, or none, the
-		// use the cached hash sequences, otherwise write all zeroes
-		// for the hashSequence.
-		if hashType&txscript.SigHashAnyOneCanPay == 0 &&
-			hashType&sigHashMask != txscript.SigHashSingle &&
-			hashType&sigHashMask != txscript.SigHashNone {
-
-			w.Write(sigHashes.HashSequenceV0[:])
-		} else {
-			w.Write(zeroHash[:])
-		}
-
-		txIn := tx.TxIn[idx]
-
-		// Next, write the outpoint being spent.
-		w.Write(txIn.PreviousOutPoint.Hash[:])
-		var bIndex [4]byte
-		binary.LittleEndian.PutUint32(
-			bIndex[:], txIn.PreviousOutPoint.Index,
-		)
-		w.Write(bIndex[:])
-
-		if isWitnessPubKeyHashScript(subScript) {
-			// The script code for a p2wkh is a length prefix
-			// varint for the next 25 bytes, followed by a
-			// re-creation of the original p2pkh pk script.
-			w.Write([]byte{0x19})
-			w.Write([]byte{txscript.OP_DUP})
-			w.Write([]byte{txscript.OP_HASH160})
-			w.Write([]byte{txscript.OP_DATA_20})
-			w.Write(extractWitnessPubKeyHash(subScript))
-			w.Write([]byte{txscript.OP_EQUALVERIFY})
-			w.Write([]byte{txscript.OP_CHECKSIG})
-		} else {
-			// For p2wsh outputs, and future outputs, the script
-			// code is the original script, with all code
-			// separators removed, serialized with a var int length
-			// prefix.
-			wire.WriteVarBytes(w, 0, subScript)
-		}
-
-		// Next, add the input amount, and sequence number of the input
-		// being signed.
-		binary.LittleEndian.PutUint64(scratch[:], uint64(amt))
-		w.Write(scratch[:])
-		binary.LittleEndian.PutUint32(scratch[:], txIn.Sequence)
-		w.Write(scratch[:4])
-
-		// If the current signature mode isn't single, or none, then we
-		// can re-use the pre-generated hashoutputs sighash fragment.
-		// Otherwise, we'll serialize and add only the target output
-		// index to the signature pre-image.
-		if hashType&sigHashMask != txscript.SigHashSingle &&
-			hashType&sigHashMask != txscript.SigHashNone {
-
-			w.Write(sigHashes.HashOutputsV0[:])
-		} else if hashType&sigHashMask == txscript.SigHashSingle &&
-			idx < len(tx.TxOut) {
-
-			h := chainhash.DoubleHashRaw(func(tw io.Writer) error {
-				wire.WriteTxOut(tw, 0, 0, tx.TxOut[idx])
-				return nil
-			})
-			w.Write(h[:])
-		} else {
-			w.Write(zeroHash[:])
-		}
-
-		// Finally, write out the transaction's locktime, and the sig
-		// hash type.
-		binary.LittleEndian.PutUint32(scratch[:], tx.LockTime)
-		w.Write(scratch[:4])
-		binary.LittleEndian.PutUint32(scratch[:], uint32(hashType))
-		w.Write(scratch[:4])
-
-		return nil
-	})
-
-	return sigHashBytes[:], nil
-}
-
-// extractWitnessPubKeyHash extracts the witness public key hash from the passed
-// script if it is a standard pay-to-witness-pubkey-hash script. It will return
-// nil otherwise.
-func extractWitnessPubKeyHash(script []byte) []byte {
-	// A pay-to-witness-pubkey-hash script is of the form:
-	//   OP_0 OP_DATA_20 <20-byte-hash>
-	if len(script) == witnessV0PubKeyHashLen &&
-		script[0] == txscript.OP_0 &&
-		script[1] == txscript.OP_DATA_20 {
-
-		return script[2:witnessV0PubKeyHashLen]
-	}
-
-	return nil
-}
-
-// isWitnessPubKeyHashScript returns whether or not the passed script is a
-// standard pay-to-witness-pubkey-hash script.
-func isWitnessPubKeyHashScript(script []byte) bool {
-	return extractWitnessPubKeyHash(script) != nil
-}
-
-// VerifyTx verifies signatures for common script types without executing the full script.
-// Supported: P2PK, P2PKH, P2WPKH, P2WSH(single-sig), Taproot key-path.
-// It verifies using the provided pubkey (single-sig assumption).
-func VerifyTx(chain *chaincfg.Params, pkt *psbt.Packet, pubkey *secp256k1.PublicKey) (bool, error) {
-	// Convert decred pubkey -> btcec pubkey for btcec/schnorr usage
-	btcecPub, err := btcec.ParsePubKey(pubkey.SerializeCompressed())
-	if err != nil {
-		return false, fmt.Errorf("invalid pubkey: %w", err)
-	}
-
-	prevOutFetcher := PsbtPrevOutputFetcher(pkt)
-	tx := pkt.UnsignedTx
-	sigHashes := txscript.NewTxSigHashes(tx, prevOutFetcher)
-
-	for i, in := range pkt.Inputs {
-		// Resolve pkScript and amount for this input
-		var pkScript []byte
-		var amount int64
-		switch {
-		case in.WitnessUtxo != nil:
-			pkScript = in.WitnessUtxo.PkScript
-			amount = in.WitnessUtxo.Value
-		case in.NonWitnessUtxo != nil:
-			// Use the outpoint index of this *input* (not i)
-			idx := tx.TxIn[i].PreviousOutPoint.Index
-			if int(idx) >= len(in.NonWitnessUtxo.TxOut) {
-				return false, fmt.Errorf("input %d: NonWitnessUtxo missing output index %d", i, idx)
-			}
-			pkScript = in.NonWitnessUtxo.TxOut[idx].PkScript
-			amount = in.NonWitnessUtxo.TxOut[idx].Value
-		default:
-			return false, fmt.Errorf("input %d: missing UTXO data", i)
-		}
-
-		class, _, _, err := txscript.ExtractPkScriptAddrs(pkScript, chain)
-		if err != nil {
-			return false, fmt.Errorf("input %d: extract class: %w", i, err)
-		}
-
-		switch class {
-		case txscript.PubKeyTy:
-			// scriptSig: <DER+ht>
-			sigPushes, err := extractPushes(tx.TxIn[i].SignatureScript)
-			if err != nil || len(sigPushes) < 1 {
-				return false, fmt.Errorf("input %d: invalid scriptSig", i)
-			}
-			raw := sigPushes[len(sigPushes)-1]
-			if len(raw) < 2 {
-				return false, fmt.Errorf("input %d: malformed DER+hashtype", i)
-			}
-			ht := txscript.SigHashType(raw[len(raw)-1])
-			der := raw[:len(raw)-1]
-
-			digest, err := txscript.CalcSignatureHash(pkScript, ht, tx, i)
-			if err != nil {
-				return false, fmt.Errorf("input %d: sighash: %w", i, err)
-			}
-			esig, err := ecdsa.ParseDERSignature(der)
-			if err != nil {
-				return false, fmt.Errorf("input %d: parse der: %w", i, err)
-			}
-			if !esig.Verify(digest, btcecPub) {
-				return false, fmt.Errorf("input %d: ECDSA verify failed", i)
-			}
-
-		case txscript.PubKeyHashTy:
-			// scriptSig: <DER+ht> <pubkey>
-			sigPushes, err := extractPushes(tx.TxIn[i].SignatureScript)
-			if err != nil || len(sigPushes) < 1 {
-				return false, fmt.Errorf("input %d: invalid scriptSig", i)
-			}
-			raw := sigPushes[0]
-			if len(raw) < 2 {
-				return false, fmt.Errorf("input %d: malformed DER+hashtype", i)
-			}
-			ht := txscript.SigHashType(raw[len(raw)-1])
-			der := raw[:len(raw)-1]
-
-			digest, err := txscript.CalcSignatureHash(pkScript, ht, tx, i)
-			if err != nil {
-				return false, fmt.Errorf("input %d: sighash: %w", i, err)
-			}
-			esig, err := ecdsa.ParseDERSignature(der)
-			if err != nil {
-				return false, fmt.Errorf("input %d: parse der: %w", i, err)
-			}
-			if !esig.Verify(digest, btcecPub) {
-				return false, fmt.Errorf("input %d: ECDSA verify failed", i)
-			}
-
-		case txscript.WitnessV0PubKeyHashTy:
-			// witness: [ <DER+ht>, <pubkey> ]
-			wit := tx.TxIn[i].Witness
-			if len(wit) < 2 || len(wit[0]) < 2 {
-				return false, fmt.Errorf("input %d: invalid witness", i)
-			}
-			raw := wit[0]
-			ht := txscript.SigHashType(raw[len(raw)-1])
-			der := raw[:len(raw)-1]
-
-			// BIP143: subScript derived from P2WPKH (handled in calcWitnessSignatureHashRaw)
-			digest, err := calcWitnessSignatureHashRaw(pkScript, sigHashes, ht, tx, i, amount)
-			if err != nil {
-				return false, fmt.Errorf("input %d: sighash(witness): %w", i, err)
-			}
-			esig, err := ecdsa.ParseDERSignature(der)
-			if err != nil {
-				return false, fmt.Errorf("input %d: parse der: %w", i, err)
-			}
-			if !esig.Verify(digest, btcecPub) {
-				return false, fmt.Errorf("input %d: ECDSA verify failed", i)
-			}
-
-		case txscript.WitnessV0ScriptHashTy:
-			// witness: [ <sig?> ... , <witnessScript> ]
-			wit := tx.TxIn[i].Witness
-			if len(wit) < 2 {
-				return false, fmt.Errorf("input %d: invalid wsh witness", i)
-			}
-			witnessScript := wit[len(wit)-1]
-			// pick first non-empty element before last as signature (single-sig assumption)
-			var raw []byte
-			for _, el := range wit[:len(wit)-1] {
-				if len(el) > 0 {
-					raw = el
-					break
-				}
-			}
-			if len(raw) < 2 {
-				return false, fmt.Errorf("input %d: missing DER+hashtype in wsh witness", i)
-			}
-			ht := txscript.SigHashType(raw[len(raw)-1])
-			der := raw[:len(raw)-1]
-
-			digest, err := calcWitnessSignatureHashRaw(witnessScript, sigHashes, ht, tx, i, amount)
-			if err != nil {
-				return false, fmt.Errorf("input %d: sighash(wsh): %w", i, err)
-			}
-			esig, err := ecdsa.ParseDERSignature(der)
-			if err != nil {
-				return false, fmt.Errorf("input %d: parse der: %w", i, err)
-			}
-			if !esig.Verify(digest, btcecPub) {
-				return false, fmt.Errorf("input %d: ECDSA verify failed", i)
-			}
-
-		case txscript.WitnessV1TaprootTy:
-			// key-path only (no script path here)
-			wit := tx.TxIn[i].Witness
-			if len(wit) < 1 {
-				return false, fmt.Errorf("input %d: empty taproot witness", i)
-			}
-			sig, err := schnorr.ParseSignature(wit[0])
-			if err != nil {
-				return false, fmt.Errorf("input %d: schnorr parse: %w", i, err)
-			}
-			digest, err := txscript.CalcTaprootSignatureHash(sigHashes, txscript.SigHashDefault, tx, i, prevOutFetcher)
-			if err != nil {
-				return false, fmt.Errorf("input %d: taproot sighash: %w", i, err)
-			}
-			tweaked := txscript.ComputeTaprootKeyNoScript(btcecPub)
-			if !sig.Verify(digest, tweaked) {
-				return false, fmt.Errorf("input %d: schnorr verify failed", i)
-			}
-
-		default:
-			// Not handled (OP_RETURN, exotic scripts, multisig, etc.)
-			// Skip rather than failing hard.
-		}
-	}
-
-	return true, nil
-}
-
-// Very small pushdata extractor for scriptSig: returns the data pushes in order.
-func extractPushes(script []byte) ([][]byte, error) {
-	var pushes [][]byte
-	r := bytes.NewReader(script)
-	for r.Len() > 0 {
-		op, err := r.ReadByte()
-		if err != nil {
-			return nil, err
-		}
-		switch {
-		case op == txscript.OP_0:
-			// empty push
-			pushes = append(pushes, []byte{})
-
-		case op >= 0x01 && op <= 0x4b:
-			// 1~75 bytes push
-			n := int(op)
-			buf := make([]byte, n)
-			if _, err := io.ReadFull(r, buf); err != nil {
-				return nil, err
-			}
-			pushes = append(pushes, buf)
-
-		default:
-		}
-	}
-	return pushes, nil
-}
+package transaction
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+
+	"github.com/gosuda/btctxbuilder/script"
+	"github.com/gosuda/btctxbuilder/types"
+)
+
+// SignTx signs the inputs of packet that pubkey can sign with sign, and
+// finalizes those that are complete. Single key inputs of other keys, P2PKH,
+// P2WPKH and P2SH-P2WPKH, are left as they are, so each owner of a shared
+// transaction signs their inputs in turn; an input nobody signed only fails
+// at finalization or extraction. The same goes for the key path of a P2TR
+// input whose output key isn't pubkey, unless pubkey signs one of its leaf
+// scripts: a wrong key, or a BIP86 key for an output with a script tree,
+// leaves the input unsigned without an error.
+func SignTx(chain *chaincfg.Params, packet *psbt.Packet, sign types.Signer, pubkey []byte) (*psbt.Packet, error) {
+	err := psbt.InputsReadyToSign(packet)
+	if err != nil {
+		return nil, err
+	}
+
+	updater, err := psbt.NewUpdater(packet)
+	if err != nil {
+		return nil, err
+	}
+
+	prevOutputFetcher := PsbtPrevOutputFetcher(packet)
+	for i, input := range packet.Inputs {
+		if input.FinalScriptSig != nil || input.FinalScriptWitness != nil {
+			// completed by an earlier signer
+			continue
+		}
+		// Extract previous transaction output information
+		if input.WitnessUtxo == nil && input.NonWitnessUtxo == nil {
+			return nil, fmt.Errorf("missing input UTXO information for input %d", i)
+		}
+
+		var prevOutValue int64
+		var pkScript []byte
+		if input.WitnessUtxo != nil {
+			updater.AddInWitnessUtxo(input.WitnessUtxo, i)
+			prevOutValue = input.WitnessUtxo.Value
+			pkScript = input.WitnessUtxo.PkScript
+		} else if input.NonWitnessUtxo != nil {
+			index := packet.UnsignedTx.TxIn[i].PreviousOutPoint.Index
+			prevOut := input.NonWitnessUtxo.TxOut[index]
+			prevOutValue = prevOut.Value
+			pkScript = prevOut.PkScript
+		}
+
+		scriptClass, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript, chain)
+		if err != nil {
+			return nil, err
+		}
+		if !ownsInput(scriptClass, addrs, &input, pkScript, pubkey) {
+			// an input of another key, whose owner signs it in turn
+			continue
+		}
+
+		switch scriptClass {
+		case txscript.WitnessV1TaprootTy: // P2TR
+			err = signInputP2TR(updater, i, pkScript, sign, pubkey, prevOutputFetcher)
+		case txscript.PubKeyTy: // P2PK
+			err = signInputP2PK(updater, i, pkScript, sign)
+		case txscript.PubKeyHashTy: // P2PKH
+			err = signInputP2PKH(updater, i, pkScript, sign, pubkey)
+		case txscript.ScriptHashTy: // P2SH
+			redeemScript := input.RedeemScript
+			if redeemScript == nil {
+				// a P2SH-P2WPKH output of the signer needs no spend info
+				redeemScript = nestedP2WPKHRedeemScript(pubkey, pkScript)
+			}
+			err = signInputP2SH(updater, redeemScript, i, pkScript, prevOutValue, prevOutputFetcher, sign, pubkey)
+		case txscript.WitnessV0PubKeyHashTy: // P2WPKH
+			err = signInputP2WPKH(updater, i, pkScript, prevOutValue, prevOutputFetcher, sign, pubkey)
+		case txscript.WitnessV0ScriptHashTy: // P2WSH
+			err = signInputP2WSH(updater, i, pkScript, input.WitnessScript, prevOutValue, prevOutputFetcher, sign, pubkey)
+		case txscript.MultiSigTy: // bare multisig
+			err = signInputMultiSig(updater, i, pkScript, sign, pubkey)
+		case txscript.NullDataTy: // OP_RETURN
+
+		default:
+			return nil, fmt.Errorf("unsupported script type")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if in := packet.Inputs[i]; len(in.TaprootScriptSpendSig) > 0 ||
+			(scriptClass == txscript.WitnessV1TaprootTy && in.TaprootKeySpendSig == nil) {
+			// still waiting for other signers of the leaf script or
+			// MuSig2 key
+			continue
+		}
+		if ms := multiSigScript(&packet.Inputs[i], pkScript); ms != nil {
+			// the library finalizer would finalize with the first
+			// signature instead of waiting for all cosigners
+			if err := finalizeMultiSig(packet, i, pkScript, ms, prevOutputFetcher); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if packet.Inputs[i].WitnessScript != nil {
+			if err := finalizeWitnessScript(packet, i, pkScript, prevOutputFetcher); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		_, err = psbt.MaybeFinalize(packet, i)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return packet, nil
+}
+
+// ownsInput reports whether pubkey can sign a single key input: P2PKH,
+// P2WPKH or P2SH-P2WPKH. A P2SH input without a redeem script is only the
+// signer's if it nests P2WPKH of pubkey. Other inputs are left to the
+// signing functions.
+func ownsInput(scriptClass txscript.ScriptClass, addrs []btcutil.Address, in *psbt.PInput, pkScript []byte, pubkey []byte) bool {
+	switch scriptClass {
+	case txscript.PubKeyHashTy, txscript.WitnessV0PubKeyHashTy:
+		return bytes.Equal(addrs[0].ScriptAddress(), btcutil.Hash160(pubkey))
+	case txscript.ScriptHashTy:
+		if in.RedeemScript == nil {
+			return nestedP2WPKHRedeemScript(pubkey, pkScript) != nil
+		}
+		if txscript.IsPayToWitnessPubKeyHash(in.RedeemScript) {
+			return bytes.Equal(in.RedeemScript[2:], btcutil.Hash160(pubkey))
+		}
+	}
+	return true
+}
+
+func signInputP2PK(updater *psbt.Updater, i int, prevPkScript []byte, sign types.Signer) error {
+	hashType, err := useSigHashType(updater, i, false)
+	if err != nil {
+		return err
+	}
+	signature, err := RawTxInSignature(updater.Upsbt.UnsignedTx, i, prevPkScript, hashType, sign)
+	if err != nil {
+		return err
+	}
+	scriptSig, err := txscript.NewScriptBuilder().AddData(signature).Script()
+	if err != nil {
+		return err
+	}
+	updater.Upsbt.Inputs[i].FinalScriptSig = scriptSig
+	return nil
+}
+
+func signInputP2PKH(updater *psbt.Updater, i int, prevPkScript []byte, sign types.Signer, pubkey []byte) error {
+	hashType, err := useSigHashType(updater, i, false)
+	if err != nil {
+		return err
+	}
+
+	signature, err := RawTxInSignature(updater.Upsbt.UnsignedTx, i, prevPkScript, hashType, sign)
+	if err != nil {
+		return err
+	}
+
+	if signOutcome, err := updater.Sign(i, signature, pubkey, nil, nil); err != nil {
+		return err
+	} else if signOutcome != psbt.SignSuccesful {
+		return fmt.Errorf("signing failed, code: %d", signOutcome)
+	}
+
+	return nil
+}
+
+func signInputP2SH(updater *psbt.Updater, redeemScript []byte, i int, prevPkScript []byte, amount int64, prevOutFetcher *txscript.MultiPrevOutFetcher, sign types.Signer, pubkey []byte) error {
+	// valid RedeemScript
+	if !ValidRedeemSignature(redeemScript, prevPkScript) {
+		return fmt.Errorf("invalid redeem script")
+	}
+
+	// Nested segwit: the redeem script is the witness program and the
+	// signature commits to the amount as in BIP143.
+	switch {
+	case txscript.IsPayToWitnessScriptHash(redeemScript):
+		return signInputP2WSH(updater, i, redeemScript, updater.Upsbt.Inputs[i].WitnessScript, amount, prevOutFetcher, sign, pubkey)
+	case txscript.IsPayToWitnessPubKeyHash(redeemScript):
+		return signInputNestedP2WPKH(updater, i, redeemScript, amount, prevOutFetcher, sign, pubkey)
+	case txscript.IsWitnessProgram(redeemScript):
+		return fmt.Errorf("input %d: unsupported witness program in redeem script", i)
+	}
+
+	if signed, err := multiSigSigned(&updater.Upsbt.Inputs[i], redeemScript, pubkey); err != nil || signed {
+		return err
+	}
+	hashType, err := useSigHashType(updater, i, false)
+	if err != nil {
+		return err
+	}
+	signature, err := RawTxInSignature(updater.Upsbt.UnsignedTx, i, redeemScript, hashType, sign)
+	if err != nil {
+		return err
+	}
+	signOutcome, err := updater.Sign(i, signature, pubkey, redeemScript, nil)
+	if err != nil {
+		return fmt.Errorf("failed to sign PSBT input: %v", err)
+	} else if signOutcome != psbt.SignSuccesful {
+		return fmt.Errorf("signing was not successful, outcome: %v", signOutcome)
+	}
+	return nil
+}
+
+// signInputMultiSig adds a partial signature for a bare multisig output.
+func signInputMultiSig(updater *psbt.Updater, i int, prevPkScript []byte, sign types.Signer, pubkey []byte) error {
+	if signed, err := multiSigSigned(&updater.Upsbt.Inputs[i], prevPkScript, pubkey); err != nil || signed {
+		return err
+	}
+	hashType, err := useSigHashType(updater, i, false)
+	if err != nil {
+		return err
+	}
+	signature, err := RawTxInSignature(updater.Upsbt.UnsignedTx, i, prevPkScript, hashType, sign)
+	if err != nil {
+		return err
+	}
+	if signOutcome, err := updater.Sign(i, signature, pubkey, nil, nil); err != nil {
+		return err
+	} else if signOutcome != psbt.SignSuccesful {
+		return fmt.Errorf("signing failed, code: %d", signOutcome)
+	}
+	return nil
+}
+
+// multiSigSigned reports whether pubkey already signed the input when
+// script is a multisig script, and fails if pubkey isn't one of its keys.
+func multiSigSigned(in *psbt.PInput, multiSig []byte, pubkey []byte) (bool, error) {
+	keys, _, err := script.ParseMultiSigScript(multiSig)
+	if err != nil {
+		// not a multisig script
+		return false, nil
+	}
+	if !slices.ContainsFunc(keys, func(key []byte) bool { return bytes.Equal(key, pubkey) }) {
+		return false, fmt.Errorf("key %x is not part of the multisig script", pubkey)
+	}
+	return slices.ContainsFunc(in.PartialSigs, func(sig *psbt.PartialSig) bool {
+		return bytes.Equal(sig.PubKey, pubkey)
+	}), nil
+}
+
+// multiSigScript returns the multisig script executed to spend an input
+// with pkScript: the pkScript itself, the redeem script or the witness
+// script. It returns nil for any other kind of input.
+func multiSigScript(in *psbt.PInput, pkScript []byte) []byte {
+	var candidate []byte
+	switch {
+	case txscript.GetScriptClass(pkScript) == txscript.MultiSigTy:
+		candidate = pkScript
+	case txscript.IsPayToScriptHash(pkScript) && txscript.IsPayToWitnessScriptHash(in.RedeemScript),
+		txscript.IsPayToWitnessScriptHash(pkScript):
+		candidate = in.WitnessScript
+	case txscript.IsPayToScriptHash(pkScript):
+		candidate = in.RedeemScript
+	}
+	if _, _, err := script.ParseMultiSigScript(candidate); err != nil {
+		return nil
+	}
+	return candidate
+}
+
+// finalizeMultiSig builds the final scriptSig and witness of a multisig
+// input once it has as many partial signatures as the script requires.
+// Signatures are put in the order of their keys in the script, behind the
+// dummy element OP_CHECKMULTISIG pops. Until then the input is left as is
+// for the other cosigners.
+func finalizeMultiSig(packet *psbt.Packet, i int, pkScript []byte, multiSig []byte, prevOutFetcher txscript.PrevOutputFetcher) error {
+	in := &packet.Inputs[i]
+	keys, nRequired, err := script.ParseMultiSigScript(multiSig)
+	if err != nil {
+		return err
+	}
+	stack := [][]byte{nil}
+	for _, key := range keys {
+		for _, sig := range in.PartialSigs {
+			if bytes.Equal(sig.PubKey, key) && len(stack) <= nRequired {
+				stack = append(stack, sig.Signature)
+			}
+		}
+	}
+	if len(stack) <= nRequired {
+		return nil
+	}
+
+	var sigScript []byte
+	var witness wire.TxWitness
+	builder := txscript.NewScriptBuilder()
+	switch {
+	case txscript.IsPayToWitnessScriptHash(pkScript):
+		witness = append(stack, multiSig)
+	case txscript.IsPayToScriptHash(pkScript) && txscript.IsPayToWitnessScriptHash(in.RedeemScript):
+		witness = append(stack, multiSig)
+		builder.AddData(in.RedeemScript)
+	default:
+		builder.AddOp(txscript.OP_0)
+		for _, sig := range stack[1:] {
+			builder.AddData(sig)
+		}
+		if txscript.IsPayToScriptHash(pkScript) {
+			builder.AddData(multiSig)
+		}
+	}
+	if sigScript, err = builder.Script(); err != nil {
+		return err
+	}
+	return finalizeScriptInput(packet, i, pkScript, sigScript, witness, prevOutFetcher)
+}
+
+// finalizeWitnessScript finalizes a P2WSH input, nested or not, whose
+// witness script isn't a multisig script, by satisfying it as miniscript
+// with the partial signatures. Like finalizeMultiSig, it leaves the input
+// as is while signatures are missing.
+func finalizeWitnessScript(packet *psbt.Packet, i int, pkScript []byte, prevOutFetcher txscript.PrevOutputFetcher) error {
+	in := &packet.Inputs[i]
+	ms, err := script.DecodeMiniscript(in.WitnessScript, script.P2WSHContext)
+	if err != nil {
+		return fmt.Errorf("input %d: can't finalize witness script: %w", i, err)
+	}
+	txIn := packet.UnsignedTx.TxIn[i]
+	satisfier := &script.StaticSatisfier{
+		Signatures: make(map[string][]byte, len(in.PartialSigs)),
+		TxVersion:  packet.UnsignedTx.Version,
+		LockTime:   packet.UnsignedTx.LockTime,
+		Sequence:   txIn.Sequence,
+	}
+	for _, sig := range in.PartialSigs {
+		satisfier.Signatures[hex.EncodeToString(sig.PubKey)] = sig.Signature
+	}
+	stack, err := ms.Satisfy(satisfier)
+	if errors.Is(err, script.ErrUnsatisfiable) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("input %d: %w", i, err)
+	}
+
+	var sigScript []byte
+	if txscript.IsPayToScriptHash(pkScript) {
+		if sigScript, err = txscript.NewScriptBuilder().AddData(in.RedeemScript).Script(); err != nil {
+			return err
+		}
+	}
+	return finalizeScriptInput(packet, i, pkScript, sigScript, append(stack, in.WitnessScript), prevOutFetcher)
+}
+
+// finalizeScriptInput checks sigScript and witness against the engine and
+// replaces input i with its finalized form.
+func finalizeScriptInput(packet *psbt.Packet, i int, pkScript []byte, sigScript []byte, witness wire.TxWitness, prevOutFetcher txscript.PrevOutputFetcher) error {
+	in := &packet.Inputs[i]
+	prevOut := prevOutFetcher.FetchPrevOutput(packet.UnsignedTx.TxIn[i].PreviousOutPoint)
+	if prevOut == nil {
+		return fmt.Errorf("input %d: missing previous output", i)
+	}
+	tx := packet.UnsignedTx.Copy()
+	tx.TxIn[i].SignatureScript = sigScript
+	tx.TxIn[i].Witness = witness
+	sigHashes := txscript.NewTxSigHashes(tx, prevOutFetcher)
+	vm, err := txscript.NewEngine(pkScript, tx, i, txscript.StandardVerifyFlags, nil, sigHashes, prevOut.Value, prevOutFetcher)
+	if err != nil {
+		return err
+	}
+	if err := vm.Execute(); err != nil {
+		return fmt.Errorf("input %d: signatures don't verify: %w", i, err)
+	}
+
+	var finalized *psbt.PInput
+	if witness != nil {
+		var buf bytes.Buffer
+		if err := psbt.WriteTxWitness(&buf, witness); err != nil {
+			return err
+		}
+		finalized = psbt.NewPsbtInput(nil, in.WitnessUtxo)
+		finalized.FinalScriptWitness = buf.Bytes()
+	} else {
+		finalized = psbt.NewPsbtInput(in.NonWitnessUtxo, nil)
+	}
+	if len(sigScript) > 0 {
+		finalized.FinalScriptSig = sigScript
+	}
+	packet.Inputs[i] = *finalized
+	return nil
+}
+
+func signInputP2WPKH(updater *psbt.Updater, i int, prevPkScript []byte, amount int64, prevOutFetcher *txscript.MultiPrevOutFetcher, sign types.Signer, pubkey []byte) error {
+	hashType, err := useSigHashType(updater, i, false)
+	if err != nil {
+		return err
+	}
+
+	signature, err := RawTxInWitnessSignature(updater.Upsbt.UnsignedTx, txscript.NewTxSigHashes(updater.Upsbt.UnsignedTx, prevOutFetcher), i, amount, prevPkScript, hashType, sign)
+	if err != nil {
+		return err
+	}
+	if signOutcome, err := updater.Sign(i, signature, pubkey, nil, nil); err != nil {
+		return err
+	} else if signOutcome != psbt.SignSuccesful {
+		return fmt.Errorf("signing failed, code: %d", signOutcome)
+	}
+	return nil
+}
+
+// signInputNestedP2WPKH signs a P2SH-P2WPKH input. The finalizer then puts
+// the redeem script in the scriptSig and the signature and key in the
+// witness.
+func signInputNestedP2WPKH(updater *psbt.Updater, i int, redeemScript []byte, amount int64, prevOutFetcher *txscript.MultiPrevOutFetcher, sign types.Signer, pubkey []byte) error {
+	if !bytes.Equal(redeemScript[2:], btcutil.Hash160(pubkey)) {
+		return fmt.Errorf("input %d: redeem script doesn't pay to the signer key", i)
+	}
+	hashType, err := useSigHashType(updater, i, false)
+	if err != nil {
+		return err
+	}
+
+	signature, err := RawTxInWitnessSignature(updater.Upsbt.UnsignedTx, txscript.NewTxSigHashes(updater.Upsbt.UnsignedTx, prevOutFetcher), i, amount, redeemScript, hashType, sign)
+	if err != nil {
+		return err
+	}
+	if signOutcome, err := updater.Sign(i, signature, pubkey, redeemScript, nil); err != nil {
+		return err
+	} else if signOutcome != psbt.SignSuccesful {
+		return fmt.Errorf("signing failed, code: %d", signOutcome)
+	}
+	return nil
+}
+
+// nestedP2WPKHRedeemScript returns the P2WPKH redeem script of pubkey if
+// pkScript is its P2SH output, or nil.
+func nestedP2WPKHRedeemScript(pubkey []byte, pkScript []byte) []byte {
+	if len(pubkey) != btcec.PubKeyBytesLenCompressed {
+		return nil
+	}
+	redeemScript, err := txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(btcutil.Hash160(pubkey)).Script()
+	if err != nil || !ValidRedeemSignature(redeemScript, pkScript) {
+		return nil
+	}
+	return redeemScript
+}
+
+func signInputP2WSH(updater *psbt.Updater, i int, prevPkScript []byte, witnessScript []byte, amount int64, prevOutFetcher *txscript.MultiPrevOutFetcher, sign types.Signer, pubkey []byte) error {
+	hashType, err := useSigHashType(updater, i, false)
+	if err != nil {
+		return err
+	}
+	ver, program, err := txscript.ExtractWitnessProgramInfo(prevPkScript)
+	if err != nil {
+		return err
+	} else if ver != 0 || len(program) != 32 {
+		return fmt.Errorf("input %d: prevPkScript is not P2WSH (v0,witLen=32)", i)
+	}
+
+	h := sha256.Sum256(witnessScript)
+	if !bytes.Equal(program, h[:]) {
+		return fmt.Errorf("input %d: witnessScript hash mismatch (pkScript != sha256(witnessScript))", i)
+	}
+	if signed, err := multiSigSigned(&updater.Upsbt.Inputs[i], witnessScript, pubkey); err != nil || signed {
+		return err
+	}
+
+	sigHashes := txscript.NewTxSigHashes(updater.Upsbt.UnsignedTx, prevOutFetcher)
+	signature, err := RawTxInWitnessSignature(updater.Upsbt.UnsignedTx, sigHashes, i, amount, witnessScript, hashType, sign)
+	if err != nil {
+		return err
+	}
+	if signOutcome, err := updater.Sign(i, signature, pubkey, nil, witnessScript); err != nil {
+		return err
+	} else if signOutcome != psbt.SignSuccesful {
+		return fmt.Errorf("signing failed, code: %d", signOutcome)
+	}
+	return nil
+}
+
+func signInputP2TR(updater *psbt.Updater, i int, prevPkScript []byte, sign types.Signer, pubkey []byte, prevOutFetcher txscript.PrevOutputFetcher) error {
+	var err error
+
+	sigHashes := txscript.NewTxSigHashes(updater.Upsbt.UnsignedTx, prevOutFetcher)
+
+	// MuSig2 participants sign with AddMuSig2PartialSigs, here their
+	// partial signatures only get aggregated once all are present.
+	if isMuSig2, err := aggregateMuSig2(updater.Upsbt, i, sigHashes, prevOutFetcher); isMuSig2 || err != nil {
+		return err
+	}
+
+	// A signer whose key shows up in one of the leaf scripts takes the
+	// script path, everyone else signs for the output key.
+	if leaves := signableTapLeaves(updater.Upsbt.Inputs[i].TaprootLeafScript, pubkey); len(leaves) > 0 {
+		return signInputP2TRScript(updater, i, prevPkScript, leaves, sign, pubkey, sigHashes, prevOutFetcher)
+	}
+	// Only the output key signs for the key path. Inputs of other owners
+	// are left for them, so each owner of a shared transaction signs in
+	// turn.
+	if !bytes.Equal(xOnlyPubKey(pubkey), prevPkScript[2:]) {
+		return nil
+	}
+
+	hashType, err := useSigHashType(updater, i, true)
+	if err != nil {
+		return err
+	}
+	msgHash, err := txscript.CalcTaprootSignatureHash(sigHashes, hashType, updater.Upsbt.UnsignedTx, i, prevOutFetcher)
+	if err != nil {
+		return err
+	}
+	signature, err := sign(msgHash)
+	if err != nil {
+		return err
+	}
+	updater.Upsbt.Inputs[i].TaprootKeySpendSig = withSigHashType(signature, hashType)
+	return nil
+}
+
+// signInputP2TRScript adds a BIP342 signature for every leaf in leaves and
+// finalizes the input as soon as one of its leaves can be satisfied with the
+// signatures collected so far. Until then the signatures stay in the PSBT for
+// the other signers.
+func signInputP2TRScript(updater *psbt.Updater, i int, prevPkScript []byte, leaves []*psbt.TaprootTapLeafScript, sign types.Signer,
+	pubkey []byte, sigHashes *txscript.TxSigHashes, prevOutFetcher txscript.PrevOutputFetcher) error {
+
+	hashType, err := useSigHashType(updater, i, true)
+	if err != nil {
+		return err
+	}
+
+	in := &updater.Upsbt.Inputs[i]
+	for _, leaf := range leaves {
+		tapLeaf := txscript.NewTapLeaf(leaf.LeafVersion, leaf.Script)
+		leafHash := tapLeaf.TapHash()
+		msgHash, err := txscript.CalcTapscriptSignaturehash(sigHashes, hashType, updater.Upsbt.UnsignedTx, i, prevOutFetcher, tapLeaf)
+		if err != nil {
+			return err
+		}
+		signature, err := sign(msgHash)
+		if err != nil {
+			return err
+		}
+		in.TaprootScriptSpendSig = append(in.TaprootScriptSpendSig, &psbt.TaprootScriptSpendSig{
+			XOnlyPubKey: xOnlyPubKey(pubkey),
+			LeafHash:    leafHash[:],
+			Signature:   signature,
+			SigHash:     hashType,
+		})
+	}
+	CheckDuplicateOfUpdater(updater, i)
+
+	return finalizeTaprootScriptSpend(updater.Upsbt, i, prevPkScript, sigHashes, prevOutFetcher)
+}
+
+// finalizeTaprootScriptSpend builds the witness <sigs...> <script> <control
+// block> for the first leaf that the collected signatures satisfy. Keys are
+// consumed from the top of the stack in script order, so signatures are
+// pushed in reverse and keys without one get an empty push, as required by
+// OP_CHECKSIGADD multisig. Each candidate is run through the script engine;
+// if none passes the input is left unfinalized.
+func finalizeTaprootScriptSpend(packet *psbt.Packet, i int, prevPkScript []byte, sigHashes *txscript.TxSigHashes, prevOutFetcher txscript.PrevOutputFetcher) error {
+	in := &packet.Inputs[i]
+	prevOut := prevOutFetcher.FetchPrevOutput(packet.UnsignedTx.TxIn[i].PreviousOutPoint)
+	if prevOut == nil {
+		return fmt.Errorf("input %d: missing previous output", i)
+	}
+
+	for _, leaf := range in.TaprootLeafScript {
+		leafHash := txscript.NewTapLeaf(leaf.LeafVersion, leaf.Script).TapHash()
+		sigs := map[string][]byte{}
+		for _, s := range in.TaprootScriptSpendSig {
+			if bytes.Equal(s.LeafHash, leafHash[:]) {
+				sigs[string(s.XOnlyPubKey)] = withSigHashType(s.Signature, s.SigHash)
+			}
+		}
+		if len(sigs) == 0 {
+			continue
+		}
+
+		keys := tapLeafKeys(leaf.Script)
+		witness := make(wire.TxWitness, 0, len(keys)+2)
+		for k := len(keys) - 1; k >= 0; k-- {
+			witness = append(witness, sigs[string(keys[k])])
+		}
+		witness = append(witness, leaf.Script, leaf.ControlBlock)
+
+		tx := packet.UnsignedTx.Copy()
+		tx.TxIn[i].Witness = witness
+		vm, err := txscript.NewEngine(prevPkScript, tx, i, txscript.StandardVerifyFlags, nil, sigHashes, prevOut.Value, prevOutFetcher)
+		if err != nil {
+			return err
+		}
+		if vm.Execute() != nil {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := psbt.WriteTxWitness(&buf, witness); err != nil {
+			return err
+		}
+		finalized := psbt.NewPsbtInput(nil, in.WitnessUtxo)
+		finalized.FinalScriptWitness = buf.Bytes()
+		packet.Inputs[i] = *finalized
+		return nil
+	}
+	return nil
+}
+
+// signableTapLeaves returns the leaves whose script contains the x-only form
+// of pubkey.
+func signableTapLeaves(leaves []*psbt.TaprootTapLeafScript, pubkey []byte) []*psbt.TaprootTapLeafScript {
+	xonly := xOnlyPubKey(pubkey)
+	if len(xonly) != schnorr.PubKeyBytesLen {
+		return nil
+	}
+	var signable []*psbt.TaprootTapLeafScript
+	for _, leaf := range leaves {
+		for _, key := range tapLeafKeys(leaf.Script) {
+			if bytes.Equal(key, xonly) {
+				signable = append(signable, leaf)
+				break
+			}
+		}
+	}
+	return signable
+}
+
+// tapLeafKeys returns the keys checked by a leaf script in script order:
+// the 32-byte pushes right before OP_CHECKSIG, OP_CHECKSIGVERIFY or
+// OP_CHECKSIGADD. Other 32-byte pushes, such as hashes or the data of an
+// inscription envelope, aren't keys.
+func tapLeafKeys(leafScript []byte) [][]byte {
+	var keys [][]byte
+	var push []byte
+	tokenizer := txscript.MakeScriptTokenizer(0, leafScript)
+	for tokenizer.Next() {
+		switch op := tokenizer.Opcode(); {
+		case op == txscript.OP_DATA_32:
+			push = tokenizer.Data()
+			continue
+		case push != nil && (op == txscript.OP_CHECKSIG || op == txscript.OP_CHECKSIGVERIFY || op == txscript.OP_CHECKSIGADD):
+			keys = append(keys, push)
+		}
+		push = nil
+	}
+	return keys
+}
+
+func xOnlyPubKey(pubkey []byte) []byte {
+	if len(pubkey) == btcec.PubKeyBytesLenCompressed {
+		return pubkey[1:]
+	}
+	return pubkey
+}
+
+// CombineTx merges the signatures of packets signed in parallel, all for
+// the same unsigned transaction, into a copy of the first one and finalizes
+// every input that is complete.
+func CombineTx(packets ...*psbt.Packet) (*psbt.Packet, error) {
+	if len(packets) == 0 {
+		return nil, fmt.Errorf("no packets to combine")
+	}
+	var buf bytes.Buffer
+	if err := packets[0].Serialize(&buf); err != nil {
+		return nil, err
+	}
+	combined, err := psbt.NewFromRawBytes(&buf, false)
+	if err != nil {
+		return nil, err
+	}
+
+	txid := combined.UnsignedTx.TxHash()
+	for _, p := range packets[1:] {
+		if p.UnsignedTx.TxHash() != txid {
+			return nil, fmt.Errorf("packets spend different transactions: %s and %s", txid, p.UnsignedTx.TxHash())
+		}
+		for i := range combined.Inputs {
+			in, other := &combined.Inputs[i], p.Inputs[i]
+			if in.FinalScriptSig != nil || in.FinalScriptWitness != nil {
+				continue
+			}
+			if other.FinalScriptSig != nil || other.FinalScriptWitness != nil {
+				*in = other
+				continue
+			}
+			for _, sig := range other.PartialSigs {
+				if !slices.ContainsFunc(in.PartialSigs, func(have *psbt.PartialSig) bool {
+					return bytes.Equal(have.PubKey, sig.PubKey)
+				}) {
+					in.PartialSigs = append(in.PartialSigs, sig)
+				}
+			}
+			for _, sig := range other.TaprootScriptSpendSig {
+				if !slices.ContainsFunc(in.TaprootScriptSpendSig, func(have *psbt.TaprootScriptSpendSig) bool {
+					return bytes.Equal(have.XOnlyPubKey, sig.XOnlyPubKey) && bytes.Equal(have.LeafHash, sig.LeafHash)
+				}) {
+					in.TaprootScriptSpendSig = append(in.TaprootScriptSpendSig, sig)
+				}
+			}
+			for _, u := range other.Unknowns {
+				if !slices.ContainsFunc(in.Unknowns, func(have *psbt.Unknown) bool {
+					return bytes.Equal(have.Key, u.Key)
+				}) {
+					in.Unknowns = append(in.Unknowns, u)
+				}
+			}
+			if in.TaprootKeySpendSig == nil {
+				in.TaprootKeySpendSig = other.TaprootKeySpendSig
+			}
+		}
+	}
+
+	updater, err := psbt.NewUpdater(combined)
+	if err != nil {
+		return nil, err
+	}
+	prevOutputFetcher := PsbtPrevOutputFetcher(combined)
+	sigHashes := txscript.NewTxSigHashes(combined.UnsignedTx, prevOutputFetcher)
+	for i, in := range combined.Inputs {
+		if len(in.TaprootScriptSpendSig) > 0 && in.WitnessUtxo != nil {
+			CheckDuplicateOfUpdater(updater, i)
+			err = finalizeTaprootScriptSpend(combined, i, in.WitnessUtxo.PkScript, sigHashes, prevOutputFetcher)
+		} else if prevOut := prevOutputFetcher.FetchPrevOutput(combined.UnsignedTx.TxIn[i].PreviousOutPoint); prevOut != nil && multiSigScript(&in, prevOut.PkScript) != nil {
+			err = finalizeMultiSig(combined, i, prevOut.PkScript, multiSigScript(&in, prevOut.PkScript), prevOutputFetcher)
+		} else if prevOut != nil && in.WitnessScript != nil {
+			err = finalizeWitnessScript(combined, i, prevOut.PkScript, prevOutputFetcher)
+		} else if _, err = aggregateMuSig2(combined, i, sigHashes, prevOutputFetcher); err == nil {
+			_, err = psbt.MaybeFinalize(combined, i)
+		}
+		if err != nil && err != psbt.ErrNotFinalizable {
+			return nil, fmt.Errorf("input %d: %w", i, err)
+		}
+	}
+	return combined, nil
+}
+
+func CheckDuplicateOfUpdater(updater *psbt.Updater, index int) {
+	signatures := updater.Upsbt.Inputs[index].TaprootScriptSpendSig
+	m := map[string]*psbt.TaprootScriptSpendSig{}
+	signs := make([]*psbt.TaprootScriptSpendSig, 0)
+	for _, v := range signatures {
+		key := append(v.XOnlyPubKey, v.LeafHash...)
+		keyHex := hex.EncodeToString(key)
+		_, ok := m[keyHex]
+		if !ok {
+			m[keyHex] = v
+			signs = append(signs, v)
+		}
+	}
+	updater.Upsbt.Inputs[index].TaprootScriptSpendSig = signs
+}
+
+const (
+	witnessV0PubKeyHashLen = 22
+
+	sigHashMask = 0x1f
+)
+
+// ValidSigHashType reports whether hashType is one of SigHashAll,
+// SigHashNone and SigHashSingle, alone or with SigHashAnyOneCanPay.
+// Taproot inputs may also use SigHashDefault.
+func ValidSigHashType(hashType txscript.SigHashType, taproot bool) bool {
+	if taproot && hashType == txscript.SigHashDefault {
+		return true
+	}
+	switch hashType &^ txscript.SigHashAnyOneCanPay {
+	case txscript.SigHashAll, txscript.SigHashNone, txscript.SigHashSingle:
+		return true
+	}
+	return false
+}
+
+// useSigHashType returns the sighash type input i asks for in the PSBT, or
+// SigHashAll (SigHashDefault for taproot) when it has none, and records it
+// in the input.
+func useSigHashType(updater *psbt.Updater, i int, taproot bool) (txscript.SigHashType, error) {
+	hashType := updater.Upsbt.Inputs[i].SighashType
+	if hashType == txscript.SigHashDefault && !taproot {
+		hashType = txscript.SigHashAll
+	}
+	if !ValidSigHashType(hashType, taproot) {
+		return 0, fmt.Errorf("input %d: invalid sighash type %#x", i, byte(hashType))
+	}
+	if hashType&sigHashMask == txscript.SigHashSingle && i >= len(updater.Upsbt.UnsignedTx.TxOut) {
+		return 0, fmt.Errorf("input %d: SIGHASH_SINGLE without a matching output", i)
+	}
+	if err := updater.AddInSighashType(hashType, i); err != nil {
+		return 0, err
+	}
+	return hashType, nil
+}
+
+// withSigHashType appends hashType to a BIP340 signature unless it is
+// SigHashDefault, which BIP341 encodes as the bare 64 bytes.
+func withSigHashType(sig []byte, hashType txscript.SigHashType) []byte {
+	if hashType == txscript.SigHashDefault {
+		return sig
+	}
+	return append(slices.Clip(sig), byte(hashType))
+}
+
+// RawTxInSignature returns the serialized ECDSA signature for the input idx of
+// the given transaction, with hashType appended to it.
+func RawTxInSignature(tx *wire.MsgTx, idx int, subScript []byte,
+	hashType txscript.SigHashType, sign types.Signer) ([]byte, error) {
+
+	hash, err := txscript.CalcSignatureHash(subScript, hashType, tx, idx)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := sign(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(signature, byte(hashType)), nil
+}
+
+// RawTxInWitnessSignature returns the serialized ECDA signature for the input
+// idx of the given transaction, with the hashType appended to it. This
+// function is identical to RawTxInSignature, however the signature generated
+// signs a new sighash digest defined in BIP0143.
+func RawTxInWitnessSignature(tx *wire.MsgTx, sigHashes *txscript.TxSigHashes, idx int,
+	amt int64, subScript []byte, hashType txscript.SigHashType, sign types.Signer) ([]byte, error) {
+
+	msgHash, err := calcWitnessSignatureHashRaw(subScript, sigHashes, hashType, tx, idx, amt)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := sign(msgHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(signature, byte(hashType)), nil
+}
+
+// calcWitnessSignatureHashRaw computes the sighash digest of a transaction's
+// segwit input using the new, optimized digest calculation algorithm defined
+// in BIP0143: https://github.com/bitcoin/bips/blob/master/bip-0143.mediawiki.
+// This function makes use of pre-calculated sighash fragments stored within
+// the passed HashCache to eliminate duplicate hashing computations when
+// calculating the final digest, reducing the complexity from O(N^2) to O(N).
+// Additionally, signatures now cover the input value of the referenced unspent
+// output. This allows offline, or hardware wallets to compute the exact amount
+// being spent, in addition to the final transaction fee. In the case the
+// wallet if fed an invalid input amount, the real sighash will differ causing
+// the produced signature to be invalid.
+func calcWitnessSignatureHashRaw(subScript []byte, sigHashes *txscript.TxSigHashes,
+	hashType txscript.SigHashType, tx *wire.MsgTx, idx int, amt int64) ([]byte, error) {
+
+	// As a sanity check, ensure the passed input index for the transaction
+	// is valid.
+	//
+	// TODO(roasbeef): check needs to be lifted elsewhere?
+	if idx > len(tx.TxIn)-1 {
+		return nil, fmt.Errorf("idx %d but %d txins", idx, len(tx.TxIn))
+	}
+
+	sigHashBytes := chainhash.DoubleHashRaw(func(w io.Writer) error {
+		var scratch [8]byte
+
+		// First write out, then encode the transaction's version
+		// number.
+		binary.LittleEndian.PutUint32(scratch[:], uint32(tx.Version))
+		w.Write(scratch[:4])
+
+		// Next write out the possibly pre-calculated hashes for the
+		// sequence numbers of all inputs, and the hashes of the
+		// previous outs for all outputs.
+		var zeroHash chainhash.Hash
+
+		// If anyone can pay isn't active, then we can use the cached
+		// hashPrevOuts, otherwise we just write zeroes for the prev
+		// outs.
+		if hashType&txscript.SigHashAnyOneCanPay == 0 {
+			w.Write(sigHashes.HashPrevOutsV0[:])
+		} else {
+			w.Write(zeroHash[:])
+		}
+
+		// If the sighash isn't anyone can pay, single, or none, the
+		// use the cached hash sequences, otherwise write all zeroes
+		// for the hashSequence.
+		if hashType&txscript.SigHashAnyOneCanPay == 0 &&
+			hashType&sigHashMask != txscript.SigHashSingle &&
+			hashType&sigHashMask != txscript.SigHashNone {
+
+			w.Write(sigHashes.HashSequenceV0[:])
+		} else {
+			w.Write(zeroHash[:])
+		}
+
+		txIn := tx.TxIn[idx]
+
+		// Next, write the outpoint being spent.
+		w.Write(txIn.PreviousOutPoint.Hash[:])
+		var bIndex [4]byte
+		binary.LittleEndian.PutUint32(
+			bIndex[:], txIn.PreviousOutPoint.Index,
+		)
+		w.Write(bIndex[:])
+
+		if isWitnessPubKeyHashScript(subScript) {
+			// The script code for a p2wkh is a length prefix
+			// varint for the next 25 bytes, followed by a
+			// re-creation of the original p2pkh pk script.
+			w.Write([]byte{0x19})
+			w.Write([]byte{txscript.OP_DUP})
+			w.Write([]byte{txscript.OP_HASH160})
+			w.Write([]byte{txscript.OP_DATA_20})
+			w.Write(extractWitnessPubKeyHash(subScript))
+			w.Write([]byte{txscript.OP_EQUALVERIFY})
+			w.Write([]byte{txscript.OP_CHECKSIG})
+		} else {
+			// For p2wsh outputs, and future outputs, the script
+			// code is the original script, with all code
+			// separators removed, serialized with a var int length
+			// prefix.
+			wire.WriteVarBytes(w, 0, subScript)
+		}
+
+		// Next, add the input amount, and sequence number of the input
+		// being signed.
+		binary.LittleEndian.PutUint64(scratch[:], uint64(amt))
+		w.Write(scratch[:])
+		binary.LittleEndian.PutUint32(scratch[:], txIn.Sequence)
+		w.Write(scratch[:4])
+
+		// If the current signature mode isn't single, or none, then we
+		// can re-use the pre-generated hashoutputs sighash fragment.
+		// Otherwise, we'll serialize and add only the target output
+		// index to the signature pre-image.
+		if hashType&sigHashMask != txscript.SigHashSingle &&
+			hashType&sigHashMask != txscript.SigHashNone {
+
+			w.Write(sigHashes.HashOutputsV0[:])
+		} else if hashType&sigHashMask == txscript.SigHashSingle &&
+			idx < len(tx.TxOut) {
+
+			h := chainhash.DoubleHashRaw(func(tw io.Writer) error {
+				wire.WriteTxOut(tw, 0, 0, tx.TxOut[idx])
+				return nil
+			})
+			w.Write(h[:])
+		} else {
+			w.Write(zeroHash[:])
+		}
+
+		// Finally, write out the transaction's locktime, and the sig
+		// hash type.
+		binary.LittleEndian.PutUint32(scratch[:], tx.LockTime)
+		w.Write(scratch[:4])
+		binary.LittleEndian.PutUint32(scratch[:], uint32(hashType))
+		w.Write(scratch[:4])
+
+		return nil
+	})
+
+	return sigHashBytes[:], nil
+}
+
+// extractWitnessPubKeyHash extracts the witness public key hash from the passed
+// script if it is a standard pay-to-witness-pubkey-hash script. It will return
+// nil otherwise.
+func extractWitnessPubKeyHash(script []byte) []byte {
+	// A pay-to-witness-pubkey-hash script is of the form:
+	//   OP_0 OP_DATA_20 <20-byte-hash>
+	if len(script) == witnessV0PubKeyHashLen &&
+		script[0] == txscript.OP_0 &&
+		script[1] == txscript.OP_DATA_20 {
+
+		return script[2:witnessV0PubKeyHashLen]
+	}
+
+	return nil
+}
+
+// isWitnessPubKeyHashScript returns whether or not the passed script is a
+// standard pay-to-witness-pubkey-hash script.
+func isWitnessPubKeyHashScript(script []byte) bool {
+	return extractWitnessPubKeyHash(script) != nil
+}
+
+// VerifyTx verifies signatures for common script types without executing the full script.
+// Supported: P2PK, P2PKH, P2WPKH, P2WSH(single-sig), Taproot key-path.
+// It verifies using the provided pubkey (single-sig assumption).
+func VerifyTx(chain *chaincfg.Params, pkt *psbt.Packet, pubkey *secp256k1.PublicKey) (bool, error) {
+	// Convert decred pubkey -> btcec pubkey for btcec/schnorr usage
+	btcecPub, err := btcec.ParsePubKey(pubkey.SerializeCompressed())
+	if err != nil {
+		return false, fmt.Errorf("invalid pubkey: %w", err)
+	}
+
+	prevOutFetcher := PsbtPrevOutputFetcher(pkt)
+	tx := pkt.UnsignedTx
+	sigHashes := txscript.NewTxSigHashes(tx, prevOutFetcher)
+
+	for i, in := range pkt.Inputs {
+		// Resolve pkScript and amount for this input
+		var pkScript []byte
+		var amount int64
+		switch {
+		case in.WitnessUtxo != nil:
+			pkScript = in.WitnessUtxo.PkScript
+			amount = in.WitnessUtxo.Value
+		case in.NonWitnessUtxo != nil:
+			// Use the outpoint index of this *input* (not i)
+			idx := tx.TxIn[i].PreviousOutPoint.Index
+			if int(idx) >= len(in.NonWitnessUtxo.TxOut) {
+				return false, fmt.Errorf("input %d: NonWitnessUtxo missing output index %d", i, idx)
+			}
+			pkScript = in.NonWitnessUtxo.TxOut[idx].PkScript
+			amount = in.NonWitnessUtxo.TxOut[idx].Value
+		default:
+			return false, fmt.Errorf("input %d: missing UTXO data", i)
+		}
+
+		class, _, _, err := txscript.ExtractPkScriptAddrs(pkScript, chain)
+		if err != nil {
+			return false, fmt.Errorf("input %d: extract class: %w", i, err)
+		}
+
+		switch class {
+		case txscript.PubKeyTy:
+			// scriptSig: <DER+ht>
+			sigPushes, err := extractPushes(tx.TxIn[i].SignatureScript)
+			if err != nil || len(sigPushes) < 1 {
+				return false, fmt.Errorf("input %d: invalid scriptSig", i)
+			}
+			raw := sigPushes[len(sigPushes)-1]
+			if len(raw) < 2 {
+				return false, fmt.Errorf("input %d: malformed DER+hashtype", i)
+			}
+			ht := txscript.SigHashType(raw[len(raw)-1])
+			der := raw[:len(raw)-1]
+
+			digest, err := txscript.CalcSignatureHash(pkScript, ht, tx, i)
+			if err != nil {
+				return false, fmt.Errorf("input %d: sighash: %w", i, err)
+			}
+			esig, err := ecdsa.ParseDERSignature(der)
+			if err != nil {
+				return false, fmt.Errorf("input %d: parse der: %w", i, err)
+			}
+			if !esig.Verify(digest, btcecPub) {
+				return false, fmt.Errorf("input %d: ECDSA verify failed", i)
+			}
+
+		case txscript.PubKeyHashTy:
+			// scriptSig: <DER+ht> <pubkey>
+			sigPushes, err := extractPushes(tx.TxIn[i].SignatureScript)
+			if err != nil || len(sigPushes) < 1 {
+				return false, fmt.Errorf("input %d: invalid scriptSig", i)
+			}
+			raw := sigPushes[0]
+			if len(raw) < 2 {
+				return false, fmt.Errorf("input %d: malformed DER+hashtype", i)
+			}
+			ht := txscript.SigHashType(raw[len(raw)-1])
+			der := raw[:len(raw)-1]
+
+			digest, err := txscript.CalcSignatureHash(pkScript, ht, tx, i)
+			if err != nil {
+				return false, fmt.Errorf("input %d: sighash: %w", i, err)
+			}
+			esig, err := ecdsa.ParseDERSignature(der)
+			if err != nil {
+				return false, fmt.Errorf("input %d: parse der: %w", i, err)
+			}
+			if !esig.Verify(digest, btcecPub) {
+				return false, fmt.Errorf("input %d: ECDSA verify failed", i)
+			}
+
+		case txscript.WitnessV0PubKeyHashTy:
+			// witness: [ <DER+ht>, <pubkey> ]
+			wit := tx.TxIn[i].Witness
+			if len(wit) < 2 || len(wit[0]) < 2 {
+				return false, fmt.Errorf("input %d: invalid witness", i)
+			}
+			raw := wit[0]
+			ht := txscript.SigHashType(raw[len(raw)-1])
+			der := raw[:len(raw)-1]
+
+			// BIP143: subScript derived from P2WPKH (handled in calcWitnessSignatureHashRaw)
+			digest, err := calcWitnessSignatureHashRaw(pkScript, sigHashes, ht, tx, i, amount)
+			if err != nil {
+				return false, fmt.Errorf("input %d: sighash(witness): %w", i, err)
+			}
+			esig, err := ecdsa.ParseDERSignature(der)
+			if err != nil {
+				return false, fmt.Errorf("input %d: parse der: %w", i, err)
+			}
+			if !esig.Verify(digest, btcecPub) {
+				return false, fmt.Errorf("input %d: ECDSA verify failed", i)
+			}
+
+		case txscript.WitnessV0ScriptHashTy:
+			// witness: [ <sig?> ... , <witnessScript> ]
+			wit := tx.TxIn[i].Witness
+			if len(wit) < 2 {
+				return false, fmt.Errorf("input %d: invalid wsh witness", i)
+			}
+			witnessScript := wit[len(wit)-1]
+			// pick first non-empty element before last as signature (single-sig assumption)
+			var raw []byte
+			for _, el := range wit[:len(wit)-1] {
+				if len(el) > 0 {
+					raw = el
+					break
+				}
+			}
+			if len(raw) < 2 {
+				return false, fmt.Errorf("input %d: missing DER+hashtype in wsh witness", i)
+			}
+			ht := txscript.SigHashType(raw[len(raw)-1])
+			der := raw[:len(raw)-1]
+
+			digest, err := calcWitnessSignatureHashRaw(witnessScript, sigHashes, ht, tx, i, amount)
+			if err != nil {
+				return false, fmt.Errorf("input %d: sighash(wsh): %w", i, err)
+			}
+			esig, err := ecdsa.ParseDERSignature(der)
+			if err != nil {
+				return false, fmt.Errorf("input %d: parse der: %w", i, err)
+			}
+			if !esig.Verify(digest, btcecPub) {
+				return false, fmt.Errorf("input %d: ECDSA verify failed", i)
+			}
+
+		case txscript.WitnessV1TaprootTy:
+			// key-path only (no script path here)
+			wit := tx.TxIn[i].Witness
+			if len(wit) < 1 {
+				return false, fmt.Errorf("input %d: empty taproot witness", i)
+			}
+			rawSig, ht := wit[0], txscript.SigHashDefault
+			if len(rawSig) == schnorr.SignatureSize+1 {
+				rawSig, ht = rawSig[:schnorr.SignatureSize], txscript.SigHashType(rawSig[schnorr.SignatureSize])
+			}
+			sig, err := schnorr.ParseSignature(rawSig)
+			if err != nil {
+				return false, fmt.Errorf("input %d: schnorr parse: %w", i, err)
+			}
+			digest, err := txscript.CalcTaprootSignatureHash(sigHashes, ht, tx, i, prevOutFetcher)
+			if err != nil {
+				return false, fmt.Errorf("input %d: taproot sighash: %w", i, err)
+			}
+			tweaked := txscript.ComputeTaprootKeyNoScript(btcecPub)
+			if !sig.Verify(digest, tweaked) {
+				return false, fmt.Errorf("input %d: schnorr verify failed", i)
+			}
+
+		default:
+			// Not handled (OP_RETURN, exotic scripts, multisig, etc.)
+			// Skip rather than failing hard.
+		}
+	}
+
+	return true, nil
+}
+
+// Very small pushdata extractor for scriptSig: returns the data pushes in order.
+func extractPushes(script []byte) ([][]byte, error) {
+	var pushes [][]byte
+	r := bytes.NewReader(script)
+	for r.Len() > 0 {
+		op, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case op == txscript.OP_0:
+			// empty push
+			pushes = append(pushes, []byte{})
+
+		case op >= 0x01 && op <= 0x4b:
+			// 1~75 bytes push
+			n := int(op)
+			buf := make([]byte, n)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return nil, err
+			}
+			pushes = append(pushes, buf)
+
+		default:
+		}
+	}
+	return pushes, nil
+}
@@ -1,85 +1,851 @@
-package transaction
-
-import (
-	"fmt"
-	"testing"
-
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-
-	"github.com/gosuda/btctxbuilder/client"
-	"github.com/gosuda/btctxbuilder/types"
-	"github.com/gosuda/btctxbuilder/utils"
-)
-
-func TestSignPsbtTx(t *testing.T) {
-	net := types.BTC_Testnet3
-	params := types.GetParams(net)
-
-	c, err := client.NewClient(net)
-	require.NoError(t, err)
-	tx, err := c.GetRawTx("0b2c23f5c2e6326c90cfa1d3925b0d83f4b08035ca6af8fd8f606385dfbc5822")
-	require.NoError(t, err)
-	msgTx, err := types.DecodeRawTransaction(tx)
-	require.NoError(t, err)
-
-	txBuild := NewTxBuilder(params)
-	txBuild.Inputs.AddInput(params, msgTx, 1, 0, "mvNnCR7EJS4aUReLEw2sL2ZtTZh8CAP8Gp")
-	txBuild.Outputs.AddOutputTransfer(c.GetParams(), "mvNnCR7EJS4aUReLEw2sL2ZtTZh8CAP8Gp", 53000)
-	txBuild.Outputs.AddOutputTransfer(c.GetParams(), "mvNnCR7EJS4aUReLEw2sL2ZtTZh8CAP8Gp", 10000)
-	build := txBuild.Build()
-	require.NoError(t, build.Err())
-	packet, err := build.Packet()
-	require.NoError(t, err)
-
-	signer, err := types.NewECDSASigner("1790962db820729606cd7b255ace1ac5ebb129ac8e9b2d8534d022194ab25b37")
-	require.NoError(t, err)
-	signedPacket, err := SignTx(c.GetParams(), packet, signer.Sign, signer.PubKey())
-	require.NoError(t, err)
-	rawTx, err := types.EncodePsbtToRawTx(signedPacket)
-	require.NoError(t, err)
-	rawTxString := utils.HexEncode(rawTx)
-	assert.Equal(t, "01000000012258bcdf8563608ffdf86aca3580b0f4830d5b92d3a1cf906c32e6c2f5232c0b010000006b483045022068645e39a0d12590c2128af094c244bea6efab786fe13127c4101247c028458f022100d2b4c8317104e9d40d0e5cdef6058a3b6239460aaca30821ba7f4d6beba0d1f40121031053e9ef0295d334b6bb22e20cc717eb1a16a546f692572c8830b4bc14c13676ffffffff0208cf0000000000001976a914a2fe215e4789e607401a4bf85358cbbfae13a97e88ac10270000000000001976a914a2fe215e4789e607401a4bf85358cbbfae13a97e88ac00000000", rawTxString)
-
-	rawTxMake, err := types.DecodeRawTransaction(rawTxString)
-	require.NoError(t, err)
-	rawTxInput, err := types.DecodeRawTransaction("01000000012258bcdf8563608ffdf86aca3580b0f4830d5b92d3a1cf906c32e6c2f5232c0b010000006a47304402206bdac667fb3d6f1a62e0b0d1123a5caa58d8c0fd95c2a2c8cd091374960a871702204f301e6883866570ce309573e569d6a32a44386af5bf928b5f9e1dcd7e2dd0ed0121022bc0ca1d6aea1c1e523bfcb33f46131bd1a3240aa04f71c34b1a177cfd5ff933ffffffff0208cf0000000000001976a914a2fe215e4789e607401a4bf85358cbbfae13a97e88ac10270000000000001976a914a2fe215e4789e607401a4bf85358cbbfae13a97e88ac00000000")
-	require.NoError(t, err)
-
-	for i, v := range rawTxMake.TxIn {
-		fmt.Println("vin", i)
-		fmt.Println("\t", v.PreviousOutPoint)
-		fmt.Println("\t", v.SignatureScript)
-		fmt.Println("\t", v.Witness)
-		fmt.Println("\t", v.Sequence)
-	}
-
-	for i, v := range rawTxInput.TxIn {
-		fmt.Println("vin", i)
-		fmt.Println("\t", v.PreviousOutPoint)
-		fmt.Println("\t", v.SignatureScript)
-		fmt.Println("\t", v.Witness)
-		fmt.Println("\t", v.Sequence)
-	}
-
-	// pub, err := secp256k1.ParsePubKey(utils.MustDecode("022bc0ca1d6aea1c1e523bfcb33f46131bd1a3240aa04f71c34b1a177cfd5ff933"))
-	// require.NoError(t, err)
-	// valid, err := VerifyTx(c.GetParams(), signedPacket, pub)
-	// require.NoError(t, err)
-	// fmt.Println(valid)
-
-}
-
-// 01000000012258bcdf8563608ffdf86aca3580b0f4830d5b92d3a1cf906c32e6c2f5232c0b010000006a47304402206bdac667fb3d6f1a62e0b0d1123a5caa58d8c0fd95c2a2c8cd091374960a871702204f301e6883866570ce309573e569d6a32a44386af5bf928b5f9e1dcd7e2dd0ed0121022bc0ca1d6aea1c1e523bfcb33f46131bd1a3240aa04f71c34b1a177cfd5ff933ffffffff0208cf0000000000001976a914a2fe215e4789e607401a4bf85358cbbfae13a97e88ac10270000000000001976a914a2fe215e4789e607401a4bf85358cbbfae13a97e88ac00000000
-// 01000000012258bcdf8563608ffdf86aca3580b0f4830d5b92d3a1cf906c32e6c2f5232c0b010000006a47304402206bdac667fb3d6f1a62e0b0d1123a5caa58d8c0fd95c2a2c8cd091374960a871702204f301e6883866570ce309573e569d6a32a44386af5bf928b5f9e1dcd7e2dd0ed0121031053e9ef0295d334b6bb22e20cc717eb1a16a546f692572c8830b4bc14c13676ffffffff0208cf0000000000001976a914a2fe215e4789e607401a4bf85358cbbfae13a97e88ac10270000000000001976a914a2fe215e4789e607401a4bf85358cbbfae13a97e88ac00000000
-
-// vin 0
-// 	 0b2c23f5c2e6326c90cfa1d3925b0d83f4b08035ca6af8fd8f606385dfbc5822:1
-// 	 [71 48 68 2 32 107 218 198 103 251 61 111 26 98 224 176 209 18 58 92 170 88 216 192 253 149 194 162 200 205 9 19 116 150 10 135 23 2 32 79 48 30 104 131 134 101 112 206 48 149 115 229 105 214 163 42 68 56 106 245 191 146 139 95 158 29 205 126 45 208 237 1 65 4 16 83 233 239 2 149 211 52 182 187 34 226 12 199 23 235 26 22 165 70 246 146 87 44 136 48 180 188 20 193 54 118 94 30 26 75 241 121 55 219 129 223 58 155 133 221 181 96 189 77 12 227 70 92 86 148 113 140 214 241 171 102 242 39]
-// 	 []
-// 	 4294967295
-// vin 0
-// 	 0b2c23f5c2e6326c90cfa1d3925b0d83f4b08035ca6af8fd8f606385dfbc5822:1
-// 	 [71 48 68 2 32 107 218 198 103 251 61 111 26 98 224 176 209 18 58 92 170 88 216 192 253 149 194 162 200 205 9 19 116 150 10 135 23 2 32 79 48 30 104 131 134 101 112 206 48 149 115 229 105 214 163 42 68 56 106 245 191 146 139 95 158 29 205 126 45 208 237 1 33 2 43 192 202 29 106 234 28 30 82 59 252 179 63 70 19 27 209 163 36 10 160 79 113 195 75 26 23 124 253 95 249 51]
-// 	 []
-// 	 4294967295
+package transaction
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosuda/btctxbuilder/client"
+	"github.com/gosuda/btctxbuilder/frost"
+	"github.com/gosuda/btctxbuilder/script"
+	"github.com/gosuda/btctxbuilder/types"
+	"github.com/gosuda/btctxbuilder/utils"
+)
+
+func TestSignPsbtTx(t *testing.T) {
+	net := types.BTC_Testnet3
+	params := types.GetParams(net)
+
+	c, err := client.NewClient(net, client.WithRetry(0, 0, 0))
+	require.NoError(t, err)
+	tx, err := c.GetRawTx(context.Background(), "0b2c23f5c2e6326c90cfa1d3925b0d83f4b08035ca6af8fd8f606385dfbc5822")
+	require.NoError(t, err)
+	msgTx, err := types.DecodeRawTransaction(tx)
+	require.NoError(t, err)
+
+	txBuild := NewTxBuilder(params)
+	txBuild.Inputs.AddInput(params, msgTx, 1, 0, "mvNnCR7EJS4aUReLEw2sL2ZtTZh8CAP8Gp")
+	txBuild.Outputs.AddOutputTransfer(c.GetParams(), "mvNnCR7EJS4aUReLEw2sL2ZtTZh8CAP8Gp", 53000)
+	txBuild.Outputs.AddOutputTransfer(c.GetParams(), "mvNnCR7EJS4aUReLEw2sL2ZtTZh8CAP8Gp", 10000)
+	build := txBuild.Build()
+	require.NoError(t, build.Err())
+	packet, err := build.Packet()
+	require.NoError(t, err)
+
+	signer, err := types.NewECDSASigner("1790962db820729606cd7b255ace1ac5ebb129ac8e9b2d8534d022194ab25b37")
+	require.NoError(t, err)
+	signedPacket, err := SignTx(c.GetParams(), packet, signer.Sign, signer.PubKey())
+	require.NoError(t, err)
+	rawTx, err := types.EncodePsbtToRawTx(signedPacket)
+	require.NoError(t, err)
+	rawTxString := utils.HexEncode(rawTx)
+	assert.Equal(t, "01000000012258bcdf8563608ffdf86aca3580b0f4830d5b92d3a1cf906c32e6c2f5232c0b010000006b483045022068645e39a0d12590c2128af094c244bea6efab786fe13127c4101247c028458f022100d2b4c8317104e9d40d0e5cdef6058a3b6239460aaca30821ba7f4d6beba0d1f40121031053e9ef0295d334b6bb22e20cc717eb1a16a546f692572c8830b4bc14c13676ffffffff0208cf0000000000001976a914a2fe215e4789e607401a4bf85358cbbfae13a97e88ac10270000000000001976a914a2fe215e4789e607401a4bf85358cbbfae13a97e88ac00000000", rawTxString)
+
+	rawTxMake, err := types.DecodeRawTransaction(rawTxString)
+	require.NoError(t, err)
+	rawTxInput, err := types.DecodeRawTransaction("01000000012258bcdf8563608ffdf86aca3580b0f4830d5b92d3a1cf906c32e6c2f5232c0b010000006a47304402206bdac667fb3d6f1a62e0b0d1123a5caa58d8c0fd95c2a2c8cd091374960a871702204f301e6883866570ce309573e569d6a32a44386af5bf928b5f9e1dcd7e2dd0ed0121022bc0ca1d6aea1c1e523bfcb33f46131bd1a3240aa04f71c34b1a177cfd5ff933ffffffff0208cf0000000000001976a914a2fe215e4789e607401a4bf85358cbbfae13a97e88ac10270000000000001976a914a2fe215e4789e607401a4bf85358cbbfae13a97e88ac00000000")
+	require.NoError(t, err)
+
+	for i, v := range rawTxMake.TxIn {
+		fmt.Println("vin", i)
+		fmt.Println("\t", v.PreviousOutPoint)
+		fmt.Println("\t", v.SignatureScript)
+		fmt.Println("\t", v.Witness)
+		fmt.Println("\t", v.Sequence)
+	}
+
+	for i, v := range rawTxInput.TxIn {
+		fmt.Println("vin", i)
+		fmt.Println("\t", v.PreviousOutPoint)
+		fmt.Println("\t", v.SignatureScript)
+		fmt.Println("\t", v.Witness)
+		fmt.Println("\t", v.Sequence)
+	}
+
+	// pub, err := secp256k1.ParsePubKey(utils.MustDecode("022bc0ca1d6aea1c1e523bfcb33f46131bd1a3240aa04f71c34b1a177cfd5ff933"))
+	// require.NoError(t, err)
+	// valid, err := VerifyTx(c.GetParams(), signedPacket, pub)
+	// require.NoError(t, err)
+	// fmt.Println(valid)
+
+}
+
+// 01000000012258bcdf8563608ffdf86aca3580b0f4830d5b92d3a1cf906c32e6c2f5232c0b010000006a47304402206bdac667fb3d6f1a62e0b0d1123a5caa58d8c0fd95c2a2c8cd091374960a871702204f301e6883866570ce309573e569d6a32a44386af5bf928b5f9e1dcd7e2dd0ed0121022bc0ca1d6aea1c1e523bfcb33f46131bd1a3240aa04f71c34b1a177cfd5ff933ffffffff0208cf0000000000001976a914a2fe215e4789e607401a4bf85358cbbfae13a97e88ac10270000000000001976a914a2fe215e4789e607401a4bf85358cbbfae13a97e88ac00000000
+// 01000000012258bcdf8563608ffdf86aca3580b0f4830d5b92d3a1cf906c32e6c2f5232c0b010000006a47304402206bdac667fb3d6f1a62e0b0d1123a5caa58d8c0fd95c2a2c8cd091374960a871702204f301e6883866570ce309573e569d6a32a44386af5bf928b5f9e1dcd7e2dd0ed0121031053e9ef0295d334b6bb22e20cc717eb1a16a546f692572c8830b4bc14c13676ffffffff0208cf0000000000001976a914a2fe215e4789e607401a4bf85358cbbfae13a97e88ac10270000000000001976a914a2fe215e4789e607401a4bf85358cbbfae13a97e88ac00000000
+
+// vin 0
+// 	 0b2c23f5c2e6326c90cfa1d3925b0d83f4b08035ca6af8fd8f606385dfbc5822:1
+// 	 [71 48 68 2 32 107 218 198 103 251 61 111 26 98 224 176 209 18 58 92 170 88 216 192 253 149 194 162 200 205 9 19 116 150 10 135 23 2 32 79 48 30 104 131 134 101 112 206 48 149 115 229 105 214 163 42 68 56 106 245 191 146 139 95 158 29 205 126 45 208 237 1 65 4 16 83 233 239 2 149 211 52 182 187 34 226 12 199 23 235 26 22 165 70 246 146 87 44 136 48 180 188 20 193 54 118 94 30 26 75 241 121 55 219 129 223 58 155 133 221 181 96 189 77 12 227 70 92 86 148 113 140 214 241 171 102 242 39]
+// 	 []
+// 	 4294967295
+// vin 0
+// 	 0b2c23f5c2e6326c90cfa1d3925b0d83f4b08035ca6af8fd8f606385dfbc5822:1
+// 	 [71 48 68 2 32 107 218 198 103 251 61 111 26 98 224 176 209 18 58 92 170 88 216 192 253 149 194 162 200 205 9 19 116 150 10 135 23 2 32 79 48 30 104 131 134 101 112 206 48 149 115 229 105 214 163 42 68 56 106 245 191 146 139 95 158 29 205 126 45 208 237 1 33 2 43 192 202 29 106 234 28 30 82 59 252 179 63 70 19 27 209 163 36 10 160 79 113 195 75 26 23 124 253 95 249 51]
+// 	 []
+// 	 4294967295
+
+// executeInputs runs every input of the signed packet through the script
+// engine.
+func executeInputs(t *testing.T, signed *psbt.Packet, utxos []*types.Utxo) {
+	t.Helper()
+	raw, err := types.EncodePsbtToRawTx(signed)
+	require.NoError(t, err)
+	tx := wire.NewMsgTx(wire.TxVersion)
+	require.NoError(t, tx.Deserialize(bytes.NewReader(raw)))
+
+	fetcher := txscript.NewMultiPrevOutFetcher(nil)
+	for _, u := range utxos {
+		fetcher.AddPrevOut(wire.OutPoint{Hash: u.RawTx.TxHash(), Index: u.Vout}, u.RawTx.TxOut[u.Vout])
+	}
+	sigHashes := txscript.NewTxSigHashes(tx, fetcher)
+	for i, in := range tx.TxIn {
+		prevOut := fetcher.FetchPrevOutput(in.PreviousOutPoint)
+		vm, err := txscript.NewEngine(prevOut.PkScript, tx, i, txscript.StandardVerifyFlags, nil, sigHashes, prevOut.Value, fetcher)
+		require.NoError(t, err)
+		require.NoError(t, vm.Execute(), "input %d", i)
+	}
+}
+
+// newTapscriptOutput commits to leaves under internal and returns the
+// address together with the spend info for the leaves selected by use.
+func newTapscriptOutput(t *testing.T, params *chaincfg.Params, internal *types.HDKey, leaves [][]byte, use ...int) (string, *types.SpendInfo) {
+	t.Helper()
+	pub, err := internal.PubKey()
+	require.NoError(t, err)
+	tapLeaves := make([]script.TapLeaf, len(leaves))
+	for i, leaf := range leaves {
+		tapLeaves[i] = script.TapLeaf{Script: leaf}
+	}
+	tree, err := script.NewTapTree(tapLeaves...)
+	require.NoError(t, err)
+	out, err := tree.Output(pub, params)
+	require.NoError(t, err)
+	return out.Address.EncodeAddress(), out.SpendInfo(use...)
+}
+
+func TestSignTx_TaprootScriptPath(t *testing.T) {
+	params := types.GetParams(types.BTC_Signet)
+	to := "n368zCWREFiRRX7icJRBb6n8nMsjJjNVK8"
+	master, err := types.NewHDKeyFromSeed(make([]byte, 32), params)
+	require.NoError(t, err)
+
+	keys := make([]*types.HDKey, 3)
+	signers := make([]*types.SchnorrSigner, 3)
+	for i := range keys {
+		keys[i], err = master.DerivePath(fmt.Sprintf("m/86'/1'/0'/0/%d", i))
+		require.NoError(t, err)
+		signers[i], err = keys[i].TapscriptSigner()
+		require.NoError(t, err)
+	}
+	a, b := signers[1].PubKey(), signers[2].PubKey()
+
+	single, err := txscript.NewScriptBuilder().AddData(a).AddOp(txscript.OP_CHECKSIG).Script()
+	require.NoError(t, err)
+	multi, err := txscript.NewScriptBuilder().
+		AddData(a).AddOp(txscript.OP_CHECKSIG).
+		AddData(b).AddOp(txscript.OP_CHECKSIGADD).
+		AddInt64(2).AddOp(txscript.OP_NUMEQUAL).
+		Script()
+	require.NoError(t, err)
+	leaves := [][]byte{single, multi}
+
+	build := func(t *testing.T, use ...int) (*psbt.Packet, []*types.Utxo) {
+		from, spend := newTapscriptOutput(t, params, keys[0], leaves, use...)
+		utxos := newTestUtxos(t, params, from, 50000)
+		packet, err := NewTxBuilder(params).
+			FeeRate(2).
+			From(from).
+			SpendInfo(from, spend).
+			To(to, 10000).
+			SelectUtxo(utxos).
+			Build().
+			Packet()
+		require.NoError(t, err)
+		require.Len(t, packet.Inputs[0].TaprootLeafScript, len(use))
+		return packet, utxos
+	}
+
+	t.Run("single key leaf", func(t *testing.T) {
+		packet, utxos := build(t, 0, 1)
+		signed, err := SignTx(params, packet, signers[1].Sign, signers[1].PubKey())
+		require.NoError(t, err)
+		require.NotEmpty(t, signed.Inputs[0].FinalScriptWitness)
+		executeInputs(t, signed, utxos)
+	})
+
+	t.Run("checksigadd leaf", func(t *testing.T) {
+		packet, utxos := build(t, 1)
+		signed, err := SignTx(params, packet, signers[1].Sign, signers[1].PubKey())
+		require.NoError(t, err)
+		require.Empty(t, signed.Inputs[0].FinalScriptWitness)
+		require.Len(t, signed.Inputs[0].TaprootScriptSpendSig, 1)
+
+		// signing twice does not duplicate the signature
+		signed, err = SignTx(params, signed, signers[1].Sign, signers[1].PubKey())
+		require.NoError(t, err)
+		require.Len(t, signed.Inputs[0].TaprootScriptSpendSig, 1)
+
+		signed, err = SignTx(params, signed, signers[2].Sign, signers[2].PubKey())
+		require.NoError(t, err)
+		require.NotEmpty(t, signed.Inputs[0].FinalScriptWitness)
+		executeInputs(t, signed, utxos)
+	})
+
+	t.Run("key path", func(t *testing.T) {
+		packet, utxos := build(t, 0)
+		internal, err := keys[0].ECDSASigner()
+		require.NoError(t, err)
+		priv, _ := btcec.PrivKeyFromBytes(internal.PrivateKey())
+		tweaked := txscript.TweakTaprootPrivKey(*priv, packet.Inputs[0].TaprootMerkleRoot)
+		signer, err := types.NewSchnorrSigner(hex.EncodeToString(tweaked.Serialize()))
+		require.NoError(t, err)
+
+		signed, err := SignTx(params, packet, signer.Sign, signer.PubKey())
+		require.NoError(t, err)
+		require.Empty(t, signed.Inputs[0].TaprootScriptSpendSig)
+		executeInputs(t, signed, utxos)
+	})
+
+	t.Run("key path of another key", func(t *testing.T) {
+		// the BIP86 key of the internal key ignores the tree, so it isn't
+		// the output key either
+		bip86, err := keys[0].SchnorrSigner()
+		require.NoError(t, err)
+		stranger, err := types.NewSchnorrSigner("")
+		require.NoError(t, err)
+		for _, signer := range []*types.SchnorrSigner{bip86, stranger} {
+			packet, _ := build(t, 0)
+			signed, err := SignTx(params, packet, signer.Sign, signer.PubKey())
+			require.NoError(t, err)
+			in := signed.Inputs[0]
+			require.Nil(t, in.TaprootKeySpendSig)
+			require.Empty(t, in.TaprootScriptSpendSig)
+			require.Nil(t, in.FinalScriptWitness)
+
+			// the unsigned input only fails on extraction
+			err = psbt.MaybeFinalizeAll(signed)
+			require.Error(t, err)
+			_, err = types.EncodePsbtToRawTx(signed)
+			require.Error(t, err)
+		}
+	})
+}
+
+func TestSignTx_TaprootMultiSig(t *testing.T) {
+	params := types.GetParams(types.BTC_Signet)
+	to := "n368zCWREFiRRX7icJRBb6n8nMsjJjNVK8"
+	master, err := types.NewHDKeyFromSeed(make([]byte, 32), params)
+	require.NoError(t, err)
+
+	signers := make([]*types.SchnorrSigner, 3)
+	pubKeys := make([][]byte, 3)
+	for i := range signers {
+		key, err := master.DerivePath(fmt.Sprintf("m/87'/1'/0'/0/%d", i))
+		require.NoError(t, err)
+		signers[i], err = key.TapscriptSigner()
+		require.NoError(t, err)
+		pubKeys[i] = signers[i].PubKey()
+	}
+
+	for _, layout := range []script.TaprootMultiSigLayout{script.TaprootMultiSigCheckSigAdd, script.TaprootMultiSigCombinations} {
+		out, err := script.NewTaprootMultiSig(types.BTC_Signet, nil, pubKeys, 2, layout)
+		require.NoError(t, err)
+		from := out.Address.EncodeAddress()
+
+		build := func(t *testing.T) (*psbt.Packet, []*types.Utxo) {
+			utxos := newTestUtxos(t, params, from, 40000, 30000)
+			packet, err := NewTxBuilder(params).
+				FeeRate(2).
+				From(from).
+				SpendInfo(from, out.SpendInfo()).
+				To(to, 50000).
+				CoinSelector(utils.Knapsack{}).
+				SelectUtxo(utxos).
+				Build().
+				Packet()
+			require.NoError(t, err)
+			require.Len(t, packet.Inputs, 2)
+			return packet, utxos
+		}
+
+		t.Run(fmt.Sprintf("layout %d sequential", layout), func(t *testing.T) {
+			packet, utxos := build(t)
+			signed, err := SignTx(params, packet, signers[0].Sign, signers[0].PubKey())
+			require.NoError(t, err)
+			for _, in := range signed.Inputs {
+				require.Empty(t, in.FinalScriptWitness)
+			}
+			signed, err = SignTx(params, signed, signers[2].Sign, signers[2].PubKey())
+			require.NoError(t, err)
+			executeInputs(t, signed, utxos)
+		})
+
+		t.Run(fmt.Sprintf("layout %d combined", layout), func(t *testing.T) {
+			packet, utxos := build(t)
+			var partial []*psbt.Packet
+			for _, signer := range signers[1:] {
+				raw, err := types.EncodePsbt(packet)
+				require.NoError(t, err)
+				copied, err := types.DecodePsbt(raw)
+				require.NoError(t, err)
+				signed, err := SignTx(params, copied, signer.Sign, signer.PubKey())
+				require.NoError(t, err)
+				partial = append(partial, signed)
+			}
+
+			combined, err := CombineTx(partial[:1]...)
+			require.NoError(t, err)
+			require.Empty(t, combined.Inputs[0].FinalScriptWitness)
+
+			// a signature seen twice is kept once, as BIP174 forbids
+			// duplicate keys
+			combined, err = CombineTx(partial[0], partial[0])
+			require.NoError(t, err)
+			for i, in := range combined.Inputs {
+				require.Len(t, in.TaprootScriptSpendSig, len(partial[0].Inputs[i].TaprootScriptSpendSig))
+			}
+			raw, err := types.EncodePsbt(combined)
+			require.NoError(t, err)
+			_, err = types.DecodePsbt(raw)
+			require.NoError(t, err)
+
+			combined, err = CombineTx(partial...)
+			require.NoError(t, err)
+			executeInputs(t, combined, utxos)
+		})
+	}
+}
+
+func TestSignTx_MultiSig(t *testing.T) {
+	params := types.GetParams(types.BTC_Signet)
+	to := "n368zCWREFiRRX7icJRBb6n8nMsjJjNVK8"
+	master, err := types.NewHDKeyFromSeed(make([]byte, 32), params)
+	require.NoError(t, err)
+
+	signers := make([]*types.ECDSASigner, 3)
+	pubKeys := make([][]byte, 3)
+	for i := range signers {
+		key, err := master.DerivePath(fmt.Sprintf("m/48'/1'/0'/2'/0/%d", i))
+		require.NoError(t, err)
+		signers[i], err = key.ECDSASigner()
+		require.NoError(t, err)
+		pubKeys[i] = signers[i].PubKey()
+	}
+	multiSig, err := script.EncodeSortedMultiSigScript(types.BTC_Signet, pubKeys, 2)
+	require.NoError(t, err)
+
+	for _, addrType := range []types.AddrType{types.P2SH, types.P2WSH, types.P2WSH_NESTED} {
+		out, err := script.NewMultiSig(types.BTC_Signet, addrType, multiSig)
+		require.NoError(t, err)
+		from := out.Address.EncodeAddress()
+
+		build := func(t *testing.T) (*psbt.Packet, []*types.Utxo) {
+			utxos := newTestUtxos(t, params, from, 40000, 30000)
+			packet, err := NewTxBuilder(params).
+				FeeRate(2).
+				From(from).
+				SpendInfo(from, out.SpendInfo()).
+				To(to, 50000).
+				CoinSelector(utils.Knapsack{}).
+				SelectUtxo(utxos).
+				Build().
+				Packet()
+			require.NoError(t, err)
+			require.Len(t, packet.Inputs, 2)
+			return packet, utxos
+		}
+
+		t.Run(string(addrType)+" sequential", func(t *testing.T) {
+			packet, utxos := build(t)
+			// signing in reverse key order still finalizes in script order
+			signed, err := SignTx(params, packet, signers[2].Sign, signers[2].PubKey())
+			require.NoError(t, err)
+			signed, err = SignTx(params, signed, signers[2].Sign, signers[2].PubKey())
+			require.NoError(t, err)
+			for _, in := range signed.Inputs {
+				require.Nil(t, in.FinalScriptSig)
+				require.Nil(t, in.FinalScriptWitness)
+				require.Len(t, in.PartialSigs, 1)
+			}
+			signed, err = SignTx(params, signed, signers[0].Sign, signers[0].PubKey())
+			require.NoError(t, err)
+			executeInputs(t, signed, utxos)
+
+			// the fee estimate covers the multisig inputs
+			raw, err := types.EncodePsbtToRawTx(signed)
+			require.NoError(t, err)
+			tx := wire.NewMsgTx(wire.TxVersion)
+			require.NoError(t, tx.Deserialize(bytes.NewReader(raw)))
+			var fee int64 = 70000
+			for _, o := range tx.TxOut {
+				fee -= o.Value
+			}
+			vSize := (blockchain.GetTransactionWeight(btcutil.NewTx(tx)) + 3) / 4
+			require.GreaterOrEqual(t, fee, 2*vSize)
+		})
+
+		t.Run(string(addrType)+" combined", func(t *testing.T) {
+			packet, utxos := build(t)
+			var partial []*psbt.Packet
+			for _, signer := range signers[1:] {
+				raw, err := types.EncodePsbt(packet)
+				require.NoError(t, err)
+				copied, err := types.DecodePsbt(raw)
+				require.NoError(t, err)
+				signed, err := SignTx(params, copied, signer.Sign, signer.PubKey())
+				require.NoError(t, err)
+				partial = append(partial, signed)
+			}
+			combined, err := CombineTx(partial...)
+			require.NoError(t, err)
+			executeInputs(t, combined, utxos)
+		})
+	}
+
+	t.Run("bare", func(t *testing.T) {
+		prevTx := wire.NewMsgTx(wire.TxVersion)
+		prevTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), nil, nil))
+		prevTx.AddTxOut(wire.NewTxOut(20000, multiSig))
+		utxos := []*types.Utxo{{Txid: prevTx.TxID(), Vout: 0, Value: 20000, RawTx: prevTx}}
+
+		tx := wire.NewMsgTx(wire.TxVersion)
+		prevHash := prevTx.TxHash()
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prevHash, 0), nil, nil))
+		toAddr, err := btcutil.DecodeAddress(to, params)
+		require.NoError(t, err)
+		toScript, err := txscript.PayToAddrScript(toAddr)
+		require.NoError(t, err)
+		tx.AddTxOut(wire.NewTxOut(19000, toScript))
+		packet, err := psbt.NewFromUnsignedTx(tx)
+		require.NoError(t, err)
+		packet.Inputs[0].NonWitnessUtxo = prevTx
+
+		signed, err := SignTx(params, packet, signers[1].Sign, signers[1].PubKey())
+		require.NoError(t, err)
+		require.Nil(t, signed.Inputs[0].FinalScriptSig)
+		signed, err = SignTx(params, signed, signers[0].Sign, signers[0].PubKey())
+		require.NoError(t, err)
+		executeInputs(t, signed, utxos)
+	})
+
+	t.Run("outsider", func(t *testing.T) {
+		out, err := script.NewMultiSig(types.BTC_Signet, types.P2WSH, multiSig)
+		require.NoError(t, err)
+		from := out.Address.EncodeAddress()
+		packet, err := NewTxBuilder(params).
+			FeeRate(2).
+			From(from).
+			SpendInfo(from, out.SpendInfo()).
+			To(to, 10000).
+			SelectUtxo(newTestUtxos(t, params, from, 40000)).
+			Build().
+			Packet()
+		require.NoError(t, err)
+		outsider, err := types.NewECDSASigner("")
+		require.NoError(t, err)
+		_, err = SignTx(params, packet, outsider.Sign, outsider.PubKey())
+		require.ErrorContains(t, err, "not part of the multisig script")
+	})
+}
+
+func TestSignTx_NestedSegwit(t *testing.T) {
+	params := types.GetParams(types.BTC_Signet)
+	to := "n368zCWREFiRRX7icJRBb6n8nMsjJjNVK8"
+	master, err := types.NewHDKeyFromSeed(make([]byte, 32), params)
+	require.NoError(t, err)
+	key, err := master.DerivePath("m/49'/1'/0'/0/0")
+	require.NoError(t, err)
+	origin, err := key.Origin()
+	require.NoError(t, err)
+	signer, err := key.ECDSASigner()
+	require.NoError(t, err)
+
+	checkFee := func(t *testing.T, signed *psbt.Packet, utxos []*types.Utxo) {
+		raw, err := types.EncodePsbtToRawTx(signed)
+		require.NoError(t, err)
+		tx := wire.NewMsgTx(wire.TxVersion)
+		require.NoError(t, tx.Deserialize(bytes.NewReader(raw)))
+		var fee int64
+		for _, u := range utxos {
+			fee += u.Value
+		}
+		for _, o := range tx.TxOut {
+			fee -= o.Value
+		}
+		vSize := (blockchain.GetTransactionWeight(btcutil.NewTx(tx)) + 3) / 4
+		require.GreaterOrEqual(t, fee, 2*vSize)
+	}
+
+	from, err := types.PubKeyToAddr(signer.PubKey(), types.P2WPKH_NESTED, params)
+	require.NoError(t, err)
+
+	t.Run("np2wpkh", func(t *testing.T) {
+		utxos := newTestUtxos(t, params, from, 40000, 30000)
+		packet, err := NewTxBuilder(params).
+			FeeRate(2).
+			From(from).
+			To(to, 50000).
+			CoinSelector(utils.Knapsack{}).
+			SelectUtxo(utxos).
+			Build().
+			Packet()
+		require.NoError(t, err)
+		require.Len(t, packet.Inputs, 2)
+
+		// the redeem script is derived from the signer key
+		signed, err := SignTx(params, packet, signer.Sign, signer.PubKey())
+		require.NoError(t, err)
+		for _, in := range signed.Inputs {
+			require.NotEmpty(t, in.FinalScriptSig)
+			require.NotEmpty(t, in.FinalScriptWitness)
+		}
+		executeInputs(t, signed, utxos)
+		checkFee(t, signed, utxos)
+	})
+
+	t.Run("np2wpkh key origin", func(t *testing.T) {
+		utxos := newTestUtxos(t, params, from, 50000)
+		packet, err := NewTxBuilder(params).
+			FeeRate(2).
+			From(from).
+			KeyOrigin(from, origin).
+			To(to, 10000).
+			SelectUtxo(utxos).
+			Build().
+			Packet()
+		require.NoError(t, err)
+		wantRedeem, err := txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(btcutil.Hash160(origin.PubKey)).Script()
+		require.NoError(t, err)
+		require.Equal(t, wantRedeem, packet.Inputs[0].RedeemScript)
+		require.NotNil(t, packet.Inputs[0].WitnessUtxo)
+
+		signed, err := SignTx(params, packet, signer.Sign, signer.PubKey())
+		require.NoError(t, err)
+		executeInputs(t, signed, utxos)
+		checkFee(t, signed, utxos)
+	})
+
+	t.Run("np2wpkh foreign key", func(t *testing.T) {
+		other, err := types.NewECDSASigner("")
+		require.NoError(t, err)
+		for _, keyOrigin := range []bool{false, true} {
+			builder := NewTxBuilder(params).
+				FeeRate(2).
+				From(from).
+				To(to, 10000)
+			if keyOrigin {
+				builder = builder.KeyOrigin(from, origin)
+			}
+			packet, err := builder.SelectUtxo(newTestUtxos(t, params, from, 50000)).Build().Packet()
+			require.NoError(t, err)
+
+			// the input is left for its owner
+			signed, err := SignTx(params, packet, other.Sign, other.PubKey())
+			require.NoError(t, err)
+			require.Empty(t, signed.Inputs[0].PartialSigs)
+			require.Nil(t, signed.Inputs[0].FinalScriptWitness)
+			_, err = types.EncodePsbtToRawTx(signed)
+			require.Error(t, err)
+		}
+	})
+
+	t.Run("np2wpkh and p2wpkh owners", func(t *testing.T) {
+		other, err := types.NewECDSASigner("")
+		require.NoError(t, err)
+		otherAddr, err := types.PubKeyToAddr(other.PubKey(), types.P2WPKH, params)
+		require.NoError(t, err)
+
+		for _, first := range []*types.ECDSASigner{signer, other} {
+			ours := newTestUtxos(t, params, from, 30000)
+			theirs := newTestUtxos(t, params, otherAddr, 20000)
+			builder := NewTxBuilder(params).FeeRate(2).From(from).To(to, 45000)
+			require.NoError(t, builder.Inputs.AddInput(params, ours[0].RawTx, 0, ours[0].Value, from))
+			require.NoError(t, builder.Inputs.AddInput(params, theirs[0].RawTx, 0, theirs[0].Value, otherAddr))
+			packet, err := builder.Build().Packet()
+			require.NoError(t, err)
+
+			// each owner signs their input, in either order
+			second := other
+			if first == other {
+				second = signer
+			}
+			signed, err := SignTx(params, packet, first.Sign, first.PubKey())
+			require.NoError(t, err)
+			signed, err = SignTx(params, signed, second.Sign, second.PubKey())
+			require.NoError(t, err)
+			executeInputs(t, signed, append(ours, theirs...))
+		}
+	})
+
+	witnessScript, err := txscript.NewScriptBuilder().AddData(signer.PubKey()).AddOp(txscript.OP_CHECKSIG).Script()
+	require.NoError(t, err)
+	for _, addrType := range []types.AddrType{types.P2WSH_NESTED, types.P2WSH} {
+		t.Run(string(addrType)+" single key", func(t *testing.T) {
+			hash := sha256.Sum256(witnessScript)
+			spend := &types.SpendInfo{WitnessScript: witnessScript}
+			var addr btcutil.Address
+			if addrType == types.P2WSH_NESTED {
+				spend.RedeemScript, err = txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(hash[:]).Script()
+				require.NoError(t, err)
+				addr, err = btcutil.NewAddressScriptHash(spend.RedeemScript, params)
+			} else {
+				addr, err = btcutil.NewAddressWitnessScriptHash(hash[:], params)
+			}
+			require.NoError(t, err)
+			from := addr.EncodeAddress()
+
+			utxos := newTestUtxos(t, params, from, 50000)
+			packet, err := NewTxBuilder(params).
+				FeeRate(2).
+				From(from).
+				SpendInfo(from, spend).
+				To(to, 10000).
+				SelectUtxo(utxos).
+				Build().
+				Packet()
+			require.NoError(t, err)
+
+			signed, err := SignTx(params, packet, signer.Sign, signer.PubKey())
+			require.NoError(t, err)
+			require.NotEmpty(t, signed.Inputs[0].FinalScriptWitness)
+			executeInputs(t, signed, utxos)
+			checkFee(t, signed, utxos)
+		})
+	}
+}
+
+func TestSignTx_SigHashType(t *testing.T) {
+	params := types.GetParams(types.BTC_Signet)
+	to := "n368zCWREFiRRX7icJRBb6n8nMsjJjNVK8"
+	master, err := types.NewHDKeyFromSeed(make([]byte, 32), params)
+	require.NoError(t, err)
+
+	type wallet struct {
+		from   string
+		sign   types.Signer
+		pubKey []byte
+	}
+	newWallet := func(t *testing.T, path string, addrType types.AddrType) wallet {
+		key, err := master.DerivePath(path)
+		require.NoError(t, err)
+		if addrType == types.P2TR {
+			signer, err := key.SchnorrSigner()
+			require.NoError(t, err)
+			from, err := types.PubKeyToAddr(signer.PubKey(), addrType, params)
+			require.NoError(t, err)
+			return wallet{from, signer.Sign, signer.PubKey()}
+		}
+		signer, err := key.ECDSASigner()
+		require.NoError(t, err)
+		from, err := types.PubKeyToAddr(signer.PubKey(), addrType, params)
+		require.NoError(t, err)
+		return wallet{from, signer.Sign, signer.PubKey()}
+	}
+	// sigHashBytes returns the sighash byte of the first signature of every
+	// input.
+	sigHashBytes := func(t *testing.T, signed *psbt.Packet) []byte {
+		raw, err := types.EncodePsbtToRawTx(signed)
+		require.NoError(t, err)
+		tx := wire.NewMsgTx(wire.TxVersion)
+		require.NoError(t, tx.Deserialize(bytes.NewReader(raw)))
+		var hashTypes []byte
+		for _, in := range tx.TxIn {
+			sig := in.Witness[0:]
+			if len(in.Witness) == 0 {
+				pushes, err := txscript.PushedData(in.SignatureScript)
+				require.NoError(t, err)
+				sig = pushes
+			}
+			hashTypes = append(hashTypes, sig[0][len(sig[0])-1])
+		}
+		return hashTypes
+	}
+
+	for _, tc := range []struct {
+		path     string
+		addrType types.AddrType
+	}{
+		{"m/44'/1'/0'/0/0", types.P2PKH},
+		{"m/49'/1'/0'/0/0", types.P2WPKH_NESTED},
+		{"m/84'/1'/0'/0/0", types.P2WPKH},
+		{"m/86'/1'/0'/0/0", types.P2TR},
+	} {
+		w := newWallet(t, tc.path, tc.addrType)
+		for _, hashType := range []txscript.SigHashType{
+			txscript.SigHashAll | txscript.SigHashAnyOneCanPay,
+			txscript.SigHashNone,
+			txscript.SigHashSingle,
+			txscript.SigHashSingle | txscript.SigHashAnyOneCanPay,
+		} {
+			t.Run(fmt.Sprintf("%s %#x", tc.addrType, byte(hashType)), func(t *testing.T) {
+				utxos := newTestUtxos(t, params, w.from, 40000, 30000)
+				packet, err := NewTxBuilder(params).
+					FeeRate(2).
+					From(w.from).
+					To(to, 50000).
+					SigHashType(hashType).
+					CoinSelector(utils.Knapsack{}).
+					SelectUtxo(utxos).
+					Build().
+					Packet()
+				require.NoError(t, err)
+				require.Len(t, packet.Inputs, 2)
+
+				signed, err := SignTx(params, packet, w.sign, w.pubKey)
+				require.NoError(t, err)
+				require.Equal(t, []byte{byte(hashType), byte(hashType)}, sigHashBytes(t, signed))
+				executeInputs(t, signed, utxos)
+			})
+		}
+	}
+
+	t.Run("single anyonecanpay offer", func(t *testing.T) {
+		seller := newWallet(t, "m/86'/1'/0'/0/1", types.P2TR)
+		buyer := newWallet(t, "m/84'/1'/0'/0/1", types.P2WPKH)
+
+		// the seller asks 30000 for a 10000 UTXO
+		offered := newTestUtxos(t, params, seller.from, 10000)
+		utxo := offered[0]
+		sellerAddr, _, err := types.DecodeAddress(seller.from, params)
+		require.NoError(t, err)
+		sellerScript, err := script.EncodeTransferScript(sellerAddr)
+		require.NoError(t, err)
+		tx := wire.NewMsgTx(wire.TxVersion)
+		offeredHash := utxo.RawTx.TxHash()
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&offeredHash, utxo.Vout), nil, nil))
+		tx.AddTxOut(wire.NewTxOut(30000, sellerScript))
+		offer, err := psbt.NewFromUnsignedTx(tx)
+		require.NoError(t, err)
+		offer.Inputs[0].WitnessUtxo = utxo.RawTx.TxOut[utxo.Vout]
+		offer.Inputs[0].SighashType = txscript.SigHashSingle | txscript.SigHashAnyOneCanPay
+
+		offer, err = SignTx(params, offer, seller.sign, seller.pubKey)
+		require.NoError(t, err)
+		require.NotNil(t, offer.Inputs[0].FinalScriptWitness)
+
+		// the buyer adds an input and change output of their own
+		paying := newTestUtxos(t, params, buyer.from, 50000)[0]
+		paymentOut := paying.RawTx.TxOut[paying.Vout]
+		payingHash := paying.RawTx.TxHash()
+		offer.UnsignedTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&payingHash, paying.Vout), nil, nil))
+		offer.Inputs = append(offer.Inputs, psbt.PInput{NonWitnessUtxo: paying.RawTx, WitnessUtxo: paymentOut})
+		offer.UnsignedTx.AddTxOut(wire.NewTxOut(29000, paymentOut.PkScript))
+		offer.Outputs = append(offer.Outputs, psbt.POutput{})
+
+		signed, err := SignTx(params, offer, buyer.sign, buyer.pubKey)
+		require.NoError(t, err)
+		executeInputs(t, signed, append(offered, paying))
+	})
+
+	t.Run("per input", func(t *testing.T) {
+		w := newWallet(t, "m/84'/1'/0'/0/0", types.P2WPKH)
+		utxos := newTestUtxos(t, params, w.from, 40000, 30000)
+		packet, err := NewTxBuilder(params).
+			FeeRate(2).
+			From(w.from).
+			To(to, 50000).
+			InputSigHashType(utxos[1].Txid, utxos[1].Vout, txscript.SigHashNone|txscript.SigHashAnyOneCanPay).
+			CoinSelector(utils.Knapsack{}).
+			SelectUtxo(utxos).
+			Build().
+			Packet()
+		require.NoError(t, err)
+		want := map[chainhash.Hash]txscript.SigHashType{
+			utxos[0].RawTx.TxHash(): txscript.SigHashAll,
+			utxos[1].RawTx.TxHash(): txscript.SigHashNone | txscript.SigHashAnyOneCanPay,
+		}
+		for i, in := range packet.UnsignedTx.TxIn {
+			require.Equal(t, want[in.PreviousOutPoint.Hash], packet.Inputs[i].SighashType)
+		}
+
+		signed, err := SignTx(params, packet, w.sign, w.pubKey)
+		require.NoError(t, err)
+		executeInputs(t, signed, utxos)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		w := newWallet(t, "m/84'/1'/0'/0/0", types.P2WPKH)
+		err := NewTxBuilder(params).
+			FeeRate(2).
+			From(w.from).
+			To(to, 10000).
+			SigHashType(0x04).
+			SelectUtxo(newTestUtxos(t, params, w.from, 40000)).
+			Build().
+			Err()
+		require.ErrorContains(t, err, "invalid sighash type")
+	})
+}
+
+func TestSignTx_FROST(t *testing.T) {
+	params := types.GetParams(types.BTC_Signet)
+	to := "n368zCWREFiRRX7icJRBb6n8nMsjJjNVK8"
+
+	shares, _, err := frost.Deal(nil, 2, 3)
+	require.NoError(t, err)
+	participants := make([]*frost.Participant, len(shares))
+	for i, s := range shares {
+		participants[i] = frost.NewParticipant(s)
+	}
+	coordinator, err := frost.NewCoordinator(participants...)
+	require.NoError(t, err)
+	coordinator.Signers = []uint16{1, 3}
+	groupKey := schnorr.SerializePubKey(coordinator.GroupKey)
+
+	build := func(t *testing.T, from string, spend *types.SpendInfo) (*psbt.Packet, []*types.Utxo) {
+		utxos := newTestUtxos(t, params, from, 50000)
+		packet, err := NewTxBuilder(params).
+			FeeRate(2).
+			From(from).
+			SpendInfo(from, spend).
+			To(to, 10000).
+			SelectUtxo(utxos).
+			Build().
+			Packet()
+		require.NoError(t, err)
+		return packet, utxos
+	}
+
+	t.Run("key path", func(t *testing.T) {
+		addr, err := coordinator.Address(nil, params)
+		require.NoError(t, err)
+		packet, utxos := build(t, addr.EncodeAddress(), &types.SpendInfo{TaprootInternalKey: groupKey})
+
+		signed, err := SignTx(params, packet, coordinator.Signer(nil), coordinator.OutputKey(nil))
+		require.NoError(t, err)
+		require.NotEmpty(t, signed.Inputs[0].FinalScriptWitness)
+		executeInputs(t, signed, utxos)
+	})
+
+	t.Run("script path", func(t *testing.T) {
+		leaf, err := txscript.NewScriptBuilder().AddData(groupKey).AddOp(txscript.OP_CHECKSIG).Script()
+		require.NoError(t, err)
+		tree, err := script.NewTapTree(script.TapLeaf{Script: leaf})
+		require.NoError(t, err)
+		nums, err := script.NUMSInternalKey(nil)
+		require.NoError(t, err)
+		out, err := tree.Output(nums, params)
+		require.NoError(t, err)
+		packet, utxos := build(t, out.Address.EncodeAddress(), out.SpendInfo())
+
+		signed, err := SignTx(params, packet, coordinator.TapscriptSigner(), groupKey)
+		require.NoError(t, err)
+		require.NotEmpty(t, signed.Inputs[0].FinalScriptWitness)
+		executeInputs(t, signed, utxos)
+	})
+}
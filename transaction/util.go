@@ -1,67 +1,84 @@
-package transaction
-
-import (
-	"bytes"
-
-	"github.com/btcsuite/btcd/btcutil"
-	"github.com/btcsuite/btcd/btcutil/psbt"
-	"github.com/btcsuite/btcd/txscript"
-	"github.com/gosuda/btctxbuilder/utils"
-)
-
-func DecodePSBT(psbtStr string) (*psbt.Packet, error) {
-	var err error
-	var psbtRaw []byte
-
-	isHex := utils.IsHex(psbtStr)
-	if isHex {
-		psbtRaw, err = utils.HexDecode(psbtStr)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		psbtRaw = []byte(psbtStr)
-	}
-	p, err := psbt.NewFromRawBytes(bytes.NewReader(psbtRaw), !isHex)
-	if err != nil {
-		return nil, err
-	}
-	return p, nil
-}
-
-// PsbtPrevOutputFetcher returns a txscript.PrevOutFetcher built from the UTXO
-// information in a PSBT packet.
-func PsbtPrevOutputFetcher(packet *psbt.Packet) *txscript.MultiPrevOutFetcher {
-	fetcher := txscript.NewMultiPrevOutFetcher(nil)
-	for idx, txIn := range packet.UnsignedTx.TxIn {
-		in := packet.Inputs[idx]
-
-		// Skip any input that has no UTXO.
-		if in.WitnessUtxo == nil && in.NonWitnessUtxo == nil {
-			continue
-		}
-
-		if in.NonWitnessUtxo != nil {
-			prevIndex := txIn.PreviousOutPoint.Index
-			fetcher.AddPrevOut(
-				txIn.PreviousOutPoint,
-				in.NonWitnessUtxo.TxOut[prevIndex],
-			)
-			continue
-		}
-
-		// Fall back to witness UTXO only for older wallets.
-		if in.WitnessUtxo != nil {
-			fetcher.AddPrevOut(
-				txIn.PreviousOutPoint, in.WitnessUtxo,
-			)
-		}
-	}
-	return fetcher
-}
-
-func ValidRedeemSignature(redeemScript []byte, pkScript []byte) bool {
-	redeemScriptHash := btcutil.Hash160(redeemScript)
-	actualScriptHash := pkScript[2 : len(pkScript)-1]
-	return bytes.Equal(redeemScriptHash, actualScriptHash)
-}
+package transaction
+
+import (
+	"bytes"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+
+	"github.com/gosuda/btctxbuilder/script"
+	"github.com/gosuda/btctxbuilder/utils"
+)
+
+func DecodePSBT(psbtStr string) (*psbt.Packet, error) {
+	var err error
+	var psbtRaw []byte
+
+	isHex := utils.IsHex(psbtStr)
+	if isHex {
+		psbtRaw, err = utils.HexDecode(psbtStr)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		psbtRaw = []byte(psbtStr)
+	}
+	p, err := psbt.NewFromRawBytes(bytes.NewReader(psbtRaw), !isHex)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// PsbtPrevOutputFetcher returns a txscript.PrevOutFetcher built from the UTXO
+// information in a PSBT packet.
+func PsbtPrevOutputFetcher(packet *psbt.Packet) *txscript.MultiPrevOutFetcher {
+	fetcher := txscript.NewMultiPrevOutFetcher(nil)
+	for idx, txIn := range packet.UnsignedTx.TxIn {
+		in := packet.Inputs[idx]
+
+		// Skip any input that has no UTXO.
+		if in.WitnessUtxo == nil && in.NonWitnessUtxo == nil {
+			continue
+		}
+
+		if in.NonWitnessUtxo != nil {
+			prevIndex := txIn.PreviousOutPoint.Index
+			fetcher.AddPrevOut(
+				txIn.PreviousOutPoint,
+				in.NonWitnessUtxo.TxOut[prevIndex],
+			)
+			continue
+		}
+
+		// Fall back to witness UTXO only for older wallets.
+		if in.WitnessUtxo != nil {
+			fetcher.AddPrevOut(
+				txIn.PreviousOutPoint, in.WitnessUtxo,
+			)
+		}
+	}
+	return fetcher
+}
+
+func ValidRedeemSignature(redeemScript []byte, pkScript []byte) bool {
+	redeemScriptHash := btcutil.Hash160(redeemScript)
+	actualScriptHash := pkScript[2 : len(pkScript)-1]
+	return bytes.Equal(redeemScriptHash, actualScriptHash)
+}
+
+// addressFromPkScript returns the address string TxInputs.AddInput expects for
+// an output paying to pkScript. P2PK outputs are identified by the hex public
+// key, like types.PubKeyToAddr does.
+func addressFromPkScript(pkScript []byte, params *chaincfg.Params) (string, error) {
+	addr, err := script.DecodeTransferScript(pkScript, params)
+	if err != nil {
+		return "", err
+	}
+	if pk, ok := addr.(*btcutil.AddressPubKey); ok {
+		return utils.HexEncode(pk.ScriptAddress()), nil
+	}
+	return addr.EncodeAddress(), nil
+}
@@ -0,0 +1,284 @@
+package types
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// HardenedKeyStart is the index of the first hardened BIP32 child.
+const HardenedKeyStart = hdkeychain.HardenedKeyStart
+
+// KeyOrigin tells which master key and BIP32 path a public key derives from,
+// the information PSBT signers use to recognise their keys.
+type KeyOrigin struct {
+	PubKey []byte // 33-byte compressed public key
+	// Fingerprint is the first 4 bytes of HASH160(master pubkey), read
+	// little endian as psbt.Bip32Derivation stores it.
+	Fingerprint uint32
+	Path        []uint32
+
+	// LeafHashes lists the tapscript leaves the key appears in. It is empty
+	// for a taproot internal key and ignored outside taproot.
+	LeafHashes [][]byte
+}
+
+// Bip32Derivation returns the origin as a PSBT BIP32 derivation entry.
+func (o *KeyOrigin) Bip32Derivation() *psbt.Bip32Derivation {
+	return &psbt.Bip32Derivation{
+		PubKey:               o.PubKey,
+		MasterKeyFingerprint: o.Fingerprint,
+		Bip32Path:            o.Path,
+	}
+}
+
+// TaprootBip32Derivation returns the origin as a PSBT taproot derivation
+// entry for the x-only key.
+func (o *KeyOrigin) TaprootBip32Derivation() *psbt.TaprootBip32Derivation {
+	return &psbt.TaprootBip32Derivation{
+		XOnlyPubKey:          o.XOnlyPubKey(),
+		LeafHashes:           o.LeafHashes,
+		MasterKeyFingerprint: o.Fingerprint,
+		Bip32Path:            o.Path,
+	}
+}
+
+// XOnlyPubKey returns the 32-byte x-only form of PubKey.
+func (o *KeyOrigin) XOnlyPubKey() []byte {
+	if len(o.PubKey) == 33 {
+		return o.PubKey[1:]
+	}
+	return o.PubKey
+}
+
+// String formats the origin the way output descriptors do, e.g.
+// "[d34db33f/84'/0'/0']".
+func (o *KeyOrigin) String() string {
+	var fp [4]byte
+	binary.LittleEndian.PutUint32(fp[:], o.Fingerprint)
+	path := FormatDerivationPath(o.Path)
+	return "[" + hex.EncodeToString(fp[:]) + strings.TrimPrefix(path, "m") + "]"
+}
+
+// HDKey is a BIP32 extended key that remembers its origin.
+type HDKey struct {
+	key         *hdkeychain.ExtendedKey
+	fingerprint uint32
+	path        []uint32
+}
+
+// NewHDKeyFromSeed returns the master key for seed on the given network.
+func NewHDKeyFromSeed(seed []byte, params *chaincfg.Params) (*HDKey, error) {
+	key, err := hdkeychain.NewMaster(seed, params)
+	if err != nil {
+		return nil, err
+	}
+	k := &HDKey{key: key}
+	k.fingerprint, err = k.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// ParseHDKey parses a base58 extended key (xprv, xpub, tprv, tpub, ...).
+// A master key is its own origin; for any deeper key the origin is unknown
+// until it is set with WithOrigin.
+func ParseHDKey(s string) (*HDKey, error) {
+	key, err := hdkeychain.NewKeyFromString(s)
+	if err != nil {
+		return nil, err
+	}
+	k := &HDKey{key: key}
+	if key.Depth() == 0 {
+		k.fingerprint, err = k.Fingerprint()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// WithOrigin returns a copy of k that derives from the master key with the
+// given fingerprint along path, e.g. an account xpub exported by a hardware
+// wallet.
+func (k *HDKey) WithOrigin(fingerprint uint32, path []uint32) (*HDKey, error) {
+	if len(path) != int(k.key.Depth()) {
+		return nil, fmt.Errorf("path %s does not match key depth %d", FormatDerivationPath(path), k.key.Depth())
+	}
+	return &HDKey{
+		key:         k.key,
+		fingerprint: fingerprint,
+		path:        append([]uint32(nil), path...),
+	}, nil
+}
+
+// Derive returns the descendant of k at the given child indexes. Indexes from
+// HardenedKeyStart on are hardened and need a private key.
+func (k *HDKey) Derive(path ...uint32) (*HDKey, error) {
+	key := k.key
+	for _, i := range path {
+		child, err := key.Derive(i)
+		if err != nil {
+			return nil, fmt.Errorf("derive %d: %w", i, err)
+		}
+		key = child
+	}
+	return &HDKey{
+		key:         key,
+		fingerprint: k.fingerprint,
+		path:        append(append([]uint32(nil), k.path...), path...),
+	}, nil
+}
+
+// DerivePath is Derive for a textual path relative to k, e.g. "0/5" or
+// "m/84'/0'/0'/1/2" when k is the master key.
+func (k *HDKey) DerivePath(path string) (*HDKey, error) {
+	indexes, err := ParseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return k.Derive(indexes...)
+}
+
+// Neuter returns the public version of k.
+func (k *HDKey) Neuter() (*HDKey, error) {
+	key, err := k.key.Neuter()
+	if err != nil {
+		return nil, err
+	}
+	return &HDKey{key: key, fingerprint: k.fingerprint, path: k.path}, nil
+}
+
+func (k *HDKey) IsPrivate() bool { return k.key.IsPrivate() }
+
+// IsForNet reports whether the key's version bytes belong to params.
+func (k *HDKey) IsForNet(params *chaincfg.Params) bool { return k.key.IsForNet(params) }
+
+// String returns the base58 serialisation (xprv/xpub...).
+func (k *HDKey) String() string { return k.key.String() }
+
+// PubKey returns the 33-byte compressed public key.
+func (k *HDKey) PubKey() ([]byte, error) {
+	pub, err := k.key.ECPubKey()
+	if err != nil {
+		return nil, err
+	}
+	return pub.SerializeCompressed(), nil
+}
+
+// PrivateKey returns the 32-byte private key.
+func (k *HDKey) PrivateKey() ([]byte, error) {
+	priv, err := k.key.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+	return priv.Serialize(), nil
+}
+
+// Fingerprint returns the fingerprint of k itself, in psbt byte order.
+func (k *HDKey) Fingerprint() (uint32, error) {
+	pub, err := k.PubKey()
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(btcutil.Hash160(pub)[:4]), nil
+}
+
+// MasterFingerprint returns the fingerprint of the master key k derives
+// from, in psbt byte order. It is 0 when the origin is unknown.
+func (k *HDKey) MasterFingerprint() uint32 { return k.fingerprint }
+
+// Path returns the derivation path from the master key to k.
+func (k *HDKey) Path() []uint32 { return append([]uint32(nil), k.path...) }
+
+// Origin returns the key origin of k for PSBT derivation fields.
+func (k *HDKey) Origin() (*KeyOrigin, error) {
+	pub, err := k.PubKey()
+	if err != nil {
+		return nil, err
+	}
+	return &KeyOrigin{PubKey: pub, Fingerprint: k.fingerprint, Path: k.Path()}, nil
+}
+
+// ECDSASigner returns a signer for the private key of k.
+func (k *HDKey) ECDSASigner() (*ECDSASigner, error) {
+	priv, err := k.key.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+	return &ECDSASigner{privkey: priv}, nil
+}
+
+// SchnorrSigner returns a key path signer for the BIP86 taproot output of
+// k, i.e. k tweaked without a script tree.
+func (k *HDKey) SchnorrSigner() (*SchnorrSigner, error) {
+	priv, err := k.key.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+	return &SchnorrSigner{privkey: txscript.TweakTaprootPrivKey(*priv, nil)}, nil
+}
+
+// TapscriptSigner returns a signer for the untweaked key of k, as used in
+// taproot leaf scripts.
+func (k *HDKey) TapscriptSigner() (*SchnorrSigner, error) {
+	priv, err := k.key.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+	return &SchnorrSigner{privkey: priv}, nil
+}
+
+// ParseDerivationPath parses a BIP32 path such as "m/84'/0'/0'/0/1". Both '
+// and h mark hardened indexes and the leading "m" is optional.
+func ParseDerivationPath(path string) ([]uint32, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "m"), "/")
+	if path == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(path, "/")
+	indexes := make([]uint32, 0, len(parts))
+	for _, p := range parts {
+		hardened := strings.HasSuffix(p, "'") || strings.HasSuffix(p, "h") || strings.HasSuffix(p, "H")
+		if hardened {
+			p = p[:len(p)-1]
+		}
+		i, err := strconv.ParseUint(p, 10, 32)
+		if err != nil || i >= HardenedKeyStart {
+			return nil, fmt.Errorf("invalid path element %q", p)
+		}
+		if hardened {
+			i += HardenedKeyStart
+		}
+		indexes = append(indexes, uint32(i))
+	}
+	return indexes, nil
+}
+
+// FormatDerivationPath is the inverse of ParseDerivationPath, using ' for
+// hardened indexes.
+func FormatDerivationPath(path []uint32) string {
+	var sb strings.Builder
+	sb.WriteString("m")
+	for _, i := range path {
+		sb.WriteString("/")
+		if i >= HardenedKeyStart {
+			sb.WriteString(strconv.FormatUint(uint64(i-HardenedKeyStart), 10))
+			sb.WriteString("'")
+		} else {
+			sb.WriteString(strconv.FormatUint(uint64(i), 10))
+		}
+	}
+	return sb.String()
+}
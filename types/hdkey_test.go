@@ -0,0 +1,122 @@
+package types
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// BIP32 test vector 1
+const (
+	bip32Seed1    = "000102030405060708090a0b0c0d0e0f"
+	bip32Master1  = "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi"
+	bip32Child1   = "xprv9z4pot5VBttmtdRTWfWQmoH1taj2axGVzFqSb8C9xaxKymcFzXBDptWmT7FwuEzG3ryjH4ktypQSAewRiNMjANTtpgP4mLTj34bhnZX7UiM"
+	bip32Child1XP = "xpub6D4BDPcP2GT577Vvch3R8wDkScZWzQzMMUm3PWbmWvVJrZwQY4VUNgqFJPMM3No2dFDFGTsxxpG5uJh7n7epu4trkrX7x7DogT5Uv6fcLW5"
+	bip32Leaf1XP  = "xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy"
+)
+
+func TestHDKey_BIP32Vector1(t *testing.T) {
+	seed, _ := hex.DecodeString(bip32Seed1)
+	master, err := NewHDKeyFromSeed(seed, GetParams(BTC))
+	require.NoError(t, err)
+	require.Equal(t, bip32Master1, master.String())
+
+	parsed, err := ParseHDKey(bip32Master1)
+	require.NoError(t, err)
+	require.Equal(t, master.MasterFingerprint(), parsed.MasterFingerprint())
+
+	child, err := master.DerivePath("m/0'/1/2h")
+	require.NoError(t, err)
+	require.Equal(t, bip32Child1, child.String())
+	require.Equal(t, []uint32{HardenedKeyStart, 1, HardenedKeyStart + 2}, child.Path())
+
+	xpub, err := child.Neuter()
+	require.NoError(t, err)
+	require.False(t, xpub.IsPrivate())
+	require.Equal(t, bip32Child1XP, xpub.String())
+
+	// public derivation matches m/0'/1/2'/2/1000000000
+	leaf, err := xpub.Derive(2, 1000000000)
+	require.NoError(t, err)
+	require.Equal(t, bip32Leaf1XP, leaf.String())
+
+	origin, err := leaf.Origin()
+	require.NoError(t, err)
+	require.Equal(t, "[3442193e/0'/1/2'/2/1000000000]", origin.String())
+	require.Len(t, origin.PubKey, 33)
+	require.Equal(t, origin.PubKey[1:], origin.TaprootBip32Derivation().XOnlyPubKey)
+	require.Equal(t, master.MasterFingerprint(), origin.Bip32Derivation().MasterKeyFingerprint)
+
+	_, err = xpub.Derive(HardenedKeyStart)
+	require.Error(t, err)
+}
+
+func TestHDKey_WithOrigin(t *testing.T) {
+	seed, _ := hex.DecodeString(bip32Seed1)
+	master, err := NewHDKeyFromSeed(seed, GetParams(BTC))
+	require.NoError(t, err)
+
+	account, err := ParseHDKey(bip32Child1XP)
+	require.NoError(t, err)
+	require.Zero(t, account.MasterFingerprint())
+
+	_, err = account.WithOrigin(master.MasterFingerprint(), []uint32{HardenedKeyStart})
+	require.Error(t, err)
+
+	path, err := ParseDerivationPath("m/0'/1/2'")
+	require.NoError(t, err)
+	account, err = account.WithOrigin(master.MasterFingerprint(), path)
+	require.NoError(t, err)
+
+	fromAccount, err := account.Derive(0, 7)
+	require.NoError(t, err)
+	fromMaster, err := master.DerivePath("0'/1/2'/0/7")
+	require.NoError(t, err)
+
+	want, err := fromMaster.Origin()
+	require.NoError(t, err)
+	got, err := fromAccount.Origin()
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+func TestHDKey_Signers(t *testing.T) {
+	seed, _ := hex.DecodeString(bip32Seed1)
+	master, err := NewHDKeyFromSeed(seed, GetParams(BTC_Signet))
+	require.NoError(t, err)
+	key, err := master.DerivePath("m/86'/1'/0'/0/0")
+	require.NoError(t, err)
+
+	ecdsaSigner, err := key.ECDSASigner()
+	require.NoError(t, err)
+	pub, err := key.PubKey()
+	require.NoError(t, err)
+	require.Equal(t, pub, ecdsaSigner.PubKey())
+
+	schnorrSigner, err := key.SchnorrSigner()
+	require.NoError(t, err)
+	require.Len(t, schnorrSigner.PubKey(), 32)
+	require.NotEqual(t, pub[1:], schnorrSigner.PubKey())
+
+	neutered, err := key.Neuter()
+	require.NoError(t, err)
+	_, err = neutered.ECDSASigner()
+	require.Error(t, err)
+}
+
+func TestParseDerivationPath(t *testing.T) {
+	path, err := ParseDerivationPath("m/84'/0h/0H/1/2")
+	require.NoError(t, err)
+	require.Equal(t, []uint32{HardenedKeyStart + 84, HardenedKeyStart, HardenedKeyStart, 1, 2}, path)
+	require.Equal(t, "m/84'/0'/0'/1/2", FormatDerivationPath(path))
+
+	path, err = ParseDerivationPath("m")
+	require.NoError(t, err)
+	require.Empty(t, path)
+
+	for _, bad := range []string{"m/x", "m/1//2", "m/2147483648", "m/-1"} {
+		_, err := ParseDerivationPath(bad)
+		require.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,285 @@
+package types
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr/musig2"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// MuSig2 (BIP327) turns n participant keys into one taproot key, so an
+// n-of-n key path spend looks like any single-sig one on chain. Keys are
+// 33-byte compressed and sorted before aggregation, so every participant
+// derives the same key whatever order they list them in. The output key
+// commits to merkleRoot, or to no script tree (BIP86) when it is nil.
+
+// MuSig2AggregateKey returns the aggregate of pubKeys before the taproot
+// tweak, the taproot internal key.
+func MuSig2AggregateKey(pubKeys [][]byte) (*btcec.PublicKey, error) {
+	keys, err := parseMuSig2Keys(pubKeys)
+	if err != nil {
+		return nil, err
+	}
+	agg, _, _, err := musig2.AggregateKeys(keys, true)
+	if err != nil {
+		return nil, err
+	}
+	return agg.FinalKey, nil
+}
+
+// MuSig2OutputKey returns the x-only taproot output key of pubKeys.
+func MuSig2OutputKey(pubKeys [][]byte, merkleRoot []byte) ([]byte, error) {
+	keys, err := parseMuSig2Keys(pubKeys)
+	if err != nil {
+		return nil, err
+	}
+	agg, _, _, err := musig2.AggregateKeys(keys, true, muSig2KeyTweak(merkleRoot))
+	if err != nil {
+		return nil, err
+	}
+	return schnorr.SerializePubKey(agg.FinalKey), nil
+}
+
+// MuSig2AggregateNonces sums the 66-byte public nonces of all participants.
+func MuSig2AggregateNonces(pubNonces [][]byte) ([]byte, error) {
+	nonces := make([][musig2.PubNonceSize]byte, len(pubNonces))
+	for i, n := range pubNonces {
+		if len(n) != musig2.PubNonceSize {
+			return nil, fmt.Errorf("public nonce %d has %d bytes, want %d", i, len(n), musig2.PubNonceSize)
+		}
+		copy(nonces[i][:], n)
+	}
+	agg, err := musig2.AggregateNonces(nonces)
+	if err != nil {
+		return nil, err
+	}
+	return agg[:], nil
+}
+
+// VerifyMuSig2PartialSig checks the partial signature of signer over msg.
+func VerifyMuSig2PartialSig(partialSig, pubNonce, aggNonce []byte, pubKeys [][]byte, signer []byte, merkleRoot []byte, msg []byte) error {
+	keys, err := parseMuSig2Keys(pubKeys)
+	if err != nil {
+		return err
+	}
+	signerKey, err := btcec.ParsePubKey(signer)
+	if err != nil {
+		return err
+	}
+	var sig musig2.PartialSignature
+	if err := sig.Decode(bytes.NewReader(partialSig)); err != nil {
+		return err
+	}
+	if len(pubNonce) != musig2.PubNonceSize || len(aggNonce) != musig2.PubNonceSize || len(msg) != 32 {
+		return errors.New("invalid nonce or message length")
+	}
+
+	if !sig.Verify([musig2.PubNonceSize]byte(pubNonce), [musig2.PubNonceSize]byte(aggNonce), keys, signerKey,
+		[32]byte(msg), muSig2SignTweak(merkleRoot), musig2.WithSortedKeys()) {
+		return fmt.Errorf("invalid partial signature from %x", signer)
+	}
+	return nil
+}
+
+// AggregateMuSig2PartialSigs combines the partial signatures of all
+// participants into a BIP340 signature for the output key.
+func AggregateMuSig2PartialSigs(partialSigs [][]byte, aggNonce []byte, pubKeys [][]byte, merkleRoot []byte, msg []byte) ([]byte, error) {
+	keys, err := parseMuSig2Keys(pubKeys)
+	if err != nil {
+		return nil, err
+	}
+	if len(aggNonce) != musig2.PubNonceSize || len(msg) != 32 {
+		return nil, errors.New("invalid nonce or message length")
+	}
+	agg, _, _, err := musig2.AggregateKeys(keys, true, muSig2KeyTweak(merkleRoot))
+	if err != nil {
+		return nil, err
+	}
+
+	sigs := make([]*musig2.PartialSignature, len(partialSigs))
+	for i, raw := range partialSigs {
+		sigs[i] = &musig2.PartialSignature{}
+		if err := sigs[i].Decode(bytes.NewReader(raw)); err != nil {
+			return nil, fmt.Errorf("partial signature %d: %w", i, err)
+		}
+	}
+
+	r, err := muSig2SigningNonce([musig2.PubNonceSize]byte(aggNonce), agg.FinalKey, [32]byte(msg))
+	if err != nil {
+		return nil, err
+	}
+	var combine musig2.CombineOption
+	if merkleRoot == nil {
+		combine = musig2.WithBip86TweakedCombine([32]byte(msg), keys, true)
+	} else {
+		combine = musig2.WithTaprootTweakedCombine([32]byte(msg), keys, merkleRoot, true)
+	}
+	sig := musig2.CombineSigs(r, sigs, combine)
+	if !sig.Verify(msg, agg.FinalKey) {
+		return nil, errors.New("aggregated MuSig2 signature does not verify")
+	}
+	return sig.Serialize(), nil
+}
+
+// MuSig2Signer is one participant of a MuSig2 signing session. It keeps
+// the secret nonce of every open session until the matching Sign call, and
+// never signs twice with the same nonce.
+type MuSig2Signer struct {
+	privkey *btcec.PrivateKey
+
+	mu        sync.Mutex
+	secNonces map[string][musig2.SecNonceSize]byte
+}
+
+// NewMuSig2Signer creates a MuSig2 participant from an untweaked private
+// key. If privkeyHex is empty, a new random private key will be generated.
+func NewMuSig2Signer(privkeyHex string) (*MuSig2Signer, error) {
+	var priv *btcec.PrivateKey
+	if privkeyHex == "" {
+		p, err := btcec.NewPrivateKey()
+		if err != nil {
+			return nil, err
+		}
+		priv = p
+	} else {
+		raw, err := hex.DecodeString(privkeyHex)
+		if err != nil {
+			return nil, err
+		}
+		priv, _ = btcec.PrivKeyFromBytes(raw)
+	}
+	return &MuSig2Signer{privkey: priv, secNonces: map[string][musig2.SecNonceSize]byte{}}, nil
+}
+
+// PubKey returns the 33-byte compressed participant key.
+func (s *MuSig2Signer) PubKey() []byte {
+	return s.privkey.PubKey().SerializeCompressed()
+}
+
+// GenNonce starts the session identified by sessionID and returns the
+// 66-byte public nonce to share with the other participants. A session
+// started already can't be started again until Sign ends it.
+func (s *MuSig2Signer) GenNonce(sessionID []byte, pubKeys [][]byte, msg []byte) ([]byte, error) {
+	aggKey, err := MuSig2AggregateKey(pubKeys)
+	if err != nil {
+		return nil, err
+	}
+	opts := []musig2.NonceGenOption{
+		musig2.WithPublicKey(s.privkey.PubKey()),
+		musig2.WithNonceSecretKeyAux(s.privkey),
+		musig2.WithNonceCombinedKeyAux(aggKey),
+		musig2.WithNonceAuxInput(sessionID),
+	}
+	if len(msg) == 32 {
+		opts = append(opts, musig2.WithNonceMessageAux([32]byte(msg)))
+	}
+	nonces, err := musig2.GenNonces(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// a public nonce shared already must never meet another secret one
+	if _, ok := s.secNonces[string(sessionID)]; ok {
+		return nil, fmt.Errorf("MuSig2 session %x already has a nonce", sessionID)
+	}
+	s.secNonces[string(sessionID)] = nonces.SecNonce
+	return nonces.PubNonce[:], nil
+}
+
+// Sign returns the 32-byte partial signature of msg for the session
+// started with GenNonce, which ends the session.
+func (s *MuSig2Signer) Sign(sessionID []byte, aggNonce []byte, pubKeys [][]byte, merkleRoot []byte, msg []byte) ([]byte, error) {
+	keys, err := parseMuSig2Keys(pubKeys)
+	if err != nil {
+		return nil, err
+	}
+	if len(aggNonce) != musig2.PubNonceSize || len(msg) != 32 {
+		return nil, errors.New("invalid nonce or message length")
+	}
+
+	s.mu.Lock()
+	secNonce, ok := s.secNonces[string(sessionID)]
+	delete(s.secNonces, string(sessionID))
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no nonce for MuSig2 session %x", sessionID)
+	}
+
+	sig, err := musig2.Sign(secNonce, s.privkey, [musig2.PubNonceSize]byte(aggNonce), keys, [32]byte(msg),
+		muSig2SignTweak(merkleRoot), musig2.WithSortedKeys())
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := sig.Encode(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func parseMuSig2Keys(pubKeys [][]byte) ([]*btcec.PublicKey, error) {
+	if len(pubKeys) == 0 {
+		return nil, errors.New("no MuSig2 participants")
+	}
+	keys := make([]*btcec.PublicKey, len(pubKeys))
+	for i, raw := range pubKeys {
+		if len(raw) != btcec.PubKeyBytesLenCompressed {
+			return nil, fmt.Errorf("MuSig2 participant %d: need a 33-byte compressed key", i)
+		}
+		key, err := btcec.ParsePubKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("MuSig2 participant %d: %w", i, err)
+		}
+		keys[i] = key
+	}
+	return keys, nil
+}
+
+func muSig2KeyTweak(merkleRoot []byte) musig2.KeyAggOption {
+	if merkleRoot == nil {
+		return musig2.WithBIP86KeyTweak()
+	}
+	return musig2.WithTaprootKeyTweak(merkleRoot)
+}
+
+func muSig2SignTweak(merkleRoot []byte) musig2.SignOption {
+	if merkleRoot == nil {
+		return musig2.WithBip86SignTweak()
+	}
+	return musig2.WithTaprootSignTweak(merkleRoot)
+}
+
+// muSig2SigningNonce computes the final nonce R = R1 + b*R2 of BIP327.
+func muSig2SigningNonce(aggNonce [musig2.PubNonceSize]byte, outputKey *btcec.PublicKey, msg [32]byte) (*btcec.PublicKey, error) {
+	var buf bytes.Buffer
+	buf.Write(aggNonce[:])
+	buf.Write(schnorr.SerializePubKey(outputKey))
+	buf.Write(msg[:])
+	var b btcec.ModNScalar
+	b.SetByteSlice(chainhash.TaggedHash(musig2.NonceBlindTag, buf.Bytes())[:])
+
+	r1, err := btcec.ParseJacobian(aggNonce[:btcec.PubKeyBytesLenCompressed])
+	if err != nil {
+		return nil, err
+	}
+	r2, err := btcec.ParseJacobian(aggNonce[btcec.PubKeyBytesLenCompressed:])
+	if err != nil {
+		return nil, err
+	}
+	var r btcec.JacobianPoint
+	btcec.ScalarMultNonConst(&b, &r2, &r2)
+	btcec.AddNonConst(&r1, &r2, &r)
+	if (r.X.IsZero() && r.Y.IsZero()) || r.Z.IsZero() {
+		return btcec.Generator(), nil
+	}
+	r.ToAffine()
+	return btcec.NewPublicKey(&r.X, &r.Y), nil
+}
@@ -0,0 +1,98 @@
+package types
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/stretchr/testify/require"
+)
+
+func newMuSig2Signers(t *testing.T, n int) ([]*MuSig2Signer, [][]byte) {
+	t.Helper()
+	signers := make([]*MuSig2Signer, n)
+	keys := make([][]byte, n)
+	for i := range signers {
+		seed := sha256.Sum256([]byte(fmt.Sprintf("musig2 %d", i)))
+		s, err := NewMuSig2Signer(fmt.Sprintf("%x", seed))
+		require.NoError(t, err)
+		signers[i], keys[i] = s, s.PubKey()
+	}
+	return signers, keys
+}
+
+func TestMuSig2_KeyAggregation(t *testing.T) {
+	_, keys := newMuSig2Signers(t, 3)
+	agg, err := MuSig2AggregateKey(keys)
+	require.NoError(t, err)
+
+	// the key order doesn't matter
+	reversed := [][]byte{keys[2], keys[1], keys[0]}
+	agg2, err := MuSig2AggregateKey(reversed)
+	require.NoError(t, err)
+	require.True(t, agg.IsEqual(agg2))
+
+	out, err := MuSig2OutputKey(keys, nil)
+	require.NoError(t, err)
+	require.Equal(t, schnorr.SerializePubKey(txscript.ComputeTaprootKeyNoScript(agg)), out)
+
+	root := sha256.Sum256([]byte("root"))
+	out, err = MuSig2OutputKey(keys, root[:])
+	require.NoError(t, err)
+	require.Equal(t, schnorr.SerializePubKey(txscript.ComputeTaprootOutputKey(agg, root[:])), out)
+
+	_, err = MuSig2AggregateKey(nil)
+	require.Error(t, err)
+	_, err = MuSig2AggregateKey([][]byte{keys[0][1:]})
+	require.Error(t, err)
+}
+
+func TestMuSig2_Sign(t *testing.T) {
+	signers, keys := newMuSig2Signers(t, 3)
+	msg := sha256.Sum256([]byte("spend"))
+	root := sha256.Sum256([]byte("root"))
+
+	for _, merkleRoot := range [][]byte{nil, root[:]} {
+		session := []byte(fmt.Sprintf("session %x", merkleRoot))
+		nonces := make([][]byte, len(signers))
+		for i, s := range signers {
+			var err error
+			nonces[i], err = s.GenNonce(session, keys, msg[:])
+			require.NoError(t, err)
+		}
+		// a started session keeps its nonce, which the others have seen
+		_, err := signers[0].GenNonce(session, keys, msg[:])
+		require.Error(t, err)
+		aggNonce, err := MuSig2AggregateNonces(nonces)
+		require.NoError(t, err)
+
+		sigs := make([][]byte, len(signers))
+		for i, s := range signers {
+			sigs[i], err = s.Sign(session, aggNonce, keys, merkleRoot, msg[:])
+			require.NoError(t, err)
+			require.NoError(t, VerifyMuSig2PartialSig(sigs[i], nonces[i], aggNonce, keys, keys[i], merkleRoot, msg[:]))
+		}
+		// a partial signature doesn't verify for another participant
+		require.Error(t, VerifyMuSig2PartialSig(sigs[0], nonces[1], aggNonce, keys, keys[1], merkleRoot, msg[:]))
+
+		// the nonce is gone after signing
+		_, err = signers[0].Sign(session, aggNonce, keys, merkleRoot, msg[:])
+		require.Error(t, err)
+
+		sig, err := AggregateMuSig2PartialSigs(sigs, aggNonce, keys, merkleRoot, msg[:])
+		require.NoError(t, err)
+		outputKey, err := MuSig2OutputKey(keys, merkleRoot)
+		require.NoError(t, err)
+		pub, err := schnorr.ParsePubKey(outputKey)
+		require.NoError(t, err)
+		parsed, err := schnorr.ParseSignature(sig)
+		require.NoError(t, err)
+		require.True(t, parsed.Verify(msg[:], pub))
+
+		// a missing participant makes the aggregate invalid
+		_, err = AggregateMuSig2PartialSigs(sigs[:2], aggNonce, keys, merkleRoot, msg[:])
+		require.Error(t, err)
+	}
+}
@@ -1,7 +1,7 @@
-package types
-
-import "github.com/btcsuite/btcd/txscript"
-
-func ParseScriptType(script []byte) txscript.ScriptClass {
-	return txscript.GetScriptClass(script)
-}
+package types
+
+import "github.com/btcsuite/btcd/txscript"
+
+func ParseScriptType(script []byte) txscript.ScriptClass {
+	return txscript.GetScriptClass(script)
+}
@@ -0,0 +1,64 @@
+package types
+
+import (
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+)
+
+// SpendInfo is what a signer needs besides the UTXO to spend an output:
+// the scripts hidden behind script hashes, the taproot tree and the origins
+// of the keys involved. Every field is optional.
+type SpendInfo struct {
+	RedeemScript  []byte // P2SH
+	WitnessScript []byte // P2WSH, nested or not
+	Origins       []*KeyOrigin
+
+	TaprootInternalKey []byte // x-only
+	TaprootMerkleRoot  []byte
+	TaprootLeafScripts []*psbt.TaprootTapLeafScript
+
+	// MuSig2PubKeys are the participants of a MuSig2 key path, whose
+	// aggregate is the internal key.
+	MuSig2PubKeys [][]byte
+}
+
+// InternalKey returns TaprootInternalKey, or else the aggregate of
+// MuSig2PubKeys. Without a script tree it falls back to the key of a single
+// origin, the internal key of a BIP86 output.
+func (s *SpendInfo) InternalKey() []byte {
+	if len(s.TaprootInternalKey) > 0 {
+		return s.TaprootInternalKey
+	}
+	if len(s.MuSig2PubKeys) > 0 {
+		if agg, err := MuSig2AggregateKey(s.MuSig2PubKeys); err == nil {
+			return schnorr.SerializePubKey(agg)
+		}
+		return nil
+	}
+	if len(s.Origins) == 1 && len(s.TaprootLeafScripts) == 0 && len(s.TaprootMerkleRoot) == 0 {
+		return s.Origins[0].XOnlyPubKey()
+	}
+	return nil
+}
+
+// Bip32Derivations returns the origins of all full (33-byte) public keys as
+// PSBT derivation entries.
+func (s *SpendInfo) Bip32Derivations() []*psbt.Bip32Derivation {
+	var ds []*psbt.Bip32Derivation
+	for _, o := range s.Origins {
+		if len(o.PubKey) == 33 {
+			ds = append(ds, o.Bip32Derivation())
+		}
+	}
+	return ds
+}
+
+// TaprootBip32Derivations returns all origins as PSBT taproot derivation
+// entries.
+func (s *SpendInfo) TaprootBip32Derivations() []*psbt.TaprootBip32Derivation {
+	var ds []*psbt.TaprootBip32Derivation
+	for _, o := range s.Origins {
+		ds = append(ds, o.TaprootBip32Derivation())
+	}
+	return ds
+}
@@ -0,0 +1,341 @@
+package utils
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sort"
+)
+
+// CoinSelector picks a subset of candidate values whose sum covers target.
+//
+// values are effective values: the amount a candidate contributes once the
+// fee for spending it has been paid. costOfChange is what it costs to create
+// a change output now and spend it later, so any selection exceeding target
+// by less than costOfChange is better off without change.
+// The returned indices refer to values.
+type CoinSelector interface {
+	Select(values []int64, target int64, costOfChange int64) (selected []int, ok bool)
+}
+
+// SelectWith runs selector over items, valuing each one with valueFn.
+func SelectWith[T any](selector CoinSelector, items []T, target int64, costOfChange int64, valueFn func(T) int64) (selected []T, unselected []T, sufficient bool) {
+	values := make([]int64, len(items))
+	for i, it := range items {
+		values[i] = valueFn(it)
+	}
+
+	indices, ok := selector.Select(values, target, costOfChange)
+	if !ok {
+		return nil, items, false
+	}
+
+	chosen := make([]bool, len(items))
+	for _, idx := range indices {
+		chosen[idx] = true
+	}
+	for i, it := range items {
+		if chosen[i] {
+			selected = append(selected, it)
+		} else {
+			unselected = append(unselected, it)
+		}
+	}
+	return selected, unselected, true
+}
+
+// -----------------------------------------------------------------------------
+// LargestFirst
+// -----------------------------------------------------------------------------
+
+// LargestFirst is the historical strategy of SelectUtxo: take the largest
+// candidate and top it up greedily with smaller ones.
+type LargestFirst struct{}
+
+func (LargestFirst) Select(values []int64, target int64, _ int64) ([]int, bool) {
+	indices := positiveIndices(values)
+	selected, _, ok := SelectUtxo(indices, int(target), func(i int) int {
+		return int(values[i])
+	})
+	return selected, ok
+}
+
+// -----------------------------------------------------------------------------
+// BranchAndBound
+// -----------------------------------------------------------------------------
+
+const defaultBnBTries = 100000
+
+// BranchAndBound searches depth first for a changeless selection, i.e. one
+// whose total lies within [target, target+costOfChange]. Among the matches
+// found within MaxTries steps, the one with the smallest excess wins.
+// It fails when no such selection exists, so it is usually combined with a
+// change-producing strategy through Fallback.
+type BranchAndBound struct {
+	MaxTries int
+}
+
+func (s BranchAndBound) Select(values []int64, target int64, costOfChange int64) ([]int, bool) {
+	pool := positiveIndices(values)
+	sort.SliceStable(pool, func(i, j int) bool {
+		return values[pool[i]] > values[pool[j]]
+	})
+
+	var remaining int64
+	for _, idx := range pool {
+		remaining += values[idx]
+	}
+	if remaining < target {
+		return nil, false
+	}
+
+	maxTries := s.MaxTries
+	if maxTries <= 0 {
+		maxTries = defaultBnBTries
+	}
+
+	var (
+		current   int64
+		pos       int
+		selection []int // positions in pool
+		best      []int
+		bestWaste int64 = math.MaxInt64
+	)
+	for try := 0; try < maxTries; try++ {
+		backtrack := false
+		switch {
+		case current+remaining < target || current > target+costOfChange:
+			backtrack = true
+		case current >= target:
+			if waste := current - target; waste < bestWaste {
+				bestWaste = waste
+				best = slices.Clone(selection)
+			}
+			backtrack = true
+		}
+		if bestWaste == 0 {
+			// an exact match cannot be improved upon
+			break
+		}
+
+		if backtrack {
+			if len(selection) == 0 {
+				break
+			}
+			// Give back every candidate omitted after the last inclusion,
+			// then turn that inclusion into an omission.
+			last := selection[len(selection)-1]
+			for p := pos - 1; p > last; p-- {
+				remaining += values[pool[p]]
+			}
+			selection = selection[:len(selection)-1]
+			current -= values[pool[last]]
+			pos = last + 1
+			continue
+		}
+
+		value := values[pool[pos]]
+		remaining -= value
+		// Including a candidate equal to one we just omitted explores the
+		// same totals again, so skip straight to its omission branch.
+		if pos > 0 && value == values[pool[pos-1]] &&
+			(len(selection) == 0 || selection[len(selection)-1] != pos-1) {
+			pos++
+			continue
+		}
+		selection = append(selection, pos)
+		current += value
+		pos++
+	}
+
+	if best == nil {
+		return nil, false
+	}
+	selected := make([]int, len(best))
+	for i, p := range best {
+		selected[i] = pool[p]
+	}
+	return selected, true
+}
+
+// -----------------------------------------------------------------------------
+// SingleRandomDraw
+// -----------------------------------------------------------------------------
+
+// SingleRandomDraw adds candidates in random order until they cover target
+// plus the cost of a change output.
+type SingleRandomDraw struct {
+	Rand *rand.Rand
+}
+
+func (s SingleRandomDraw) Select(values []int64, target int64, costOfChange int64) ([]int, bool) {
+	pool := positiveIndices(values)
+	shuffle(s.Rand, pool)
+
+	var total int64
+	for i, idx := range pool {
+		total += values[idx]
+		if total >= target+costOfChange {
+			return pool[:i+1], true
+		}
+	}
+	return nil, false
+}
+
+// -----------------------------------------------------------------------------
+// Knapsack
+// -----------------------------------------------------------------------------
+
+const defaultKnapsackIterations = 1000
+
+// Knapsack is the stochastic subset-sum approximation Bitcoin Core used
+// before branch and bound: it looks for the smallest total reaching target
+// exactly or leaving at least costOfChange for a change output, falling back
+// to the single smallest candidate larger than that.
+type Knapsack struct {
+	Iterations int
+	Rand       *rand.Rand
+}
+
+func (s Knapsack) Select(values []int64, target int64, costOfChange int64) ([]int, bool) {
+	pool := positiveIndices(values)
+	shuffle(s.Rand, pool)
+
+	lowestLarger := -1
+	var lower []int
+	var totalLower int64
+	for _, idx := range pool {
+		switch v := values[idx]; {
+		case v == target:
+			return []int{idx}, true
+		case v < target+costOfChange:
+			lower = append(lower, idx)
+			totalLower += v
+		case lowestLarger < 0 || v < values[lowestLarger]:
+			lowestLarger = idx
+		}
+	}
+
+	if totalLower == target {
+		return lower, true
+	}
+	if totalLower < target {
+		if lowestLarger < 0 {
+			return nil, false
+		}
+		return []int{lowestLarger}, true
+	}
+
+	sort.SliceStable(lower, func(i, j int) bool {
+		return values[lower[i]] > values[lower[j]]
+	})
+
+	iterations := s.Iterations
+	if iterations <= 0 {
+		iterations = defaultKnapsackIterations
+	}
+	chosen, best := approximateBestSubset(s.Rand, values, lower, totalLower, target, iterations)
+	if best != target && totalLower >= target+costOfChange {
+		chosen, best = approximateBestSubset(s.Rand, values, lower, totalLower, target+costOfChange, iterations)
+	}
+
+	if lowestLarger >= 0 &&
+		((best != target && best < target+costOfChange) || values[lowestLarger] <= best) {
+		return []int{lowestLarger}, true
+	}
+
+	selected := make([]int, 0, len(lower))
+	for i, idx := range lower {
+		if chosen[i] {
+			selected = append(selected, idx)
+		}
+	}
+	return selected, true
+}
+
+func approximateBestSubset(r *rand.Rand, values []int64, pool []int, totalLower int64, target int64, iterations int) ([]bool, int64) {
+	best := make([]bool, len(pool))
+	for i := range best {
+		best[i] = true
+	}
+	bestTotal := totalLower
+
+	included := make([]bool, len(pool))
+	for rep := 0; rep < iterations && bestTotal != target; rep++ {
+		clear(included)
+		var total int64
+		reached := false
+		for pass := 0; pass < 2 && !reached; pass++ {
+			for i, idx := range pool {
+				// The first pass picks at random, the second one fills in
+				// everything left out by the first.
+				pick := !included[i]
+				if pass == 0 {
+					pick = randBool(r)
+				}
+				if !pick {
+					continue
+				}
+				total += values[idx]
+				included[i] = true
+				if total >= target {
+					reached = true
+					if total < bestTotal {
+						bestTotal = total
+						copy(best, included)
+					}
+					total -= values[idx]
+					included[i] = false
+				}
+			}
+		}
+	}
+	return best, bestTotal
+}
+
+// -----------------------------------------------------------------------------
+// Fallback
+// -----------------------------------------------------------------------------
+
+// Fallback tries each selector in order and returns the first success, e.g.
+// Fallback{BranchAndBound{}, Knapsack{}} prefers a changeless result.
+type Fallback []CoinSelector
+
+func (f Fallback) Select(values []int64, target int64, costOfChange int64) ([]int, bool) {
+	for _, s := range f {
+		if selected, ok := s.Select(values, target, costOfChange); ok {
+			return selected, true
+		}
+	}
+	return nil, false
+}
+
+// -----------------------------------------------------------------------------
+// helpers
+// -----------------------------------------------------------------------------
+
+// positiveIndices drops candidates that cost more to spend than they are worth.
+func positiveIndices(values []int64) []int {
+	indices := make([]int, 0, len(values))
+	for i, v := range values {
+		if v > 0 {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+func shuffle(r *rand.Rand, s []int) {
+	if r == nil {
+		rand.Shuffle(len(s), func(i, j int) { s[i], s[j] = s[j], s[i] })
+		return
+	}
+	r.Shuffle(len(s), func(i, j int) { s[i], s[j] = s[j], s[i] })
+}
+
+func randBool(r *rand.Rand) bool {
+	if r == nil {
+		return rand.IntN(2) == 1
+	}
+	return r.IntN(2) == 1
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sumOf(values []int64, indices []int) int64 {
+	var total int64
+	for _, i := range indices {
+		total += values[i]
+	}
+	return total
+}
+
+func TestBranchAndBound(t *testing.T) {
+	values := []int64{1000, 2000, 5000, 7000, 11000}
+
+	// 2000 + 5000 hits the target exactly
+	selected, ok := BranchAndBound{}.Select(values, 7000-1000, 0)
+	require.True(t, ok)
+	require.Equal(t, int64(6000), sumOf(values, selected))
+
+	// within the cost of change window
+	selected, ok = BranchAndBound{}.Select(values, 8500, 600)
+	require.True(t, ok)
+	total := sumOf(values, selected)
+	require.GreaterOrEqual(t, total, int64(8500))
+	require.LessOrEqual(t, total, int64(9100))
+
+	// no changeless solution
+	_, ok = BranchAndBound{}.Select(values, 500, 100)
+	require.False(t, ok)
+
+	// insufficient funds
+	_, ok = BranchAndBound{}.Select(values, 30000, 1000)
+	require.False(t, ok)
+
+	// negative effective values are never picked
+	selected, ok = BranchAndBound{}.Select([]int64{-10, 300, 700}, 1000, 0)
+	require.True(t, ok)
+	require.ElementsMatch(t, []int{1, 2}, selected)
+}
+
+func TestSingleRandomDraw(t *testing.T) {
+	values := []int64{1000, 2000, 5000, 7000, 11000}
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 20 {
+		selected, ok := SingleRandomDraw{Rand: r}.Select(values, 9000, 500)
+		require.True(t, ok)
+		require.GreaterOrEqual(t, sumOf(values, selected), int64(9500))
+	}
+
+	_, ok := SingleRandomDraw{Rand: r}.Select(values, 26000, 500)
+	require.False(t, ok)
+}
+
+func TestKnapsack(t *testing.T) {
+	r := rand.New(rand.NewPCG(3, 4))
+
+	// exact single match
+	values := []int64{1000, 2000, 5000, 7000, 11000}
+	selected, ok := Knapsack{Rand: r}.Select(values, 7000, 500)
+	require.True(t, ok)
+	require.Equal(t, []int{3}, selected)
+
+	// smaller ones sum up exactly
+	selected, ok = Knapsack{Rand: r}.Select([]int64{100, 200, 300, 10000}, 600, 50)
+	require.True(t, ok)
+	require.Equal(t, int64(600), sumOf([]int64{100, 200, 300, 10000}, selected))
+
+	// leaves room for change with the smallest possible total
+	values = []int64{400, 400, 400, 2000, 5000}
+	selected, ok = Knapsack{Rand: r}.Select(values, 1100, 1000)
+	require.True(t, ok)
+	require.Equal(t, int64(2400), sumOf(values, selected))
+
+	// the lowest larger candidate beats any combination of smaller ones
+	values = []int64{300, 300, 300, 1500}
+	selected, ok = Knapsack{Rand: r}.Select(values, 800, 500)
+	require.True(t, ok)
+	require.Equal(t, []int{3}, selected)
+
+	_, ok = Knapsack{Rand: r}.Select(values, 9000, 0)
+	require.False(t, ok)
+}
+
+func TestFallback(t *testing.T) {
+	values := []int64{1000, 2000, 5000, 7000, 11000}
+	selector := Fallback{BranchAndBound{}, LargestFirst{}}
+
+	selected, ok := selector.Select(values, 8000, 0)
+	require.True(t, ok)
+	require.Equal(t, int64(8000), sumOf(values, selected))
+
+	selected, ok = selector.Select(values, 500, 100)
+	require.True(t, ok)
+	require.Equal(t, []int{4}, selected)
+}
+
+func TestSelectWith(t *testing.T) {
+	type coin struct{ value int64 }
+	coins := []*coin{{1000}, {2000}, {5000}}
+
+	selected, unselected, ok := SelectWith(BranchAndBound{}, coins, 3000, 0, func(c *coin) int64 {
+		return c.value
+	})
+	require.True(t, ok)
+	require.ElementsMatch(t, []*coin{coins[0], coins[1]}, selected)
+	require.Equal(t, []*coin{coins[2]}, unselected)
+}